@@ -68,6 +68,24 @@ const (
 	LogglyT                 = "LOGGLY_TOKEN"
 	LogglySubdomain         = "LOGGLY_SUBDOMAIN"
 	DemoEmail               = "DEMO_EMAIL"
+	WaitlistOfferWindow     = "WAITLIST_OFFER_WINDOW"
+	NoShowGracePeriod       = "NO_SHOW_GRACE_PERIOD"
+	NoShowSweepInterval     = "NO_SHOW_SWEEP_INTERVAL"
+	RSVPBaseURL             = "RSVP_BASE_URL"
+	RSVPLinkExpiry          = "RSVP_LINK_EXPIRY"
+	QRRotationInterval      = "QR_ROTATION_INTERVAL"
+	BookNowMaxDuration      = "BOOK_NOW_MAX_DURATION"
+	NotificationWorkers     = "NOTIFICATION_WORKERS"
+	NotificationMaxAttempts = "NOTIFICATION_MAX_ATTEMPTS"
+	NotificationRetryBase   = "NOTIFICATION_RETRY_BASE"
+	NotificationStaleAfter  = "NOTIFICATION_STALE_AFTER"
+	VAPIDPublicKey          = "VAPID_PUBLIC_KEY"
+	VAPIDPrivateKey         = "VAPID_PRIVATE_KEY"
+	VAPIDSubject            = "VAPID_SUBJECT"
+	WebhookTimeout          = "WEBHOOK_TIMEOUT"
+	ReceiptPollInterval     = "RECEIPT_POLL_INTERVAL"
+	ExpoReceiptsURL         = "EXPO_RECEIPTS_URL"
+	DeviceActiveDays        = "DEVICE_ACTIVE_DAYS"
 )
 
 // init viper
@@ -603,3 +621,159 @@ func GetDemoEmail() string {
 	}
 	return email
 }
+
+// gets the time a waitlisted user has to accept an offered slot as defined in the config.yaml file in seconds
+func GetWaitlistOfferWindow() int {
+	window := viper.GetInt(WaitlistOfferWindow)
+	if window == 0 {
+		window = 900
+	}
+	return window
+}
+
+// gets how long after a booking starts it is released if nobody checked in as defined in the config.yaml file in seconds
+func GetNoShowGracePeriod() int {
+	period := viper.GetInt(NoShowGracePeriod)
+	if period == 0 {
+		period = 900
+	}
+	return period
+}
+
+// gets how often the no-show sweeper runs as defined in the config.yaml file in seconds
+func GetNoShowSweepInterval() int {
+	interval := viper.GetInt(NoShowSweepInterval)
+	if interval == 0 {
+		interval = 60
+	}
+	return interval
+}
+
+// gets the base url of the rsvp links sent in booking emails as defined in the config.yaml file
+func GetRSVPBaseURL() string {
+	url := viper.GetString(RSVPBaseURL)
+	if url == "" {
+		url = "https://dev.occupi.tech"
+	}
+	return url
+}
+
+// gets how long the rsvp links sent in booking emails can be used for as defined in the config.yaml file in seconds
+func GetRSVPLinkExpiry() int {
+	expiry := viper.GetInt(RSVPLinkExpiry)
+	if expiry <= 0 {
+		expiry = 30 * 24 * 60 * 60
+	}
+	return expiry
+}
+
+// gets how often the qr codes shown on room doors change as defined in the config.yaml file in seconds
+func GetQRRotationInterval() int {
+	interval := viper.GetInt(QRRotationInterval)
+	if interval <= 0 {
+		interval = 60
+	}
+	return interval
+}
+
+// gets the longest a room can be booked on the spot for as defined in the config.yaml file in seconds
+func GetBookNowMaxDuration() int {
+	duration := viper.GetInt(BookNowMaxDuration)
+	if duration <= 0 {
+		duration = 3600
+	}
+	return duration
+}
+
+// gets how many notifications are sent at the same time as defined in the config.yaml file
+func GetNotificationWorkers() int {
+	workers := viper.GetInt(NotificationWorkers)
+	if workers <= 0 {
+		workers = 10
+	}
+	return workers
+}
+
+// gets how many times a notification is sent before it is dead-lettered as defined in the config.yaml file
+func GetNotificationMaxAttempts() int {
+	attempts := viper.GetInt(NotificationMaxAttempts)
+	if attempts <= 0 {
+		attempts = 5
+	}
+	return attempts
+}
+
+// gets how long the first retry of a failed notification waits as defined in the config.yaml file in seconds,
+// every later retry waits twice as long as the one before
+func GetNotificationRetryBase() int {
+	base := viper.GetInt(NotificationRetryBase)
+	if base <= 0 {
+		base = 30
+	}
+	return base
+}
+
+// gets how long after its send time a notification is still sent as defined in the config.yaml file in seconds,
+// notifications that are found later than this are dead-lettered instead
+func GetNotificationStaleAfter() int {
+	staleAfter := viper.GetInt(NotificationStaleAfter)
+	if staleAfter <= 0 {
+		staleAfter = 3600
+	}
+	return staleAfter
+}
+
+// gets the public key browsers use to subscribe to web push notifications as defined in the config.yaml file
+func GetVAPIDPublicKey() string {
+	return viper.GetString(VAPIDPublicKey)
+}
+
+// gets the private key web push notifications are signed with as defined in the config.yaml file
+func GetVAPIDPrivateKey() string {
+	return viper.GetString(VAPIDPrivateKey)
+}
+
+// gets the contact push services can reach the sender of web push notifications on as defined in the config.yaml file
+func GetVAPIDSubject() string {
+	subject := viper.GetString(VAPIDSubject)
+	if subject == "" {
+		subject = "mailto:" + GetSystemEmail()
+	}
+	return subject
+}
+
+// gets how long a notification webhook has to respond as defined in the config.yaml file in seconds
+func GetWebhookTimeout() int {
+	timeout := viper.GetInt(WebhookTimeout)
+	if timeout <= 0 {
+		timeout = 5
+	}
+	return timeout
+}
+
+// gets how often the receipts of sent push notifications are checked as defined in the config.yaml file in seconds
+func GetReceiptPollInterval() int {
+	interval := viper.GetInt(ReceiptPollInterval)
+	if interval <= 0 {
+		interval = 900
+	}
+	return interval
+}
+
+// gets the url expo push receipts are fetched from as defined in the config.yaml file
+func GetExpoReceiptsURL() string {
+	url := viper.GetString(ExpoReceiptsURL)
+	if url == "" {
+		url = "https://exp.host/--/api/v2/push/getReceipts"
+	}
+	return url
+}
+
+// gets how many days a device keeps getting push notifications after it was last seen as defined in the config.yaml file
+func GetDeviceActiveDays() int {
+	days := viper.GetInt(DeviceActiveDays)
+	if days <= 0 {
+		days = 90
+	}
+	return days
+}
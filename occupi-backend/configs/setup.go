@@ -228,14 +228,26 @@ func CreateRabbitChannel(conn *amqp.Connection) *amqp.Channel {
 		logrus.Fatal(err)
 	}
 
+	// publisher confirms let messages be acknowledged only once what they were moved to has been confirmed
+	if err := ch.Confirm(false); err != nil {
+		logrus.Fatal(err)
+	}
+
 	return ch
 }
 
+// the delay queues notifications wait in until they are due, in seconds, every message in a queue waits
+// the same time so they expire in the order they were published
+var NotificationDelaySteps = []int{60, 600, 3600}
+
+// the queue notifications were published to before it was made durable, messages left in it are moved over on startup
+const LegacyNotificationQueue = "notification_queue"
+
 func CreateRabbitQueue(ch *amqp.Channel) amqp.Queue {
-	// Declare a queue
+	// Declare a durable queue so scheduled notifications survive a restart of the broker
 	q, err := ch.QueueDeclare(
-		"notification_queue",
-		false,
+		"notifications",
+		true,
 		false,
 		false,
 		false,
@@ -245,9 +257,33 @@ func CreateRabbitQueue(ch *amqp.Channel) amqp.Queue {
 		logrus.Fatal(err)
 	}
 
+	// messages in a delay queue are dead-lettered back to the notification queue once they expire
+	for _, step := range NotificationDelaySteps {
+		_, err := ch.QueueDeclare(
+			NotificationDelayQueue(q.Name, step),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl":             int32(step * 1000),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": q.Name,
+			},
+		)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
 	return q
 }
 
+// the name of the delay queue of a notification queue that holds messages for step seconds
+func NotificationDelayQueue(queue string, step int) string {
+	return fmt.Sprintf("%s.delay.%ds", queue, step)
+}
+
 func CreateWebAuthnInstance() *webauthn.WebAuthn {
 	// WebAuthn parameters
 	rpID := GetRPID()
@@ -30,7 +30,7 @@ func CreateOfficeHoursMatchFilter(email string, filter models.AnalyticsFilterStr
 		matchFilter = append(matchFilter, bson.E{Key: "entered", Value: timeRangeFilter})
 	}
 
-	return matchFilter
+	return AppendLocationFilter(matchFilter, filter)
 }
 
 func CreateBookingMatchFilter(creatorEmail string, attendeesEmail []string, filter models.AnalyticsFilterStruct, dateFilter string) bson.D {
@@ -64,6 +64,16 @@ func CreateBookingMatchFilter(creatorEmail string, attendeesEmail []string, filt
 		matchFilter = append(matchFilter, bson.E{Key: dateFilter, Value: timeRangeFilter})
 	}
 
+	return AppendLocationFilter(matchFilter, filter)
+}
+
+// AppendLocationFilter conditionally limits the match filter to a site, building and floor
+func AppendLocationFilter(matchFilter bson.D, filter models.AnalyticsFilterStruct) bson.D {
+	for _, key := range []string{"siteId", "buildingId", "floorNo"} {
+		if value, ok := filter.Filter[key].(string); ok && value != "" {
+			matchFilter = append(matchFilter, bson.E{Key: key, Value: bson.D{{Key: "$eq", Value: value}}})
+		}
+	}
 	return matchFilter
 }
 
@@ -1121,6 +1131,196 @@ func AggregateBookings(creatorEmail string, attendeeEmails []string, filter mode
 	}
 }
 
+func AggregateNoShowsByUser(creatorEmail string, attendeeEmails []string, filter models.AnalyticsFilterStruct, dateFilter string) bson.A {
+	// Create the match filter using the reusable function
+	matchFilter := CreateBookingMatchFilter(creatorEmail, attendeeEmails, filter, dateFilter)
+	return bson.A{
+		// Stage 1: Match filter conditions (email and time range)
+		bson.D{{Key: "$match", Value: matchFilter}},
+		// Stage 2: Group by the creator to count their no-shows
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$creator"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "lastNoShow", Value: bson.D{{Key: "$max", Value: "$start"}}},
+		}}},
+		// Stage 3: Rename the creator
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "email", Value: "$_id"},
+			{Key: "count", Value: "$count"},
+			{Key: "lastNoShow", Value: "$lastNoShow"},
+		}}},
+		// Stage 4: Sort by count
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}}}},
+		// Stage 5: Apply skip for pagination
+		bson.D{{Key: "$skip", Value: filter.Skip}},
+		// Stage 6: Apply limit for pagination
+		bson.D{{Key: "$limit", Value: filter.Limit}},
+	}
+}
+
+// countResponses counts the invitees of a booking that gave a response, the creator does not respond to their own booking
+func countResponses(response string) bson.D {
+	return bson.D{{Key: "$size", Value: bson.D{{Key: "$filter", Value: bson.D{
+		{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$attendees", bson.A{}}}}},
+		{Key: "as", Value: "attendee"},
+		{Key: "cond", Value: bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$$attendee.response", response}}},
+			bson.D{{Key: "$ne", Value: bson.A{"$$attendee.email", "$creator"}}},
+		}}}},
+	}}}}}
+}
+
+// countCheckIns counts the people that checked in to a booking, bookings checked in before attendees checked in
+// on their own only count their creator
+func countCheckIns() bson.D {
+	checkIns := bson.D{{Key: "$size", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$checkIns", bson.A{}}}}}}
+	return bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$gt", Value: bson.A{checkIns, 0}}},
+		checkIns,
+		bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: bson.A{"$checkedIn", true}}}, 1, 0}}},
+	}}}
+}
+
+// safeRatio divides two fields, giving 0 when there is nothing to divide by
+func safeRatio(numerator interface{}, denominator interface{}) bson.D {
+	return bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$gt", Value: bson.A{denominator, 0}}},
+		bson.D{{Key: "$divide", Value: bson.A{numerator, denominator}}},
+		0,
+	}}}
+}
+
+// AggregateRSVPsByRoom function to calculate how invitations to each room's bookings were answered and how many
+// people checked in to them
+func AggregateRSVPsByRoom(creatorEmail string, attendeeEmails []string, filter models.AnalyticsFilterStruct, dateFilter string) bson.A {
+	// Create the match filter using the reusable function
+	matchFilter := CreateBookingMatchFilter(creatorEmail, attendeeEmails, filter, dateFilter)
+	return bson.A{
+		// Stage 1: Match filter conditions (email and time range)
+		bson.D{{Key: "$match", Value: matchFilter}},
+		// Stage 2: Count the invitees and responses of each booking
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "roomId", Value: "$roomId"},
+			{Key: "roomName", Value: "$roomName"},
+			{Key: "floorNo", Value: "$floorNo"},
+			{Key: "checkedIn", Value: bson.D{{Key: "$cond", Value: bson.A{"$checkedIn", 1, 0}}}},
+			{Key: "invited", Value: bson.D{{Key: "$size", Value: bson.D{{Key: "$filter", Value: bson.D{
+				{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$emails", bson.A{}}}}},
+				{Key: "as", Value: "email"},
+				{Key: "cond", Value: bson.D{{Key: "$ne", Value: bson.A{"$$email", "$creator"}}}},
+			}}}}}},
+			{Key: "accepted", Value: countResponses("accepted")},
+			{Key: "declined", Value: countResponses("declined")},
+			{Key: "tentative", Value: countResponses("tentative")},
+			{Key: "attended", Value: countCheckIns()},
+		}}},
+		// Stage 3: Group by the room ID to total the counts
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$roomId"},
+			{Key: "roomName", Value: bson.D{{Key: "$first", Value: "$roomName"}}},
+			{Key: "floorNo", Value: bson.D{{Key: "$first", Value: "$floorNo"}}},
+			{Key: "bookings", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "checkedIn", Value: bson.D{{Key: "$sum", Value: "$checkedIn"}}},
+			{Key: "invited", Value: bson.D{{Key: "$sum", Value: "$invited"}}},
+			{Key: "accepted", Value: bson.D{{Key: "$sum", Value: "$accepted"}}},
+			{Key: "declined", Value: bson.D{{Key: "$sum", Value: "$declined"}}},
+			{Key: "tentative", Value: bson.D{{Key: "$sum", Value: "$tentative"}}},
+			{Key: "attended", Value: bson.D{{Key: "$sum", Value: "$attended"}}},
+		}}},
+		// Stage 4: Calculate the rates, everyone invited to a booking includes its creator
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "roomId", Value: "$_id"},
+			{Key: "roomName", Value: "$roomName"},
+			{Key: "floorNo", Value: "$floorNo"},
+			{Key: "bookings", Value: "$bookings"},
+			{Key: "checkedIn", Value: "$checkedIn"},
+			{Key: "invited", Value: "$invited"},
+			{Key: "accepted", Value: "$accepted"},
+			{Key: "declined", Value: "$declined"},
+			{Key: "tentative", Value: "$tentative"},
+			{Key: "noResponse", Value: bson.D{{Key: "$subtract", Value: bson.A{"$invited", bson.D{{Key: "$add", Value: bson.A{"$accepted", "$declined", "$tentative"}}}}}}},
+			{Key: "acceptanceRate", Value: safeRatio("$accepted", "$invited")},
+			{Key: "attended", Value: "$attended"},
+			{Key: "attendanceRate", Value: safeRatio("$attended", bson.D{{Key: "$add", Value: bson.A{"$invited", "$bookings"}}})},
+		}}},
+		// Stage 5: Sort by the number of invitees
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "invited", Value: -1}}}},
+		// Stage 6: Apply skip for pagination
+		bson.D{{Key: "$skip", Value: filter.Skip}},
+		// Stage 7: Apply limit for pagination
+		bson.D{{Key: "$limit", Value: filter.Limit}},
+	}
+}
+
+// AggregateOccupancyByRoom function to compare how many people checked in to each room's bookings with how many
+// were booked and how many the room holds, rooms with a low utilisation are larger than their meetings need
+func AggregateOccupancyByRoom(creatorEmail string, attendeeEmails []string, filter models.AnalyticsFilterStruct, dateFilter string) bson.A {
+	// Create the match filter using the reusable function
+	matchFilter := CreateBookingMatchFilter(creatorEmail, attendeeEmails, filter, dateFilter)
+	return bson.A{
+		// Stage 1: Match filter conditions (email and time range)
+		bson.D{{Key: "$match", Value: matchFilter}},
+		// Stage 2: Count the people booked and checked in for each booking, the creator is always booked
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "roomId", Value: "$roomId"},
+			{Key: "roomName", Value: "$roomName"},
+			{Key: "floorNo", Value: "$floorNo"},
+			{Key: "booked", Value: bson.D{{Key: "$size", Value: bson.D{{Key: "$setUnion", Value: bson.A{
+				bson.D{{Key: "$ifNull", Value: bson.A{"$emails", bson.A{}}}},
+				bson.A{"$creator"},
+			}}}}}},
+			{Key: "occupancy", Value: countCheckIns()},
+		}}},
+		// Stage 3: Group by the room ID to average the counts
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$roomId"},
+			{Key: "roomName", Value: bson.D{{Key: "$first", Value: "$roomName"}}},
+			{Key: "floorNo", Value: bson.D{{Key: "$first", Value: "$floorNo"}}},
+			{Key: "bookings", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "booked", Value: bson.D{{Key: "$sum", Value: "$booked"}}},
+			{Key: "occupancy", Value: bson.D{{Key: "$sum", Value: "$occupancy"}}},
+			{Key: "averageBooked", Value: bson.D{{Key: "$avg", Value: "$booked"}}},
+			{Key: "averageOccupancy", Value: bson.D{{Key: "$avg", Value: "$occupancy"}}},
+			{Key: "peakOccupancy", Value: bson.D{{Key: "$max", Value: "$occupancy"}}},
+		}}},
+		// Stage 4: Get the capacity of each room
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "Rooms"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "roomId"},
+			{Key: "as", Value: "room"},
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$room"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		// Stage 5: Calculate how much of what was booked and of the room was used
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "roomId", Value: "$_id"},
+			{Key: "roomName", Value: "$roomName"},
+			{Key: "floorNo", Value: "$floorNo"},
+			{Key: "maxOccupancy", Value: "$room.maxOccupancy"},
+			{Key: "bookings", Value: "$bookings"},
+			{Key: "booked", Value: "$booked"},
+			{Key: "occupancy", Value: "$occupancy"},
+			{Key: "averageBooked", Value: "$averageBooked"},
+			{Key: "averageOccupancy", Value: "$averageOccupancy"},
+			{Key: "peakOccupancy", Value: "$peakOccupancy"},
+			{Key: "attendanceRate", Value: safeRatio("$occupancy", "$booked")},
+			{Key: "utilisation", Value: safeRatio("$averageOccupancy", bson.D{{Key: "$ifNull", Value: bson.A{"$room.maxOccupancy", 0}}})},
+		}}},
+		// Stage 6: Sort by utilisation so the most over-sized rooms come first
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "utilisation", Value: 1}}}},
+		// Stage 7: Apply skip for pagination
+		bson.D{{Key: "$skip", Value: filter.Skip}},
+		// Stage 8: Apply limit for pagination
+		bson.D{{Key: "$limit", Value: filter.Limit}},
+	}
+}
+
 func GetUsersLocationsPipeLine(limit int64, skip int64, order string, email string) bson.A {
 	// Create a match filter
 	matchFilter := bson.D{}
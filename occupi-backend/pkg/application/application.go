@@ -85,6 +85,8 @@ func (app *Application) CreateAppSession() *Application {
 
 func (app *Application) StartConsumer() *Application {
 	go receiver.StartConsumeMessage(app.appsession)
+	go receiver.StartNoShowSweeper(app.appsession)
+	go receiver.StartReceiptPoller(app.appsession)
 	return app
 }
 
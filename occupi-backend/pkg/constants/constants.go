@@ -33,4 +33,82 @@ const (
 	LowWidth                  = 600
 	MidWidth                  = 1200
 	HighWidth                 = 2000
+	Daily                     = "daily"
+	Weekly                    = "weekly"
+	Monthly                   = "monthly"
+	MaxRecurringOccurrences   = 100
+	ThisOccurrence            = "occurrence"
+	ThisAndFollowing          = "following"
+	WholeSeries               = "series"
+	RoomCapacityExceededCode  = "ROOM_CAPACITY_EXCEEDED"
+	RoomUnderOccupiedCode     = "ROOM_UNDER_OCCUPIED"
+	WaitlistWaiting           = "waiting"
+	WaitlistOffered           = "offered"
+	WaitlistAccepted          = "accepted"
+	WaitlistExpired           = "expired"
+	DefaultOpeningTime        = "08:00"
+	DefaultClosingTime        = "17:00"
+	OutsideBusinessHoursCode  = "OUTSIDE_BUSINESS_HOURS"
+	SlotMisalignedCode        = "SLOT_MISALIGNED"
+	BlackoutPeriodCode        = "BLACKOUT_PERIOD"
+	MaxRoomSearchDays         = 7
+	RoomActive                = "active"
+	RoomInactive              = "inactive"
+	RoomArchived              = "archived"
+	RoomUnavailableCode       = "ROOM_UNAVAILABLE"
+	DeskMorning               = "morning"
+	DeskAfternoon             = "afternoon"
+	DeskFullDay               = "fullday"
+	DeskMiddayTime            = "12:00"
+	UsualDeskDays             = 30
+	RoomCheckIn               = "room"
+	DeskCheckIn               = "desk"
+	ICalendarRequest          = "REQUEST"
+	ICalendarCancel           = "CANCEL"
+	ICalendarPublish          = "PUBLISH"
+	ICalendarProdID           = "-//Occupi//Occupi Bookings//EN"
+	CalendarFeedDays          = 90
+	CalDAVPastDays            = 30
+	BookingPending            = "pending"
+	BookingConfirmed          = "confirmed"
+	RSVPAccepted              = "accepted"
+	RSVPDeclined              = "declined"
+	RSVPTentative             = "tentative"
+	RSVPNeedsAction           = "needs-action"
+	CheckInApp                = "app"
+	CheckInQR                 = "qr"
+	CheckInNFC                = "nfc"
+	CheckInBLE                = "ble"
+	QRCheckInEarlyMinutes     = 15
+	PolicyScopeRole           = "role"
+	PolicyScopeDepartment     = "department"
+	PolicyScopeRoom           = "room"
+	PolicyMaxDurationCode     = "POLICY_MAX_DURATION"
+	PolicyAdvanceWindowCode   = "POLICY_ADVANCE_WINDOW"
+	PolicyActiveBookingsCode  = "POLICY_ACTIVE_BOOKINGS"
+	PolicyWeeklyQuotaCode     = "POLICY_WEEKLY_QUOTA"
+	NotificationMsgVersion    = 1
+	NotificationMsgType       = "scheduledNotification"
+	NotificationContentType   = "application/json"
+	LegacyNotificationFields  = 8
+	NotificationAttemptHeader = "x-attempt"
+	NotificationNotBefore     = "x-not-before"
+	NotificationInvite        = "invite"
+	NotificationReminder      = "bookingReminder"
+	NotificationWaitlist      = "waitlistOffer"
+	NotificationReleased      = "bookingReleased"
+	NotificationUpdate        = "bookingUpdate"
+	ChannelPush               = "push"
+	ChannelEmail              = "email"
+	ChannelWebhook            = "webhook"
+	ChannelWebPush            = "webPush"
+	DeliverySent              = "sent"
+	DeliveryFailed            = "failed"
+	DeliveryRetrying          = "retrying"
+	DeliverySkipped           = "skipped"
+	TicketPending             = "pending"
+	TicketDelivered           = "delivered"
+	TicketFailed              = "failed"
+	TicketExpired             = "expired"
+	ExpoReceiptBatch          = 1000
 )
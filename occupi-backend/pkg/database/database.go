@@ -59,7 +59,7 @@ func GetAllData(ctx *gin.Context, appsession *models.AppSession) []bson.M {
 	return users
 }
 
-func CheckCoincidingBookings(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) (bool, error) {
+func CheckCoincidingBookings(ctx context.Context, appsession *models.AppSession, booking models.Booking) (bool, error) {
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return false, errors.New("database is nil")
@@ -76,6 +76,11 @@ func CheckCoincidingBookings(ctx *gin.Context, appsession *models.AppSession, bo
 		},
 	}
 
+	// an existing booking should not coincide with itself
+	if booking.OccupiID != "" {
+		filter["occupiId"] = bson.M{"$ne": booking.OccupiID}
+	}
+
 	var existingbooking models.Booking
 	err := collection.FindOne(ctx, filter).Decode(&existingbooking)
 	if err != nil {
@@ -89,6 +94,90 @@ func CheckCoincidingBookings(ctx *gin.Context, appsession *models.AppSession, bo
 	return true, nil
 }
 
+// checks every occurrence of a recurring booking against existing bookings and returns the dates that coincide
+func CheckCoincidingOccurrences(ctx *gin.Context, appsession *models.AppSession, occurrences []models.Booking) ([]time.Time, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	if len(occurrences) == 0 {
+		return []time.Time{}, nil
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	// build a single query that matches any booking overlapping any of the occurrences
+	overlaps := make([]bson.M, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		overlaps = append(overlaps, bson.M{
+			"start": bson.M{"$lt": occurrence.End},
+			"end":   bson.M{"$gt": occurrence.Start},
+		})
+	}
+
+	filter := bson.M{
+		"roomId": occurrences[0].RoomID,
+		"$or":    overlaps,
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var existingBookings []models.Booking
+	if err = cursor.All(ctx, &existingBookings); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return FindCoincidingOccurrences(occurrences, existingBookings), nil
+}
+
+// attempts to save a booking series and all of its occurrences in the database
+func SaveBookingSeries(ctx *gin.Context, appsession *models.AppSession, series models.BookingSeries, occurrences []models.Booking) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	seriesCollection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingSeries")
+	_, err := seriesCollection.InsertOne(ctx, series)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	documents := make([]interface{}, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		documents = append(documents, occurrence)
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+	_, err = collection.InsertMany(ctx, documents)
+	if err != nil {
+		logrus.Error(err)
+		// remove the series and any occurrences inserted before the error so no partial series is left behind
+		if _, rollbackErr := collection.DeleteMany(ctx, bson.M{"seriesId": series.SeriesID}); rollbackErr != nil {
+			logrus.Error("Failed to remove occurrences of booking series because: ", rollbackErr)
+		}
+		if _, rollbackErr := seriesCollection.DeleteOne(ctx, bson.M{"seriesId": series.SeriesID}); rollbackErr != nil {
+			logrus.Error("Failed to remove booking series because: ", rollbackErr)
+		}
+		return false, err
+	}
+
+	for _, occurrence := range occurrences {
+		cache.SetBooking(appsession, occurrence)
+	}
+
+	return true, nil
+}
+
 // attempts to save booking in database
 func SaveBooking(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) (bool, error) {
 	// check if database is nil
@@ -117,16 +206,25 @@ func ConfirmCheckIn(ctx *gin.Context, appsession *models.AppSession, checkIn mod
 		return false, errors.New("database is nil")
 	}
 
+	if checkIn.Type == constants.DeskCheckIn {
+		return ConfirmDeskCheckIn(ctx, appsession, checkIn)
+	}
+
 	// Save the check-in to the database
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
 
-	// Find the booking by bookingId, occupiId, and creator
-	filter := bson.M{
-		"occupiId": checkIn.BookingID,
-		"creator":  checkIn.Creator,
+	// every attendee checks in on their own, checking in again keeps the first check-in
+	filter := RoomCheckInFilter(checkIn.BookingID, checkIn.Creator)
+
+	attendee := models.AttendeeCheckIn{Email: checkIn.Creator, Method: checkIn.Method, CheckedInAt: time.Now()}
+	if attendee.Method == "" {
+		attendee.Method = constants.CheckInApp
 	}
 
-	update := bson.M{"$set": bson.M{"checkedIn": true}}
+	update := bson.M{
+		"$set":  bson.M{"checkedIn": true},
+		"$push": bson.M{"checkIns": attendee},
+	}
 
 	_, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -136,6 +234,9 @@ func ConfirmCheckIn(ctx *gin.Context, appsession *models.AppSession, checkIn mod
 
 	if booking, err := cache.GetBooking(appsession, checkIn.BookingID); err == nil {
 		booking.CheckedIn = true
+		if !utils.HasCheckedIn(booking, checkIn.Creator) {
+			booking.CheckIns = append(booking.CheckIns, attendee)
+		}
 		cache.SetBooking(appsession, booking)
 	}
 
@@ -507,6 +608,214 @@ func ConfirmCancellation(ctx *gin.Context, appsession *models.AppSession, id str
 	return true, nil
 }
 
+// gets a booking by its occupi id
+func GetBooking(ctx *gin.Context, appsession *models.AppSession, id string) (models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.Booking{}, errors.New("database is nil")
+	}
+
+	// check if the booking exists in the cache
+	if booking, err := cache.GetBooking(appsession, id); err == nil {
+		return booking, nil
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{"occupiId": id}
+	var booking models.Booking
+	err := collection.FindOne(ctx, filter).Decode(&booking)
+	if err != nil {
+		logrus.Error(err)
+		return models.Booking{}, err
+	}
+
+	cache.SetBooking(appsession, booking)
+
+	return booking, nil
+}
+
+// updates the room, time and attendees of an existing booking in a single write
+func UpdateBooking(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{"occupiId": booking.OccupiID, "creator": booking.Creator}
+	update := bson.M{"$set": bson.M{
+		"roomId":    booking.RoomID,
+		"roomName":  booking.RoomName,
+		"floorNo":   booking.FloorNo,
+		"emails":    booking.Emails,
+		"attendees": booking.Attendees,
+		"date":      booking.Date,
+		"start":     booking.Start,
+		"end":       booking.End,
+	}}
+
+	// bookings moved to a room outside of the hierarchy lose their site and building
+	if booking.SiteID != "" || booking.BuildingID != "" {
+		update["$set"].(bson.M)["siteId"] = booking.SiteID
+		update["$set"].(bson.M)["buildingId"] = booking.BuildingID
+	} else {
+		update["$unset"] = bson.M{"siteId": "", "buildingId": ""}
+	}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	if res.MatchedCount == 0 {
+		return false, errors.New("booking not found")
+	}
+
+	cache.SetBooking(appsession, booking)
+
+	return true, nil
+}
+
+// adds attendees to an existing booking
+func AddAttendeesToBooking(ctx *gin.Context, appsession *models.AppSession, id string, emails []string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{"occupiId": id}
+	update := bson.M{"$addToSet": bson.M{"emails": bson.M{"$each": emails}}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// the cached booking is stale so remove it
+	cache.DeleteBooking(appsession, id)
+
+	return nil
+}
+
+// adds an exception date to a booking series so the cancelled occurrence is not regenerated
+func AddSeriesException(ctx *gin.Context, appsession *models.AppSession, seriesID string, date time.Time) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingSeries")
+
+	filter := bson.M{"seriesId": seriesID}
+	update := bson.M{"$addToSet": bson.M{"recurrence.exceptions": date}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// cancels either the occurrences of a series starting from a date or the whole series
+func CancelBookingSeries(ctx *gin.Context, appsession *models.AppSession, seriesID string, email string, from time.Time, scope string) (int64, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return 0, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter, err := SeriesCancellationFilter(seriesID, email, from, scope)
+	if err != nil {
+		return 0, err
+	}
+
+	// find the occurrences first so they can be removed from the cache
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"occupiId": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+
+	var occurrences []models.Booking
+	if err = cursor.All(ctx, &occurrences); err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+
+	res, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		logrus.Error("Failed to cancel booking series:", err)
+		return 0, err
+	}
+
+	for _, occurrence := range occurrences {
+		cache.DeleteBooking(appsession, occurrence.OccupiID)
+	}
+
+	seriesCollection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingSeries")
+	seriesFilter := bson.M{"seriesId": seriesID, "creator": email}
+
+	if scope == constants.WholeSeries {
+		_, err = seriesCollection.DeleteOne(ctx, seriesFilter)
+	} else {
+		// end the series the day before the first cancelled occurrence
+		_, err = seriesCollection.UpdateOne(ctx, seriesFilter, bson.M{"$set": bson.M{
+			"recurrence.until": utils.StartOfDay(from).AddDate(0, 0, -1),
+			"recurrence.count": 0,
+		}})
+	}
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+// finds the occurrences of a series that cancelling with the scope would remove
+func FindSeriesOccurrences(ctx *gin.Context, appsession *models.AppSession, seriesID string, email string, from time.Time, scope string) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter, err := SeriesCancellationFilter(seriesID, email, from, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var occurrences []models.Booking
+	if err = cursor.All(ctx, &occurrences); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return occurrences, nil
+}
+
 // Get user information
 func GetUserDetails(ctx *gin.Context, appsession *models.AppSession, email string) (models.UserDetailsRequest, error) {
 	// check if database is nil
@@ -914,7 +1223,7 @@ func CheckIfUserIsLoggingInFromKnownLocation(ctx *gin.Context, appsession *model
 	return false, info, nil
 }
 
-func GetUsersPushTokens(ctx *gin.Context, appsession *models.AppSession, emails []string) ([]bson.M, error) {
+func GetUsersPushTokens(ctx context.Context, appsession *models.AppSession, emails []string) ([]bson.M, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
@@ -926,7 +1235,7 @@ func GetUsersPushTokens(ctx *gin.Context, appsession *models.AppSession, emails
 	}
 
 	findOptions := options.Find()
-	findOptions.SetProjection(bson.M{"expoPushToken": 1, "_id": 0})
+	findOptions.SetProjection(bson.M{"expoPushToken": 1, "devices": 1, "_id": 0})
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
@@ -938,15 +1247,25 @@ func GetUsersPushTokens(ctx *gin.Context, appsession *models.AppSession, emails
 		return nil, err
 	}
 
-	var results []bson.M
-	if err = cursor.All(ctx, &results); err != nil {
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
 		return nil, err
 	}
 
+	// every active device of a user gets the notification
+	activeSince := time.Now().AddDate(0, 0, -configs.GetDeviceActiveDays())
+
+	var results []bson.M
+	for _, user := range users {
+		for _, token := range utils.ActivePushTokens(user, activeSince) {
+			results = append(results, bson.M{"expoPushToken": token})
+		}
+	}
+
 	return results, nil
 }
 
-func AddNotification(ctx *gin.Context, appsession *models.AppSession, notification models.ScheduledNotification, pushNotification bool) (bool, error) {
+func AddNotification(ctx context.Context, appsession *models.AppSession, notification models.ScheduledNotification, pushNotification bool) (bool, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
@@ -1004,36 +1323,34 @@ func GetScheduledNotifications(ctx context.Context, appsession *models.AppSessio
 	return notifications, nil
 }
 
-func MarkNotificationAsSent(ctx context.Context, appsession *models.AppSession, notificationID string) error {
+// checks whether a notification is still waiting to be sent, rescheduled notifications are removed so they are not sent twice
+func IsNotificationScheduled(ctx context.Context, appsession *models.AppSession, notificationID string) bool {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return errors.New("database is nil")
+		return false
 	}
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
 
 	id, err := primitive.ObjectIDFromHex(notificationID)
-
 	if err != nil {
 		logrus.Error(err)
-		return err
+		return false
 	}
 
-	// update the notification to sent
-	filter := bson.M{"notificationId": id}
-	update := bson.M{"$set": bson.M{"sent": true}}
-
-	_, err = collection.UpdateOne(ctx, filter, update)
+	filter := bson.M{"_id": id, "sent": false}
+	count, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
 		logrus.Error(err)
-		return err
+		return false
 	}
 
-	return nil
+	return count > 0
 }
 
-func ReadNotifications(ctx *gin.Context, appsession *models.AppSession, email string) error {
+// deletes the unsent scheduled notifications of a booking
+func DeleteScheduledBookingNotifications(ctx *gin.Context, appsession *models.AppSession, bookingID string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
@@ -1042,12 +1359,8 @@ func ReadNotifications(ctx *gin.Context, appsession *models.AppSession, email st
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
 
-	// update many by removing this email from unreademails array for all notifications it is in
-	updateFilter := bson.M{"emails": bson.M{"$in": []string{email}}}
-
-	updateProjection := bson.M{"$pull": bson.M{"unreadEmails": email}}
-
-	_, err := collection.UpdateMany(ctx, updateFilter, updateProjection)
+	filter := bson.M{"bookingId": bookingID, "sent": false}
+	_, err := collection.DeleteMany(ctx, filter)
 	if err != nil {
 		logrus.Error(err)
 		return err
@@ -1056,7 +1369,7 @@ func ReadNotifications(ctx *gin.Context, appsession *models.AppSession, email st
 	return nil
 }
 
-func DeleteNotificationForUser(ctx *gin.Context, appsession *models.AppSession, request models.DeleteNotiRequest) error {
+func MarkNotificationAsSent(ctx context.Context, appsession *models.AppSession, notificationID string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
@@ -1065,12 +1378,18 @@ func DeleteNotificationForUser(ctx *gin.Context, appsession *models.AppSession,
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
 
-	// update this notification by removing this email from emails and unreademails array
-	updateFilter := bson.M{"notiId": request.NotiID}
+	id, err := primitive.ObjectIDFromHex(notificationID)
 
-	updateProjection := bson.M{"$pull": bson.M{"emails": request.Email, "unreadEmails": request.Email}}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
 
-	_, err := collection.UpdateOne(ctx, updateFilter, updateProjection)
+	// update the notification to sent
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"sent": true}}
+
+	_, err = collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		logrus.Error(err)
 		return err
@@ -1079,25 +1398,100 @@ func DeleteNotificationForUser(ctx *gin.Context, appsession *models.AppSession,
 	return nil
 }
 
-func GetSecuritySettings(ctx *gin.Context, appsession *models.AppSession, email string) (models.SecuritySettingsRequest, error) {
+// marks a notification as sent if it has not been sent yet, only the caller that claims a notification sends it
+// so a message that is delivered twice is only sent once
+func ClaimNotification(ctx context.Context, appsession *models.AppSession, notificationID string) (bool, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return models.SecuritySettingsRequest{}, errors.New("database is nil")
+		return false, errors.New("database is nil")
 	}
 
-	// check if user is in cache
-	if userData, err := cache.GetUser(appsession, email); err == nil {
-		var mfa string
-		if userData.Security.MFA {
-			mfa = constants.On
-		} else {
-			mfa = constants.Off
-		}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
 
-		var forceLogout string
-		if userData.Security.ForceLogout {
-			forceLogout = constants.On
+	id, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	filter := bson.M{"_id": id, "sent": false}
+	update := bson.M{"$set": bson.M{"sent": true}}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	return res.ModifiedCount > 0, nil
+}
+
+func ReadNotifications(ctx *gin.Context, appsession *models.AppSession, email string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
+
+	// update many by removing this email from unreademails array for all notifications it is in
+	updateFilter := bson.M{"emails": bson.M{"$in": []string{email}}}
+
+	updateProjection := bson.M{"$pull": bson.M{"unreadEmails": email}}
+
+	_, err := collection.UpdateMany(ctx, updateFilter, updateProjection)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func DeleteNotificationForUser(ctx *gin.Context, appsession *models.AppSession, request models.DeleteNotiRequest) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
+
+	// update this notification by removing this email from emails and unreademails array
+	updateFilter := bson.M{"notiId": request.NotiID}
+
+	updateProjection := bson.M{"$pull": bson.M{"emails": request.Email, "unreadEmails": request.Email}}
+
+	_, err := collection.UpdateOne(ctx, updateFilter, updateProjection)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func GetSecuritySettings(ctx *gin.Context, appsession *models.AppSession, email string) (models.SecuritySettingsRequest, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.SecuritySettingsRequest{}, errors.New("database is nil")
+	}
+
+	// check if user is in cache
+	if userData, err := cache.GetUser(appsession, email); err == nil {
+		var mfa string
+		if userData.Security.MFA {
+			mfa = constants.On
+		} else {
+			mfa = constants.Off
+		}
+
+		var forceLogout string
+		if userData.Security.ForceLogout {
+			forceLogout = constants.On
 		} else {
 			forceLogout = constants.Off
 		}
@@ -1232,6 +1626,8 @@ func GetNotificationSettings(ctx *gin.Context, appsession *models.AppSession, em
 			Email:           userData.Email,
 			Invites:         invites,
 			BookingReminder: bookingReminder,
+			Channels:        userData.Notifications.Channels,
+			WebhookURL:      webhookURLSetting(userData),
 		}, nil
 	}
 
@@ -1266,9 +1662,19 @@ func GetNotificationSettings(ctx *gin.Context, appsession *models.AppSession, em
 		Email:           user.Email,
 		Invites:         invites,
 		BookingReminder: bookingReminder,
+		Channels:        user.Notifications.Channels,
+		WebhookURL:      webhookURLSetting(user),
 	}, nil
 }
 
+// the webhook url in a user's notification settings, nil when they have not set one
+func webhookURLSetting(user models.User) *string {
+	if user.Notifications.WebhookURL == "" {
+		return nil
+	}
+	return &user.Notifications.WebhookURL
+}
+
 func UpdateNotificationSettings(ctx *gin.Context, appsession *models.AppSession, notificationSettings models.NotificationsRequest) error {
 	// check if database is nil
 	if appsession.DB == nil {
@@ -1308,6 +1714,20 @@ func UpdateNotificationSettings(ctx *gin.Context, appsession *models.AppSession,
 		}
 	}
 
+	if notificationSettings.Channels != nil {
+		update["$set"].(bson.M)["notifications.channels"] = notificationSettings.Channels
+		if cacheErr == nil {
+			userData.Notifications.Channels = notificationSettings.Channels
+		}
+	}
+
+	if notificationSettings.WebhookURL != nil {
+		update["$set"].(bson.M)["notifications.webhookUrl"] = *notificationSettings.WebhookURL
+		if cacheErr == nil {
+			userData.Notifications.WebhookURL = *notificationSettings.WebhookURL
+		}
+	}
+
 	_, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		logrus.Error(err)
@@ -1415,13 +1835,18 @@ func AddRoom(ctx *gin.Context, appsession *models.AppSession, rroom models.Reque
 	}
 
 	room := models.Room{
-		RoomID:       rroom.RoomID,
-		RoomNo:       rroom.RoomNo,
-		FloorNo:      rroom.FloorNo,
-		MinOccupancy: rroom.MinOccupancy,
-		MaxOccupancy: rroom.MaxOccupancy,
-		Description:  rroom.Description,
-		RoomName:     rroom.RoomName,
+		RoomID:           rroom.RoomID,
+		RoomNo:           rroom.RoomNo,
+		FloorNo:          rroom.FloorNo,
+		MinOccupancy:     rroom.MinOccupancy,
+		MaxOccupancy:     rroom.MaxOccupancy,
+		Description:      rroom.Description,
+		RoomName:         rroom.RoomName,
+		Amenities:        utils.NormalizeTags(rroom.Amenities),
+		Status:           constants.RoomActive,
+		RequiresApproval: rroom.RequiresApproval,
+		Approvers:        rroom.Approvers,
+		CheckInTokens:    rroom.CheckInTokens,
 		RoomImage: models.RoomImage{
 			UUID:         "",
 			ThumbnailRes: fmt.Sprintf("https://%s.blob.core.windows.net/%s/default-office-%s.png", configs.GetAzureAccountName(), configs.GetAzureRoomsContainerName(), constants.ThumbnailRes),
@@ -1447,6 +1872,13 @@ func AddRoom(ctx *gin.Context, appsession *models.AppSession, rroom models.Reque
 		return "", errors.New("room already exists")
 	}
 
+	if rroom.BuildingID != "" {
+		room, err = PlaceRoomInBuilding(ctx, appsession, room, rroom.BuildingID)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	res, err := collection.InsertOne(ctx, room)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to add room")
@@ -1456,354 +1888,1463 @@ func AddRoom(ctx *gin.Context, appsession *models.AppSession, rroom models.Reque
 	return res.InsertedID.(primitive.ObjectID).Hex(), nil
 }
 
-func GetUserCredentials(ctx *gin.Context, appsession *models.AppSession, email string) (webauthn.Credential, error) {
+// gets a room by its room id
+func GetRoom(ctx context.Context, appsession *models.AppSession, roomID string) (models.Room, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return webauthn.Credential{}, errors.New("database is nil")
+		return models.Room{}, errors.New("database is nil")
 	}
 
-	// check if user is in cache
-	if userData, err := cache.GetUser(appsession, email); err == nil {
-		return userData.Security.Credentials, nil
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+
+	filter := bson.M{"roomId": roomID}
+	var room models.Room
+	err := collection.FindOne(ctx, filter).Decode(&room)
+	if err != nil {
+		logrus.Error(err)
+		return models.Room{}, err
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	return room, nil
+}
 
-	filter := bson.M{"email": email}
-	var user models.User
-	err := collection.FindOne(ctx, filter).Decode(&user)
+// updates the details of a room, bookings keep the room details they were made with
+func UpdateRoom(ctx *gin.Context, appsession *models.AppSession, request models.RequestUpdateRoom) (models.Room, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.Room{}, errors.New("database is nil")
+	}
+
+	room, err := GetRoom(ctx, appsession, request.RoomID)
 	if err != nil {
-		return webauthn.Credential{}, err
+		if err == mongo.ErrNoDocuments {
+			return models.Room{}, errors.New("room not found")
+		}
+		return models.Room{}, err
 	}
 
-	// Add the user to the cache if cache is not nil
-	cache.SetUser(appsession, user)
+	room, err = ApplyRoomUpdate(room, request)
+	if err != nil {
+		return models.Room{}, err
+	}
 
-	return user.Security.Credentials, nil
+	// the floor has to exist in the room's building, whether the room or the floor moved
+	if request.BuildingID != "" || (request.FloorNo != "" && room.BuildingID != "") {
+		buildingID := request.BuildingID
+		if buildingID == "" {
+			buildingID = room.BuildingID
+		}
+		room, err = PlaceRoomInBuilding(ctx, appsession, room, buildingID)
+		if err != nil {
+			return models.Room{}, err
+		}
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+
+	// the room number must stay unique
+	if request.RoomNo != "" {
+		var existingRoom models.Room
+		err = collection.FindOne(ctx, bson.M{"roomNo": request.RoomNo, "roomId": bson.M{"$ne": request.RoomID}}).Decode(&existingRoom)
+		if err == nil {
+			return models.Room{}, errors.New("room already exists")
+		}
+		if err != mongo.ErrNoDocuments {
+			logrus.Error(err)
+			return models.Room{}, err
+		}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"roomNo":           room.RoomNo,
+		"floorNo":          room.FloorNo,
+		"minOccupancy":     room.MinOccupancy,
+		"maxOccupancy":     room.MaxOccupancy,
+		"description":      room.Description,
+		"roomName":         room.RoomName,
+		"amenities":        room.Amenities,
+		"buildingId":       room.BuildingID,
+		"siteId":           room.SiteID,
+		"requiresApproval": room.RequiresApproval,
+		"approvers":        room.Approvers,
+		"checkInTokens":    room.CheckInTokens,
+	}}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"roomId": request.RoomID}, update)
+	if err != nil {
+		logrus.Error(err)
+		return models.Room{}, err
+	}
+
+	return room, nil
 }
 
-func AddUserCredential(ctx *gin.Context, appsession *models.AppSession, email string, credential *webauthn.Credential) error {
+// sets whether a room is active, inactive or archived, existing bookings are left as they are
+func SetRoomStatus(ctx *gin.Context, appsession *models.AppSession, roomID string, status string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
 
-	// get user from cache
-	userData, cacheErr := cache.GetUser(appsession, email)
-
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
-
-	filter := bson.M{"email": email}
-	update := bson.M{"$set": bson.M{"security.credentials": credential}}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
 
-	_, err := collection.UpdateOne(ctx, filter, update)
+	res, err := collection.UpdateOne(ctx, bson.M{"roomId": roomID}, bson.M{"$set": bson.M{"status": status}})
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
-	// update user in cache
-	if cacheErr == nil {
-		userData.Security.Credentials = *credential
-		cache.SetUser(appsession, userData)
+	if res.MatchedCount == 0 {
+		return errors.New("room not found")
 	}
 
 	return nil
 }
 
-func IsIPWithinRange(ctx *gin.Context, appsession *models.AppSession, email string, unrecognizedLogger *ipinfo.Core) bool {
+// assigns a room to a building, the room's floor must be one of the building's floors
+func PlaceRoomInBuilding(ctx *gin.Context, appsession *models.AppSession, room models.Room, buildingID string) (models.Room, error) {
+	building, err := LocateFloor(ctx, appsession, buildingID, room.FloorNo)
+	if err != nil {
+		return room, err
+	}
+
+	room.BuildingID = building.BuildingID
+	room.SiteID = building.SiteID
+	return room, nil
+}
+
+// gets the building a floor belongs to, erroring if the building or floor does not exist
+func LocateFloor(ctx *gin.Context, appsession *models.AppSession, buildingID string, floorNo string) (models.Building, error) {
+	building, err := GetBuilding(ctx, appsession, buildingID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Building{}, errors.New("building not found")
+		}
+		return models.Building{}, err
+	}
+
+	exists, err := FloorExists(ctx, appsession, buildingID, floorNo)
+	if err != nil {
+		return models.Building{}, err
+	}
+	if !exists {
+		return models.Building{}, errors.New("floor not found")
+	}
+
+	return building, nil
+}
+
+// gets a site by its site id
+func GetSite(ctx context.Context, appsession *models.AppSession, siteID string) (models.Site, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return false
+		return models.Site{}, errors.New("database is nil")
 	}
 
-	// get the ip ranges from the cache
-	if user, err := cache.GetUser(appsession, email); err == nil {
-		return IsLocationInRange(user.KnownLocations, unrecognizedLogger)
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Sites")
+
+	var site models.Site
+	err := collection.FindOne(ctx, bson.M{"siteId": siteID}).Decode(&site)
+	if err != nil {
+		logrus.Error(err)
+		return models.Site{}, err
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	return site, nil
+}
 
-	filter := bson.M{"email": email}
+// adds a site and returns its generated site id
+func AddSite(ctx *gin.Context, appsession *models.AppSession, site models.Site) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
 
-	var user models.User
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Sites")
 
-	err := collection.FindOne(ctx, filter).Decode(&user)
+	site.SiteID = utils.GenerateUUID()
 
+	_, err := collection.InsertOne(ctx, site)
 	if err != nil {
 		logrus.Error(err)
-		return false
+		return "", err
 	}
 
-	// Add the user to the cache if cache is not nil
-	cache.SetUser(appsession, user)
-
-	return IsLocationInRange(user.KnownLocations, unrecognizedLogger)
+	return site.SiteID, nil
 }
 
-func GetAvailableSlots(ctx *gin.Context, appsession *models.AppSession, request models.RequestAvailableSlots) ([]models.Slot, error) {
+// updates the name, address and timezone of a site
+func UpdateSite(ctx *gin.Context, appsession *models.AppSession, site models.Site) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return nil, errors.New("database is nil")
+		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Sites")
 
-	filter := bson.M{
-		"roomId": request.RoomID,
-		"date":   request.Date,
-	}
+	update := bson.M{"$set": bson.M{"name": site.Name, "address": site.Address, "timeZone": site.TimeZone}}
 
-	cursor, err := collection.Find(ctx, filter)
+	res, err := collection.UpdateOne(ctx, bson.M{"siteId": site.SiteID}, update)
 	if err != nil {
 		logrus.Error(err)
-		return nil, err
+		return err
 	}
 
-	var bookings []models.Booking
-	if err = cursor.All(ctx, &bookings); err != nil {
-		return nil, err
+	if res.MatchedCount == 0 {
+		return errors.New("site not found")
 	}
 
-	// get all slots for the room
-	slots := ComputeAvailableSlots(bookings, request.Date)
-
-	return slots, nil
+	return nil
 }
 
-func ToggleOnsite(ctx *gin.Context, appsession *models.AppSession, request models.RequestOnsite) error {
+// deletes a site as long as none of its buildings are left
+func DeleteSite(ctx *gin.Context, appsession *models.AppSession, siteID string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
-
-	var updatedStatus bool
-	switch request.OnSite {
-	case "Yes":
-		updatedStatus = true
-	case "No":
-		updatedStatus = false
-	default:
-		return errors.New("invalid status")
-	}
+	buildings := appsession.DB.Database(configs.GetMongoDBName()).Collection("Buildings")
 
-	// check if user is already on site or off site and are trying to perform the same action again
-	var userData models.User
-	userData, err := cache.GetUser(appsession, request.Email)
+	count, err := buildings.CountDocuments(ctx, bson.M{"siteId": siteID})
 	if err != nil {
-		// get the user from the database
-		filter := bson.M{"email": request.Email}
-		err := collection.FindOne(ctx, filter).Decode(&userData)
-		if err != nil {
-			logrus.WithError(err).Error("Failed to get user from database")
-			return err
-		}
+		logrus.Error(err)
+		return err
 	}
-
-	if userData.OnSite && updatedStatus {
-		return errors.New("user is already onsite")
-	} else if !userData.OnSite && !updatedStatus {
-		return errors.New("user is already offsite")
+	if count > 0 {
+		return errors.New("site has buildings")
 	}
 
-	filter := bson.M{"email": request.Email}
-	update := bson.M{"$set": bson.M{"onSite": updatedStatus}}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Sites")
 
-	_, err = collection.UpdateOne(ctx, filter, update)
+	res, err := collection.DeleteOne(ctx, bson.M{"siteId": siteID})
 	if err != nil {
-		logrus.WithError(err).Error("Failed to update user status")
+		logrus.Error(err)
 		return err
 	}
 
-	// update user in cache
-	userData.OnSite = updatedStatus
-	cache.SetUser(appsession, userData)
+	if res.DeletedCount == 0 {
+		return errors.New("site not found")
+	}
 
-	if updatedStatus {
-		// add the user to the office hours collection
-		err = AddHoursToOfficeHoursCollection(ctx, appsession, request.Email)
-		if err != nil {
-			logrus.Error(err)
-			return err
-		}
+	return nil
+}
 
-		// add their attendance to the attendance collection if there is no
-		// attendance object for this date otherwise increment the Number_Attended field
-		err = AddAttendance(ctx, appsession, request.Email)
-		if err != nil {
-			logrus.Error(err)
-			return err
-		}
-	} else {
-		// find the user's office hours and remove them and add the removed office hours to the OfficeHoursArchive collection
-		officeHours, err := FindAndRemoveOfficeHours(ctx, appsession, request.Email)
-		if err != nil {
-			logrus.Error(err)
-			return err
+// gets a building by its building id
+func GetBuilding(ctx *gin.Context, appsession *models.AppSession, buildingID string) (models.Building, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.Building{}, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Buildings")
+
+	var building models.Building
+	err := collection.FindOne(ctx, bson.M{"buildingId": buildingID}).Decode(&building)
+	if err != nil {
+		logrus.Error(err)
+		return models.Building{}, err
+	}
+
+	return building, nil
+}
+
+// adds a building to an existing site and returns its generated building id
+func AddBuilding(ctx *gin.Context, appsession *models.AppSession, building models.Building) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
+
+	if _, err := GetSite(ctx, appsession, building.SiteID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", errors.New("site not found")
 		}
+		return "", err
+	}
 
-		// update the fields and add to the OfficeHoursArchive time series collection
-		err = AddOfficeHoursToArchive(ctx, appsession, officeHours)
-		if err != nil {
-			logrus.Error(err)
-			return err
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Buildings")
+
+	building.BuildingID = utils.GenerateUUID()
+
+	_, err := collection.InsertOne(ctx, building)
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	return building.BuildingID, nil
+}
+
+// updates a building, moving it to another site moves its rooms along with it
+func UpdateBuilding(ctx *gin.Context, appsession *models.AppSession, building models.Building) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	if _, err := GetSite(ctx, appsession, building.SiteID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("site not found")
 		}
+		return err
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Buildings")
+
+	update := bson.M{"$set": bson.M{"siteId": building.SiteID, "name": building.Name, "address": building.Address}}
+
+	res, err := collection.UpdateOne(ctx, bson.M{"buildingId": building.BuildingID}, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.New("building not found")
+	}
+
+	rooms := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+
+	_, err = rooms.UpdateMany(ctx, bson.M{"buildingId": building.BuildingID}, bson.M{"$set": bson.M{"siteId": building.SiteID}})
+	if err != nil {
+		logrus.Error(err)
+		return err
 	}
 
 	return nil
 }
 
-func AddHoursToOfficeHoursCollection(ctx *gin.Context, appsession *models.AppSession, email string) error {
+// deletes a building as long as none of its floors are left
+func DeleteBuilding(ctx *gin.Context, appsession *models.AppSession, buildingID string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
 
-	// add the user to the office hours collection
-	officeHours := models.OfficeHours{
-		Email:   email,
-		Entered: CapTimeRange(),
-		Exited:  CapTimeRange(),
+	floors := appsession.DB.Database(configs.GetMongoDBName()).Collection("Floors")
+
+	count, err := floors.CountDocuments(ctx, bson.M{"buildingId": buildingID})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if count > 0 {
+		return errors.New("building has floors")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHours")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Buildings")
 
-	_, err := collection.InsertOne(ctx, officeHours)
+	res, err := collection.DeleteOne(ctx, bson.M{"buildingId": buildingID})
 	if err != nil {
-		logrus.WithError(err).Error("Failed to add user to office hours")
+		logrus.Error(err)
 		return err
 	}
 
+	if res.DeletedCount == 0 {
+		return errors.New("building not found")
+	}
+
 	return nil
 }
 
-func FindAndRemoveOfficeHours(ctx *gin.Context, appsession *models.AppSession, email string) (models.OfficeHours, error) {
+// checks whether a building has the given floor
+func FloorExists(ctx *gin.Context, appsession *models.AppSession, buildingID string, floorNo string) (bool, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return models.OfficeHours{}, errors.New("database is nil")
+		return false, errors.New("database is nil")
 	}
 
-	// find the user's office hours and remove them
-	filter := bson.M{"email": email}
-
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHours")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Floors")
 
-	var officeHours models.OfficeHours
-	err := collection.FindOne(ctx, filter).Decode(&officeHours)
+	count, err := collection.CountDocuments(ctx, bson.M{"buildingId": buildingID, "floorNo": floorNo})
 	if err != nil {
 		logrus.Error(err)
-		return models.OfficeHours{}, err
+		return false, err
 	}
 
-	// remove the office hours from the OfficeHours collection
-	_, err = collection.DeleteOne(ctx, filter)
+	return count > 0, nil
+}
+
+// adds a floor to an existing building
+func AddFloor(ctx *gin.Context, appsession *models.AppSession, floor models.Floor) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	if _, err := GetBuilding(ctx, appsession, floor.BuildingID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("building not found")
+		}
+		return err
+	}
+
+	exists, err := FloorExists(ctx, appsession, floor.BuildingID, floor.FloorNo)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("floor already exists")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Floors")
+
+	_, err = collection.InsertOne(ctx, floor)
 	if err != nil {
 		logrus.Error(err)
-		return models.OfficeHours{}, err
+		return err
 	}
 
-	return officeHours, nil
+	return nil
 }
 
-func AddOfficeHoursToArchive(ctx *gin.Context, appsession *models.AppSession, officeHours models.OfficeHours) error {
+// updates the name of a floor
+func UpdateFloor(ctx *gin.Context, appsession *models.AppSession, floor models.Floor) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
 
-	// update the fields and add to the OfficeHoursArchive time series collection
-	officeHours.Exited = CompareAndReturnTime(officeHours.Entered, CapTimeRange())
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Floors")
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHoursArchive")
+	filter := bson.M{"buildingId": floor.BuildingID, "floorNo": floor.FloorNo}
 
-	_, err := collection.InsertOne(ctx, officeHours)
+	res, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"name": floor.Name}})
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
+	if res.MatchedCount == 0 {
+		return errors.New("floor not found")
+	}
+
 	return nil
 }
 
-func AddAttendance(ctx *gin.Context, appsession *models.AppSession, email string) error {
+// deletes a floor as long as no rooms are left on it
+func DeleteFloor(ctx *gin.Context, appsession *models.AppSession, buildingID string, floorNo string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
-	// add their attendance to the attendance collection if there is no
-	// attendance object for this date otherwise increment the Number_Attended field
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("attendance")
 
-	// Define the start and end of the day
-	now := time.Now().In(time.Local).Truncate(24 * time.Hour)
-	endOfDay := now.Add(24 * time.Hour)
+	filter := bson.M{"buildingId": buildingID, "floorNo": floorNo}
 
-	// Create the filter for date
+	rooms := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+
+	count, err := rooms.CountDocuments(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if count > 0 {
+		return errors.New("floor has rooms")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Floors")
+
+	res, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("floor not found")
+	}
+
+	return nil
+}
+
+// checks the person a desk is booked for in and counts them as attending the office
+func ConfirmDeskCheckIn(ctx *gin.Context, appsession *models.AppSession, checkIn models.CheckIn) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("DeskBooking")
+
+	// desks are checked in by the person sitting at them rather than whoever booked them
 	filter := bson.M{
-		"Date": bson.M{
-			"$gte": now,
-			"$lt":  endOfDay,
-		},
+		"occupiId": checkIn.BookingID,
+		"email":    checkIn.Creator,
 	}
 
-	var attendance models.Attendance
-	err := collection.FindOne(ctx, filter).Decode(&attendance)
+	res, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"checkedIn": true}})
 	if err != nil {
-		logrus.WithError(err).Error("Failed to get attendance")
-		attendance = models.Attendance{
-			Date:           time.Now().In(time.Local),
-			IsWeekend:      IsWeekend(time.Now().In(time.Local)),
-			WeekOfTheYear:  WeekOfTheYear(time.Now().In(time.Local)),
-			DayOfWeek:      DayOfTheWeek(time.Now().In(time.Local)),
-			DayOfMonth:     DayofTheMonth(time.Now().In(time.Local)),
-			Month:          Month(time.Now().In(time.Local)),
-			SpecialEvent:   false, // admins can set this to true if there is a special event at a later stage
-			NumberAttended: 1,
-			AttendeesEmail: []string{email},
-		}
+		logrus.Error("Failed to update desk booking:", err)
+		return false, err
+	}
 
-		_, err = collection.InsertOne(ctx, attendance)
-		if err != nil {
-			logrus.WithError(err).Error("Failed to add user to attendance")
-			return err
-		}
-	} else {
-		// check if the email is in the Attendees_Email array
-		if utils.Contains(attendance.AttendeesEmail, email) {
-			return nil
-		}
+	if res.MatchedCount == 0 {
+		return false, errors.New("booking not found")
+	}
 
-		update := bson.M{"$inc": bson.M{"Number_Attended": 1}, "$push": bson.M{"Attendees_Email": email}}
-		_, err = collection.UpdateOne(ctx, filter, update)
+	if err := AddAttendance(ctx, appsession, checkIn.Creator); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// gets a desk booking by its occupi id
+func GetDeskBooking(ctx *gin.Context, appsession *models.AppSession, id string) (models.DeskBooking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.DeskBooking{}, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("DeskBooking")
+
+	var booking models.DeskBooking
+	err := collection.FindOne(ctx, bson.M{"occupiId": id}).Decode(&booking)
+	if err != nil {
+		logrus.Error(err)
+		return models.DeskBooking{}, err
+	}
+
+	return booking, nil
+}
+
+// gets a desk by its desk id
+func GetDesk(ctx *gin.Context, appsession *models.AppSession, deskID string) (models.Desk, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.Desk{}, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Desks")
+
+	var desk models.Desk
+	err := collection.FindOne(ctx, bson.M{"deskId": deskID}).Decode(&desk)
+	if err != nil {
+		logrus.Error(err)
+		return models.Desk{}, err
+	}
+
+	return desk, nil
+}
+
+// adds a desk to a floor's inventory, desks in a building must be on one of its floors
+func AddDesk(ctx *gin.Context, appsession *models.AppSession, desk models.Desk) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	if desk.BuildingID != "" {
+		building, err := LocateFloor(ctx, appsession, desk.BuildingID, desk.FloorNo)
 		if err != nil {
-			logrus.WithError(err).Error("Failed to update attendance")
 			return err
 		}
+		desk.SiteID = building.SiteID
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Desks")
+
+	count, err := collection.CountDocuments(ctx, bson.M{"deskId": desk.DeskID})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if count > 0 {
+		return errors.New("desk already exists")
+	}
+
+	desk.Amenities = utils.NormalizeTags(desk.Amenities)
+
+	_, err = collection.InsertOne(ctx, desk)
+	if err != nil {
+		logrus.Error(err)
+		return err
 	}
+
 	return nil
 }
 
-func GetAnalyticsOnHours(ctx *gin.Context, appsession *models.AppSession, email string, filter models.AnalyticsFilterStruct, calculate string) ([]primitive.M, int64, error) {
+// deletes a desk from the inventory, bookings already made for it are kept
+func DeleteDesk(ctx *gin.Context, appsession *models.AppSession, deskID string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return nil, 0, errors.New("database is nil")
+		return errors.New("database is nil")
 	}
 
-	// Prepare the aggregate
-	var pipeline bson.A
-	switch calculate {
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Desks")
+
+	res, err := collection.DeleteOne(ctx, bson.M{"deskId": deskID})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("desk not found")
+	}
+
+	return nil
+}
+
+// finds the desks matching the filter ordered by desk id
+func FindDesks(ctx *gin.Context, appsession *models.AppSession, filter bson.M) ([]models.Desk, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Desks")
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "deskId", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var desks []models.Desk
+	if err = cursor.All(ctx, &desks); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return desks, nil
+}
+
+// returns the ids of the given desks that are booked at some point between start and end
+func BookedDeskIDs(ctx *gin.Context, appsession *models.AppSession, deskIDs []string, start time.Time, end time.Time) ([]string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("DeskBooking")
+
+	filter := bson.M{
+		"deskId": bson.M{"$in": deskIDs},
+		"start":  bson.M{"$lt": end},
+		"end":    bson.M{"$gt": start},
+	}
+
+	booked, err := collection.Distinct(ctx, "deskId", filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(booked))
+	for _, id := range booked {
+		if deskID, ok := id.(string); ok {
+			ids = append(ids, deskID)
+		}
+	}
+
+	return ids, nil
+}
+
+// finds a desk booking that clashes with the new one, either for the same desk or for the same person at another desk
+func CheckCoincidingDeskBookings(ctx *gin.Context, appsession *models.AppSession, emails []string, deskIDs []string, start time.Time, end time.Time) (models.DeskBooking, bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.DeskBooking{}, false, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("DeskBooking")
+
+	filter := bson.M{
+		"$or": []bson.M{
+			{"deskId": bson.M{"$in": deskIDs}},
+			{"email": bson.M{"$in": emails}},
+		},
+		"start": bson.M{"$lt": end},
+		"end":   bson.M{"$gt": start},
+	}
+
+	var existing models.DeskBooking
+	err := collection.FindOne(ctx, filter).Decode(&existing)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.DeskBooking{}, false, nil
+		}
+		logrus.Error(err)
+		return models.DeskBooking{}, false, err
+	}
+
+	return existing, true, nil
+}
+
+// saves one or more desk bookings
+func AddDeskBookings(ctx *gin.Context, appsession *models.AppSession, bookings []models.DeskBooking) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("DeskBooking")
+
+	documents := make([]interface{}, len(bookings))
+	for i, booking := range bookings {
+		documents[i] = booking
+	}
+
+	_, err := collection.InsertMany(ctx, documents)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// gets the desk a user has booked most often over the last few weeks
+func GetUsualDesk(ctx *gin.Context, appsession *models.AppSession, email string) (models.Desk, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.Desk{}, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("DeskBooking")
+
+	since := time.Now().AddDate(0, 0, -constants.UsualDeskDays)
+
+	cursor, err := collection.Aggregate(ctx, UsualDeskPipeline(email, since))
+	if err != nil {
+		logrus.Error(err)
+		return models.Desk{}, err
+	}
+
+	var usual []bson.M
+	if err = cursor.All(ctx, &usual); err != nil {
+		logrus.Error(err)
+		return models.Desk{}, err
+	}
+
+	if len(usual) == 0 {
+		return models.Desk{}, errors.New("no usual desk")
+	}
+
+	deskID, _ := usual[0]["_id"].(string)
+
+	desk, err := GetDesk(ctx, appsession, deskID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Desk{}, errors.New("no usual desk")
+		}
+		return models.Desk{}, err
+	}
+
+	return desk, nil
+}
+
+// cancels a desk booking made by or for the user
+func CancelDeskBooking(ctx *gin.Context, appsession *models.AppSession, bookingID string, email string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("DeskBooking")
+
+	filter := bson.M{
+		"occupiId": bookingID,
+		"$or":      []bson.M{{"email": email}, {"creator": email}},
+	}
+
+	res, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("booking not found")
+	}
+
+	return nil
+}
+
+func GetUserCredentials(ctx *gin.Context, appsession *models.AppSession, email string) (webauthn.Credential, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return webauthn.Credential{}, errors.New("database is nil")
+	}
+
+	// check if user is in cache
+	if userData, err := cache.GetUser(appsession, email); err == nil {
+		return userData.Security.Credentials, nil
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": email}
+	var user models.User
+	err := collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		return webauthn.Credential{}, err
+	}
+
+	// Add the user to the cache if cache is not nil
+	cache.SetUser(appsession, user)
+
+	return user.Security.Credentials, nil
+}
+
+func AddUserCredential(ctx *gin.Context, appsession *models.AppSession, email string, credential *webauthn.Credential) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	// get user from cache
+	userData, cacheErr := cache.GetUser(appsession, email)
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": email}
+	update := bson.M{"$set": bson.M{"security.credentials": credential}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// update user in cache
+	if cacheErr == nil {
+		userData.Security.Credentials = *credential
+		cache.SetUser(appsession, userData)
+	}
+
+	return nil
+}
+
+func IsIPWithinRange(ctx *gin.Context, appsession *models.AppSession, email string, unrecognizedLogger *ipinfo.Core) bool {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false
+	}
+
+	// get the ip ranges from the cache
+	if user, err := cache.GetUser(appsession, email); err == nil {
+		return IsLocationInRange(user.KnownLocations, unrecognizedLogger)
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": email}
+
+	var user models.User
+
+	err := collection.FindOne(ctx, filter).Decode(&user)
+
+	if err != nil {
+		logrus.Error(err)
+		return false
+	}
+
+	// Add the user to the cache if cache is not nil
+	cache.SetUser(appsession, user)
+
+	return IsLocationInRange(user.KnownLocations, unrecognizedLogger)
+}
+
+func GetAvailableSlots(ctx *gin.Context, appsession *models.AppSession, request models.RequestAvailableSlots) ([]models.Slot, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{
+		"roomId": request.RoomID,
+		"date":   request.Date,
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+
+	// fall back to the default hours rather than failing if the settings cannot be read
+	settings, err := GetBookingSettings(ctx, appsession, request.RoomID)
+	if err != nil {
+		logrus.Error(err)
+		settings = DefaultBookingSettings()
+	}
+
+	// get all slots for the room
+	slots := ComputeAvailableSlotsWithSettings(bookings, request.Date, settings)
+
+	return slots, nil
+}
+
+// gets the booking settings that apply to a room, combining the room, building and default settings
+func GetBookingSettings(ctx context.Context, appsession *models.AppSession, roomID string) (models.BookingSettings, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.BookingSettings{}, errors.New("database is nil")
+	}
+
+	// a missing room only means there are no building settings to apply
+	room, err := GetRoom(ctx, appsession, roomID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return models.BookingSettings{}, err
+	}
+
+	return GetLocationBookingSettings(ctx, appsession, roomID, room.BuildingID, room.SiteID)
+}
+
+// gets the booking settings for a room or, without a room, for anything else booked in a building such as desks
+func GetLocationBookingSettings(ctx context.Context, appsession *models.AppSession, roomID string, buildingID string, siteID string) (models.BookingSettings, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.BookingSettings{}, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingSettings")
+
+	levels := []bson.M{
+		{"roomId": "", "buildingId": ""},
+	}
+	if roomID != "" {
+		levels = append(levels, bson.M{"roomId": roomID})
+	}
+	if buildingID != "" {
+		levels = append(levels, bson.M{"roomId": "", "buildingId": buildingID})
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"$or": levels})
+	if err != nil {
+		logrus.Error(err)
+		return models.BookingSettings{}, err
+	}
+
+	var settings []models.BookingSettings
+	if err = cursor.All(ctx, &settings); err != nil {
+		logrus.Error(err)
+		return models.BookingSettings{}, err
+	}
+
+	resolved := ResolveBookingSettings(settings, roomID, buildingID)
+
+	// the opening hours are in the timezone of the site
+	if siteID != "" {
+		site, err := GetSite(ctx, appsession, siteID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return models.BookingSettings{}, err
+		}
+		resolved.TimeZone = site.TimeZone
+	}
+
+	return resolved, nil
+}
+
+// searches every room that fits the request in a single aggregation and returns those with free slots in the window, best fit first
+func SearchAvailableRooms(ctx *gin.Context, appsession *models.AppSession, request models.RequestRoomSearch, preferredFloor string) ([]models.RoomAvailability, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+
+	cursor, err := collection.Aggregate(ctx, RoomSearchPipeline(request, preferredFloor))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var rooms []models.RoomAvailability
+	if err = cursor.All(ctx, &rooms); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	available := make([]models.RoomAvailability, 0, len(rooms))
+	for _, room := range rooms {
+		settings := ResolveBookingSettings(room.Settings, room.RoomID, room.BuildingID)
+		if len(room.Site) > 0 {
+			settings.TimeZone = room.Site[0].TimeZone
+		}
+		room.Slots = FindSlotsInWindow(room.Bookings, settings, request.Start, request.End, request.Duration)
+		if len(room.Slots) > 0 {
+			available = append(available, room)
+		}
+	}
+
+	return available, nil
+}
+
+// gets the floor of the users department, an empty floor means the department has not been placed on one
+func GetDepartmentFloor(ctx *gin.Context, appsession *models.AppSession, email string) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetProjection(bson.M{"departmentNo": 1})).Decode(&user)
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	if user.DepartmentNo == "" {
+		return "", nil
+	}
+
+	collection = appsession.DB.Database(configs.GetMongoDBName()).Collection("Departments")
+
+	var department models.Department
+	err = collection.FindOne(ctx, bson.M{"departmentNo": user.DepartmentNo}).Decode(&department)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		logrus.Error(err)
+		return "", err
+	}
+
+	return department.FloorNo, nil
+}
+
+// places a department on a floor, replacing the floor it was placed on before
+func SetDepartment(ctx *gin.Context, appsession *models.AppSession, department models.Department) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Departments")
+
+	filter := bson.M{"departmentNo": department.DepartmentNo}
+	update := bson.M{"$set": bson.M{"floorNo": department.FloorNo}}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// gets every department and the floor it is placed on
+func GetDepartments(ctx *gin.Context, appsession *models.AppSession) ([]models.Department, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Departments")
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"departmentNo": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	departments := []models.Department{}
+	if err = cursor.All(ctx, &departments); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return departments, nil
+}
+
+// removes a department from its floor
+func DeleteDepartment(ctx *gin.Context, appsession *models.AppSession, departmentNo string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Departments")
+
+	res, err := collection.DeleteOne(ctx, bson.M{"departmentNo": departmentNo})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("department not found")
+	}
+
+	return nil
+}
+
+// creates or replaces the booking settings of a room, a building or the defaults when neither is given
+func SetBookingSettings(ctx *gin.Context, appsession *models.AppSession, settings models.BookingSettings) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingSettings")
+
+	if settings.Blackouts == nil {
+		settings.Blackouts = []models.Blackout{}
+	}
+
+	filter := bson.M{"roomId": settings.RoomID, "buildingId": settings.BuildingID}
+	update := bson.M{"$set": bson.M{
+		"openingTime": settings.OpeningTime,
+		"closingTime": settings.ClosingTime,
+		"slotLength":  settings.SlotLength,
+		"buffer":      settings.Buffer,
+		"blackouts":   settings.Blackouts,
+	}}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func ToggleOnsite(ctx *gin.Context, appsession *models.AppSession, request models.RequestOnsite) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	var updatedStatus bool
+	switch request.OnSite {
+	case "Yes":
+		updatedStatus = true
+	case "No":
+		updatedStatus = false
+	default:
+		return errors.New("invalid status")
+	}
+
+	// check if user is already on site or off site and are trying to perform the same action again
+	var userData models.User
+	userData, err := cache.GetUser(appsession, request.Email)
+	if err != nil {
+		// get the user from the database
+		filter := bson.M{"email": request.Email}
+		err := collection.FindOne(ctx, filter).Decode(&userData)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to get user from database")
+			return err
+		}
+	}
+
+	if userData.OnSite && updatedStatus {
+		return errors.New("user is already onsite")
+	} else if !userData.OnSite && !updatedStatus {
+		return errors.New("user is already offsite")
+	}
+
+	// check the site and building before marking the user onsite so a bad location leaves them offsite
+	var officeHours models.OfficeHours
+	var location *time.Location
+	if updatedStatus {
+		officeHours, location, err = ResolveOfficeLocation(ctx, appsession, request)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	}
+
+	filter := bson.M{"email": request.Email}
+	update := bson.M{"$set": bson.M{"onSite": updatedStatus}}
+
+	_, err = collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to update user status")
+		return err
+	}
+
+	// update user in cache
+	userData.OnSite = updatedStatus
+	cache.SetUser(appsession, userData)
+
+	if updatedStatus {
+		// add the user to the office hours collection at the site, building and floor they are in
+		err = AddOfficeHours(ctx, appsession, officeHours, location)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+
+		// add their attendance to the attendance collection if there is no
+		// attendance object for this date otherwise increment the Number_Attended field
+		err = AddAttendance(ctx, appsession, request.Email)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	} else {
+		// find the user's office hours and remove them and add the removed office hours to the OfficeHoursArchive collection
+		officeHours, err := FindAndRemoveOfficeHours(ctx, appsession, request.Email)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+
+		// the hours are capped in the timezone of the site they were spent at
+		location := time.Local
+		if officeHours.SiteID != "" {
+			if site, err := GetSite(ctx, appsession, officeHours.SiteID); err == nil {
+				location = LoadTimeZone(site.TimeZone)
+			}
+		}
+		officeHours.Entered = officeHours.Entered.In(location)
+
+		// update the fields and add to the OfficeHoursArchive time series collection
+		err = AddOfficeHoursToArchive(ctx, appsession, officeHours)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func AddHoursToOfficeHoursCollection(ctx *gin.Context, appsession *models.AppSession, email string) error {
+	return AddOfficeHours(ctx, appsession, models.OfficeHours{Email: email}, time.Local)
+}
+
+// adds office hours starting now, capped to office hours in the given location
+func AddOfficeHours(ctx *gin.Context, appsession *models.AppSession, officeHours models.OfficeHours, location *time.Location) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	// add the user to the office hours collection
+	officeHours.Entered = CapTimeRangeIn(location)
+	officeHours.Exited = officeHours.Entered
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHours")
+
+	_, err := collection.InsertOne(ctx, officeHours)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to add user to office hours")
+		return err
+	}
+
+	return nil
+}
+
+// works out where a user is going onsite and the timezone their hours are kept in,
+// the site is taken from the building when only the building is given
+func ResolveOfficeLocation(ctx *gin.Context, appsession *models.AppSession, request models.RequestOnsite) (models.OfficeHours, *time.Location, error) {
+	officeHours := models.OfficeHours{
+		Email:      request.Email,
+		SiteID:     request.SiteID,
+		BuildingID: request.BuildingID,
+		FloorNo:    request.FloorNo,
+	}
+
+	if request.BuildingID != "" {
+		building, err := GetBuilding(ctx, appsession, request.BuildingID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return officeHours, nil, errors.New("building not found")
+			}
+			return officeHours, nil, err
+		}
+		if request.SiteID != "" && request.SiteID != building.SiteID {
+			return officeHours, nil, errors.New("building is not part of the site")
+		}
+		officeHours.SiteID = building.SiteID
+	}
+
+	if officeHours.SiteID == "" {
+		return officeHours, time.Local, nil
+	}
+
+	site, err := GetSite(ctx, appsession, officeHours.SiteID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return officeHours, nil, errors.New("site not found")
+		}
+		return officeHours, nil, err
+	}
+
+	return officeHours, LoadTimeZone(site.TimeZone), nil
+}
+
+func FindAndRemoveOfficeHours(ctx *gin.Context, appsession *models.AppSession, email string) (models.OfficeHours, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.OfficeHours{}, errors.New("database is nil")
+	}
+
+	// find the user's office hours and remove them
+	filter := bson.M{"email": email}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHours")
+
+	var officeHours models.OfficeHours
+	err := collection.FindOne(ctx, filter).Decode(&officeHours)
+	if err != nil {
+		logrus.Error(err)
+		return models.OfficeHours{}, err
+	}
+
+	// remove the office hours from the OfficeHours collection
+	_, err = collection.DeleteOne(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return models.OfficeHours{}, err
+	}
+
+	return officeHours, nil
+}
+
+func AddOfficeHoursToArchive(ctx *gin.Context, appsession *models.AppSession, officeHours models.OfficeHours) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	// update the fields and add to the OfficeHoursArchive time series collection
+	officeHours.Exited = CompareAndReturnTime(officeHours.Entered, CapTimeRangeIn(officeHours.Entered.Location()))
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHoursArchive")
+
+	_, err := collection.InsertOne(ctx, officeHours)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func AddAttendance(ctx *gin.Context, appsession *models.AppSession, email string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+	// add their attendance to the attendance collection if there is no
+	// attendance object for this date otherwise increment the Number_Attended field
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("attendance")
+
+	// Define the start and end of the day
+	now := time.Now().In(time.Local).Truncate(24 * time.Hour)
+	endOfDay := now.Add(24 * time.Hour)
+
+	// Create the filter for date
+	filter := bson.M{
+		"Date": bson.M{
+			"$gte": now,
+			"$lt":  endOfDay,
+		},
+	}
+
+	var attendance models.Attendance
+	err := collection.FindOne(ctx, filter).Decode(&attendance)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get attendance")
+		attendance = models.Attendance{
+			Date:           time.Now().In(time.Local),
+			IsWeekend:      IsWeekend(time.Now().In(time.Local)),
+			WeekOfTheYear:  WeekOfTheYear(time.Now().In(time.Local)),
+			DayOfWeek:      DayOfTheWeek(time.Now().In(time.Local)),
+			DayOfMonth:     DayofTheMonth(time.Now().In(time.Local)),
+			Month:          Month(time.Now().In(time.Local)),
+			SpecialEvent:   false, // admins can set this to true if there is a special event at a later stage
+			NumberAttended: 1,
+			AttendeesEmail: []string{email},
+		}
+
+		_, err = collection.InsertOne(ctx, attendance)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to add user to attendance")
+			return err
+		}
+	} else {
+		// check if the email is in the Attendees_Email array
+		if utils.Contains(attendance.AttendeesEmail, email) {
+			return nil
+		}
+
+		update := bson.M{"$inc": bson.M{"Number_Attended": 1}, "$push": bson.M{"Attendees_Email": email}}
+		_, err = collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to update attendance")
+			return err
+		}
+	}
+	return nil
+}
+
+func GetAnalyticsOnHours(ctx *gin.Context, appsession *models.AppSession, email string, filter models.AnalyticsFilterStruct, calculate string) ([]primitive.M, int64, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, 0, errors.New("database is nil")
+	}
+
+	// Prepare the aggregate
+	var pipeline bson.A
+	switch calculate {
 	case "hoursbyday":
 		pipeline = analytics.GroupOfficeHoursByDay(email, filter)
 	case "hoursbyweekday":
@@ -1824,41 +3365,1675 @@ func GetAnalyticsOnHours(ctx *gin.Context, appsession *models.AppSession, email
 		return nil, 0, errors.New("invalid calculate value")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHoursArchive")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("OfficeHoursArchive")
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		logrus.Error(err)
+		return nil, 0, err
+	}
+
+	mongoFilter := MakeEmailAndTimeFilter(email, filter)
+
+	results, totalResults, errv := GetResultsAndCount(ctx, collection, cursor, mongoFilter)
+
+	if errv != nil {
+		logrus.Error(errv)
+		return nil, 0, errv
+	}
+
+	return results, totalResults, nil
+}
+
+func CreateUser(ctx *gin.Context, appsession *models.AppSession, user models.UserRequest) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	// validate role
+	if user.Role != "admin" && user.Role != "user" {
+		return errors.New("invalid role")
+	}
+
+	_, err := collection.InsertOne(ctx, CreateAUser(user))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func AddIP(ctx *gin.Context, appsession *models.AppSession, request models.RequestIP) (*ipinfo.Core, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	ipInfo, err := configs.GetIPInfo(request.IP, appsession.IPInfo)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	location := models.Location{
+		City:      ipInfo.City,
+		Region:    ipInfo.Region,
+		Country:   ipInfo.Country,
+		Location:  ipInfo.Location,
+		IPAddress: request.IP,
+	}
+
+	// filter for all users emails which are in the request.Emails array also these emails
+	// don't have this location in the knownLocations array
+	filter := bson.M{"email": bson.M{"$in": request.Emails}, "knownLocations": bson.M{"$ne": location}}
+
+	update := bson.M{"$push": bson.M{"knownLocations": location}}
+
+	_, err = collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	// get users from cache and update their knownLocations
+	for _, email := range request.Emails {
+		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
+			userData.KnownLocations = append(userData.KnownLocations, location)
+			cache.SetUser(appsession, userData)
+		}
+	}
+
+	return ipInfo, nil
+}
+
+func WhiteListIP(ctx *gin.Context, appsession *models.AppSession, emails []string, ip string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	// filter for all users emails which are in emails that have this ip in the blackListedIP field
+	filter := bson.M{"email": bson.M{"$in": emails}, "blackListedIP": bson.M{"$eq": ip}}
+
+	// pull this ip from the array
+	update := bson.M{"$pull": bson.M{"blackListedIP": ip}}
+
+	_, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	for _, email := range emails {
+		// Fetch user from cache
+		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
+			updatedBlackListedIPs := []string{}
+
+			// Process the blackListedIP array
+			for _, blackIP := range userData.BlackListedIP {
+				// Exclude the IP that needs to be whitelisted
+				if blackIP != ip {
+					updatedBlackListedIPs = append(updatedBlackListedIPs, blackIP)
+				}
+			}
+
+			// Update the user's BlackListedIP array if a change occurred
+			if len(updatedBlackListedIPs) != len(userData.BlackListedIP) {
+				userData.BlackListedIP = updatedBlackListedIPs
+				// Update the cache once per user, after processing all IPs
+				cache.SetUser(appsession, userData)
+			}
+		}
+	}
+
+	return nil
+}
+
+func RemoveIP(ctx *gin.Context, appsession *models.AppSession, request models.RequestIP) (*ipinfo.Core, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	ipInfo, err := configs.GetIPInfo(request.IP, appsession.IPInfo)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	location := models.Location{
+		City:      ipInfo.City,
+		Region:    ipInfo.Region,
+		Country:   ipInfo.Country,
+		Location:  ipInfo.Location,
+		IPAddress: request.IP,
+	}
+
+	// filter for all users emails which are in the request.Emails array and have this location in the knownLocations array
+	filter := bson.M{"email": bson.M{"$in": request.Emails}, "knownLocations": bson.M{"$eq": location}}
+
+	update := bson.M{"$pull": bson.M{"knownLocations": location}}
+
+	_, err = collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	// get users from cache and update their knownLocations
+	for _, email := range request.Emails {
+		// Fetch user from cache
+		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
+			updatedKnownLocations := []models.Location{}
+
+			// Process the KnownLocations array
+			for _, loc := range userData.KnownLocations {
+				// Exclude the location that needs to be removed
+				if loc != location {
+					updatedKnownLocations = append(updatedKnownLocations, loc)
+				}
+			}
+
+			// Update the user's KnownLocations array if a change occurred
+			if len(updatedKnownLocations) != len(userData.KnownLocations) {
+				userData.KnownLocations = updatedKnownLocations
+				// Update the cache once per user, after processing all locations
+				cache.SetUser(appsession, userData)
+			}
+		}
+	}
+
+	return ipInfo, nil
+}
+
+func BlackListIP(ctx *gin.Context, appsession *models.AppSession, emails []string, ip string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": bson.M{"$in": emails}}
+
+	update := bson.M{"$push": bson.M{"blackListedIP": ip}}
+
+	_, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// get users from cache and update their knownLocations
+	for _, email := range emails {
+		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
+			userData.BlackListedIP = append(userData.BlackListedIP, ip)
+			cache.SetUser(appsession, userData)
+		}
+	}
+
+	return nil
+}
+
+func IsIPBlackListed(ctx *gin.Context, appsession *models.AppSession, email, ip string) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	// check if user is in cache
+	if userData, err := cache.GetUser(appsession, email); err == nil {
+		return utils.Contains(userData.BlackListedIP, ip), nil
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	// check if ip is in blackListedIP array
+	filter := bson.M{"email": email}
+	var user models.User
+	err := collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	// Add the user to the cache if cache is not nil
+	cache.SetUser(appsession, user)
+
+	return utils.Contains(user.BlackListedIP, ip), nil
+}
+
+func UserHasImage(ctx *gin.Context, appsession *models.AppSession, email string) bool {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false
+	}
+
+	// check if user is in cache
+	if userData, err := cache.GetUser(appsession, email); err == nil {
+		return userData.Details.HasImage
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": email}
+	var user models.User
+	err := collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		logrus.Error(err)
+		return false
+	}
+
+	// Add the user to the cache if cache is not nil
+	cache.SetUser(appsession, user)
+
+	return user.Details.HasImage
+}
+
+func SetHasImage(ctx *gin.Context, appsession *models.AppSession, email string, hasImage bool) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": email}
+	update := bson.M{"$set": bson.M{"details.hasImage": hasImage}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// get user from cache
+	if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
+		userData.Details.HasImage = hasImage
+		cache.SetUser(appsession, userData)
+	}
+
+	return nil
+}
+
+func GetUsersGender(ctx *gin.Context, appsession *models.AppSession, email string) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
+
+	// check if user is in cache
+	if userData, err := cache.GetUser(appsession, email); err == nil {
+		return userData.Details.Gender, nil
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": email}
+	var user models.User
+	err := collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	// Add the user to the cache if cache is not nil
+	cache.SetUser(appsession, user)
+
+	return user.Details.Gender, nil
+}
+
+func CheckIfUserIsAllowedNewIP(ctx *gin.Context, appsession *models.AppSession, email string) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	// check if user is in cache
+	if userData, err := cache.GetUser(appsession, email); err == nil {
+		return userData.BlockAnonymousIPAddress, nil
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	filter := bson.M{"email": email}
+	var user models.User
+	err := collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	// Add the user to the cache if cache is not nil
+	cache.SetUser(appsession, user)
+
+	return user.BlockAnonymousIPAddress, nil
+}
+
+func CheckIfUserShouldResetPassword(ctx *gin.Context, appsession *models.AppSession, email string) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	filter := bson.M{"email": email}
+
+	// get user from cache
+	var userData models.User
+	var cacheErr error
+	if userData, cacheErr = cache.GetUser(appsession, email); cacheErr != nil {
+		// get the user from the database
+		err := collection.FindOne(ctx, filter).Decode(&userData)
+		if err != nil {
+			logrus.Error(err)
+			return false, err
+		}
+	}
+
+	if !userData.ResetPassword {
+		return false, nil
+	}
+
+	update := bson.M{"$set": bson.M{"resetPassword": !userData.ResetPassword}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	// update user in cache
+	userData.ResetPassword = !userData.ResetPassword
+	cache.SetUser(appsession, userData)
+
+	return true, nil
+}
+
+func ToggleAllowAnonymousIP(ctx *gin.Context, appsession *models.AppSession, request models.AllowAnonymousIPRequest) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	// filter for all users emails which are in the request.Emails array
+	filter := bson.M{"email": bson.M{"$in": request.Emails}}
+
+	update := bson.M{"$set": bson.M{"blockAnonymousIPAddress": request.BlockAnonymousIPAddress}}
+
+	_, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// fetch the emails in cache and update the blockAnonymousIPAddress field
+	for _, email := range request.Emails {
+		userData, cacheErr := cache.GetUser(appsession, email)
+		if cacheErr != nil {
+			continue
+		}
+
+		userData.BlockAnonymousIPAddress = request.BlockAnonymousIPAddress
+		cache.SetUser(appsession, userData)
+	}
+
+	return nil
+}
+
+func GetAnalyticsOnBookings(ctx *gin.Context, appsession *models.AppSession, creatorEmail string,
+	attendeeEmails []string, filter models.AnalyticsFilterStruct, calculate string) ([]primitive.M, int64, error) {
+
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, 0, errors.New("database is nil")
+	}
+
+	// Prepare the aggregate
+	var pipeline bson.A
+	var dateFilter string
+	switch calculate {
+	case "top3":
+		dateFilter = "date"
+		filter.Filter["timeTo"] = ""
+		pipeline = analytics.GetTop3MostBookedRooms(creatorEmail, attendeeEmails, filter, dateFilter)
+	case "historical":
+		// add or overwrite "timeTo" with time.Now and delete "timeFrom" if present
+		filter.Filter["timeTo"] = time.Now().In(time.Local)
+		filter.Filter["timeFrom"] = ""
+		dateFilter = "end"
+		pipeline = analytics.AggregateBookings(creatorEmail, attendeeEmails, filter, dateFilter)
+	case "upcoming":
+		// add or overwrite "timeFrom" with time.Now and delete "timeTo" if present
+		filter.Filter["timeFrom"] = time.Now().In(time.Local)
+		filter.Filter["timeTo"] = ""
+		dateFilter = "end"
+		pipeline = analytics.AggregateBookings(creatorEmail, attendeeEmails, filter, dateFilter)
+	case "noshows":
+		dateFilter = "start"
+		pipeline = analytics.AggregateNoShowsByUser(creatorEmail, attendeeEmails, filter, dateFilter)
+	case "rsvp":
+		dateFilter = "start"
+		pipeline = analytics.AggregateRSVPsByRoom(creatorEmail, attendeeEmails, filter, dateFilter)
+	case "occupancy":
+		dateFilter = "start"
+		pipeline = analytics.AggregateOccupancyByRoom(creatorEmail, attendeeEmails, filter, dateFilter)
+	default:
+		return nil, 0, errors.New("invalid calculate value")
+	}
+
+	// no-shows are kept in their own collection once released
+	isNoShows := calculate == "noshows"
+	collectionName := "RoomBooking"
+	if isNoShows {
+		collectionName = "NoShowBookings"
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection(collectionName)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		logrus.Error(err)
+		return nil, 0, err
+	}
+
+	mongoFilter := MakeEmailAndEmailsAndTimeFilter(creatorEmail, attendeeEmails, filter, dateFilter)
+
+	results, totalResults, errv := GetResultsAndCount(ctx, collection, cursor, mongoFilter)
+
+	if errv != nil {
+		logrus.Error(errv)
+		return nil, 0, errv
+	}
+
+	// no-show results are grouped by user so have no rooms to add images for
+	if !isNoShows {
+		results = GetImagesForRooms(ctx, appsession, results)
+	}
+
+	return results, totalResults, nil
+}
+
+func GetImagesForRooms(ctx *gin.Context, appsession *models.AppSession, results []primitive.M) []primitive.M {
+	// iterate through results and extract the roomId and add it to the roomIds array
+	roomIds := make([]string, 0)
+	for _, result := range results {
+		roomIds = append(roomIds, result["roomId"].(string))
+	}
+
+	// get the images for the rooms from the Rooms collection
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+	filter := bson.M{"roomId": bson.M{"$in": roomIds}}
+
+	// return only the roomImage field
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"roomImage": 1, "roomId": 1}))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get data from database")
+		return results
+	}
+
+	// iterate through the cursor and add the roomImage to the results matching on roomId
+	var imageRes []bson.M
+	if err := cursor.All(ctx, &imageRes); err != nil {
+		logrus.WithError(err).Error("Failed to get data from cursor")
+		return results
+	}
+
+	// Create a map for quick lookup of room images by roomId
+	imageMap := make(map[string]interface{})
+	for _, image := range imageRes {
+		imageMap[image["roomId"].(string)] = image["roomImage"]
+	}
+
+	// Iterate through the results and add the roomImage from the map
+	for _, result := range results {
+		if roomImage, ok := imageMap[result["roomId"].(string)]; ok {
+			result["roomImage"] = roomImage
+		}
+	}
+
+	return results
+}
+
+func ToggleAdminStatus(ctx *gin.Context, appsession *models.AppSession, request models.RoleRequest) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	// filter for user with the email
+	filter := bson.M{"email": request.Email}
+
+	// validate role is either basic or admin
+	if request.Role != constants.Admin && request.Role != constants.Basic {
+		request.Role = constants.Basic
+	}
+
+	update := bson.M{"$set": bson.M{"role": request.Role}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// fetch the email in cache and update the role field
+	if userData, cacheErr := cache.GetUser(appsession, request.Email); cacheErr == nil {
+		userData.Role = request.Role
+		cache.SetUser(appsession, userData)
+	}
+
+	return nil
+}
+
+func CountNotifications(ctx *gin.Context, appsession *models.AppSession, email string) (int64, int64, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return 0, 0, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
+
+	// filter for all notifications with the email in the unreadEmails field
+	filter := bson.M{"unreadEmails": email}
+
+	// get the count of unread notifications
+	unreadCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return 0, 0, err
+	}
+
+	// count total notifications
+	totalCount, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		logrus.Error(err)
+		return 0, 0, err
+	}
+
+	return unreadCount, totalCount, nil
+}
+
+func GetUsersLocations(ctx *gin.Context, appsession *models.AppSession, limit int64, skip int64, order string, email string, ipPrivelege string) ([]primitive.M, int64, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, 0, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	var pipeline primitive.A
+	if ipPrivelege == "whitelist" {
+		pipeline = analytics.GetUsersLocationsPipeLine(limit, skip, order, email)
+	} else {
+		pipeline = analytics.GetBlacklistPipeLine(limit, skip, order, email)
+	}
+
+	// get all known locations
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		logrus.Error(err)
+		return nil, 0, err
+	}
+
+	type FacetResult struct {
+		Metadata []struct {
+			Total int64 `bson:"total"`
+		} `bson:"metadata"`
+		Data []primitive.M `bson:"data"`
+	}
+
+	var facetResults []FacetResult
+	if err = cursor.All(ctx, &facetResults); err != nil {
+		logrus.Error(err)
+		return nil, 0, err
+	}
+
+	// Initialize variables for data and count
+	var locations []primitive.M
+	var totalResults int64
+
+	// Check if facetResults contains data
+	if len(facetResults) > 0 {
+		// Extract data (locations)
+		locations = facetResults[0].Data
+
+		// Extract total count from metadata
+		if len(facetResults[0].Metadata) > 0 {
+			totalResults = facetResults[0].Metadata[0].Total
+		}
+	}
+
+	return locations, totalResults, nil
+}
+
+// adds a user to the waitlist of a room and time window
+func AddToWaitlist(ctx *gin.Context, appsession *models.AppSession, entry models.WaitlistEntry) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Waitlist")
+
+	_, err := collection.InsertOne(ctx, entry)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// gets a waitlist entry by its waitlist id
+func GetWaitlistEntry(ctx *gin.Context, appsession *models.AppSession, waitlistID string) (models.WaitlistEntry, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.WaitlistEntry{}, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Waitlist")
+
+	filter := bson.M{"waitlistId": waitlistID}
+	var entry models.WaitlistEntry
+	err := collection.FindOne(ctx, filter).Decode(&entry)
+	if err != nil {
+		logrus.Error(err)
+		return models.WaitlistEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// removes a user from a waitlist
+func RemoveFromWaitlist(ctx *gin.Context, appsession *models.AppSession, waitlistID string, email string) (bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return false, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Waitlist")
+
+	filter := bson.M{"waitlistId": waitlistID, "creator": email}
+	res, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	if res.DeletedCount == 0 {
+		return false, errors.New("waitlist entry not found")
+	}
+
+	return true, nil
+}
+
+// expires the offers that were not accepted in time and returns them so their slots can be offered again,
+// an offer expired by another call at the same time is only returned once
+func ExpireWaitlistOffers(ctx context.Context, appsession *models.AppSession) ([]models.WaitlistEntry, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Waitlist")
+
+	filter := bson.M{"status": constants.WaitlistOffered, "offerExpires": bson.M{"$lt": time.Now()}}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var offers []models.WaitlistEntry
+	if err = cursor.All(ctx, &offers); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	expired := make([]models.WaitlistEntry, 0, len(offers))
+	for _, offer := range offers {
+		res, err := collection.UpdateOne(ctx,
+			bson.M{"waitlistId": offer.WaitlistID, "status": constants.WaitlistOffered},
+			bson.M{"$set": bson.M{"status": constants.WaitlistExpired}})
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if res.ModifiedCount > 0 {
+			expired = append(expired, offer)
+		}
+	}
+
+	return expired, nil
+}
+
+// gets the waiting entries whose window falls within a freed up slot, oldest first
+func GetWaitlistForSlot(ctx context.Context, appsession *models.AppSession, roomID string, start time.Time, end time.Time) ([]models.WaitlistEntry, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Waitlist")
+
+	filter := bson.M{
+		"roomId": roomID,
+		"status": constants.WaitlistWaiting,
+		"start":  bson.M{"$lt": end},
+		"end":    bson.M{"$gt": start},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var entries []models.WaitlistEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// offers a freed up slot to the first waitlisted user whose window is now free
+// and lets them know with a push notification
+func OfferSlotToWaitlist(ctx context.Context, appsession *models.AppSession, booking models.Booking) error {
+	if err := ReofferExpiredWaitlistOffers(ctx, appsession); err != nil {
+		return err
+	}
+
+	return offerWaitlistSlot(ctx, appsession, booking)
+}
+
+// expires the offers that were not accepted in time and offers
+// each of their slots to the next user on the waitlist
+func ReofferExpiredWaitlistOffers(ctx context.Context, appsession *models.AppSession) error {
+	expired, err := ExpireWaitlistOffers(ctx, appsession)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range expired {
+		if err := offerWaitlistSlot(ctx, appsession, WaitlistEntryToBooking(entry)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// offers a slot to the first waitlisted user whose window within it is free
+func offerWaitlistSlot(ctx context.Context, appsession *models.AppSession, booking models.Booking) error {
+	entries, err := GetWaitlistForSlot(ctx, appsession, booking.RoomID, booking.Start, booking.End)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	settings, err := GetBookingSettings(ctx, appsession, booking.RoomID)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// the window must be free including the buffer, the same as when booking it
+		coinciding, err := CheckCoincidingBookings(ctx, appsession, ApplyBookingBuffer(WaitlistEntryToBooking(entry), settings.Buffer))
+		if err != nil {
+			return err
+		}
+
+		if coinciding {
+			continue
+		}
+
+		window := configs.GetWaitlistOfferWindow()
+		offerExpires := time.Now().Add(time.Duration(window) * time.Second)
+		if err := UpdateWaitlistStatus(ctx, appsession, entry.WaitlistID, constants.WaitlistOffered, offerExpires); err != nil {
+			return err
+		}
+
+		tokens, err := GetUsersPushTokens(ctx, appsession, []string{entry.Creator})
+		if err != nil {
+			return err
+		}
+
+		tokenArr, err := utils.ConvertTokensToStringArray(tokens, "expoPushToken")
+		if err != nil {
+			return err
+		}
+
+		notification := models.ScheduledNotification{
+			NotiID:               utils.GenerateUUID(),
+			Title:                "Booking Slot Available",
+			Message:              utils.ConstructWaitlistOfferString(entry.RoomName, fmt.Sprintf("%d mins", window/60)),
+			Sent:                 false,
+			SendTime:             time.Now(),
+			Emails:               []string{entry.Creator},
+			UnsentExpoPushTokens: tokenArr,
+			UnreadEmails:         []string{entry.Creator},
+			Type:                 constants.NotificationWaitlist,
+		}
+
+		success, err := AddNotification(ctx, appsession, notification, true)
+		if err != nil {
+			return err
+		}
+
+		if !success {
+			return errors.New("failed to send waitlist offer notification")
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// sets the status of a waitlist entry, the offer expiry is only set when an offer is made
+func UpdateWaitlistStatus(ctx context.Context, appsession *models.AppSession, waitlistID string, status string, offerExpires time.Time) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Waitlist")
+
+	set := bson.M{"status": status}
+	if !offerExpires.IsZero() {
+		set["offerExpires"] = offerExpires
+	}
+
+	filter := bson.M{"waitlistId": waitlistID}
+	_, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// releases the bookings that nobody checked into within the grace period so the slots can be booked again
+// and records them as no-shows against their creators
+func ReleaseNoShowBookings(ctx context.Context, appsession *models.AppSession, gracePeriod time.Duration) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	// only bookings that are still in progress hold a slot that can be freed
+	now := time.Now()
+	// bookings awaiting approval are not confirmed yet so nobody is expected to check in
+	filter := bson.M{
+		"checkedIn": false,
+		"status":    bson.M{"$ne": constants.BookingPending},
+		"start":     bson.M{"$lte": now.Add(-gracePeriod)},
+		"end":       bson.M{"$gt": now},
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var candidates []models.Booking
+	if err = cursor.All(ctx, &candidates); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	noShowCollection := appsession.DB.Database(configs.GetMongoDBName()).Collection("NoShowBookings")
+	usersCollection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	released := make([]models.Booking, 0, len(candidates))
+	for _, candidate := range candidates {
+		// the booking is only released if nobody checked in since it was found
+		var booking models.Booking
+		err := collection.FindOneAndDelete(ctx, bson.M{"occupiId": candidate.OccupiID, "checkedIn": false, "status": bson.M{"$ne": constants.BookingPending}}).Decode(&booking)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			logrus.Error(err)
+			return released, err
+		}
+
+		cache.DeleteBooking(appsession, booking.OccupiID)
+
+		if _, err := noShowCollection.InsertOne(ctx, models.NoShowBooking{Booking: booking, ReleasedAt: now}); err != nil {
+			logrus.Error(err)
+			return released, err
+		}
+
+		if _, err := usersCollection.UpdateOne(ctx, bson.M{"email": booking.Creator}, bson.M{"$inc": bson.M{"noShows": 1}}); err != nil {
+			logrus.Error(err)
+			return released, err
+		}
+
+		cache.DeleteUser(appsession, booking.Creator)
+
+		released = append(released, booking)
+	}
+
+	return released, nil
+}
+
+// gets the user's calendar feed token, creating one the first time the feed is requested
+func GetCalendarFeedToken(ctx *gin.Context, appsession *models.AppSession, email string) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("CalendarFeeds")
+
+	var feed models.CalendarFeed
+	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&feed)
+	if err == nil {
+		return feed.Token, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		logrus.Error(err)
+		return "", err
+	}
+
+	token, err := utils.GenerateFeedToken()
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	_, err = collection.InsertOne(ctx, models.CalendarFeed{
+		Email:     email,
+		Token:     token,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// replaces the user's calendar feed token so the old feed url stops working
+func ResetCalendarFeedToken(ctx *gin.Context, appsession *models.AppSession, email string) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("CalendarFeeds")
+
+	token, err := utils.GenerateFeedToken()
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$set": bson.M{
+		"token":     token,
+		"createdAt": time.Now(),
+	}}, options.Update().SetUpsert(true))
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// gets the email of the user a calendar feed token belongs to
+func GetCalendarFeedEmail(ctx *gin.Context, appsession *models.AppSession, token string) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("CalendarFeeds")
+
+	var feed models.CalendarFeed
+	if err := collection.FindOne(ctx, bson.M{"token": token}).Decode(&feed); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", errors.New("calendar feed not found")
+		}
+		logrus.Error(err)
+		return "", err
+	}
+
+	return feed.Email, nil
+}
+
+// replaces the user's caldav app password so calendar clients signed in with the old one stop working
+func ResetCalDAVPassword(ctx *gin.Context, appsession *models.AppSession, email string, passwordHash string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("CalDAVPasswords")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$set": bson.M{
+		"passwordHash": passwordHash,
+		"createdAt":    time.Now(),
+	}}, options.Update().SetUpsert(true))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// gets the email of the user a caldav app password belongs to
+func GetCalDAVPasswordEmail(ctx *gin.Context, appsession *models.AppSession, passwordHash string) (string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return "", errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("CalDAVPasswords")
+
+	var password models.CalDAVPassword
+	if err := collection.FindOne(ctx, bson.M{"passwordHash": passwordHash}).Decode(&password); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", errors.New("caldav password not found")
+		}
+		logrus.Error(err)
+		return "", err
+	}
+
+	return password.Email, nil
+}
+
+// gets the bookings the user made or was invited to that have not ended yet and start before until
+func GetUpcomingBookings(ctx *gin.Context, appsession *models.AppSession, email string, until time.Time) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"creator": email},
+			bson.M{"emails": email},
+		},
+		"end":   bson.M{"$gte": time.Now()},
+		"start": bson.M{"$lt": until},
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// gets the rooms that can be shown as calendars, archived rooms are left out
+func FindRooms(ctx *gin.Context, appsession *models.AppSession) ([]models.Room, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+
+	cursor, err := collection.Find(ctx, utils.ApplyRoomFilters(bson.M{}, nil), options.Find().SetSort(bson.M{"roomId": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var rooms []models.Room
+	if err = cursor.All(ctx, &rooms); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return rooms, nil
+}
+
+// gets the bookings of a room that overlap start and end, a zero end leaves the range open
+func GetRoomBookingsInRange(ctx *gin.Context, appsession *models.AppSession, roomID string, start time.Time, end time.Time) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{
+		"roomId": roomID,
+		"end":    bson.M{"$gt": start},
+	}
+	if !end.IsZero() {
+		filter["start"] = bson.M{"$lt": end}
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// gets the emails of every admin, they approve bookings of rooms that have no approvers of their own
+func GetAdminEmails(ctx *gin.Context, appsession *models.AppSession) ([]string, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	cursor, err := collection.Find(ctx, bson.M{"role": constants.Admin}, options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var admins []models.User
+	if err = cursor.All(ctx, &admins); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	emails := make([]string, 0, len(admins))
+	for _, admin := range admins {
+		emails = append(emails, admin.Email)
+	}
+
+	return emails, nil
+}
+
+// confirms a pending booking, or every pending occurrence when it is part of a series
+func ConfirmPendingBookings(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := PendingBookingsFilter(booking)
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if len(bookings) == 0 {
+		return nil, errors.New("booking is not pending")
+	}
+
+	if _, err = collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"status": constants.BookingConfirmed}}); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	for i := range bookings {
+		bookings[i].Status = constants.BookingConfirmed
+		cache.DeleteBooking(appsession, bookings[i].OccupiID)
+	}
+
+	return bookings, nil
+}
+
+// removes a rejected booking, or every pending occurrence and the series when it is part of a series
+func RejectPendingBookings(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := PendingBookingsFilter(booking)
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if len(bookings) == 0 {
+		return nil, errors.New("booking is not pending")
+	}
+
+	if _, err = collection.DeleteMany(ctx, filter); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	for _, rejected := range bookings {
+		cache.DeleteBooking(appsession, rejected.OccupiID)
+	}
+
+	if booking.SeriesID != "" {
+		seriesCollection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingSeries")
+		if _, err = seriesCollection.DeleteOne(ctx, bson.M{"seriesId": booking.SeriesID}); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+	}
+
+	return bookings, nil
+}
+
+// gets the bookings waiting for the user's approval, admins see every pending booking
+func GetPendingBookings(ctx *gin.Context, appsession *models.AppSession, email string, isAdmin bool) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	filter := bson.M{"status": constants.BookingPending}
+
+	if !isAdmin {
+		roomCollection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
+
+		roomIDs, err := roomCollection.Distinct(ctx, "roomId", bson.M{"approvers": email})
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+
+		if len(roomIDs) == 0 {
+			return []models.Booking{}, nil
+		}
+		filter["roomId"] = bson.M{"$in": roomIDs}
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	bookings := []models.Booking{}
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// records an attendee's response to a booking, replacing any earlier response
+func RespondToBooking(ctx *gin.Context, appsession *models.AppSession, booking models.Booking, email string, response string, series bool) ([]models.Booking, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := RSVPFilter(booking, email, series)
+
+	// find the bookings first so they can be removed from the cache
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"occupiId": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if len(bookings) == 0 {
+		return nil, errors.New("not invited to booking")
+	}
+
+	// an element of an array cannot be pulled and pushed in the same update
+	if _, err = collection.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"attendees": bson.M{"email": email}}}); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	attendee := models.AttendeeResponse{Email: email, Response: response, RespondedAt: time.Now()}
+	if _, err = collection.UpdateMany(ctx, filter, bson.M{"$push": bson.M{"attendees": attendee}}); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	for _, responded := range bookings {
+		cache.DeleteBooking(appsession, responded.OccupiID)
+	}
+
+	return bookings, nil
+}
+
+// gets the user's booking of a room that is in progress at now or starts within the check-in window,
+// the second return is false when the user has no such booking
+func GetCurrentRoomBooking(ctx *gin.Context, appsession *models.AppSession, roomID string, email string, now time.Time) (models.Booking, bool, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.Booking{}, false, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{
+		"roomId": roomID,
+		"$or": bson.A{
+			bson.M{"creator": email},
+			bson.M{"emails": email},
+		},
+		"start":  bson.M{"$lte": now.Add(constants.QRCheckInEarlyMinutes * time.Minute)},
+		"end":    bson.M{"$gt": now},
+		"status": bson.M{"$ne": constants.BookingPending},
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"start": 1}).SetLimit(1))
+	if err != nil {
+		logrus.Error(err)
+		return models.Booking{}, false, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return models.Booking{}, false, err
+	}
+
+	if len(bookings) == 0 {
+		return models.Booking{}, false, nil
+	}
+
+	return bookings[0], true, nil
+}
+
+// saves a booking policy, replacing the policy already set for its scope and target
+func SetBookingPolicy(ctx *gin.Context, appsession *models.AppSession, policy models.BookingPolicy) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingPolicies")
+
+	filter := bson.M{"scope": policy.Scope, "target": policy.Target}
+	update := bson.M{"$set": bson.M{
+		"maxDuration":       policy.MaxDuration,
+		"maxAdvanceDays":    policy.MaxAdvanceDays,
+		"maxActiveBookings": policy.MaxActiveBookings,
+		"weeklyHours":       policy.WeeklyHours,
+	}}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// gets every booking policy
+func GetBookingPolicies(ctx *gin.Context, appsession *models.AppSession) ([]models.BookingPolicy, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingPolicies")
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "scope", Value: 1}, {Key: "target", Value: 1}}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	policies := []models.BookingPolicy{}
+	if err = cursor.All(ctx, &policies); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// deletes the booking policy of a scope and target
+func DeleteBookingPolicy(ctx *gin.Context, appsession *models.AppSession, scope string, target string) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingPolicies")
+
+	res, err := collection.DeleteOne(ctx, bson.M{"scope": scope, "target": target})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("booking policy not found")
+	}
+
+	return nil
+}
+
+// gets the policies that apply to a user booking a room, through their role, their department or the room
+func GetApplicableBookingPolicies(ctx *gin.Context, appsession *models.AppSession, email string, roomID string) ([]models.BookingPolicy, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
+
+	users := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+
+	var user models.User
+	// users that are not found only get the room's policy
+	err := users.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetProjection(bson.M{"role": 1, "departmentNo": 1})).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("BookingPolicies")
+
+	cursor, err := collection.Find(ctx, BookingPoliciesFilter(user, roomID))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	var policies []models.BookingPolicy
+	if err = cursor.All(ctx, &policies); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// counts the bookings a user created that have not ended yet, a recurring series counts as one booking
+func CountActiveBookings(ctx *gin.Context, appsession *models.AppSession, email string, now time.Time) (int64, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return 0, errors.New("database is nil")
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"creator": email, "end": bson.M{"$gt": now}}},
+		bson.M{"$group": bson.M{"_id": bson.M{"$ifNull": bson.A{"$seriesId", "$occupiId"}}}},
+		bson.M{"$count": "n"},
+	}
 
 	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		logrus.Error(err)
-		return nil, 0, err
+		return 0, err
 	}
 
-	mongoFilter := MakeEmailAndTimeFilter(email, filter)
+	var results []struct {
+		N int64 `bson:"n"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
 
-	results, totalResults, errv := GetResultsAndCount(ctx, collection, cursor, mongoFilter)
+	if len(results) == 0 {
+		return 0, nil
+	}
 
-	if errv != nil {
-		logrus.Error(errv)
-		return nil, 0, errv
+	return results[0].N, nil
+}
+
+// gets how many minutes of the week from start to end a user has booked
+func GetBookedMinutes(ctx *gin.Context, appsession *models.AppSession, email string, start time.Time, end time.Time) (int, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return 0, errors.New("database is nil")
 	}
 
-	return results, totalResults, nil
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+
+	filter := bson.M{
+		"creator": email,
+		"start":   bson.M{"$lt": end},
+		"end":     bson.M{"$gt": start},
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"start": 1, "end": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+
+	var bookings []models.Booking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+
+	return BookedMinutesBetween(bookings, start, end), nil
 }
 
-func CreateUser(ctx *gin.Context, appsession *models.AppSession, user models.UserRequest) error {
+// lets the delegate make bookings for the principal, granting the same delegate again keeps the first grant
+func GrantDelegation(ctx *gin.Context, appsession *models.AppSession, principal string, delegate string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Delegations")
 
-	// validate role
-	if user.Role != "admin" && user.Role != "user" {
-		return errors.New("invalid role")
-	}
+	filter := bson.M{"principal": principal, "delegate": delegate}
+	update := bson.M{"$setOnInsert": bson.M{"createdAt": time.Now()}}
 
-	_, err := collection.InsertOne(ctx, CreateAUser(user))
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
 	if err != nil {
 		logrus.Error(err)
 		return err
@@ -1867,246 +5042,252 @@ func CreateUser(ctx *gin.Context, appsession *models.AppSession, user models.Use
 	return nil
 }
 
-func AddIP(ctx *gin.Context, appsession *models.AppSession, request models.RequestIP) (*ipinfo.Core, error) {
+// stops the delegate making bookings for the principal, bookings they already made are kept
+func RevokeDelegation(ctx *gin.Context, appsession *models.AppSession, principal string, delegate string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return nil, errors.New("database is nil")
+		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Delegations")
 
-	ipInfo, err := configs.GetIPInfo(request.IP, appsession.IPInfo)
+	res, err := collection.DeleteOne(ctx, bson.M{"principal": principal, "delegate": delegate})
 	if err != nil {
 		logrus.Error(err)
-		return nil, err
+		return err
 	}
 
-	location := models.Location{
-		City:      ipInfo.City,
-		Region:    ipInfo.Region,
-		Country:   ipInfo.Country,
-		Location:  ipInfo.Location,
-		IPAddress: request.IP,
+	if res.DeletedCount == 0 {
+		return errors.New("delegation not found")
 	}
 
-	// filter for all users emails which are in the request.Emails array also these emails
-	// don't have this location in the knownLocations array
-	filter := bson.M{"email": bson.M{"$in": request.Emails}, "knownLocations": bson.M{"$ne": location}}
+	return nil
+}
 
-	update := bson.M{"$push": bson.M{"knownLocations": location}}
+// gets the delegations the user granted and the ones granted to them
+func GetDelegations(ctx *gin.Context, appsession *models.AppSession, email string) ([]models.Delegation, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return nil, errors.New("database is nil")
+	}
 
-	_, err = collection.UpdateMany(ctx, filter, update)
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Delegations")
+
+	filter := bson.M{"$or": bson.A{bson.M{"principal": email}, bson.M{"delegate": email}}}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": 1}))
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
 	}
 
-	// get users from cache and update their knownLocations
-	for _, email := range request.Emails {
-		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
-			userData.KnownLocations = append(userData.KnownLocations, location)
-			cache.SetUser(appsession, userData)
-		}
+	delegations := []models.Delegation{}
+	if err = cursor.All(ctx, &delegations); err != nil {
+		logrus.Error(err)
+		return nil, err
 	}
 
-	return ipInfo, nil
+	return delegations, nil
 }
 
-func WhiteListIP(ctx *gin.Context, appsession *models.AppSession, emails []string, ip string) error {
+// checks whether the principal lets the delegate make bookings for them
+func IsDelegate(ctx *gin.Context, appsession *models.AppSession, principal string, delegate string) (bool, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return errors.New("database is nil")
+		return false, errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
-
-	// filter for all users emails which are in emails that have this ip in the blackListedIP field
-	filter := bson.M{"email": bson.M{"$in": emails}, "blackListedIP": bson.M{"$eq": ip}}
-
-	// pull this ip from the array
-	update := bson.M{"$pull": bson.M{"blackListedIP": ip}}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Delegations")
 
-	_, err := collection.UpdateMany(ctx, filter, update)
+	count, err := collection.CountDocuments(ctx, bson.M{"principal": principal, "delegate": delegate})
 	if err != nil {
 		logrus.Error(err)
-		return err
+		return false, err
 	}
 
-	for _, email := range emails {
-		// Fetch user from cache
-		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
-			updatedBlackListedIPs := []string{}
+	return count > 0, nil
+}
 
-			// Process the blackListedIP array
-			for _, blackIP := range userData.BlackListedIP {
-				// Exclude the IP that needs to be whitelisted
-				if blackIP != ip {
-					updatedBlackListedIPs = append(updatedBlackListedIPs, blackIP)
-				}
-			}
+// dead-letters a notification that could not be sent after all its retries
+func AddFailedNotification(ctx context.Context, appsession *models.AppSession, failed models.FailedNotification) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
+	}
 
-			// Update the user's BlackListedIP array if a change occurred
-			if len(updatedBlackListedIPs) != len(userData.BlackListedIP) {
-				userData.BlackListedIP = updatedBlackListedIPs
-				// Update the cache once per user, after processing all IPs
-				cache.SetUser(appsession, userData)
-			}
-		}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("FailedNotifications")
+
+	_, err := collection.InsertOne(ctx, failed)
+	if err != nil {
+		logrus.Error(err)
+		return err
 	}
 
 	return nil
 }
 
-func RemoveIP(ctx *gin.Context, appsession *models.AppSession, request models.RequestIP) (*ipinfo.Core, error) {
+// gets the dead-lettered notifications, the most recent failures first
+func GetFailedNotifications(ctx *gin.Context, appsession *models.AppSession) ([]models.FailedNotification, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return nil, errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("FailedNotifications")
 
-	ipInfo, err := configs.GetIPInfo(request.IP, appsession.IPInfo)
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"failedAt": -1}))
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
 	}
 
-	location := models.Location{
-		City:      ipInfo.City,
-		Region:    ipInfo.Region,
-		Country:   ipInfo.Country,
-		Location:  ipInfo.Location,
-		IPAddress: request.IP,
+	failed := []models.FailedNotification{}
+	if err = cursor.All(ctx, &failed); err != nil {
+		logrus.Error(err)
+		return nil, err
 	}
 
-	// filter for all users emails which are in the request.Emails array and have this location in the knownLocations array
-	filter := bson.M{"email": bson.M{"$in": request.Emails}, "knownLocations": bson.M{"$eq": location}}
+	return failed, nil
+}
 
-	update := bson.M{"$pull": bson.M{"knownLocations": location}}
+// gets a dead-lettered notification by its id
+func GetFailedNotification(ctx *gin.Context, appsession *models.AppSession, failedID string) (models.FailedNotification, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.FailedNotification{}, errors.New("database is nil")
+	}
 
-	_, err = collection.UpdateMany(ctx, filter, update)
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("FailedNotifications")
+
+	id, err := primitive.ObjectIDFromHex(failedID)
 	if err != nil {
 		logrus.Error(err)
-		return nil, err
+		return models.FailedNotification{}, errors.New("failed notification not found")
 	}
 
-	// get users from cache and update their knownLocations
-	for _, email := range request.Emails {
-		// Fetch user from cache
-		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
-			updatedKnownLocations := []models.Location{}
-
-			// Process the KnownLocations array
-			for _, loc := range userData.KnownLocations {
-				// Exclude the location that needs to be removed
-				if loc != location {
-					updatedKnownLocations = append(updatedKnownLocations, loc)
-				}
-			}
-
-			// Update the user's KnownLocations array if a change occurred
-			if len(updatedKnownLocations) != len(userData.KnownLocations) {
-				userData.KnownLocations = updatedKnownLocations
-				// Update the cache once per user, after processing all locations
-				cache.SetUser(appsession, userData)
-			}
-		}
+	var failed models.FailedNotification
+	err = collection.FindOne(ctx, bson.M{"_id": id}).Decode(&failed)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.FailedNotification{}, errors.New("failed notification not found")
+	}
+	if err != nil {
+		logrus.Error(err)
+		return models.FailedNotification{}, err
 	}
 
-	return ipInfo, nil
+	return failed, nil
 }
 
-func BlackListIP(ctx *gin.Context, appsession *models.AppSession, emails []string, ip string) error {
+// removes a dead-lettered notification once it has been replayed
+func DeleteFailedNotification(ctx *gin.Context, appsession *models.AppSession, failedID string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
-
-	filter := bson.M{"email": bson.M{"$in": emails}}
-
-	update := bson.M{"$push": bson.M{"blackListedIP": ip}}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("FailedNotifications")
 
-	_, err := collection.UpdateMany(ctx, filter, update)
+	id, err := primitive.ObjectIDFromHex(failedID)
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
-	// get users from cache and update their knownLocations
-	for _, email := range emails {
-		if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
-			userData.BlackListedIP = append(userData.BlackListedIP, ip)
-			cache.SetUser(appsession, userData)
-		}
+	_, err = collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		logrus.Error(err)
+		return err
 	}
 
 	return nil
 }
 
-func IsIPBlackListed(ctx *gin.Context, appsession *models.AppSession, email, ip string) (bool, error) {
+// gets the users a notification is for with the settings that decide how it reaches them
+func GetNotificationRecipients(ctx context.Context, appsession *models.AppSession, emails []string) ([]models.User, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return false, errors.New("database is nil")
-	}
-
-	// check if user is in cache
-	if userData, err := cache.GetUser(appsession, email); err == nil {
-		return utils.Contains(userData.BlackListedIP, ip), nil
+		return nil, errors.New("database is nil")
 	}
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
-	// check if ip is in blackListedIP array
-	filter := bson.M{"email": email}
-	var user models.User
-	err := collection.FindOne(ctx, filter).Decode(&user)
+	findOptions := options.Find().SetProjection(bson.M{"email": 1, "expoPushToken": 1, "devices": 1, "notifications": 1})
+
+	cursor, err := collection.Find(ctx, bson.M{"email": bson.M{"$in": emails}}, findOptions)
 	if err != nil {
 		logrus.Error(err)
-		return false, err
+		return nil, err
 	}
 
-	// Add the user to the cache if cache is not nil
-	cache.SetUser(appsession, user)
+	users := []models.User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
 
-	return utils.Contains(user.BlackListedIP, ip), nil
+	return users, nil
 }
 
-func UserHasImage(ctx *gin.Context, appsession *models.AppSession, email string) bool {
+// records how a notification was delivered on each channel
+func RecordNotificationDeliveries(ctx context.Context, appsession *models.AppSession, notiID string, deliveries []models.NotificationDelivery) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return false
+		return errors.New("database is nil")
 	}
 
-	// check if user is in cache
-	if userData, err := cache.GetUser(appsession, email); err == nil {
-		return userData.Details.HasImage
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
+
+	filter := bson.M{"notiId": notiID}
+	update := bson.M{"$push": bson.M{"deliveries": bson.M{"$each": deliveries}}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// adds a browser's web push subscription to a user, a browser that is already subscribed is left as is
+func AddWebPushSubscription(ctx *gin.Context, appsession *models.AppSession, email string, subscription models.WebPushSubscription) error {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return errors.New("database is nil")
 	}
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
-	filter := bson.M{"email": email}
-	var user models.User
-	err := collection.FindOne(ctx, filter).Decode(&user)
+	filter := bson.M{"email": email, "notifications.webPush.endpoint": bson.M{"$ne": subscription.Endpoint}}
+	update := bson.M{"$push": bson.M{"notifications.webPush": subscription}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		logrus.Error(err)
-		return false
+		return err
 	}
 
-	// Add the user to the cache if cache is not nil
-	cache.SetUser(appsession, user)
+	cache.DeleteUser(appsession, email)
 
-	return user.Details.HasImage
+	return nil
 }
 
-func SetHasImage(ctx *gin.Context, appsession *models.AppSession, email string, hasImage bool) error {
+// removes a browser's web push subscription from a user
+func RemoveWebPushSubscription(ctx context.Context, appsession *models.AppSession, email string, endpoint string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
@@ -2116,7 +5297,7 @@ func SetHasImage(ctx *gin.Context, appsession *models.AppSession, email string,
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
 	filter := bson.M{"email": email}
-	update := bson.M{"$set": bson.M{"details.hasImage": hasImage}}
+	update := bson.M{"$pull": bson.M{"notifications.webPush": bson.M{"endpoint": endpoint}}}
 
 	_, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -2124,243 +5305,201 @@ func SetHasImage(ctx *gin.Context, appsession *models.AppSession, email string,
 		return err
 	}
 
-	// get user from cache
-	if userData, cacheErr := cache.GetUser(appsession, email); cacheErr == nil {
-		userData.Details.HasImage = hasImage
-		cache.SetUser(appsession, userData)
-	}
+	cache.DeleteUser(appsession, email)
 
 	return nil
 }
 
-func GetUsersGender(ctx *gin.Context, appsession *models.AppSession, email string) (string, error) {
+// saves the ticket expo gave for a push notification so its receipt can be checked later
+func AddPushTicket(ctx context.Context, appsession *models.AppSession, ticket models.PushTicket) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return "", errors.New("database is nil")
-	}
-
-	// check if user is in cache
-	if userData, err := cache.GetUser(appsession, email); err == nil {
-		return userData.Details.Gender, nil
+		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("PushTickets")
 
-	filter := bson.M{"email": email}
-	var user models.User
-	err := collection.FindOne(ctx, filter).Decode(&user)
+	_, err := collection.InsertOne(ctx, ticket)
 	if err != nil {
 		logrus.Error(err)
-		return "", err
+		return err
 	}
 
-	// Add the user to the cache if cache is not nil
-	cache.SetUser(appsession, user)
-
-	return user.Details.Gender, nil
+	return nil
 }
 
-func CheckIfUserIsAllowedNewIP(ctx *gin.Context, appsession *models.AppSession, email string) (bool, error) {
+// gets the push tickets sent before the given time whose receipts have not been checked yet, oldest first
+func GetPendingPushTickets(ctx context.Context, appsession *models.AppSession, before time.Time) ([]models.PushTicket, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return false, errors.New("database is nil")
+		return nil, errors.New("database is nil")
 	}
 
-	// check if user is in cache
-	if userData, err := cache.GetUser(appsession, email); err == nil {
-		return userData.BlockAnonymousIPAddress, nil
-	}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("PushTickets")
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
+	filter := bson.M{"status": constants.TicketPending, "createdAt": bson.M{"$lte": before}}
+	findOptions := options.Find().SetSort(bson.M{"createdAt": 1})
 
-	filter := bson.M{"email": email}
-	var user models.User
-	err := collection.FindOne(ctx, filter).Decode(&user)
+	cursor, err := collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		logrus.Error(err)
-		return false, err
+		return nil, err
 	}
 
-	// Add the user to the cache if cache is not nil
-	cache.SetUser(appsession, user)
+	tickets := []models.PushTicket{}
+	if err = cursor.All(ctx, &tickets); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
 
-	return user.BlockAnonymousIPAddress, nil
+	return tickets, nil
 }
 
-func CheckIfUserShouldResetPassword(ctx *gin.Context, appsession *models.AppSession, email string) (bool, error) {
+// records what the receipt of a push ticket said
+func ResolvePushTicket(ctx context.Context, appsession *models.AppSession, ticketID string, status string, errorCode string, message string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return false, errors.New("database is nil")
-	}
-
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
-	filter := bson.M{"email": email}
-
-	// get user from cache
-	var userData models.User
-	var cacheErr error
-	if userData, cacheErr = cache.GetUser(appsession, email); cacheErr != nil {
-		// get the user from the database
-		err := collection.FindOne(ctx, filter).Decode(&userData)
-		if err != nil {
-			logrus.Error(err)
-			return false, err
-		}
+		return errors.New("database is nil")
 	}
 
-	if !userData.ResetPassword {
-		return false, nil
-	}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("PushTickets")
 
-	update := bson.M{"$set": bson.M{"resetPassword": !userData.ResetPassword}}
+	filter := bson.M{"ticketId": ticketID}
+	update := bson.M{"$set": bson.M{"status": status, "error": errorCode, "message": message, "checkedAt": time.Now()}}
 
 	_, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		logrus.Error(err)
-		return false, err
+		return err
 	}
 
-	// update user in cache
-	userData.ResetPassword = !userData.ResetPassword
-	cache.SetUser(appsession, userData)
-
-	return true, nil
+	return nil
 }
 
-func ToggleAllowAnonymousIP(ctx *gin.Context, appsession *models.AppSession, request models.AllowAnonymousIPRequest) error {
+// removes a push token that a notification reached from the notification's unsent tokens
+func PullUnsentPushToken(ctx context.Context, appsession *models.AppSession, notiID string, token string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
 		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
-
-	// filter for all users emails which are in the request.Emails array
-	filter := bson.M{"email": bson.M{"$in": request.Emails}}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
 
-	update := bson.M{"$set": bson.M{"blockAnonymousIPAddress": request.BlockAnonymousIPAddress}}
+	filter := bson.M{"notiId": notiID}
+	update := bson.M{"$pull": bson.M{"unsentExpoPushTokens": token}}
 
-	_, err := collection.UpdateMany(ctx, filter, update)
+	_, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
-	// fetch the emails in cache and update the blockAnonymousIPAddress field
-	for _, email := range request.Emails {
-		userData, cacheErr := cache.GetUser(appsession, email)
-		if cacheErr != nil {
-			continue
-		}
-
-		userData.BlockAnonymousIPAddress = request.BlockAnonymousIPAddress
-		cache.SetUser(appsession, userData)
-	}
-
 	return nil
 }
 
-func GetAnalyticsOnBookings(ctx *gin.Context, appsession *models.AppSession, creatorEmail string,
-	attendeeEmails []string, filter models.AnalyticsFilterStruct, calculate string) ([]primitive.M, int64, error) {
-
+// removes a push token expo no longer delivers to from the users and devices it belongs to
+func RemoveExpoPushToken(ctx context.Context, appsession *models.AppSession, token string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return nil, 0, errors.New("database is nil")
+		return errors.New("database is nil")
 	}
 
-	// Prepare the aggregate
-	var pipeline bson.A
-	var dateFilter string
-	switch calculate {
-	case "top3":
-		dateFilter = "date"
-		filter.Filter["timeTo"] = ""
-		pipeline = analytics.GetTop3MostBookedRooms(creatorEmail, attendeeEmails, filter, dateFilter)
-	case "historical":
-		// add or overwrite "timeTo" with time.Now and delete "timeFrom" if present
-		filter.Filter["timeTo"] = time.Now().In(time.Local)
-		filter.Filter["timeFrom"] = ""
-		dateFilter = "end"
-		pipeline = analytics.AggregateBookings(creatorEmail, attendeeEmails, filter, dateFilter)
-	case "upcoming":
-		// add or overwrite "timeFrom" with time.Now and delete "timeTo" if present
-		filter.Filter["timeFrom"] = time.Now().In(time.Local)
-		filter.Filter["timeTo"] = ""
-		dateFilter = "end"
-		pipeline = analytics.AggregateBookings(creatorEmail, attendeeEmails, filter, dateFilter)
-	default:
-		return nil, 0, errors.New("invalid calculate value")
-	}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("RoomBooking")
+	filter := bson.M{"$or": bson.A{bson.M{"expoPushToken": token}, bson.M{"devices.token": token}}}
 
-	cursor, err := collection.Aggregate(ctx, pipeline)
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"email": 1}))
 	if err != nil {
 		logrus.Error(err)
-		return nil, 0, err
+		return err
 	}
 
-	mongoFilter := MakeEmailAndEmailsAndTimeFilter(creatorEmail, attendeeEmails, filter, dateFilter)
+	users := []models.User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		logrus.Error(err)
+		return err
+	}
 
-	results, totalResults, errv := GetResultsAndCount(ctx, collection, cursor, mongoFilter)
+	if len(users) == 0 {
+		return nil
+	}
 
-	results = GetImagesForRooms(ctx, appsession, results)
+	_, err = collection.UpdateMany(ctx, bson.M{"expoPushToken": token}, bson.M{"$set": bson.M{"expoPushToken": ""}})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
 
-	if errv != nil {
-		logrus.Error(errv)
-		return nil, 0, errv
+	_, err = collection.UpdateMany(ctx, bson.M{"devices.token": token}, bson.M{"$pull": bson.M{"devices": bson.M{"token": token}}})
+	if err != nil {
+		logrus.Error(err)
+		return err
 	}
 
-	return results, totalResults, nil
+	for _, user := range users {
+		cache.DeleteUser(appsession, user.Email)
+	}
+
+	return nil
 }
 
-func GetImagesForRooms(ctx *gin.Context, appsession *models.AppSession, results []primitive.M) []primitive.M {
-	// iterate through results and extract the roomId and add it to the roomIds array
-	roomIds := make([]string, 0)
-	for _, result := range results {
-		roomIds = append(roomIds, result["roomId"].(string))
+// counts how the push notifications sent since the given time were delivered
+func GetPushDeliveryStats(ctx *gin.Context, appsession *models.AppSession, since time.Time) (models.PushDeliveryStats, error) {
+	// check if database is nil
+	if appsession.DB == nil {
+		logrus.Error("Database is nil")
+		return models.PushDeliveryStats{}, errors.New("database is nil")
 	}
 
-	// get the images for the rooms from the Rooms collection
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Rooms")
-	filter := bson.M{"roomId": bson.M{"$in": roomIds}}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("PushTickets")
 
-	// return only the roomImage field
-	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"roomImage": 1, "roomId": 1}))
+	cursor, err := collection.Aggregate(ctx, PushDeliveryStatsPipeline(since))
 	if err != nil {
-		logrus.WithError(err).Error("Failed to get data from database")
-		return results
-	}
-
-	// iterate through the cursor and add the roomImage to the results matching on roomId
-	var imageRes []bson.M
-	if err := cursor.All(ctx, &imageRes); err != nil {
-		logrus.WithError(err).Error("Failed to get data from cursor")
-		return results
+		logrus.Error(err)
+		return models.PushDeliveryStats{}, err
 	}
 
-	// Create a map for quick lookup of room images by roomId
-	imageMap := make(map[string]interface{})
-	for _, image := range imageRes {
-		imageMap[image["roomId"].(string)] = image["roomImage"]
+	var counts []struct {
+		ID struct {
+			Status string `bson:"status"`
+			Error  string `bson:"error"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
 	}
-
-	// Iterate through the results and add the roomImage from the map
-	for _, result := range results {
-		if roomImage, ok := imageMap[result["roomId"].(string)]; ok {
-			result["roomImage"] = roomImage
+	if err = cursor.All(ctx, &counts); err != nil {
+		logrus.Error(err)
+		return models.PushDeliveryStats{}, err
+	}
+
+	stats := models.PushDeliveryStats{Since: since, Errors: map[string]int{}}
+	for _, count := range counts {
+		stats.Total += count.Count
+		switch count.ID.Status {
+		case constants.TicketPending:
+			stats.Pending += count.Count
+		case constants.TicketDelivered:
+			stats.Delivered += count.Count
+		case constants.TicketFailed:
+			stats.Failed += count.Count
+			if count.ID.Error != "" {
+				stats.Errors[count.ID.Error] += count.Count
+			}
+		case constants.TicketExpired:
+			stats.Expired += count.Count
 		}
 	}
 
-	return results
+	return stats, nil
 }
 
-func ToggleAdminStatus(ctx *gin.Context, appsession *models.AppSession, request models.RoleRequest) error {
+// registers a device a user signed in on for push notifications, a device that was registered to someone else stops
+// getting their notifications and one that is already registered to the user is marked as seen
+func RegisterDevice(ctx *gin.Context, appsession *models.AppSession, email string, device models.Device) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
@@ -2369,110 +5508,93 @@ func ToggleAdminStatus(ctx *gin.Context, appsession *models.AppSession, request
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
-	// filter for user with the email
-	filter := bson.M{"email": request.Email}
+	// a device only belongs to the last user that signed in on it
+	_, err := collection.UpdateMany(ctx, bson.M{"email": bson.M{"$ne": email}, "devices.token": device.Token}, bson.M{"$pull": bson.M{"devices": bson.M{"token": device.Token}}})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
 
-	// validate role is either basic or admin
-	if request.Role != constants.Admin && request.Role != constants.Basic {
-		request.Role = constants.Basic
+	_, err = collection.UpdateMany(ctx, bson.M{"email": bson.M{"$ne": email}, "expoPushToken": device.Token}, bson.M{"$set": bson.M{"expoPushToken": ""}})
+	if err != nil {
+		logrus.Error(err)
+		return err
 	}
 
-	update := bson.M{"$set": bson.M{"role": request.Role}}
+	filter := bson.M{"email": email, "devices.token": device.Token}
+	update := bson.M{"$set": bson.M{
+		"devices.$.platform":   device.Platform,
+		"devices.$.appVersion": device.AppVersion,
+		"devices.$.lastSeen":   device.LastSeen,
+	}}
 
-	_, err := collection.UpdateOne(ctx, filter, update)
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
-	// fetch the email in cache and update the role field
-	if userData, cacheErr := cache.GetUser(appsession, request.Email); cacheErr == nil {
-		userData.Role = request.Role
-		cache.SetUser(appsession, userData)
+	if result.MatchedCount == 0 {
+		_, err = collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$push": bson.M{"devices": device}})
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
 	}
 
+	cache.DeleteUser(appsession, email)
+
 	return nil
 }
 
-func CountNotifications(ctx *gin.Context, appsession *models.AppSession, email string) (int64, int64, error) {
+// stops sending push notifications to a device a user signed out of
+func UnregisterDevice(ctx *gin.Context, appsession *models.AppSession, email string, token string) error {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return 0, 0, errors.New("database is nil")
+		return errors.New("database is nil")
 	}
 
-	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Notifications")
-
-	// filter for all notifications with the email in the unreadEmails field
-	filter := bson.M{"unreadEmails": email}
+	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
-	// get the count of unread notifications
-	unreadCount, err := collection.CountDocuments(ctx, filter)
+	_, err := collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$pull": bson.M{"devices": bson.M{"token": token}}})
 	if err != nil {
 		logrus.Error(err)
-		return 0, 0, err
+		return err
 	}
 
-	// count total notifications
-	totalCount, err := collection.CountDocuments(ctx, bson.M{})
+	// the device may be the one the user signed up on
+	_, err = collection.UpdateOne(ctx, bson.M{"email": email, "expoPushToken": token}, bson.M{"$set": bson.M{"expoPushToken": ""}})
 	if err != nil {
 		logrus.Error(err)
-		return 0, 0, err
+		return err
 	}
 
-	return unreadCount, totalCount, nil
+	cache.DeleteUser(appsession, email)
+
+	return nil
 }
 
-func GetUsersLocations(ctx *gin.Context, appsession *models.AppSession, limit int64, skip int64, order string, email string, ipPrivelege string) ([]primitive.M, int64, error) {
+// gets the devices a user gets push notifications on
+func GetDevices(ctx *gin.Context, appsession *models.AppSession, email string) ([]models.Device, error) {
 	// check if database is nil
 	if appsession.DB == nil {
 		logrus.Error("Database is nil")
-		return nil, 0, errors.New("database is nil")
+		return nil, errors.New("database is nil")
 	}
 
 	collection := appsession.DB.Database(configs.GetMongoDBName()).Collection("Users")
 
-	var pipeline primitive.A
-	if ipPrivelege == "whitelist" {
-		pipeline = analytics.GetUsersLocationsPipeLine(limit, skip, order, email)
-	} else {
-		pipeline = analytics.GetBlacklistPipeLine(limit, skip, order, email)
-	}
-
-	// get all known locations
-	cursor, err := collection.Aggregate(ctx, pipeline)
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetProjection(bson.M{"devices": 1})).Decode(&user)
 	if err != nil {
 		logrus.Error(err)
-		return nil, 0, err
-	}
-
-	type FacetResult struct {
-		Metadata []struct {
-			Total int64 `bson:"total"`
-		} `bson:"metadata"`
-		Data []primitive.M `bson:"data"`
-	}
-
-	var facetResults []FacetResult
-	if err = cursor.All(ctx, &facetResults); err != nil {
-		logrus.Error(err)
-		return nil, 0, err
+		return nil, err
 	}
 
-	// Initialize variables for data and count
-	var locations []primitive.M
-	var totalResults int64
-
-	// Check if facetResults contains data
-	if len(facetResults) > 0 {
-		// Extract data (locations)
-		locations = facetResults[0].Data
-
-		// Extract total count from metadata
-		if len(facetResults[0].Metadata) > 0 {
-			totalResults = facetResults[0].Metadata[0].Total
-		}
+	if user.Devices == nil {
+		return []models.Device{}, nil
 	}
 
-	return locations, totalResults, nil
+	return user.Devices, nil
 }
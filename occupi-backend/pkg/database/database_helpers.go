@@ -1,7 +1,10 @@
 package database
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +20,7 @@ import (
 
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 )
 
 func CreateBasicUser(user models.RegisterUser) models.User {
@@ -197,43 +201,460 @@ func IsLocationInRange(locations []models.Location, unrecognizedLogger *ipinfo.C
 }
 
 func ComputeAvailableSlots(bookings []models.Booking, dateOfBooking time.Time) []models.Slot {
+	return ComputeAvailableSlotsWithSettings(bookings, dateOfBooking, DefaultBookingSettings())
+}
+
+// computes the free slots of a room on a day within its opening hours, keeping the buffer clear around
+// every booking and skipping blackout periods, free time is split into slots of the configured length
+func ComputeAvailableSlotsWithSettings(bookings []models.Booking, dateOfBooking time.Time, settings models.BookingSettings) []models.Slot {
 	var availableSlots []models.Slot
 
-	// Define the boundaries
-	// 8:00 AM to 5:00 PM
-	startOfDay := time.Date(dateOfBooking.Year(), dateOfBooking.Month(), dateOfBooking.Day(), 8, 0, 0, 0, time.Local) // 8:00 AM
-	endOfDay := time.Date(dateOfBooking.Year(), dateOfBooking.Month(), dateOfBooking.Day(), 17, 0, 0, 0, time.Local)  // 5:00 PM
+	startOfDay, endOfDay := BusinessHours(dateOfBooking, settings)
+	buffer := time.Duration(settings.Buffer) * time.Minute
+
+	busy := make([]models.Slot, 0, len(bookings)+len(settings.Blackouts))
+	for _, booking := range bookings {
+		busy = append(busy, models.Slot{Start: booking.Start.Add(-buffer), End: booking.End.Add(buffer)})
+	}
+	for _, blackout := range settings.Blackouts {
+		busy = append(busy, models.Slot{Start: blackout.Start, End: blackout.End})
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
 
 	previousEnd := startOfDay
 
-	for _, booking := range bookings {
-		if booking.Start.After(previousEnd) {
-			availableSlots = append(availableSlots, models.Slot{
-				Start: previousEnd,
-				End:   booking.Start,
-			})
+	for _, period := range busy {
+		if period.Start.After(previousEnd) && previousEnd.Before(endOfDay) {
+			end := period.Start
+			if end.After(endOfDay) {
+				end = endOfDay
+			}
+			availableSlots = append(availableSlots, SplitIntoSlots(previousEnd, end, startOfDay, settings.SlotLength)...)
+		}
+		if period.End.After(previousEnd) {
+			previousEnd = period.End
 		}
-		previousEnd = booking.End
 	}
 
 	// Check for a slot after the last booking
 	if previousEnd.Before(endOfDay) {
-		availableSlots = append(availableSlots, models.Slot{
-			Start: previousEnd,
-			End:   endOfDay,
-		})
+		availableSlots = append(availableSlots, SplitIntoSlots(previousEnd, endOfDay, startOfDay, settings.SlotLength)...)
 	}
 
 	return availableSlots
 }
 
+// returns when a room that is free at now next becomes busy, capped at closing time, the second return is
+// false when the room is outside its hours, inside a booking's buffer or blacked out at now
+func FreeUntil(bookings []models.Booking, now time.Time, settings models.BookingSettings) (time.Time, bool) {
+	// the hours are on the site's calendar day, which may not be the server's
+	opening, closing := BusinessHours(now.In(LoadTimeZone(settings.TimeZone)), settings)
+	if now.Before(opening) || !now.Before(closing) {
+		return time.Time{}, false
+	}
+
+	buffer := time.Duration(settings.Buffer) * time.Minute
+
+	busy := make([]models.Slot, 0, len(bookings)+len(settings.Blackouts))
+	for _, booking := range bookings {
+		busy = append(busy, models.Slot{Start: booking.Start.Add(-buffer), End: booking.End.Add(buffer)})
+	}
+	for _, blackout := range settings.Blackouts {
+		busy = append(busy, models.Slot{Start: blackout.Start, End: blackout.End})
+	}
+
+	until := closing
+	for _, period := range busy {
+		if !period.Start.After(now) && period.End.After(now) {
+			return time.Time{}, false
+		}
+		if period.Start.After(now) && period.Start.Before(until) {
+			until = period.Start
+		}
+	}
+
+	return until, true
+}
+
+// works out when a booking made on the spot in a free slot ends, the requested duration in minutes is capped
+// by the maximum and the booking always ends by the time the room is next needed
+func AdHocBookingEnd(slot models.Slot, duration int, maxDuration time.Duration) time.Time {
+	length := maxDuration
+	if duration > 0 && time.Duration(duration)*time.Minute < length {
+		length = time.Duration(duration) * time.Minute
+	}
+
+	end := slot.Start.Add(length)
+	if slot.End.Before(end) {
+		end = slot.End
+	}
+	return end
+}
+
+// splits free time into whole slots aligned to the opening time, a slot length of 0 returns the free time as is
+func SplitIntoSlots(start time.Time, end time.Time, opening time.Time, slotLength int) []models.Slot {
+	if slotLength <= 0 {
+		return []models.Slot{{Start: start, End: end}}
+	}
+
+	length := time.Duration(slotLength) * time.Minute
+	slots := make([]models.Slot, 0)
+
+	// round the start up to the next slot boundary
+	slotStart := opening
+	if start.After(opening) {
+		slotStart = opening.Add(((start.Sub(opening) + length - 1) / length) * length)
+	}
+
+	for ; !slotStart.Add(length).After(end); slotStart = slotStart.Add(length) {
+		slots = append(slots, models.Slot{Start: slotStart, End: slotStart.Add(length)})
+	}
+
+	return slots
+}
+
+// returns the settings used when neither the room nor its building have any configured
+func DefaultBookingSettings() models.BookingSettings {
+	return models.BookingSettings{
+		OpeningTime: constants.DefaultOpeningTime,
+		ClosingTime: constants.DefaultClosingTime,
+		SlotLength:  0,
+		Buffer:      0,
+		Blackouts:   []models.Blackout{},
+	}
+}
+
+// picks the most specific settings for a room, room settings override building settings which override the defaults,
+// blackouts from every level apply
+func ResolveBookingSettings(settings []models.BookingSettings, roomID string, buildingID string) models.BookingSettings {
+	resolved := DefaultBookingSettings()
+	rank := 0
+	blackouts := make([]models.Blackout, 0)
+
+	for _, setting := range settings {
+		var level int
+		switch {
+		case setting.RoomID != "" && setting.RoomID == roomID:
+			level = 3
+		case setting.RoomID == "" && setting.BuildingID != "" && setting.BuildingID == buildingID:
+			level = 2
+		case setting.RoomID == "" && setting.BuildingID == "":
+			level = 1
+		default:
+			continue
+		}
+
+		blackouts = append(blackouts, setting.Blackouts...)
+		if level > rank {
+			resolved = setting
+			rank = level
+		}
+	}
+
+	resolved.Blackouts = blackouts
+	return resolved
+}
+
+// parses a HH:MM clock time onto the given date in the given location
+func ParseClockTime(date time.Time, clock string, location *time.Location) (time.Time, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %s, expected HH:MM", clock)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), parsed.Hour(), parsed.Minute(), 0, 0, location), nil
+}
+
+// loads a sites timezone, rooms without a site or with an unknown timezone use the server's timezone
+func LoadTimeZone(timeZone string) *time.Location {
+	if timeZone == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		logrus.Error(err)
+		return time.Local
+	}
+	return location
+}
+
+// returns the opening and closing times on the given date, falling back to the default hours if the settings are invalid
+func BusinessHours(date time.Time, settings models.BookingSettings) (time.Time, time.Time) {
+	location := LoadTimeZone(settings.TimeZone)
+	opening, openErr := ParseClockTime(date, settings.OpeningTime, location)
+	closing, closeErr := ParseClockTime(date, settings.ClosingTime, location)
+	if openErr != nil || closeErr != nil || !opening.Before(closing) {
+		defaults := DefaultBookingSettings()
+		opening, _ = ParseClockTime(date, defaults.OpeningTime, location)
+		closing, _ = ParseClockTime(date, defaults.ClosingTime, location)
+	}
+	return opening, closing
+}
+
+// checks that booking settings are consistent before they are saved
+func ValidateBookingSettings(settings models.BookingSettings) error {
+	now := time.Now()
+	opening, err := ParseClockTime(now, settings.OpeningTime, time.UTC)
+	if err != nil {
+		return err
+	}
+	closing, err := ParseClockTime(now, settings.ClosingTime, time.UTC)
+	if err != nil {
+		return err
+	}
+	if !opening.Before(closing) {
+		return errors.New("opening time must be before closing time")
+	}
+	if settings.SlotLength < 0 || settings.Buffer < 0 {
+		return errors.New("slot length and buffer cannot be negative")
+	}
+	if settings.SlotLength > int(closing.Sub(opening).Minutes()) {
+		return errors.New("slot length cannot be longer than the opening hours")
+	}
+	for _, blackout := range settings.Blackouts {
+		if !blackout.Start.Before(blackout.End) {
+			return errors.New("blackout start must be before its end")
+		}
+	}
+	return nil
+}
+
+// checks a booking against the opening hours, slot length and blackouts of its room,
+// returning the error code of the rule it breaks
+func CheckBookingAgainstSettings(booking models.Booking, settings models.BookingSettings) (string, error) {
+	opening, closing := BusinessHours(booking.Start.In(LoadTimeZone(settings.TimeZone)), settings)
+
+	if booking.Start.Before(opening) || booking.End.After(closing) || !booking.Start.Before(booking.End) {
+		return constants.OutsideBusinessHoursCode, fmt.Errorf("bookings must fall between %s and %s", opening.Format("15:04"), closing.Format("15:04"))
+	}
+
+	if settings.SlotLength > 0 {
+		length := time.Duration(settings.SlotLength) * time.Minute
+		if booking.Start.Sub(opening)%length != 0 || booking.End.Sub(opening)%length != 0 {
+			return constants.SlotMisalignedCode, fmt.Errorf("bookings must start and end on %d minute slots from %s", settings.SlotLength, opening.Format("15:04"))
+		}
+	}
+
+	if blackout, found := BlackoutDuring(booking.Start, booking.End, settings.Blackouts); found {
+		return constants.BlackoutPeriodCode, fmt.Errorf("room is unavailable from %s to %s: %s", blackout.Start.Format(time.RFC1123), blackout.End.Format(time.RFC1123), blackout.Reason)
+	}
+
+	return "", nil
+}
+
+// returns the first blackout that overlaps the given time
+func BlackoutDuring(start time.Time, end time.Time, blackouts []models.Blackout) (models.Blackout, bool) {
+	for _, blackout := range blackouts {
+		if blackout.Start.Before(end) && blackout.End.After(start) {
+			return blackout, true
+		}
+	}
+	return models.Blackout{}, false
+}
+
+// reports whether a booking from start to end can be checked into at now, check-in opens a few minutes before
+// the booking starts, the same as scanning a room's qr code, and stays open until the booking ends
+func CheckInWindowOpen(start time.Time, end time.Time, now time.Time) bool {
+	return !now.Before(start.Add(-constants.QRCheckInEarlyMinutes*time.Minute)) && now.Before(end)
+}
+
+// works out when a desk slot starts and ends on the calendar day of date, mornings end and afternoons start at midday
+func DeskSlotTimes(date time.Time, slot string, settings models.BookingSettings) (time.Time, time.Time, error) {
+	location := LoadTimeZone(settings.TimeZone)
+	opening, closing := BusinessHours(date, settings)
+
+	if slot == constants.DeskFullDay {
+		return opening, closing, nil
+	}
+
+	midday, _ := ParseClockTime(opening, constants.DeskMiddayTime, location)
+	if !opening.Before(midday) || !midday.Before(closing) {
+		return time.Time{}, time.Time{}, errors.New("half day desk bookings are not available on this day")
+	}
+
+	switch slot {
+	case constants.DeskMorning:
+		return opening, midday, nil
+	case constants.DeskAfternoon:
+		return midday, closing, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid slot %s, expected morning, afternoon or fullday", slot)
+	}
+}
+
+// finds the desk a user has booked most often since the given time, ties go to the most recently booked desk
+func UsualDeskPipeline(email string, since time.Time) bson.A {
+	return bson.A{
+		bson.M{"$match": bson.M{"email": email, "start": bson.M{"$gte": since}}},
+		bson.M{"$group": bson.M{
+			"_id":        "$deskId",
+			"count":      bson.M{"$sum": 1},
+			"lastBooked": bson.M{"$max": "$start"},
+		}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "lastBooked", Value: -1}}},
+		bson.M{"$limit": 1},
+	}
+}
+
+// counts the push tickets sent since the given time by their status and error
+func PushDeliveryStatsPipeline(since time.Time) bson.A {
+	return bson.A{
+		bson.M{"$match": bson.M{"createdAt": bson.M{"$gte": since}}},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"status": "$status", "error": "$error"},
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+}
+
+// removes the booked desks, keeping the order of the rest
+func FreeDesks(desks []models.Desk, bookedDeskIDs []string) []models.Desk {
+	free := make([]models.Desk, 0, len(desks))
+	for _, desk := range desks {
+		if !utils.Contains(bookedDeskIDs, desk.DeskID) {
+			free = append(free, desk)
+		}
+	}
+	return free
+}
+
+// gives every person in a team one of the free desks, the team is only booked if everyone gets a desk
+func AssignNeighbourhoodDesks(free []models.Desk, emails []string, template models.DeskBooking) ([]models.DeskBooking, error) {
+	if len(free) < len(emails) {
+		return nil, fmt.Errorf("only %d desks are free in the neighbourhood but %d are needed", len(free), len(emails))
+	}
+
+	bookings := make([]models.DeskBooking, 0, len(emails))
+	for i, email := range emails {
+		booking := template
+		booking.OccupiID = utils.GenerateBookingID()
+		booking.DeskID = free[i].DeskID
+		booking.DeskName = free[i].DeskName
+		booking.FloorNo = free[i].FloorNo
+		booking.BuildingID = free[i].BuildingID
+		booking.SiteID = free[i].SiteID
+		booking.Neighbourhood = free[i].Neighbourhood
+		booking.Email = email
+		bookings = append(bookings, booking)
+	}
+	return bookings, nil
+}
+
+// builds the filter for the bookings an approval decides on, approving or rejecting one occurrence of a
+// series decides on every pending occurrence of it
+func PendingBookingsFilter(booking models.Booking) bson.M {
+	if booking.SeriesID != "" {
+		return bson.M{"seriesId": booking.SeriesID, "status": constants.BookingPending}
+	}
+	return bson.M{"occupiId": booking.OccupiID, "status": constants.BookingPending}
+}
+
+// checks whether a user can approve bookings of a room, admins can approve any booking and room owners
+// only the bookings of their rooms
+func CanApproveBooking(room models.Room, email string, isAdmin bool) bool {
+	return isAdmin || utils.Contains(room.Approvers, email)
+}
+
+// builds the filter for an attendee checking in to a room booking, the creator and invited attendees can check in
+// and bookings the attendee already checked in to are not matched again
+func RoomCheckInFilter(bookingID string, email string) bson.M {
+	return bson.M{
+		"occupiId": bookingID,
+		"$or": bson.A{
+			bson.M{"creator": email},
+			bson.M{"emails": email},
+		},
+		"checkIns.email": bson.M{"$ne": email},
+	}
+}
+
+// builds the filter for the bookings an attendee responds to, with series set the response also covers the
+// following occurrences of a recurring booking
+func RSVPFilter(booking models.Booking, email string, series bool) bson.M {
+	if series && booking.SeriesID != "" {
+		return bson.M{"seriesId": booking.SeriesID, "start": bson.M{"$gte": booking.Start}, "emails": email}
+	}
+	return bson.M{"occupiId": booking.OccupiID, "emails": email}
+}
+
+// builds the filter for the occurrences of a series removed by a "following" or "series" cancellation
+func SeriesCancellationFilter(seriesID string, email string, from time.Time, scope string) (bson.M, error) {
+	filter := bson.M{
+		"seriesId": seriesID,
+		"creator":  email,
+	}
+
+	switch scope {
+	case constants.ThisAndFollowing:
+		filter["start"] = bson.M{"$gte": from}
+	case constants.WholeSeries:
+	default:
+		return nil, errors.New("invalid cancellation scope")
+	}
+
+	return filter, nil
+}
+
+// widens a booking by the buffer on either side so coinciding checks keep the buffer free
+func ApplyBookingBuffer(booking models.Booking, buffer int) models.Booking {
+	buffered := booking
+	buffered.Start = booking.Start.Add(-time.Duration(buffer) * time.Minute)
+	buffered.End = booking.End.Add(time.Duration(buffer) * time.Minute)
+	return buffered
+}
+
+// returns the start dates of the occurrences that overlap any of the existing bookings
+func FindCoincidingOccurrences(occurrences []models.Booking, existingBookings []models.Booking) []time.Time {
+	conflicts := make([]time.Time, 0)
+
+	for _, occurrence := range occurrences {
+		for _, existing := range existingBookings {
+			if existing.Start.Before(occurrence.End) && existing.End.After(occurrence.Start) {
+				conflicts = append(conflicts, occurrence.Start)
+				break
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// counts the unique attendees of a booking, the creator is always counted as an attendee
+func CountAttendees(emails []string, creator string) int {
+	attendees := make(map[string]bool)
+	for _, email := range emails {
+		attendees[strings.ToLower(email)] = true
+	}
+	if creator != "" {
+		attendees[strings.ToLower(creator)] = true
+	}
+	return len(attendees)
+}
+
+// checks that the number of attendees is within the rooms occupancy bounds, an occupancy of 0 means unbounded
+func CheckRoomOccupancy(room models.Room, attendees int) (string, error) {
+	if room.MaxOccupancy > 0 && attendees > room.MaxOccupancy {
+		return constants.RoomCapacityExceededCode, fmt.Errorf("room %s can hold at most %d attendees but %d were given", room.RoomID, room.MaxOccupancy, attendees)
+	}
+
+	if room.MinOccupancy > 0 && attendees < room.MinOccupancy {
+		return constants.RoomUnderOccupiedCode, fmt.Errorf("room %s requires at least %d attendees but %d were given", room.RoomID, room.MinOccupancy, attendees)
+	}
+
+	return "", nil
+}
+
 // caps time now to range of 8:00 AM to 5:00 PM
 func CapTimeRange() time.Time {
-	now := time.Now().In(time.Local)
+	return CapTimeRangeIn(time.Local)
+}
+
+// caps the current time to office hours in the given location
+func CapTimeRangeIn(location *time.Location) time.Time {
+	now := time.Now().In(location)
 	if now.Hour() < 7 {
-		now = time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, time.Local)
+		now = time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, location)
 	} else if now.Hour() > 17 {
-		now = time.Date(now.Year(), now.Month(), now.Day(), 17, 0, 0, 0, time.Local)
+		now = time.Date(now.Year(), now.Month(), now.Day(), 17, 0, 0, 0, location)
 	}
 	return now
 }
@@ -344,3 +765,291 @@ func GetResultsAndCount(ctx *gin.Context, collection *mongo.Collection, cursor *
 
 	return results, totalResults, nil
 }
+
+// builds the aggregation that finds every room that can hold the attendees along with
+// its bookings and booking settings for the search window, ranked by how well the room fits
+func RoomSearchPipeline(request models.RequestRoomSearch, preferredFloor string) bson.A {
+	match := bson.M{
+		// an occupancy of 0 or none means the room is unbounded, the same as CheckRoomOccupancy
+		"$or": bson.A{
+			bson.M{"maxOccupancy": bson.M{"$gte": request.Attendees}},
+			bson.M{"maxOccupancy": 0},
+			bson.M{"maxOccupancy": bson.M{"$exists": false}},
+		},
+		"minOccupancy": bson.M{"$not": bson.M{"$gt": request.Attendees}},
+		"status":       bson.M{"$nin": bson.A{constants.RoomInactive, constants.RoomArchived}},
+	}
+	if request.FloorNo != "" {
+		match["floorNo"] = request.FloorNo
+	}
+	// amenities are stored lower case, the same as room tags
+	if amenities := utils.NormalizeTags(request.Amenities); len(amenities) > 0 {
+		match["amenities"] = bson.M{"$all": amenities}
+	}
+
+	// a day either side is fetched so that buffers around bookings just outside the window are respected
+	// whatever the timezone of the room's site
+	from := utils.StartOfDay(request.Start).AddDate(0, 0, -1)
+	to := utils.StartOfDay(request.End).AddDate(0, 0, 2)
+
+	var sameFloor interface{} = bson.M{"$literal": false}
+	if preferredFloor != "" {
+		sameFloor = bson.M{"$eq": bson.A{"$floorNo", preferredFloor}}
+	}
+
+	return bson.A{
+		bson.M{"$match": match},
+		bson.M{"$lookup": bson.M{
+			"from": "RoomBooking",
+			"let":  bson.M{"roomId": "$roomId"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$roomId", "$$roomId"}},
+					bson.M{"$lt": bson.A{"$start", to}},
+					bson.M{"$gt": bson.A{"$end", from}},
+				}}}},
+				bson.M{"$project": bson.M{"start": 1, "end": 1}},
+			},
+			"as": "bookings",
+		}},
+		bson.M{"$lookup": bson.M{
+			"from": "BookingSettings",
+			"let":  bson.M{"roomId": "$roomId", "buildingId": bson.M{"$ifNull": bson.A{"$buildingId", ""}}},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$or": bson.A{
+					bson.M{"$eq": bson.A{"$roomId", "$$roomId"}},
+					bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$roomId", ""}},
+						bson.M{"$eq": bson.A{"$buildingId", "$$buildingId"}},
+					}},
+					// the global settings, ResolveBookingSettings prefers the room's and building's over them
+					bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$roomId", ""}},
+						bson.M{"$eq": bson.A{"$buildingId", ""}},
+					}},
+				}}}},
+			},
+			"as": "settings",
+		}},
+		bson.M{"$addFields": bson.M{
+			// unbounded rooms fit any group but are ranked after the rooms sized for it
+			"capacityGap": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$maxOccupancy", 0}}, 0}},
+				bson.M{"$subtract": bson.A{"$maxOccupancy", request.Attendees}},
+				math.MaxInt32,
+			}},
+			"sameFloor": sameFloor,
+		}},
+		bson.M{"$lookup": bson.M{
+			"from":         "Sites",
+			"localField":   "siteId",
+			"foreignField": "siteId",
+			"as":           "site",
+		}},
+		bson.M{"$sort": bson.D{
+			{Key: "capacityGap", Value: 1},
+			{Key: "sameFloor", Value: -1},
+			{Key: "roomId", Value: 1},
+		}},
+	}
+}
+
+// finds the free time within the window that is long enough for the duration, slotted rooms
+// only return whole slots and the duration is rounded up to a whole number of slots
+func FindSlotsInWindow(bookings []models.Booking, settings models.BookingSettings, windowStart time.Time, windowEnd time.Time, duration int) []models.Slot {
+	required := time.Duration(duration) * time.Minute
+	if settings.SlotLength > 0 {
+		length := time.Duration(settings.SlotLength) * time.Minute
+		required = ((required + length - 1) / length) * length
+	}
+
+	runs := make([]models.Slot, 0)
+	for day := utils.StartOfDay(windowStart.In(LoadTimeZone(settings.TimeZone))); day.Before(windowEnd); day = day.AddDate(0, 0, 1) {
+		var dayRuns []models.Slot
+		for _, slot := range ComputeAvailableSlotsWithSettings(bookings, day, settings) {
+			if settings.SlotLength > 0 {
+				// partial slots cannot be booked
+				if slot.Start.Before(windowStart) || slot.End.After(windowEnd) {
+					continue
+				}
+			} else {
+				if slot.Start.Before(windowStart) {
+					slot.Start = windowStart
+				}
+				if slot.End.After(windowEnd) {
+					slot.End = windowEnd
+				}
+				if !slot.Start.Before(slot.End) {
+					continue
+				}
+			}
+
+			// join consecutive slots so that longer bookings can span them
+			if n := len(dayRuns); n > 0 && dayRuns[n-1].End.Equal(slot.Start) {
+				dayRuns[n-1].End = slot.End
+				continue
+			}
+			dayRuns = append(dayRuns, slot)
+		}
+
+		for _, run := range dayRuns {
+			if run.End.Sub(run.Start) >= required {
+				runs = append(runs, run)
+			}
+		}
+	}
+
+	return runs
+}
+
+// applies the fields set on an update request to a room and checks the result is still a valid room
+func ApplyRoomUpdate(room models.Room, request models.RequestUpdateRoom) (models.Room, error) {
+	if request.RoomNo != "" {
+		room.RoomNo = request.RoomNo
+	}
+	if request.FloorNo != "" {
+		room.FloorNo = request.FloorNo
+	}
+	if request.MinOccupancy != nil {
+		room.MinOccupancy = *request.MinOccupancy
+	}
+	if request.MaxOccupancy != nil {
+		room.MaxOccupancy = *request.MaxOccupancy
+	}
+	if request.Description != "" {
+		room.Description = request.Description
+	}
+	if request.RoomName != "" {
+		room.RoomName = request.RoomName
+	}
+	if request.Amenities != nil {
+		room.Amenities = utils.NormalizeTags(*request.Amenities)
+	}
+	if request.RequiresApproval != nil {
+		room.RequiresApproval = *request.RequiresApproval
+	}
+	if request.Approvers != nil {
+		room.Approvers = *request.Approvers
+	}
+	if request.CheckInTokens != nil {
+		room.CheckInTokens = *request.CheckInTokens
+	}
+
+	if room.MinOccupancy < 0 || room.MaxOccupancy < 1 {
+		return room, errors.New("occupancy must be positive")
+	}
+	if room.MinOccupancy > room.MaxOccupancy {
+		return room, errors.New("minimum occupancy cannot be more than the maximum occupancy")
+	}
+
+	return room, nil
+}
+
+// checks whether new bookings can be made for a room, rooms saved before statuses were added are active
+func CheckRoomBookable(room models.Room) (string, error) {
+	if room.Status == constants.RoomInactive || room.Status == constants.RoomArchived {
+		return constants.RoomUnavailableCode, fmt.Errorf("room %s is %s and cannot be booked", room.RoomID, room.Status)
+	}
+	return "", nil
+}
+
+// builds the filter for the booking policies of a user's role and department and of a room
+func BookingPoliciesFilter(user models.User, roomID string) bson.M {
+	scopes := bson.A{bson.M{"scope": constants.PolicyScopeRoom, "target": roomID}}
+	if user.Role != "" {
+		scopes = append(scopes, bson.M{"scope": constants.PolicyScopeRole, "target": user.Role})
+	}
+	if user.DepartmentNo != "" {
+		scopes = append(scopes, bson.M{"scope": constants.PolicyScopeDepartment, "target": user.DepartmentNo})
+	}
+	return bson.M{"$or": scopes}
+}
+
+// combines the policies that apply to a booking into one, keeping the strictest of each limit
+func MergeBookingPolicies(policies []models.BookingPolicy) models.BookingPolicy {
+	strictest := func(current int, limit int) int {
+		if limit > 0 && (current == 0 || limit < current) {
+			return limit
+		}
+		return current
+	}
+
+	var merged models.BookingPolicy
+	for _, policy := range policies {
+		merged.MaxDuration = strictest(merged.MaxDuration, policy.MaxDuration)
+		merged.MaxAdvanceDays = strictest(merged.MaxAdvanceDays, policy.MaxAdvanceDays)
+		merged.MaxActiveBookings = strictest(merged.MaxActiveBookings, policy.MaxActiveBookings)
+		merged.WeeklyHours = strictest(merged.WeeklyHours, policy.WeeklyHours)
+	}
+	return merged
+}
+
+// checks a booking's length and how far ahead it starts against a policy
+func CheckBookingAgainstPolicy(booking models.Booking, policy models.BookingPolicy, now time.Time) (string, error) {
+	if policy.MaxDuration > 0 && booking.End.Sub(booking.Start) > time.Duration(policy.MaxDuration)*time.Minute {
+		return constants.PolicyMaxDurationCode, fmt.Errorf("bookings can be at most %d minutes long", policy.MaxDuration)
+	}
+
+	if policy.MaxAdvanceDays > 0 && booking.Start.After(now.AddDate(0, 0, policy.MaxAdvanceDays)) {
+		return constants.PolicyAdvanceWindowCode, fmt.Errorf("bookings can be made at most %d days ahead", policy.MaxAdvanceDays)
+	}
+
+	return "", nil
+}
+
+// checks a new booking against a policy's quotas given the user's active bookings and the minutes they
+// already booked in the week of the booking
+func CheckBookingQuota(booking models.Booking, policy models.BookingPolicy, activeBookings int64, bookedMinutes int) (string, error) {
+	if policy.MaxActiveBookings > 0 && activeBookings >= int64(policy.MaxActiveBookings) {
+		return constants.PolicyActiveBookingsCode, fmt.Errorf("users can have at most %d bookings that have not ended", policy.MaxActiveBookings)
+	}
+
+	if policy.WeeklyHours > 0 {
+		weekStart, weekEnd := WeekBounds(booking.Start)
+		minutes := bookedMinutes + BookedMinutesBetween([]models.Booking{booking}, weekStart, weekEnd)
+		if minutes > policy.WeeklyHours*60 {
+			return constants.PolicyWeeklyQuotaCode, fmt.Errorf("users can book at most %d hours a week", policy.WeeklyHours)
+		}
+	}
+
+	return "", nil
+}
+
+// the monday the week of t starts on and the monday after it, in t's location
+func WeekBounds(t time.Time) (time.Time, time.Time) {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	start := time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 0, 7)
+}
+
+// adds up the minutes of the bookings that fall between start and end
+func BookedMinutesBetween(bookings []models.Booking, start time.Time, end time.Time) int {
+	var total time.Duration
+	for _, booking := range bookings {
+		from, to := booking.Start, booking.End
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if to.After(from) {
+			total += to.Sub(from)
+		}
+	}
+	return int(total / time.Minute)
+}
+
+// converts a waitlist entry into the booking it is waiting for
+func WaitlistEntryToBooking(entry models.WaitlistEntry) models.Booking {
+	return models.Booking{
+		RoomID:   entry.RoomID,
+		RoomName: entry.RoomName,
+		FloorNo:  entry.FloorNo,
+		Creator:  entry.Creator,
+		Emails:   entry.Emails,
+		Date:     entry.Date,
+		Start:    entry.Start,
+		End:      entry.End,
+	}
+}
@@ -17,12 +17,13 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/mail"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/sender"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // PingHandler is a simple handler for testing if the server is up and running
@@ -84,101 +85,63 @@ func BookRoom(ctx *gin.Context, appsession *models.AppSession) {
 		return
 	}
 
-	// check if no booking has been made that coincides with the start and end time of this booking
-	coinciding, err := database.CheckCoincidingBookings(ctx, appsession, booking)
-	if err != nil {
-		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book", constants.InternalServerErrorCode, "Failed to book", nil))
+	// the creator is the signed in user, or a user who delegated booking to them
+	booking, ok := ResolveBookingCreator(ctx, appsession, booking)
+	if !ok {
 		return
 	}
 
-	if coinciding {
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking coincides with another booking", constants.BadRequestCode, "Booking coincides with another booking", nil))
+	// check the number of attendees against the room's occupancy
+	if !ValidateRoomCapacity(ctx, appsession, booking.RoomID, booking.Emails, booking.Creator) {
 		return
 	}
 
-	// Generate a unique ID for the booking
-	booking.ID = primitive.NewObjectID().Hex()
-	booking.OccupiID = utils.GenerateBookingID()
-	booking.CheckedIn = false
+	// check the booking against the room's opening hours, slot length and blackouts
+	room, settings, ok := ValidateBookingRules(ctx, appsession, booking)
+	if !ok {
+		return
+	}
+	booking.SiteID = room.SiteID
+	booking.BuildingID = room.BuildingID
 
-	// Save the booking to the database
-	_, err = database.SaveBooking(ctx, appsession, booking)
+	// recurring bookings are validated and saved as a series
+	rule, isRecurring, err := utils.ExtractRecurrenceRule(bookingRequest)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to save booking", constants.InternalServerErrorCode, "Failed to save booking", nil))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, err.Error(), nil))
 		return
 	}
 
-	if err := mail.SendBookingEmails(booking, appsession); err != nil {
-		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to send booking email", constants.InternalServerErrorCode, "Failed to send booking email", nil))
+	if isRecurring {
+		BookRecurringRoom(ctx, appsession, room, booking, rule, settings)
 		return
 	}
 
-	tokens, err := database.GetUsersPushTokens(ctx, appsession, booking.Emails)
-	if err != nil {
-		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get push tokens", constants.InternalServerErrorCode, "Failed to get push tokens", nil))
+	// check the booking against the admin set limits on length, lead time and quotas
+	if !ValidateBookingPolicies(ctx, appsession, booking) {
 		return
 	}
 
-	tokenArr, err := utils.ConvertTokensToStringArray(tokens, "expoPushToken")
-
+	// check if no booking has been made that coincides with the start and end time of this booking, including the buffer
+	coinciding, err := database.CheckCoincidingBookings(ctx, appsession, database.ApplyBookingBuffer(booking, settings.Buffer))
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to convert tokens to string array because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
-		return
-	}
-
-	scheduledNotification := models.ScheduledNotification{
-		NotiID:               utils.GenerateUUID(),
-		Title:                "Booking Starting Soon",
-		Message:              utils.ConstructBookingStartingInScheduledString(booking.Emails, "3 mins"),
-		Sent:                 false,
-		SendTime:             booking.Start.Add(-3 * time.Minute),
-		Emails:               booking.Emails,
-		UnsentExpoPushTokens: tokenArr,
-		UnreadEmails:         booking.Emails,
-	}
-
-	success, errv := database.AddNotification(ctx, appsession, scheduledNotification, true)
-
-	if errv != nil {
-		configs.CaptureError(ctx, errv)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book", constants.InternalServerErrorCode, "Failed to book", nil))
 		return
 	}
 
-	if !success {
-		configs.CaptureMessage(ctx, "failed to schedule notification booking starting soon")
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+	if coinciding {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking coincides with another booking", constants.BadRequestCode, "Booking coincides with another booking", nil))
 		return
 	}
 
-	notification := models.ScheduledNotification{
-		NotiID:               utils.GenerateUUID(),
-		Title:                "Booking Invitation",
-		Message:              utils.ConstructBookingScheduledString(booking.Emails),
-		Sent:                 true,
-		SendTime:             utils.GetClientTime(ctx),
-		Emails:               booking.Emails,
-		UnsentExpoPushTokens: tokenArr,
-		UnreadEmails:         booking.Emails,
-	}
-
-	success, errv = database.AddNotification(ctx, appsession, notification, false)
-
-	if errv != nil {
-		configs.CaptureError(ctx, errv)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+	booking, ok = SaveOrRequestBooking(ctx, appsession, room, booking)
+	if !ok {
 		return
 	}
 
-	if !success {
-		configs.CaptureMessage(ctx, "failed to schedule notification booking invitation")
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+	if booking.Status == constants.BookingPending {
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Booking is awaiting approval!", booking.OccupiID))
 		return
 	}
 
@@ -210,23 +173,83 @@ func CancelBooking(ctx *gin.Context, appsession *models.AppSession) {
 		return
 	}
 
+	// ValidateJSON only checks required fields so the scope is validated against its binding here
+	if err := binding.Validator.Engine().(*validator.Validate).StructPartial(cancel, "Scope"); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, "scope must be one of 'occurrence', 'following' or 'series'", nil))
+		return
+	}
+
 	// Check if the booking exists
-	exists := database.BookingExists(ctx, appsession, cancel.BookingID)
-	if !exists {
+	booking, err := database.GetBooking(ctx, appsession, cancel.BookingID)
+	if err != nil {
 		configs.CaptureMessage(ctx, "booking not found")
 		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
 		return
 	}
 
-	// Confirm the cancellation to the database
-	_, err = database.ConfirmCancellation(ctx, appsession, cancel.BookingID, cancel.Creator)
-	if err != nil {
-		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to cancel booking", constants.InternalServerErrorCode, "Failed to cancel booking", nil))
+	// only the creator, their delegates and admins can cancel a booking
+	if _, ok := AuthorizeBookingAction(ctx, appsession, booking.Creator, "cancel bookings", true); !ok {
 		return
 	}
+	cancel.Creator = booking.Creator
+
+	// the cancelled bookings are sent as a calendar cancellation so they disappear from everyone's calendar
+	cancelled := []models.Booking{booking}
+
+	switch cancel.Scope {
+	case "", constants.ThisOccurrence:
+		// Confirm the cancellation to the database
+		_, err = database.ConfirmCancellation(ctx, appsession, cancel.BookingID, cancel.Creator)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to cancel booking", constants.InternalServerErrorCode, "Failed to cancel booking", nil))
+			return
+		}
+
+		// record the cancelled occurrence as an exception on its series
+		if booking.SeriesID != "" {
+			if err := database.AddSeriesException(ctx, appsession, booking.SeriesID, booking.Start); err != nil {
+				configs.CaptureError(ctx, err)
+				logrus.Error("Failed to add exception to booking series because: ", err)
+			}
+		}
+
+		// the freed up slot goes to the next person on the waitlist
+		if err := database.OfferSlotToWaitlist(ctx, appsession, booking); err != nil {
+			configs.CaptureError(ctx, err)
+			logrus.Error("Failed to offer slot to waitlist because: ", err)
+		}
+	case constants.ThisAndFollowing, constants.WholeSeries:
+		if booking.SeriesID == "" {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, "Booking is not part of a recurring series", nil))
+			return
+		}
+
+		cancelled, err = database.FindSeriesOccurrences(ctx, appsession, booking.SeriesID, cancel.Creator, booking.Start, cancel.Scope)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to cancel booking", constants.InternalServerErrorCode, "Failed to cancel booking", nil))
+			return
+		}
+
+		_, err = database.CancelBookingSeries(ctx, appsession, booking.SeriesID, cancel.Creator, booking.Start, cancel.Scope)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to cancel booking", constants.InternalServerErrorCode, "Failed to cancel booking", nil))
+			return
+		}
+
+		// every freed up slot goes to the next person on the waitlist
+		for _, occurrence := range cancelled {
+			if err := database.OfferSlotToWaitlist(ctx, appsession, occurrence); err != nil {
+				configs.CaptureError(ctx, err)
+				logrus.Error("Failed to offer slot to waitlist because: ", err)
+			}
+		}
+	}
 
-	if err := mail.SendCancellationEmails(cancel, appsession); err != nil {
+	if err := mail.SendCancellationEmails(cancel, cancelled, appsession); err != nil {
 		configs.CaptureError(ctx, err)
 		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "An error occurred", constants.InternalServerErrorCode, "Failed to send booking email", nil))
 		return
@@ -235,648 +258,599 @@ func CancelBooking(ctx *gin.Context, appsession *models.AppSession) {
 	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully cancelled booking!", nil))
 }
 
-// CheckIn handles the check-in process for a booking
-func CheckIn(ctx *gin.Context, appsession *models.AppSession) {
-	var checkInRequest map[string]interface{}
-	if err := ctx.ShouldBindJSON(&checkInRequest); err != nil {
+// UpdateBooking changes the room, time or attendees of an existing booking while keeping its occupi id
+func UpdateBooking(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestUpdateBooking
+	if err := ctx.ShouldBindJSON(&request); err != nil {
 		configs.CaptureError(ctx, err)
 		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	// Validate JSON
-	validatedData, err := utils.ValidateJSON(checkInRequest, reflect.TypeOf(models.CheckIn{}))
+	existing, err := database.GetBooking(ctx, appsession, request.BookingID)
 	if err != nil {
-		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, err.Error(), nil))
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
 		return
 	}
 
-	// Convert validated JSON to CheckIn struct
-	var checkIn models.CheckIn
-	checkInBytes, _ := json.Marshal(validatedData)
-	if err := json.Unmarshal(checkInBytes, &checkIn); err != nil {
-		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to check in", constants.InternalServerErrorCode, "Failed to check in", nil))
+	if _, ok := AuthorizeBookingAction(ctx, appsession, existing.Creator, "update bookings", true); !ok {
 		return
 	}
 
-	// Check if the booking exists
-	exists := database.BookingExists(ctx, appsession, checkIn.BookingID)
-	if !exists {
-		configs.CaptureMessage(ctx, "booking not found")
-		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+	booking := ApplyBookingUpdate(existing, request)
+
+	if !booking.Start.Before(booking.End) {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, "start must be before end", nil))
 		return
 	}
 
-	// Confirm the check-in to the database
-	_, err = database.ConfirmCheckIn(ctx, appsession, checkIn)
-	if err != nil {
+	roomChanged := booking.RoomID != existing.RoomID
+	timeChanged := !booking.Start.Equal(existing.Start) || !booking.End.Equal(existing.End)
+	added, removed := utils.DiffEmails(existing.Emails, booking.Emails)
+	attendeesChanged := len(added) > 0 || len(removed) > 0
+
+	if !roomChanged && !timeChanged && !attendeesChanged {
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated booking!", booking))
+		return
+	}
+
+	// check the number of attendees against the room's occupancy
+	if (roomChanged || attendeesChanged) && !ValidateRoomCapacity(ctx, appsession, booking.RoomID, booking.Emails, booking.Creator) {
+		return
+	}
+
+	// check the new room and time against the room's settings and every other booking
+	if roomChanged || timeChanged {
+		room, settings, ok := ValidateBookingRules(ctx, appsession, booking)
+		if !ok {
+			return
+		}
+		booking.SiteID = room.SiteID
+		booking.BuildingID = room.BuildingID
+
+		// a confirmed booking cannot skip approval by moving into a room that requires it
+		if roomChanged && room.RequiresApproval && booking.Status != constants.BookingPending {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Room requires approval", constants.BadRequestCode, "Bookings of this room must be requested through book-room", nil))
+			return
+		}
+
+		// the new room and time must still keep to the booking policies
+		if !ValidateBookingChangePolicies(ctx, appsession, existing, booking) {
+			return
+		}
+
+		coinciding, err := database.CheckCoincidingBookings(ctx, appsession, database.ApplyBookingBuffer(booking, settings.Buffer))
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update booking", constants.InternalServerErrorCode, "Failed to update booking", nil))
+			return
+		}
+
+		if coinciding {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking coincides with another booking", constants.BadRequestCode, "Booking coincides with another booking", nil))
+			return
+		}
+	}
+
+	if _, err := database.UpdateBooking(ctx, appsession, booking); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to check in", constants.InternalServerErrorCode, "Failed to check in. Email not associated with booking", nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update booking", constants.InternalServerErrorCode, "Failed to update booking", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully checked in!", nil))
+	if err := mail.SendBookingUpdateEmails(existing, booking, appsession); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to send booking email", constants.InternalServerErrorCode, "Failed to send booking email", nil))
+		return
+	}
+
+	// the reminder has to be rescheduled when the start time or the attendees change
+	if timeChanged || attendeesChanged {
+		if err := RescheduleBookingNotifications(ctx, appsession, booking, added); err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated booking!", booking))
 }
 
-func GetUserDetails(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestEmail
+// BookNow books a free room from now until it is next needed, or for the requested duration when that is sooner.
+// The booking is never longer than the configured maximum.
+func BookNow(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestBookNow
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		emailStr := ctx.Query("email")
-		if emailStr == "" {
-			email, err := AttemptToGetEmail(ctx, appsession)
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-					http.StatusBadRequest,
-					"Invalid request payload",
-					constants.InvalidRequestPayloadCode,
-					"Email must be provided",
-					nil))
-				return
-			} else {
-				request.Email = email
-			}
-		} else {
-			request.Email = emailStr
-		}
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
 	}
 
-	// Get all the user details
-	user, err := database.GetUserDetails(ctx, appsession, request.Email)
+	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get user details", constants.InternalServerErrorCode, "Failed to get user details", nil))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched user details!", user))
-}
+	room, err := database.GetRoom(ctx, appsession, request.RoomID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return
+	}
 
-func UpdateUserDetails(ctx *gin.Context, appsession *models.AppSession) {
-	var user models.UserDetailsRequest
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+	emails := request.Emails
+	if !utils.Contains(emails, email) {
+		emails = append(emails, email)
+	}
+
+	if !ValidateRoomCapacity(ctx, appsession, room.RoomID, emails, email) {
+		return
+	}
+
+	now := time.Now().Truncate(time.Second)
+	slot, ok := GetAdHocSlot(ctx, appsession, room, now)
+	if !ok {
+		return
+	}
+
+	booking := models.Booking{
+		RoomID:     room.RoomID,
+		RoomName:   room.RoomName,
+		FloorNo:    room.FloorNo,
+		Emails:     emails,
+		Creator:    email,
+		CreatedBy:  email,
+		Date:       time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		Start:      now,
+		End:        database.AdHocBookingEnd(slot, request.Duration, time.Duration(configs.GetBookNowMaxDuration())*time.Second),
+		SiteID:     room.SiteID,
+		BuildingID: room.BuildingID,
+	}
+
+	if !ValidateBookingPolicies(ctx, appsession, booking) {
+		return
+	}
+
+	settings, err := database.GetBookingSettings(ctx, appsession, room.RoomID)
+	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid JSON payload", nil))
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	// Update the user details in the database
-	_, err := database.UpdateUserDetails(ctx, appsession, user)
+	// the room may have been booked since it was found to be free
+	coinciding, err := database.CheckCoincidingBookings(ctx, appsession, database.ApplyBookingBuffer(booking, settings.Buffer))
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update user details", constants.InternalServerErrorCode, "Failed to update user details", nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book", constants.InternalServerErrorCode, "Failed to book", nil))
 		return
 	}
 
-	// if user is updating their email, create a new token for them
-	if user.Email != "" {
-		if err := AttemptToSignNewEmail(ctx, appsession, user.Email); err != nil {
-			logrus.Error("Failed to sign new email because: ", err)
-			configs.CaptureError(ctx, err)
-		}
+	if coinciding {
+		ctx.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, "Booking coincides with another booking", constants.BadRequestCode, "Booking coincides with another booking", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated user details!", nil))
+	booking, ok = SaveOrRequestBooking(ctx, appsession, room, booking)
+	if !ok {
+		return
+	}
+
+	if booking.Status == constants.BookingPending {
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Booking is awaiting approval!", booking))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully booked!", booking))
 }
 
-// Helper function to handle validation of requests
-func HandleValidationErrors(ctx *gin.Context, err error) {
-	var ve validator.ValidationErrors
-	if errors.As(err, &ve) {
-		out := make([]models.ErrorMsg, len(ve))
-		for i, err := range ve {
-			out[i] = models.ErrorMsg{
-				Field:   utils.LowercaseFirstLetter(err.Field()),
-				Message: utils.GetErrorMsg(err),
-			}
-		}
+// ExtendBooking moves the end of a booking that is in progress later, as long as the room is not needed
+// in the extra time
+func ExtendBooking(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestExtendBooking
+	if err := ctx.ShouldBindJSON(&request); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid request payload", gin.H{"errors": out}))
+		HandleValidationErrors(ctx, err)
+		return
 	}
-}
 
-func FilterCollection(ctx *gin.Context, appsession *models.AppSession, collectionName string) {
-	var queryInput models.QueryInput
-	if err := ctx.ShouldBindJSON(&queryInput); err != nil {
-		// try to bind the query input to the struct
-		queryInput = models.QueryInput{}
-		operatorStr := ctx.Query("operator")
-		if operatorStr != "" {
-			queryInput.Operator = operatorStr
-		}
+	existing, err := database.GetBooking(ctx, appsession, request.BookingID)
+	if err != nil {
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+		return
+	}
 
-		orderAscStr := ctx.Query("order_asc")
-		if orderAscStr != "" {
-			queryInput.OrderAsc = orderAscStr
-		}
+	if _, ok := AuthorizeBookingAction(ctx, appsession, existing.Creator, "extend bookings", true); !ok {
+		return
+	}
 
-		orderDescStr := ctx.Query("order_desc")
-		if orderDescStr != "" {
-			queryInput.OrderDesc = orderDescStr
-		}
+	now := time.Now()
+	if now.Before(existing.Start) || !now.Before(existing.End) {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking is not in progress", constants.BadRequestCode, "Only bookings that are in progress can be extended", nil))
+		return
+	}
 
-		projectionStr := ctx.Query("projection")
-		if projectionStr != "" {
-			queryInput.Projection = utils.ConvertCommaDelimitedStringToArray(projectionStr)
-		}
+	booking := existing
+	booking.End = existing.End.Add(time.Duration(request.Minutes) * time.Minute)
 
-		limitStr := ctx.Query("limit")
-		if limitStr != "" {
-			limit, err := strconv.ParseInt(limitStr, 10, 64) // Base 10, 64-bit size
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				// Handle the error if the conversion fails, maybe set an error response
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid limit format", constants.InvalidRequestPayloadCode, "Invalid limit format", nil))
-				return
-			}
-			queryInput.Limit = limit
-		}
-
-		pageStr := ctx.Query("page")
-		if pageStr != "" {
-			page, err := strconv.ParseInt(pageStr, 10, 64) // Base 10, 64-bit size
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				// Handle the error if the conversion fails, maybe set an error response
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid page format", constants.InvalidRequestPayloadCode, "Invalid page format", nil))
-				return
-			}
-			queryInput.Page = page
-		}
-
-		// For Filter, which expects a map[string]interface{}, unmarshal the JSON string
-		filterStr := ctx.Query("filter")
-		if filterStr != "" {
-			var filterMap map[string]interface{}
-			if err := json.Unmarshal([]byte(filterStr), &filterMap); err != nil {
-				configs.CaptureError(ctx, err)
-				// Handle JSON unmarshal error, maybe set an error response
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid filter format", constants.InvalidRequestPayloadCode, "Invalid filter format", nil))
-				return
-			}
-			queryInput.Filter = filterMap
-		}
+	// the extra time must still fall within the room's hours and outside its blackouts
+	_, settings, ok := ValidateBookingRules(ctx, appsession, booking)
+	if !ok {
+		return
 	}
 
-	sanitizedFilter := utils.SantizeFilter(queryInput)
-	sanitizedSort := utils.SanitizeSort(queryInput)
-
-	sanitizedProjection := utils.SantizeProjection(queryInput)
-
-	projection := utils.ConstructProjection(queryInput, sanitizedProjection)
-
-	limit, page, skip := utils.GetLimitPageSkip(queryInput)
-
-	filter := models.FilterStruct{
-		Filter:     sanitizedFilter,
-		Projection: projection,
-		Limit:      limit,
-		Skip:       skip,
-		Sort:       sanitizedSort,
+	// and keep the booking within the policy's length and weekly quota
+	if !ValidateBookingChangePolicies(ctx, appsession, existing, booking) {
+		return
 	}
 
-	if collectionName == "RoomBooking" {
-		// check that the email field is set
-		if _, ok := filter.Filter["email"]; !ok {
-			configs.CaptureError(ctx, errors.New("email field not set"))
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
-			return
-		}
-
-		// delete email field from the filter
-		email := filter.Filter["email"]
-		delete(filter.Filter, "email")
-
-		// set emails field in the filter
-		filter.Filter["emails"] = bson.M{"$in": []string{email.(string)}}
+	coinciding, err := database.CheckCoincidingBookings(ctx, appsession, database.ApplyBookingBuffer(booking, settings.Buffer))
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to extend booking", constants.InternalServerErrorCode, "Failed to extend booking", nil))
+		return
 	}
 
-	res, totalResults, err := database.FilterCollectionWithProjection(ctx, appsession, collectionName, filter)
+	if coinciding {
+		ctx.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, "Booking coincides with another booking", constants.BadRequestCode, "The room is booked straight after this booking", nil))
+		return
+	}
 
-	if err != nil {
+	if _, err := database.UpdateBooking(ctx, appsession, booking); err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to filter collection because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to extend booking", constants.InternalServerErrorCode, "Failed to extend booking", nil))
 		return
 	}
 
-	if collectionName == "Notifications" {
-		email, err := AttemptToGetEmail(ctx, appsession)
-
-		if err == nil {
-			err := database.ReadNotifications(ctx, appsession, email)
+	if err := mail.SendBookingUpdateEmails(existing, booking, appsession); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to send booking email", constants.InternalServerErrorCode, "Failed to send booking email", nil))
+		return
+	}
 
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				logrus.Error("Failed to read notifications because: ", err)
-				// it's not a critical error so we don't return an error response
-			}
-		}
+	if err := RescheduleBookingNotifications(ctx, appsession, booking, nil); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "success", res, gin.H{
-		"totalResults": len(res), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully extended booking!", booking))
 }
 
-func DeleteNotification(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.DeleteNotiRequest
+// JoinWaitlist adds the user to the waitlist of a room and time window that is already booked
+func JoinWaitlist(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestJoinWaitlist
 	if err := ctx.ShouldBindJSON(&request); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid JSON payload", nil))
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	// check if email is set otherwise attempt to get it from the appsession
-	if request.Email == "" {
+	if !request.Start.Before(request.End) {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, "start must be before end", nil))
+		return
+	}
+
+	// users join for themselves unless they are a delegate or an admin joining for someone else
+	creator := request.Creator
+	if creator == "" {
 		email, err := AttemptToGetEmail(ctx, appsession)
 		if err != nil {
 			configs.CaptureError(ctx, err)
 			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 			return
 		}
-		request.Email = email
+		creator = email
 	}
 
-	// delete the notification
-	err := database.DeleteNotificationForUser(ctx, appsession, request)
+	if _, ok := AuthorizeBookingAction(ctx, appsession, creator, "join waitlists", true); !ok {
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		WaitlistID: utils.GenerateUUID(),
+		RoomID:     request.RoomID,
+		RoomName:   request.RoomName,
+		FloorNo:    request.FloorNo,
+		Creator:    creator,
+		Emails:     request.Emails,
+		Date:       request.Date,
+		Start:      request.Start,
+		End:        request.End,
+		Status:     constants.WaitlistWaiting,
+		CreatedAt:  time.Now(),
+	}
+
+	// check the number of attendees against the room's occupancy
+	if !ValidateRoomCapacity(ctx, appsession, entry.RoomID, entry.Emails, entry.Creator) {
+		return
+	}
+
+	// a slot the room can never be booked for cannot be waited on
+	_, settings, ok := ValidateBookingRules(ctx, appsession, database.WaitlistEntryToBooking(entry))
+	if !ok {
+		return
+	}
 
+	// there is no point in waiting for a slot that is free
+	coinciding, err := database.CheckCoincidingBookings(ctx, appsession, database.ApplyBookingBuffer(database.WaitlistEntryToBooking(entry), settings.Buffer))
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to delete notification because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to delete notification", constants.InternalServerErrorCode, "Failed to delete notification", nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to join waitlist", constants.InternalServerErrorCode, "Failed to join waitlist", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted notification!", nil))
-}
+	if !coinciding {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Room is available", constants.BadRequestCode, "The room is available for this time, book it instead", nil))
+		return
+	}
 
-func GetPushTokens(ctx *gin.Context, appsession *models.AppSession) {
-	var emails models.RequestEmails
-	if err := ctx.ShouldBindJSON(&emails); err != nil {
-		emailsStr := ctx.Query("emails")
-		if emailsStr != "" {
-			emails.Emails = utils.ConvertCommaDelimitedStringToArray(emailsStr)
-		} else {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Invalid request payload",
-				constants.InvalidRequestPayloadCode,
-				"Unexpected fields found or invalid format",
-				nil))
-			return
-		}
+	if _, err := database.AddToWaitlist(ctx, appsession, entry); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to join waitlist", constants.InternalServerErrorCode, "Failed to join waitlist", nil))
+		return
 	}
 
-	// sanitize the emails
-	emails.Emails = utils.SanitizeInputArray(emails.Emails)
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully joined waitlist!", entry.WaitlistID))
+}
 
-	// validate the emails
-	if !utils.ValidateEmails(emails.Emails) {
-		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+// LeaveWaitlist removes the user from a waitlist
+func LeaveWaitlist(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestWaitlistEntry
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	pushTokens, err := database.GetUsersPushTokens(ctx, appsession, emails.Emails)
-
+	entry, err := database.GetWaitlistEntry(ctx, appsession, request.WaitlistID)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to get users: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get push tokens", constants.InternalServerErrorCode, "Failed to get push tokens", nil))
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Waitlist entry not found", constants.BadRequestCode, "Waitlist entry not found", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched push tokens!", pushTokens))
-}
+	if _, ok := AuthorizeBookingAction(ctx, appsession, entry.Creator, "leave waitlists", true); !ok {
+		return
+	}
 
-func UpdateSecuritySettings(ctx *gin.Context, appsession *models.AppSession) {
-	var securitySettings models.SecuritySettingsRequest
-	if err := ctx.ShouldBindJSON(&securitySettings); err != nil {
+	if _, err := database.RemoveFromWaitlist(ctx, appsession, entry.WaitlistID, entry.Creator); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
-			nil))
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Waitlist entry not found", constants.BadRequestCode, "Waitlist entry not found", nil))
 		return
 	}
 
-	// check if the email is set
-	if securitySettings.Email == "" {
-		// get the email from the appsession
-		email, err := AttemptToGetEmail(ctx, appsession)
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully left waitlist!", nil))
+}
 
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			logrus.Error("Failed to get email because: ", err)
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Invalid request payload",
-				constants.InvalidRequestPayloadCode,
-				"Email must be provided",
-				nil))
-			return
-		}
+// AcceptWaitlistOffer books the slot that was offered to a waitlisted user if the offer has not expired
+func AcceptWaitlistOffer(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestWaitlistEntry
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
 
-		securitySettings.Email = email
+	entry, err := database.GetWaitlistEntry(ctx, appsession, request.WaitlistID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Waitlist entry not found", constants.BadRequestCode, "Waitlist entry not found", nil))
+		return
 	}
 
-	// check that if current password is provided, new password and new password confirm are also provided and vice versa
-	if (securitySettings.CurrentPassword == "" && (securitySettings.NewPassword != "" || securitySettings.NewPasswordConfirm != "")) ||
-		(securitySettings.NewPassword == "" && (securitySettings.CurrentPassword != "" || securitySettings.NewPasswordConfirm != "")) ||
-		(securitySettings.NewPasswordConfirm == "" && (securitySettings.CurrentPassword != "" || securitySettings.NewPassword != "")) {
-		configs.CaptureError(ctx, errors.New("current password, new password and new password confirm must all be provided"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Current password, new password and new password confirm must all be provided",
-			nil))
+	if _, ok := AuthorizeBookingAction(ctx, appsession, entry.Creator, "accept waitlist offers", true); !ok {
 		return
 	}
 
-	// check if the password match
-	if securitySettings.NewPassword != "" && securitySettings.NewPassword != securitySettings.NewPasswordConfirm {
-		configs.CaptureError(ctx, errors.New("new password and new password confirm do not match"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"New password and new password confirm do not match",
-			nil))
+	if entry.Status != constants.WaitlistOffered {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "No offer to accept", constants.BadRequestCode, "This waitlist entry has no pending offer", nil))
 		return
 	}
 
-	// Validate the given passwords if they exist
-	if securitySettings.CurrentPassword != "" && securitySettings.NewPassword != "" && securitySettings.NewPasswordConfirm != "" {
-		securitySetting, err, success := SanitizeSecuritySettingsPassword(ctx, appsession, securitySettings)
-		if err != nil || !success {
-			configs.CaptureError(ctx, fmt.Errorf("failed to sanitize security settings because: %v", err))
-			logrus.Error("Failed to sanitize security settings because: ", err)
-			return
-		}
+	booking := database.WaitlistEntryToBooking(entry)
 
-		securitySettings = securitySetting
+	// an expired offer is passed on to the next person on the waitlist
+	if time.Now().After(entry.OfferExpires) {
+		if err := database.UpdateWaitlistStatus(ctx, appsession, entry.WaitlistID, constants.WaitlistExpired, time.Time{}); err != nil {
+			configs.CaptureError(ctx, err)
+		}
+		if err := database.OfferSlotToWaitlist(ctx, appsession, booking); err != nil {
+			configs.CaptureError(ctx, err)
+			logrus.Error("Failed to offer slot to waitlist because: ", err)
+		}
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Offer has expired", constants.BadRequestCode, "The offer was not accepted in time", nil))
+		return
 	}
 
-	// if mfa string is set, ensure it's either "on" or "off"
-	if securitySettings.Mfa != "" && securitySettings.Mfa != constants.On && securitySettings.Mfa != constants.Off {
-		configs.CaptureMessage(ctx, "mfa must be either 'on' or 'off'")
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"mfa must be either 'on' or 'off'",
-			nil))
+	// blackouts may have been added since the user joined the waitlist
+	room, settings, ok := ValidateBookingRules(ctx, appsession, booking)
+	if !ok {
 		return
 	}
+	booking.SiteID = room.SiteID
+	booking.BuildingID = room.BuildingID
 
-	// if forceLogout string is set, ensure it's either "on" or "off"
-	if securitySettings.ForceLogout != "" && securitySettings.ForceLogout != constants.On && securitySettings.ForceLogout != constants.Off {
-		configs.CaptureMessage(ctx, "forceLogout must be either 'on' or 'off'")
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"forceLogout must be either 'on' or 'off'",
-			nil))
+	// policies may have changed or the user may have booked elsewhere since joining the waitlist
+	if !ValidateBookingPolicies(ctx, appsession, booking) {
 		return
 	}
 
-	if err := database.UpdateSecuritySettings(ctx, appsession, securitySettings); err != nil {
+	coinciding, err := database.CheckCoincidingBookings(ctx, appsession, database.ApplyBookingBuffer(booking, settings.Buffer))
+	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to update security settings",
-			constants.InternalServerErrorCode,
-			"Failed to update security settings",
-			nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book", constants.InternalServerErrorCode, "Failed to book", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated security settings!", nil))
-}
-
-func GetSecuritySettings(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestEmail
-	if err := ctx.ShouldBindJSON(&request); err != nil {
-		emailStr := ctx.Query("email")
-		if emailStr == "" {
-			email, err := AttemptToGetEmail(ctx, appsession)
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-					http.StatusBadRequest,
-					"Invalid request payload",
-					constants.InvalidRequestPayloadCode,
-					"Email must be provided",
-					nil))
-				return
-			} else {
-				request.Email = email
-			}
-		} else {
-			request.Email = emailStr
-		}
+	if coinciding {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking coincides with another booking", constants.BadRequestCode, "Booking coincides with another booking", nil))
+		return
 	}
 
-	securitySettings, err := database.GetSecuritySettings(ctx, appsession, request.Email)
-	if err != nil {
+	booking, ok = SaveOrRequestBooking(ctx, appsession, room, booking)
+	if !ok {
+		return
+	}
+
+	if err := database.UpdateWaitlistStatus(ctx, appsession, entry.WaitlistID, constants.WaitlistAccepted, time.Time{}); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to get security settings",
-			constants.InternalServerErrorCode,
-			"Failed to get security settings",
-			nil))
+		logrus.Error("Failed to mark waitlist entry as accepted because: ", err)
+	}
+
+	if booking.Status == constants.BookingPending {
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Booking is awaiting approval!", booking.OccupiID))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched security settings!", securitySettings))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully booked!", booking.OccupiID))
 }
 
-func UpdateNotificationSettings(ctx *gin.Context, appsession *models.AppSession) {
-	var notificationsSettings models.NotificationsRequest
-	if err := ctx.ShouldBindJSON(&notificationsSettings); err != nil {
-		email, err := AttemptToGetEmail(ctx, appsession)
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Invalid request payload",
-				constants.InvalidRequestPayloadCode,
-				"Invalid JSON payload",
-				nil))
-			return
-		}
-		notificationsSettings.Email = email
+// AddAttendees adds attendees to an existing booking as long as the room can hold them
+func AddAttendees(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestAddAttendees
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
 
-		invitesStr := ctx.Query("invites")
-		if invitesStr != "" {
-			notificationsSettings.Invites = invitesStr
-		}
+	booking, err := database.GetBooking(ctx, appsession, request.BookingID)
+	if err != nil {
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+		return
+	}
 
-		bookingReminderStr := ctx.Query("bookingReminder")
-		if bookingReminderStr != "" {
-			notificationsSettings.BookingReminder = bookingReminderStr
+	if _, ok := AuthorizeBookingAction(ctx, appsession, booking.Creator, "add attendees", true); !ok {
+		return
+	}
+
+	// only invite attendees that are not already part of the booking
+	var newAttendees []string
+	for _, email := range request.Emails {
+		if !utils.Contains(booking.Emails, email) && !utils.Contains(newAttendees, email) {
+			newAttendees = append(newAttendees, email)
 		}
 	}
 
-	// If invites is set, ensure it's either "on" or "off"
-	if notificationsSettings.Invites != "" && notificationsSettings.Invites != constants.On && notificationsSettings.Invites != constants.Off {
-		configs.CaptureMessage(ctx, "invites must be either 'on' or 'off'")
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"invites must be either 'on' or 'off'",
-			nil))
+	if len(newAttendees) == 0 {
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added attendees!", booking.Emails))
 		return
 	}
 
-	// If bookingReminder is set, ensure it's either "on" or "off"
-	if notificationsSettings.BookingReminder != "" && notificationsSettings.BookingReminder != constants.On && notificationsSettings.BookingReminder != constants.Off {
-		configs.CaptureMessage(ctx, "bookingReminder must be either 'on' or 'off'")
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"bookingReminder must be either 'on' or 'off'",
-			nil))
+	emails := append(append([]string{}, booking.Emails...), newAttendees...)
+
+	// check the number of attendees against the room's occupancy
+	if !ValidateRoomCapacity(ctx, appsession, booking.RoomID, emails, booking.Creator) {
 		return
 	}
 
-	// update the notification settings
-	if err := database.UpdateNotificationSettings(ctx, appsession, notificationsSettings); err != nil {
+	if err := database.AddAttendeesToBooking(ctx, appsession, booking.OccupiID, newAttendees); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to update notification settings",
-			constants.InternalServerErrorCode,
-			"Failed to update notification settings",
-			nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to add attendees", constants.InternalServerErrorCode, "Failed to add attendees", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated notification settings!", nil))
-}
-
-func GetNotificationSettings(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestEmail
-	if err := ctx.ShouldBindJSON(&request); err != nil {
-		emailStr := ctx.Query("email")
-		if emailStr == "" {
-			email, err := AttemptToGetEmail(ctx, appsession)
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-					http.StatusBadRequest,
-					"Invalid request payload",
-					constants.InvalidRequestPayloadCode,
-					"Email must be provided",
-					nil))
-				return
-			} else {
-				request.Email = email
-			}
-		} else {
-			request.Email = emailStr
-		}
+	withAttendees := booking
+	withAttendees.Emails = emails
+	invite := utils.FormatBookingInvite(constants.ICalendarRequest, []models.Booking{withAttendees}, time.Now())
+	if err := mail.SendInvitations(withAttendees, newAttendees, invite, appsession); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to send booking email", constants.InternalServerErrorCode, "Failed to send booking email", nil))
+		return
 	}
 
-	notificationSettings, err := database.GetNotificationSettings(ctx, appsession, request.Email)
+	tokens, err := database.GetUsersPushTokens(ctx, appsession, newAttendees)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to get notification settings",
-			constants.InternalServerErrorCode,
-			"Failed to get notification settings",
-			nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get push tokens", constants.InternalServerErrorCode, "Failed to get push tokens", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched notification settings!", notificationSettings))
-}
-
-func UploadProfileImage(ctx *gin.Context, appsession *models.AppSession) {
-	file, err := ctx.FormFile("image")
+	tokenArr, err := utils.ConvertTokensToStringArray(tokens, "expoPushToken")
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid file format",
-			nil))
+		logrus.Error("Failed to convert tokens to string array because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	var requestEmail models.RequestEmail
-	if err := ctx.ShouldBindJSON(&requestEmail); err != nil {
-		// attempt to get email from form data
-		email := ctx.PostForm("email")
-		if email == "" {
-			emaila, err := AttemptToGetEmail(ctx, appsession)
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-					http.StatusBadRequest,
-					"Invalid request payload",
-					constants.InvalidRequestPayloadCode,
-					"Email must be provided",
-					nil))
-				return
-			}
-			requestEmail.Email = emaila
-		} else {
-			requestEmail.Email = email
-		}
+	invited := booking
+	invited.Emails = newAttendees
+	if err := SendBookingInvitationNotification(ctx, appsession, invited, tokenArr); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+		return
 	}
 
-	// remove @ from email
-	email := requestEmail.Email
-	requestEmail.Email = strings.ReplaceAll(requestEmail.Email, "@", "")
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added attendees!", emails))
+}
 
-	imageIds := []string{requestEmail.Email + constants.ThumbnailRes, requestEmail.Email + constants.LowRes, requestEmail.Email + constants.MidRes, requestEmail.Email + constants.HighRes}
+// CheckIn handles the check-in process for a booking
+func CheckIn(ctx *gin.Context, appsession *models.AppSession) {
+	var checkInRequest map[string]interface{}
+	if err := ctx.ShouldBindJSON(&checkInRequest); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
 
-	// del user image if it exists
-	if err := MultiDeleteImages(ctx, appsession, configs.GetAzurePFPContainerName(), imageIds); err != nil {
+	// Validate JSON
+	validatedData, err := utils.ValidateJSON(checkInRequest, reflect.TypeOf(models.CheckIn{}))
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, err.Error(), nil))
 		return
 	}
 
-	files, err := ResizeImagesAndReturnAsFiles(ctx, appsession, file, requestEmail.Email)
+	// Convert validated JSON to CheckIn struct
+	var checkIn models.CheckIn
+	checkInBytes, _ := json.Marshal(validatedData)
+	if err := json.Unmarshal(checkInBytes, &checkIn); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to check in", constants.InternalServerErrorCode, "Failed to check in", nil))
+		return
+	}
 
-	if err != nil {
+	if checkIn.Type != "" && checkIn.Type != constants.RoomCheckIn && checkIn.Type != constants.DeskCheckIn {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, "type must be room or desk", nil))
 		return
 	}
 
-	// upload image associated with this email
-	if err := MultiUploadImages(ctx, appsession, configs.GetAzurePFPContainerName(), files); err != nil {
+	// attendees check in for themselves, only admins can check someone else in
+	if _, ok := AuthorizeBookingAction(ctx, appsession, checkIn.Creator, "check in", false); !ok {
 		return
 	}
 
-	// update has image field in the database
-	if err := database.SetHasImage(ctx, appsession, email, true); err != nil {
+	// Check if the booking exists
+	if checkIn.Type == constants.DeskCheckIn {
+		if !ValidateDeskCheckIn(ctx, appsession, checkIn, time.Now()) {
+			return
+		}
+	} else if !ValidateRoomCheckIn(ctx, appsession, checkIn) {
+		return
+	}
+
+	// Confirm the check-in to the database
+	_, err = database.ConfirmCheckIn(ctx, appsession, checkIn)
+	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to check in", constants.InternalServerErrorCode, "Failed to check in. Email not associated with booking", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully uploaded image!", nil))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully checked in!", nil))
 }
 
-func DownloadProfileImage(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.ProfileImageRequest
+func GetUserDetails(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestEmail
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		email := ctx.Query("email")
-		quality := ctx.Query("quality")
-		if email == "" {
+		emailStr := ctx.Query("email")
+		if emailStr == "" {
 			email, err := AttemptToGetEmail(ctx, appsession)
 			if err != nil {
 				configs.CaptureError(ctx, err)
@@ -887,1056 +861,3199 @@ func DownloadProfileImage(ctx *gin.Context, appsession *models.AppSession) {
 					"Email must be provided",
 					nil))
 				return
+			} else {
+				request.Email = email
 			}
-			request.Email = email
 		} else {
-			request.Email = email
+			request.Email = emailStr
 		}
-		request.Quality = quality
 	}
 
-	if hasImage := database.UserHasImage(ctx, appsession, request.Email); !hasImage {
-		gender, err := database.GetUsersGender(ctx, appsession, request.Email)
-
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
-			return
-		}
+	// Get all the user details
+	user, err := database.GetUserDetails(ctx, appsession, request.Email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get user details", constants.InternalServerErrorCode, "Failed to get user details", nil))
+		return
+	}
 
-		var blobURL string
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched user details!", user))
+}
 
-		switch gender {
-		case "Male":
-			blobURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), DefaultMalePFP())
-		case "Female":
-			blobURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), DefaultMalePFP())
-		default:
-			blobURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), DefaultNBPFP())
-		}
-
-		http.Redirect(ctx.Writer, ctx.Request, blobURL, http.StatusSeeOther)
+func UpdateUserDetails(ctx *gin.Context, appsession *models.AppSession) {
+	var user models.UserDetailsRequest
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid JSON payload", nil))
 		return
 	}
 
-	if request.Quality != "" && request.Quality != constants.ThumbnailRes && request.Quality != constants.LowRes && request.Quality != constants.MidRes && request.Quality != constants.HighRes {
-		request.Quality = constants.MidRes
-	} else if request.Quality == "" {
-		request.Quality = constants.MidRes
+	// Update the user details in the database
+	_, err := database.UpdateUserDetails(ctx, appsession, user)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update user details", constants.InternalServerErrorCode, "Failed to update user details", nil))
+		return
 	}
 
-	// remove @ from email
-	request.Email = strings.ReplaceAll(request.Email, "@", "")
+	// if user is updating their email, create a new token for them
+	if user.Email != "" {
+		if err := AttemptToSignNewEmail(ctx, appsession, user.Email); err != nil {
+			logrus.Error("Failed to sign new email because: ", err)
+			configs.CaptureError(ctx, err)
+		}
+		return
+	}
 
-	// redirect to the image on azure
-	blobURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), request.Email, request.Quality)
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated user details!", nil))
+}
 
-	http.Redirect(ctx.Writer, ctx.Request, blobURL, http.StatusSeeOther)
+// Helper function to handle validation of requests
+func HandleValidationErrors(ctx *gin.Context, err error) {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		out := make([]models.ErrorMsg, len(ve))
+		for i, err := range ve {
+			out[i] = models.ErrorMsg{
+				Field:   utils.LowercaseFirstLetter(err.Field()),
+				Message: utils.GetErrorMsg(err),
+			}
+		}
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid request payload", gin.H{"errors": out}))
+	}
 }
 
-func DeleteProfileImage(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestEmail
-	if err := ctx.ShouldBindJSON(&request); err != nil {
-		email := ctx.Query("email")
-		if email == "" {
-			email, err := AttemptToGetEmail(ctx, appsession)
+func FilterCollection(ctx *gin.Context, appsession *models.AppSession, collectionName string) {
+	var queryInput models.QueryInput
+	if err := ctx.ShouldBindJSON(&queryInput); err != nil {
+		// try to bind the query input to the struct
+		queryInput = models.QueryInput{}
+		operatorStr := ctx.Query("operator")
+		if operatorStr != "" {
+			queryInput.Operator = operatorStr
+		}
+
+		orderAscStr := ctx.Query("order_asc")
+		if orderAscStr != "" {
+			queryInput.OrderAsc = orderAscStr
+		}
+
+		orderDescStr := ctx.Query("order_desc")
+		if orderDescStr != "" {
+			queryInput.OrderDesc = orderDescStr
+		}
+
+		projectionStr := ctx.Query("projection")
+		if projectionStr != "" {
+			queryInput.Projection = utils.ConvertCommaDelimitedStringToArray(projectionStr)
+		}
+
+		limitStr := ctx.Query("limit")
+		if limitStr != "" {
+			limit, err := strconv.ParseInt(limitStr, 10, 64) // Base 10, 64-bit size
 			if err != nil {
 				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-					http.StatusBadRequest,
-					"Invalid request payload",
-					constants.InvalidRequestPayloadCode,
-					"Email must be provided",
-					nil))
+				// Handle the error if the conversion fails, maybe set an error response
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid limit format", constants.InvalidRequestPayloadCode, "Invalid limit format", nil))
 				return
 			}
-			request.Email = email
-		} else {
-			request.Email = email
+			queryInput.Limit = limit
+		}
+
+		pageStr := ctx.Query("page")
+		if pageStr != "" {
+			page, err := strconv.ParseInt(pageStr, 10, 64) // Base 10, 64-bit size
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				// Handle the error if the conversion fails, maybe set an error response
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid page format", constants.InvalidRequestPayloadCode, "Invalid page format", nil))
+				return
+			}
+			queryInput.Page = page
+		}
+
+		tagsStr := ctx.Query("tags")
+		if tagsStr != "" {
+			queryInput.Tags = utils.ConvertCommaDelimitedStringToArray(tagsStr)
+		}
+
+		// For Filter, which expects a map[string]interface{}, unmarshal the JSON string
+		filterStr := ctx.Query("filter")
+		if filterStr != "" {
+			var filterMap map[string]interface{}
+			if err := json.Unmarshal([]byte(filterStr), &filterMap); err != nil {
+				configs.CaptureError(ctx, err)
+				// Handle JSON unmarshal error, maybe set an error response
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid filter format", constants.InvalidRequestPayloadCode, "Invalid filter format", nil))
+				return
+			}
+			queryInput.Filter = filterMap
 		}
 	}
 
-	// remove @ from email
-	email := request.Email
-	request.Email = strings.ReplaceAll(request.Email, "@", "")
+	sanitizedFilter := utils.SantizeFilter(queryInput)
+	sanitizedSort := utils.SanitizeSort(queryInput)
 
-	imageIds := []string{request.Email + constants.ThumbnailRes, request.Email + constants.LowRes, request.Email + constants.MidRes, request.Email + constants.HighRes}
+	sanitizedProjection := utils.SantizeProjection(queryInput)
 
-	// del user image if it exists
-	if err := MultiDeleteImages(ctx, appsession, configs.GetAzurePFPContainerName(), imageIds); err != nil {
-		return
+	projection := utils.ConstructProjection(queryInput, sanitizedProjection)
+
+	limit, page, skip := utils.GetLimitPageSkip(queryInput)
+
+	filter := models.FilterStruct{
+		Filter:     sanitizedFilter,
+		Projection: projection,
+		Limit:      limit,
+		Skip:       skip,
+		Sort:       sanitizedSort,
 	}
 
-	// update has image field in the database
-	if err := database.SetHasImage(ctx, appsession, email, false); err != nil {
+	if collectionName == "RoomBooking" {
+		// check that the email field is set
+		if _, ok := filter.Filter["email"]; !ok {
+			configs.CaptureError(ctx, errors.New("email field not set"))
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+			return
+		}
+
+		// delete email field from the filter
+		email := filter.Filter["email"]
+		delete(filter.Filter, "email")
+
+		// set emails field in the filter
+		filter.Filter["emails"] = bson.M{"$in": []string{email.(string)}}
+	}
+
+	if collectionName == "Rooms" || collectionName == "Desks" {
+		// only rooms and desks with every requested tag are returned and archived rooms are hidden unless asked for
+		filter.Filter = utils.ApplyRoomFilters(filter.Filter, queryInput.Tags)
+	}
+
+	if collectionName == "Rooms" {
+		filter = utils.HideCheckInTokens(filter)
+	}
+
+	res, totalResults, err := database.FilterCollectionWithProjection(ctx, appsession, collectionName, filter)
+
+	if err != nil {
 		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to filter collection because: ", err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted image!", nil))
+	if collectionName == "Notifications" {
+		email, err := AttemptToGetEmail(ctx, appsession)
+
+		if err == nil {
+			err := database.ReadNotifications(ctx, appsession, email)
+
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				logrus.Error("Failed to read notifications because: ", err)
+				// it's not a critical error so we don't return an error response
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "success", res, gin.H{
+		"totalResults": len(res), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
+}
+
+func DeleteNotification(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.DeleteNotiRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid JSON payload", nil))
+		return
+	}
+
+	// check if email is set otherwise attempt to get it from the appsession
+	if request.Email == "" {
+		email, err := AttemptToGetEmail(ctx, appsession)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+			return
+		}
+		request.Email = email
+	}
+
+	// delete the notification
+	err := database.DeleteNotificationForUser(ctx, appsession, request)
+
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to delete notification because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to delete notification", constants.InternalServerErrorCode, "Failed to delete notification", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted notification!", nil))
+}
+
+func GetPushTokens(ctx *gin.Context, appsession *models.AppSession) {
+	var emails models.RequestEmails
+	if err := ctx.ShouldBindJSON(&emails); err != nil {
+		emailsStr := ctx.Query("emails")
+		if emailsStr != "" {
+			emails.Emails = utils.ConvertCommaDelimitedStringToArray(emailsStr)
+		} else {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Invalid request payload",
+				constants.InvalidRequestPayloadCode,
+				"Unexpected fields found or invalid format",
+				nil))
+			return
+		}
+	}
+
+	// sanitize the emails
+	emails.Emails = utils.SanitizeInputArray(emails.Emails)
+
+	// validate the emails
+	if !utils.ValidateEmails(emails.Emails) {
+		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+		return
+	}
+
+	pushTokens, err := database.GetUsersPushTokens(ctx, appsession, emails.Emails)
+
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to get users: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get push tokens", constants.InternalServerErrorCode, "Failed to get push tokens", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched push tokens!", pushTokens))
+}
+
+func UpdateSecuritySettings(ctx *gin.Context, appsession *models.AppSession) {
+	var securitySettings models.SecuritySettingsRequest
+	if err := ctx.ShouldBindJSON(&securitySettings); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
+		return
+	}
+
+	// check if the email is set
+	if securitySettings.Email == "" {
+		// get the email from the appsession
+		email, err := AttemptToGetEmail(ctx, appsession)
+
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			logrus.Error("Failed to get email because: ", err)
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Invalid request payload",
+				constants.InvalidRequestPayloadCode,
+				"Email must be provided",
+				nil))
+			return
+		}
+
+		securitySettings.Email = email
+	}
+
+	// check that if current password is provided, new password and new password confirm are also provided and vice versa
+	if (securitySettings.CurrentPassword == "" && (securitySettings.NewPassword != "" || securitySettings.NewPasswordConfirm != "")) ||
+		(securitySettings.NewPassword == "" && (securitySettings.CurrentPassword != "" || securitySettings.NewPasswordConfirm != "")) ||
+		(securitySettings.NewPasswordConfirm == "" && (securitySettings.CurrentPassword != "" || securitySettings.NewPassword != "")) {
+		configs.CaptureError(ctx, errors.New("current password, new password and new password confirm must all be provided"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Current password, new password and new password confirm must all be provided",
+			nil))
+		return
+	}
+
+	// check if the password match
+	if securitySettings.NewPassword != "" && securitySettings.NewPassword != securitySettings.NewPasswordConfirm {
+		configs.CaptureError(ctx, errors.New("new password and new password confirm do not match"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"New password and new password confirm do not match",
+			nil))
+		return
+	}
+
+	// Validate the given passwords if they exist
+	if securitySettings.CurrentPassword != "" && securitySettings.NewPassword != "" && securitySettings.NewPasswordConfirm != "" {
+		securitySetting, err, success := SanitizeSecuritySettingsPassword(ctx, appsession, securitySettings)
+		if err != nil || !success {
+			configs.CaptureError(ctx, fmt.Errorf("failed to sanitize security settings because: %v", err))
+			logrus.Error("Failed to sanitize security settings because: ", err)
+			return
+		}
+
+		securitySettings = securitySetting
+	}
+
+	// if mfa string is set, ensure it's either "on" or "off"
+	if securitySettings.Mfa != "" && securitySettings.Mfa != constants.On && securitySettings.Mfa != constants.Off {
+		configs.CaptureMessage(ctx, "mfa must be either 'on' or 'off'")
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"mfa must be either 'on' or 'off'",
+			nil))
+		return
+	}
+
+	// if forceLogout string is set, ensure it's either "on" or "off"
+	if securitySettings.ForceLogout != "" && securitySettings.ForceLogout != constants.On && securitySettings.ForceLogout != constants.Off {
+		configs.CaptureMessage(ctx, "forceLogout must be either 'on' or 'off'")
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"forceLogout must be either 'on' or 'off'",
+			nil))
+		return
+	}
+
+	if err := database.UpdateSecuritySettings(ctx, appsession, securitySettings); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to update security settings",
+			constants.InternalServerErrorCode,
+			"Failed to update security settings",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated security settings!", nil))
+}
+
+func GetSecuritySettings(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestEmail
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		emailStr := ctx.Query("email")
+		if emailStr == "" {
+			email, err := AttemptToGetEmail(ctx, appsession)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+					http.StatusBadRequest,
+					"Invalid request payload",
+					constants.InvalidRequestPayloadCode,
+					"Email must be provided",
+					nil))
+				return
+			} else {
+				request.Email = email
+			}
+		} else {
+			request.Email = emailStr
+		}
+	}
+
+	securitySettings, err := database.GetSecuritySettings(ctx, appsession, request.Email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to get security settings",
+			constants.InternalServerErrorCode,
+			"Failed to get security settings",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched security settings!", securitySettings))
+}
+
+func UpdateNotificationSettings(ctx *gin.Context, appsession *models.AppSession) {
+	var notificationsSettings models.NotificationsRequest
+	if err := ctx.ShouldBindJSON(&notificationsSettings); err != nil {
+		email, err := AttemptToGetEmail(ctx, appsession)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Invalid request payload",
+				constants.InvalidRequestPayloadCode,
+				"Invalid JSON payload",
+				nil))
+			return
+		}
+		notificationsSettings.Email = email
+
+		invitesStr := ctx.Query("invites")
+		if invitesStr != "" {
+			notificationsSettings.Invites = invitesStr
+		}
+
+		bookingReminderStr := ctx.Query("bookingReminder")
+		if bookingReminderStr != "" {
+			notificationsSettings.BookingReminder = bookingReminderStr
+		}
+	}
+
+	// If invites is set, ensure it's either "on" or "off"
+	if notificationsSettings.Invites != "" && notificationsSettings.Invites != constants.On && notificationsSettings.Invites != constants.Off {
+		configs.CaptureMessage(ctx, "invites must be either 'on' or 'off'")
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"invites must be either 'on' or 'off'",
+			nil))
+		return
+	}
+
+	// If bookingReminder is set, ensure it's either "on" or "off"
+	if notificationsSettings.BookingReminder != "" && notificationsSettings.BookingReminder != constants.On && notificationsSettings.BookingReminder != constants.Off {
+		configs.CaptureMessage(ctx, "bookingReminder must be either 'on' or 'off'")
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"bookingReminder must be either 'on' or 'off'",
+			nil))
+		return
+	}
+
+	if err := utils.ValidateNotificationChannels(notificationsSettings.Channels); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		return
+	}
+
+	if notificationsSettings.WebhookURL != nil {
+		if err := utils.ValidateWebhookURL(*notificationsSettings.WebhookURL); err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+			return
+		}
+	}
+
+	// update the notification settings
+	if err := database.UpdateNotificationSettings(ctx, appsession, notificationsSettings); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to update notification settings",
+			constants.InternalServerErrorCode,
+			"Failed to update notification settings",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated notification settings!", nil))
+}
+
+func GetNotificationSettings(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestEmail
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		emailStr := ctx.Query("email")
+		if emailStr == "" {
+			email, err := AttemptToGetEmail(ctx, appsession)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+					http.StatusBadRequest,
+					"Invalid request payload",
+					constants.InvalidRequestPayloadCode,
+					"Email must be provided",
+					nil))
+				return
+			} else {
+				request.Email = email
+			}
+		} else {
+			request.Email = emailStr
+		}
+	}
+
+	notificationSettings, err := database.GetNotificationSettings(ctx, appsession, request.Email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to get notification settings",
+			constants.InternalServerErrorCode,
+			"Failed to get notification settings",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched notification settings!", notificationSettings))
+}
+
+func UploadProfileImage(ctx *gin.Context, appsession *models.AppSession) {
+	file, err := ctx.FormFile("image")
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid file format",
+			nil))
+		return
+	}
+
+	var requestEmail models.RequestEmail
+	if err := ctx.ShouldBindJSON(&requestEmail); err != nil {
+		// attempt to get email from form data
+		email := ctx.PostForm("email")
+		if email == "" {
+			emaila, err := AttemptToGetEmail(ctx, appsession)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+					http.StatusBadRequest,
+					"Invalid request payload",
+					constants.InvalidRequestPayloadCode,
+					"Email must be provided",
+					nil))
+				return
+			}
+			requestEmail.Email = emaila
+		} else {
+			requestEmail.Email = email
+		}
+	}
+
+	// remove @ from email
+	email := requestEmail.Email
+	requestEmail.Email = strings.ReplaceAll(requestEmail.Email, "@", "")
+
+	imageIds := []string{requestEmail.Email + constants.ThumbnailRes, requestEmail.Email + constants.LowRes, requestEmail.Email + constants.MidRes, requestEmail.Email + constants.HighRes}
+
+	// del user image if it exists
+	if err := MultiDeleteImages(ctx, appsession, configs.GetAzurePFPContainerName(), imageIds); err != nil {
+		return
+	}
+
+	files, err := ResizeImagesAndReturnAsFiles(ctx, appsession, file, requestEmail.Email)
+
+	if err != nil {
+		return
+	}
+
+	// upload image associated with this email
+	if err := MultiUploadImages(ctx, appsession, configs.GetAzurePFPContainerName(), files); err != nil {
+		return
+	}
+
+	// update has image field in the database
+	if err := database.SetHasImage(ctx, appsession, email, true); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully uploaded image!", nil))
+}
+
+func DownloadProfileImage(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.ProfileImageRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		email := ctx.Query("email")
+		quality := ctx.Query("quality")
+		if email == "" {
+			email, err := AttemptToGetEmail(ctx, appsession)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+					http.StatusBadRequest,
+					"Invalid request payload",
+					constants.InvalidRequestPayloadCode,
+					"Email must be provided",
+					nil))
+				return
+			}
+			request.Email = email
+		} else {
+			request.Email = email
+		}
+		request.Quality = quality
+	}
+
+	if hasImage := database.UserHasImage(ctx, appsession, request.Email); !hasImage {
+		gender, err := database.GetUsersGender(ctx, appsession, request.Email)
+
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return
+		}
+
+		var blobURL string
+
+		switch gender {
+		case "Male":
+			blobURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), DefaultMalePFP())
+		case "Female":
+			blobURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), DefaultMalePFP())
+		default:
+			blobURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), DefaultNBPFP())
+		}
+
+		http.Redirect(ctx.Writer, ctx.Request, blobURL, http.StatusSeeOther)
+		return
+	}
+
+	if request.Quality != "" && request.Quality != constants.ThumbnailRes && request.Quality != constants.LowRes && request.Quality != constants.MidRes && request.Quality != constants.HighRes {
+		request.Quality = constants.MidRes
+	} else if request.Quality == "" {
+		request.Quality = constants.MidRes
+	}
+
+	// remove @ from email
+	request.Email = strings.ReplaceAll(request.Email, "@", "")
+
+	// redirect to the image on azure
+	blobURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s%s", configs.GetAzureAccountName(), configs.GetAzurePFPContainerName(), request.Email, request.Quality)
+
+	http.Redirect(ctx.Writer, ctx.Request, blobURL, http.StatusSeeOther)
+}
+
+func DeleteProfileImage(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestEmail
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		email := ctx.Query("email")
+		if email == "" {
+			email, err := AttemptToGetEmail(ctx, appsession)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+					http.StatusBadRequest,
+					"Invalid request payload",
+					constants.InvalidRequestPayloadCode,
+					"Email must be provided",
+					nil))
+				return
+			}
+			request.Email = email
+		} else {
+			request.Email = email
+		}
+	}
+
+	// remove @ from email
+	email := request.Email
+	request.Email = strings.ReplaceAll(request.Email, "@", "")
+
+	imageIds := []string{request.Email + constants.ThumbnailRes, request.Email + constants.LowRes, request.Email + constants.MidRes, request.Email + constants.HighRes}
+
+	// del user image if it exists
+	if err := MultiDeleteImages(ctx, appsession, configs.GetAzurePFPContainerName(), imageIds); err != nil {
+		return
+	}
+
+	// update has image field in the database
+	if err := database.SetHasImage(ctx, appsession, email, false); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted image!", nil))
+}
+
+func DownloadRoomImage(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.ImageRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		request.ID = ctx.Param("id")
+		request.Quality = ctx.Query("quality")
+		if request.ID == "" {
+			configs.CaptureError(ctx, errors.New("id must be provided"))
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Invalid request payload",
+				constants.InvalidRequestPayloadCode,
+				"ID must be provided",
+				nil))
+			return
+		}
+	}
+
+	if request.Quality != "" && request.Quality != constants.ThumbnailRes && request.Quality != constants.LowRes && request.Quality != constants.MidRes && request.Quality != constants.HighRes {
+		request.Quality = constants.MidRes
+	} else if request.Quality == "" {
+		request.Quality = constants.MidRes
+	}
+
+	if request.ID == "null" {
+		request.ID = "emproom.jpg"
+		request.Quality = ""
+	}
+
+	// redirect to the image on azure
+	blobURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s%s", configs.GetAzureAccountName(), configs.GetAzureRoomsContainerName(), request.ID, request.Quality)
+
+	http.Redirect(ctx.Writer, ctx.Request, blobURL, http.StatusSeeOther)
+}
+
+func UploadRoomImage(ctx *gin.Context, appsession *models.AppSession) {
+	file, err := ctx.FormFile("image")
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid file format",
+			nil))
+		return
+	}
+
+	uuid := utils.GenerateUUID()
+
+	files, err := ResizeImagesAndReturnAsFiles(ctx, appsession, file, uuid)
+
+	if err != nil {
+		return
+	}
+
+	// upload image associated with this email
+	if err := MultiUploadImages(ctx, appsession, configs.GetAzureRoomsContainerName(), files); err != nil {
+		return
+	}
+
+	// get room id from json body
+	roomid := ctx.Query("roomid")
+
+	if roomid == "" {
+		roomid = ctx.PostForm("roomid")
+		if roomid == "" {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid JSON payload", nil))
+			return
+		}
+	}
+
+	// Update the room details with the image id
+	err = database.AddImageToRoom(ctx, appsession, roomid, uuid)
+
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.WithError(err).Error("Failed to update image id")
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update room image id", constants.InternalServerErrorCode, "Failed to update room image", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully uploaded image!", gin.H{"id": uuid}))
+}
+
+func DeleteRoomImage(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.ImageRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		request.ID = ctx.Query("id")
+		request.RoomID = ctx.Query("roomid")
+		if request.ID == "" || request.RoomID == "" {
+			configs.CaptureError(ctx, errors.New("id and roomid must be provided"))
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Invalid request payload",
+				constants.InvalidRequestPayloadCode,
+				"ID and roomid must be provided",
+				nil))
+			return
+		}
+	}
+
+	// del user image if it exists
+	if err := MultiDeleteImages(ctx, appsession, configs.GetAzureRoomsContainerName(), []string{request.ID + constants.ThumbnailRes, request.ID + constants.LowRes, request.ID + constants.MidRes, request.ID + constants.HighRes}); err != nil {
+		return
+	}
+
+	// Update the room details with the image id
+	if err := database.DeleteImageFromRoom(ctx, appsession, request.RoomID, request.ID); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.WithError(err).Error("Failed to delete image id")
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to delete room image id", constants.InternalServerErrorCode, "Failed to delete room image", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted image!", nil))
+}
+
+func AddRoom(ctx *gin.Context, appsession *models.AppSession) {
+	var room models.RequestRoom
+	if err := ctx.ShouldBindJSON(&room); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
+		return
+	}
+
+	// Save the room to the database
+	roomID, err := database.AddRoom(ctx, appsession, room)
+	if err != nil && (err.Error() == "building not found" || err.Error() == "floor not found") {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		return
+	}
+	if err != nil {
+		var msg string
+		if err.Error() == "room already exists" {
+			msg = "Room already exists"
+		} else {
+			msg = "Failed to add room"
+		}
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to add room",
+			constants.InternalServerErrorCode,
+			"Failed to add room",
+			gin.H{"message": msg}))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added room!", gin.H{"roomid": roomID}))
+}
+
+// SearchAvailableRooms finds every room that can hold the attendees and has a free slot of the requested
+// duration within the time window, ranked by how closely the room fits the headcount and the user's floor
+func SearchAvailableRooms(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestRoomSearch
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	window := request.End.Sub(request.Start)
+	if window <= 0 || window > constants.MaxRoomSearchDays*24*time.Hour {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, fmt.Sprintf("end must be after start and within %d days of it", constants.MaxRoomSearchDays), nil))
+		return
+	}
+
+	if time.Duration(request.Duration)*time.Minute > window {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "duration cannot be longer than the time window", nil))
+		return
+	}
+
+	// rooms on the user's floor are preferred but the search works without knowing it
+	var preferredFloor string
+	if email, err := AttemptToGetEmail(ctx, appsession); err == nil {
+		if preferredFloor, err = database.GetDepartmentFloor(ctx, appsession, email); err != nil {
+			configs.CaptureError(ctx, err)
+			logrus.Error("Failed to get department floor because: ", err)
+		}
+	}
+
+	rooms, err := database.SearchAvailableRooms(ctx, appsession, request, preferredFloor)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched available rooms!", rooms))
+}
+
+// UpdateBookingSettings sets the opening hours, slot length, buffer and blackouts of a room, a building or the defaults
+func UpdateBookingSettings(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestBookingSettings
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	settings := models.BookingSettings{
+		BuildingID:  request.BuildingID,
+		RoomID:      request.RoomID,
+		OpeningTime: request.OpeningTime,
+		ClosingTime: request.ClosingTime,
+		SlotLength:  request.SlotLength,
+		Buffer:      request.Buffer,
+		Blackouts:   request.Blackouts,
+	}
+
+	if err := database.ValidateBookingSettings(settings); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		return
+	}
+
+	if err := database.SetBookingSettings(ctx, appsession, settings); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update booking settings", constants.InternalServerErrorCode, "Failed to update booking settings", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated booking settings!", nil))
+}
+
+// UpdateBookingPolicy sets the limits on bookings of a role, department or room
+func UpdateBookingPolicy(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestBookingPolicy
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	policy := models.BookingPolicy{
+		Scope:             request.Scope,
+		Target:            request.Target,
+		MaxDuration:       request.MaxDuration,
+		MaxAdvanceDays:    request.MaxAdvanceDays,
+		MaxActiveBookings: request.MaxActiveBookings,
+		WeeklyHours:       request.WeeklyHours,
+	}
+
+	if err := database.SetBookingPolicy(ctx, appsession, policy); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update booking policy", constants.InternalServerErrorCode, "Failed to update booking policy", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated booking policy!", nil))
+}
+
+// GetBookingPolicies returns every booking policy
+func GetBookingPolicies(ctx *gin.Context, appsession *models.AppSession) {
+	policies, err := database.GetBookingPolicies(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get booking policies", constants.InternalServerErrorCode, "Failed to get booking policies", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched booking policies!", policies))
+}
+
+// DeleteBookingPolicy removes the limits on bookings of a role, department or room
+func DeleteBookingPolicy(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDeleteBookingPolicy
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.DeleteBookingPolicy(ctx, appsession, request.Scope, request.Target); err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "booking policy not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking policy not found", constants.BadRequestCode, err.Error(), nil))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to delete booking policy", constants.InternalServerErrorCode, "Failed to delete booking policy", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted booking policy!", nil))
+}
+
+// UpdateDepartment places a department on a floor so the room search prefers rooms near its members
+func UpdateDepartment(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDepartment
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	department := models.Department{
+		DepartmentNo: request.DepartmentNo,
+		FloorNo:      request.FloorNo,
+	}
+
+	if err := database.SetDepartment(ctx, appsession, department); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update department", constants.InternalServerErrorCode, "Failed to update department", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated department!", nil))
+}
+
+// GetDepartments returns every department and the floor it is placed on
+func GetDepartments(ctx *gin.Context, appsession *models.AppSession) {
+	departments, err := database.GetDepartments(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get departments", constants.InternalServerErrorCode, "Failed to get departments", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched departments!", departments))
+}
+
+// DeleteDepartment removes a department from its floor
+func DeleteDepartment(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDeleteDepartment
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.DeleteDepartment(ctx, appsession, request.DepartmentNo); err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "department not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Department not found", constants.BadRequestCode, err.Error(), nil))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to delete department", constants.InternalServerErrorCode, "Failed to delete department", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted department!", nil))
+}
+
+// GetBookingSettings returns the settings that apply to a room after combining its room, building and default settings
+func GetBookingSettings(ctx *gin.Context, appsession *models.AppSession) {
+	roomID := ctx.Query("roomId")
+	if roomID == "" {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "roomId must be provided", nil))
+		return
+	}
+
+	settings, err := database.GetBookingSettings(ctx, appsession, roomID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get booking settings", constants.InternalServerErrorCode, "Failed to get booking settings", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched booking settings!", settings))
+}
+
+// AddSite adds an office site with the timezone its rooms are booked in
+func AddSite(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestSite
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if _, err := time.LoadLocation(request.TimeZone); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid timezone", nil))
+		return
+	}
+
+	siteID, err := database.AddSite(ctx, appsession, models.Site{Name: request.Name, Address: request.Address, TimeZone: request.TimeZone})
+	if err != nil {
+		LocationErrorResponse(ctx, err, "Failed to add site")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added site!", gin.H{"siteId": siteID}))
+}
+
+// UpdateSite updates the name, address and timezone of a site
+func UpdateSite(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestSite
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if request.SiteID == "" {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "siteId must be provided", nil))
+		return
+	}
+
+	if _, err := time.LoadLocation(request.TimeZone); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid timezone", nil))
+		return
+	}
+
+	site := models.Site{SiteID: request.SiteID, Name: request.Name, Address: request.Address, TimeZone: request.TimeZone}
+	if err := database.UpdateSite(ctx, appsession, site); err != nil {
+		LocationErrorResponse(ctx, err, "Failed to update site")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated site!", nil))
+}
+
+// DeleteSite deletes a site that no longer has any buildings
+func DeleteSite(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestSiteID
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.DeleteSite(ctx, appsession, request.SiteID); err != nil {
+		LocationErrorResponse(ctx, err, "Failed to delete site")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted site!", nil))
+}
+
+// AddBuilding adds a building to a site
+func AddBuilding(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestBuilding
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	buildingID, err := database.AddBuilding(ctx, appsession, models.Building{SiteID: request.SiteID, Name: request.Name, Address: request.Address})
+	if err != nil {
+		LocationErrorResponse(ctx, err, "Failed to add building")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added building!", gin.H{"buildingId": buildingID}))
+}
+
+// UpdateBuilding updates a building or moves it to another site
+func UpdateBuilding(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestBuilding
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if request.BuildingID == "" {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "buildingId must be provided", nil))
+		return
+	}
+
+	building := models.Building{BuildingID: request.BuildingID, SiteID: request.SiteID, Name: request.Name, Address: request.Address}
+	if err := database.UpdateBuilding(ctx, appsession, building); err != nil {
+		LocationErrorResponse(ctx, err, "Failed to update building")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated building!", nil))
+}
+
+// DeleteBuilding deletes a building that no longer has any floors
+func DeleteBuilding(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestBuildingID
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.DeleteBuilding(ctx, appsession, request.BuildingID); err != nil {
+		LocationErrorResponse(ctx, err, "Failed to delete building")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted building!", nil))
+}
+
+// AddFloor adds a floor to a building so that rooms can be placed on it
+func AddFloor(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestFloor
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.AddFloor(ctx, appsession, models.Floor{BuildingID: request.BuildingID, FloorNo: request.FloorNo, Name: request.Name}); err != nil {
+		LocationErrorResponse(ctx, err, "Failed to add floor")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added floor!", nil))
+}
+
+// UpdateFloor updates the name of a floor
+func UpdateFloor(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestFloor
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.UpdateFloor(ctx, appsession, models.Floor{BuildingID: request.BuildingID, FloorNo: request.FloorNo, Name: request.Name}); err != nil {
+		LocationErrorResponse(ctx, err, "Failed to update floor")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated floor!", nil))
+}
+
+// DeleteFloor deletes a floor that no longer has any rooms
+func DeleteFloor(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestFloor
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.DeleteFloor(ctx, appsession, request.BuildingID, request.FloorNo); err != nil {
+		LocationErrorResponse(ctx, err, "Failed to delete floor")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted floor!", nil))
+}
+
+// AddDesk adds a desk to the desk inventory of a floor
+func AddDesk(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDesk
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	desk := models.Desk{
+		DeskID:        request.DeskID,
+		DeskName:      request.DeskName,
+		FloorNo:       request.FloorNo,
+		BuildingID:    request.BuildingID,
+		Neighbourhood: request.Neighbourhood,
+		Amenities:     request.Amenities,
+	}
+
+	if err := database.AddDesk(ctx, appsession, desk); err != nil {
+		configs.CaptureError(ctx, err)
+		switch err.Error() {
+		case "desk already exists":
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Desk already exists", constants.BadRequestCode, "A desk with this id already exists", nil))
+		case "building not found", "floor not found":
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		default:
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to add desk", constants.InternalServerErrorCode, "Failed to add desk", nil))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added desk!", nil))
+}
+
+// DeleteDesk removes a desk from the desk inventory
+func DeleteDesk(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDeskID
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.DeleteDesk(ctx, appsession, request.DeskID); err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "desk not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Desk not found", constants.BadRequestCode, "Desk not found", nil))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to delete desk", constants.InternalServerErrorCode, "Failed to delete desk", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted desk!", nil))
+}
+
+// BookDesk books a desk for a morning, afternoon or the whole day, the user's usual desk is booked when asked for
+func BookDesk(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDeskBooking
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if request.Email == "" {
+		email, err := AttemptToGetEmail(ctx, appsession)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+			return
+		}
+		request.Email = email
+	}
+
+	// desks are booked for the signed in user, or a user who delegated booking to them
+	resolved, ok := ResolveBookingCreator(ctx, appsession, models.Booking{Creator: request.Email})
+	if !ok {
+		return
+	}
+	creator := resolved.CreatedBy
+
+	var desk models.Desk
+	var err error
+	switch {
+	case request.DeskID != "":
+		desk, err = database.GetDesk(ctx, appsession, request.DeskID)
+	case request.Usual:
+		desk, err = database.GetUsualDesk(ctx, appsession, request.Email)
+	default:
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "deskId must be provided unless booking the usual desk", nil))
+		return
+	}
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "no usual desk" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "No usual desk", constants.BadRequestCode, "No desk has been booked recently to book again", nil))
+			return
+		}
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Desk not found", constants.BadRequestCode, "Desk not found", nil))
+		return
+	}
+
+	start, end, ok := DeskSlot(ctx, appsession, desk, request.Date, request.Slot)
+	if !ok {
+		return
+	}
+
+	existing, coinciding, err := database.CheckCoincidingDeskBookings(ctx, appsession, []string{request.Email}, []string{desk.DeskID}, start, end)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book desk", constants.InternalServerErrorCode, "Failed to book desk", nil))
+		return
+	}
+	if coinciding {
+		msg := "User already has a desk booked at this time"
+		if existing.DeskID == desk.DeskID {
+			msg = "Desk is already booked at this time"
+		}
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking coincides with another booking", constants.BadRequestCode, msg, nil))
+		return
+	}
+
+	booking := models.DeskBooking{
+		OccupiID:      utils.GenerateBookingID(),
+		DeskID:        desk.DeskID,
+		DeskName:      desk.DeskName,
+		Email:         request.Email,
+		Creator:       creator,
+		FloorNo:       desk.FloorNo,
+		BuildingID:    desk.BuildingID,
+		SiteID:        desk.SiteID,
+		Neighbourhood: desk.Neighbourhood,
+		Slot:          request.Slot,
+		Date:          utils.StartOfDay(start),
+		Start:         start,
+		End:           end,
+	}
+
+	if err := database.AddDeskBookings(ctx, appsession, []models.DeskBooking{booking}); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book desk", constants.InternalServerErrorCode, "Failed to book desk", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully booked desk!", booking))
+}
+
+// BookNeighbourhood books a desk in the same neighbourhood for everyone in a team, either everyone gets a desk or nobody does
+func BookNeighbourhood(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestNeighbourhoodBooking
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	// members of the team book for it themselves, anyone else must be allowed to book for its first member
+	owner := request.Emails[0]
+	if claims, err := utils.GetClaimsFromCTX(ctx); err == nil && utils.Contains(request.Emails, claims.Email) {
+		owner = claims.Email
+	}
+
+	resolved, ok := ResolveBookingCreator(ctx, appsession, models.Booking{Creator: owner})
+	if !ok {
+		return
+	}
+	creator := resolved.CreatedBy
+
+	filter := bson.M{"neighbourhood": request.Neighbourhood}
+	if request.BuildingID != "" {
+		filter["buildingId"] = request.BuildingID
+	}
+	if request.FloorNo != "" {
+		filter["floorNo"] = request.FloorNo
+	}
+
+	desks, err := database.FindDesks(ctx, appsession, filter)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book neighbourhood", constants.InternalServerErrorCode, "Failed to book neighbourhood", nil))
+		return
+	}
+	if len(desks) == 0 {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Neighbourhood not found", constants.BadRequestCode, "No desks are in this neighbourhood", nil))
+		return
+	}
+
+	start, end, ok := DeskSlot(ctx, appsession, desks[0], request.Date, request.Slot)
+	if !ok {
+		return
+	}
+
+	existing, coinciding, err := database.CheckCoincidingDeskBookings(ctx, appsession, request.Emails, []string{}, start, end)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book neighbourhood", constants.InternalServerErrorCode, "Failed to book neighbourhood", nil))
+		return
+	}
+	if coinciding {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking coincides with another booking", constants.BadRequestCode, existing.Email+" already has a desk booked at this time", nil))
+		return
+	}
+
+	deskIDs := make([]string, len(desks))
+	for i, desk := range desks {
+		deskIDs[i] = desk.DeskID
+	}
+
+	booked, err := database.BookedDeskIDs(ctx, appsession, deskIDs, start, end)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book neighbourhood", constants.InternalServerErrorCode, "Failed to book neighbourhood", nil))
+		return
+	}
+
+	template := models.DeskBooking{
+		Creator: creator,
+		GroupID: utils.GenerateUUID(),
+		Slot:    request.Slot,
+		Date:    utils.StartOfDay(start),
+		Start:   start,
+		End:     end,
+	}
+
+	bookings, err := database.AssignNeighbourhoodDesks(database.FreeDesks(desks, booked), request.Emails, template)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Not enough free desks", constants.BadRequestCode, err.Error(), nil))
+		return
+	}
+
+	if err := database.AddDeskBookings(ctx, appsession, bookings); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book neighbourhood", constants.InternalServerErrorCode, "Failed to book neighbourhood", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully booked neighbourhood!", bookings))
+}
+
+// GetAvailableDesks lists the desks that are free for a slot on a day, optionally narrowed to a building, floor or neighbourhood
+func GetAvailableDesks(ctx *gin.Context, appsession *models.AppSession) {
+	date, err := time.Parse("2006-01-02", ctx.Query("date"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "date must be provided as YYYY-MM-DD", nil))
+		return
+	}
+
+	slot := ctx.DefaultQuery("slot", constants.DeskFullDay)
+
+	filter := bson.M{}
+	for _, key := range []string{"buildingId", "floorNo", "neighbourhood"} {
+		if value := ctx.Query(key); value != "" {
+			filter[key] = value
+		}
+	}
+
+	desks, err := database.FindDesks(ctx, appsession, filter)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get available desks", constants.InternalServerErrorCode, "Failed to get available desks", nil))
+		return
+	}
+	if len(desks) == 0 {
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched available desks!", []models.Desk{}))
+		return
+	}
+
+	start, end, ok := DeskSlot(ctx, appsession, desks[0], date, slot)
+	if !ok {
+		return
+	}
+
+	deskIDs := make([]string, len(desks))
+	for i, desk := range desks {
+		deskIDs[i] = desk.DeskID
+	}
+
+	booked, err := database.BookedDeskIDs(ctx, appsession, deskIDs, start, end)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get available desks", constants.InternalServerErrorCode, "Failed to get available desks", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched available desks!", database.FreeDesks(desks, booked)))
+}
+
+// CancelDeskBooking cancels a desk booking made by or for the user
+func CancelDeskBooking(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDeskBookingID
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+		return
+	}
+
+	if err := database.CancelDeskBooking(ctx, appsession, request.BookingID, email); err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "booking not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.BadRequestCode, "Booking not found", nil))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to cancel booking", constants.InternalServerErrorCode, "Failed to cancel booking", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully cancelled desk booking!", nil))
+}
+
+// UpdateRoom updates the details and amenities of a room
+func UpdateRoom(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestUpdateRoom
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	room, err := database.UpdateRoom(ctx, appsession, request)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		switch err.Error() {
+		case "room not found":
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		case "room already exists":
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Room already exists", constants.BadRequestCode, "Another room has this room number", nil))
+		case "building not found", "floor not found":
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		case "occupancy must be positive", "minimum occupancy cannot be more than the maximum occupancy":
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		default:
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update room", constants.InternalServerErrorCode, "Failed to update room", nil))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated room!", room))
+}
+
+// UpdateRoomStatus activates, deactivates or archives a room. Inactive and archived rooms cannot be booked
+// but their existing bookings are kept.
+func UpdateRoomStatus(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestRoomStatus
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	if err := database.SetRoomStatus(ctx, appsession, request.RoomID, request.Status); err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "room not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update room status", constants.InternalServerErrorCode, "Failed to update room status", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully updated room status!", nil))
+}
+
+// GetRoomCheckInTokens lists the ids of the qr codes, nfc tags and ble beacons of a room, they are left out of every
+// other room response so only admins can see them
+func GetRoomCheckInTokens(ctx *gin.Context, appsession *models.AppSession) {
+	roomID := ctx.Query("roomId")
+	if roomID == "" {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "roomId must be provided", nil))
+		return
+	}
+
+	room, err := database.GetRoom(ctx, appsession, roomID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return
+	}
+
+	tokens := room.CheckInTokens
+	if tokens == nil {
+		tokens = []string{}
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched check in tokens!", gin.H{"roomId": room.RoomID, "checkInTokens": tokens}))
+}
+
+func GetAvailableSlots(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestAvailableSlots
+
+	// Try binding from JSON payload
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		// If JSON binding fails, try to bind from URL parameters
+		roomID := ctx.Query("roomId")
+		dateStr := ctx.Query("date")
+
+		// If URL parameters are not empty, try parsing them
+		if roomID != "" {
+			request.RoomID = roomID
+		}
+		if dateStr != "" {
+			// Parse the date string to time.Time
+			parsedDate, err := time.Parse(time.RFC3339, dateStr)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+				return
+			}
+			request.Date = parsedDate
+		}
+
+		// Validate roomID and Date
+		if request.RoomID == "" || request.Date.IsZero() {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Valid room id and date are required",
+				constants.BadRequestCode,
+				"You may have sent an empty room id or an invalid date",
+				nil))
+			return
+		}
+	}
+
+	// Get the available slots
+	availableSlots, err := database.GetAvailableSlots(ctx, appsession, request)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to get available slots",
+			constants.InternalServerErrorCode,
+			"Failed to get available slots",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched available slots!", availableSlots))
+}
+
+func ToggleOnsite(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestOnsite
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
+		return
+	}
+
+	// if email is not set, get it from the appsession
+	if request.Email == "" {
+		email, err := AttemptToGetEmail(ctx, appsession)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Invalid request payload",
+				constants.InvalidRequestPayloadCode,
+				"Email must be provided",
+				nil))
+			return
+		}
+		request.Email = email
+	}
+
+	// Toggle the onsite status
+	err := database.ToggleOnsite(ctx, appsession, request)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to toggle onsite status because: ", err)
+		if err.Error() == "site not found" || err.Error() == "building not found" || err.Error() == "building is not part of the site" {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Failed to toggle onsite status",
+				constants.InvalidRequestPayloadCode,
+				err.Error(),
+				nil))
+			return
+		}
+		if err.Error() == "invalid status" || err.Error() == "user is already onsite" || err.Error() == "user is already offsite" {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Failed to toggle onsite status",
+				constants.InvalidRequestPayloadCode,
+				"Failed due to invalid status or user is perhaps already onsite or offsite",
+				nil))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to toggle onsite status",
+			constants.InternalServerErrorCode,
+			"Failed to toggle onsite status",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled onsite status!", nil))
+}
+
+func GetAnalyticsOnHours(ctx *gin.Context, appsession *models.AppSession, calculate string, forAllUsers bool) {
+	var request models.RequestHours
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		emailStr := ctx.DefaultQuery("email", "")
+		if emailStr == "" && !forAllUsers {
+			email, err := AttemptToGetEmail(ctx, appsession)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+					http.StatusBadRequest,
+					"Invalid request payload",
+					constants.InvalidRequestPayloadCode,
+					"Email must be provided",
+					nil))
+				return
+			} else {
+				request.Email = email
+			}
+		} else {
+			request.Email = emailStr
+		}
+
+		// default time is since 1970
+		timeFromStr := ctx.DefaultQuery("timeFrom", "1970-01-01T00:00:00Z")
+		// default time is now
+		timeToStr := ctx.DefaultQuery("timeTo", time.Now().In(time.Local).Format(time.RFC3339))
+
+		timeFrom, err1 := time.Parse(time.RFC3339, timeFromStr)
+		timeTo, err2 := time.Parse(time.RFC3339, timeToStr)
+
+		if err1 != nil || err2 != nil {
+			configs.CaptureError(ctx, err1)
+			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+			return
+		}
+
+		request.TimeFrom = timeFrom
+		request.TimeTo = timeTo
+
+		limitStr := ctx.DefaultQuery("limit", "50")
+		limit, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+			return
+		}
+
+		request.Limit = limit
+
+		pageStr := ctx.DefaultQuery("page", "1")
+		page, err := strconv.ParseInt(pageStr, 10, 64)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+			return
+		}
+
+		request.Page = page
+
+		request.SiteID = ctx.DefaultQuery("siteId", "")
+		request.BuildingID = ctx.DefaultQuery("buildingId", "")
+		request.FloorNo = ctx.DefaultQuery("floorNo", "")
+	} else {
+		// ensure that the time from and time to are set else set them to default
+		if request.TimeFrom.IsZero() || request.TimeTo.IsZero() {
+			request.TimeFrom = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+			request.TimeTo = time.Now().In(time.Local)
+		}
+
+		// ensure that the limit is set else set it to default
+		if request.Limit == 0 {
+			request.Limit = 50
+		}
+
+	}
+
+	limit, page, skip := utils.ComputeLimitPageSkip(request.Limit, request.Page)
+
+	filter := models.AnalyticsFilterStruct{
+		Filter: bson.M{
+			"timeFrom":   request.TimeFrom,
+			"timeTo":     request.TimeTo,
+			"siteId":     request.SiteID,
+			"buildingId": request.BuildingID,
+			"floorNo":    request.FloorNo,
+		},
+		Limit: limit, // or whatever limit you want to apply
+		Skip:  skip,  // or whatever skip you want to apply
+	}
+
+	// if for all users set email to empty string
+	if forAllUsers {
+		request.Email = ""
+	}
+
+	// Get the user analytics
+	userHours, totalResults, err := database.GetAnalyticsOnHours(ctx, appsession, request.Email, filter, calculate)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to get user analytics because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to get user analytics",
+			constants.InternalServerErrorCode,
+			"Failed to get user analytics",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "Successfully fetched user analytics! Note that all analytics are measured in hours.", userHours,
+		gin.H{"totalResults": len(userHours), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
+}
+
+func CreateUser(ctx *gin.Context, appsession *models.AppSession) {
+	var user models.UserRequest
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
+		return
+	}
+
+	// if employee id is not set, generate a random one
+	if user.EmployeeID == "" {
+		user.EmployeeID = utils.GenerateEmployeeID()
+	}
+
+	// check email does not exist
+	if exists := database.EmailExists(ctx, appsession, user.Email); exists {
+		configs.CaptureError(ctx, errors.New("email already exists"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Email already exists",
+			constants.InvalidRequestPayloadCode,
+			"Email already exists",
+			nil))
+		return
+	}
+
+	// hash the password
+	hashedPassword, err := utils.Argon2IDHash(user.Password)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	user.Password = hashedPassword
+
+	// Create the user in the database
+	errv := database.CreateUser(ctx, appsession, user)
+	if errv != nil {
+		configs.CaptureError(ctx, errv)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to create user",
+			constants.InternalServerErrorCode,
+			"Failed to create user",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully created user!", nil))
+}
+
+func GetIPInfo(ctx *gin.Context, appsession *models.AppSession) {
+	ipAddress := ctx.ClientIP()
+	info, err := configs.GetIPInfo(ipAddress, appsession.IPInfo)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched IP information!", info))
+}
+
+func AddIP(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestIP
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
+		return
+	}
+
+	// validate the IP
+	if !utils.ValidateIP(request.IP) {
+		configs.CaptureError(ctx, errors.New("invalid IP address"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid IP address",
+			nil))
+		return
+	}
+
+	// valdidate the emails
+	if !utils.ValidateEmails(request.Emails) || len(request.Emails) == 0 {
+		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+		return
+	}
+
+	// Add the IP to the database
+	ipInfo, err := database.AddIP(ctx, appsession, request)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to add IP",
+			constants.InternalServerErrorCode,
+			"Failed to add IP",
+			nil))
+		return
+	}
+
+	// remove the ip address from the blacklist for this user
+	err = database.WhiteListIP(ctx, appsession, request.Emails, request.IP)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	// get logged users email from ctx
+	email, errv := AttemptToGetEmail(ctx, appsession)
+	if errv != nil {
+		configs.CaptureError(ctx, errv)
+		logrus.Error("Failed to get logged users email because: ", errv)
+		// we are more focused on theadding of the ip address so we will not return an error
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status!", nil))
+		return
+	}
+
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		email,
+		request.Emails,
+		"IP address added",
+		fmt.Sprintf("%s has added %s to the list of allowed IP addresses for you. Check your email for more details.", email, request.IP),
+		fmt.Sprintf("You have successfully added %s to the list of allowed IP addresses for %s.", request.IP, utils.ConvertArrayToCommaDelimitedString(request.Emails)),
+	); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to send notification because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	subject := "IP address added"
+	body := utils.FormatIPAddressAddedEmailBody(ipInfo, email)
+
+	if err := mail.SendBulkEmailWithBCC(request.Emails, subject, body, appsession); err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added IP!", nil))
+}
+
+func RemoveIP(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestIP
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
+		return
+	}
+
+	// valdidate the emails
+	if !utils.ValidateEmails(request.Emails) || len(request.Emails) == 0 {
+		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+		return
+	}
+
+	// Remove the IP from the database
+	ipInfo, err := database.RemoveIP(ctx, appsession, request)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to remove IP",
+			constants.InternalServerErrorCode,
+			"Failed to remove IP",
+			nil))
+		return
+	}
+
+	// add this ip address to blacklist for this user
+	err = database.BlackListIP(ctx, appsession, request.Emails, request.IP)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	// get logged users email from ctx
+	email, errv := AttemptToGetEmail(ctx, appsession)
+	if errv != nil {
+		configs.CaptureError(ctx, errv)
+		logrus.Error("Failed to get logged users email because: ", errv)
+		// we are more focused on the removing the ip address so we will not return an error
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status!", nil))
+		return
+	}
+
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		email,
+		request.Emails,
+		"IP address removed",
+		fmt.Sprintf("%s has removed %s from the list of allowed IP addresses for you. Check your email for more details.", email, request.IP),
+		fmt.Sprintf("You have successfully removed %s from the list of allowed IP addresses for %s.", request.IP, utils.ConvertArrayToCommaDelimitedString(request.Emails)),
+	); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to send notification because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	subject := "IP address removed"
+	body := utils.FormatIPAddressRemovedEmailBody(ipInfo, email)
+
+	if err := mail.SendBulkEmailWithBCC(request.Emails, subject, body, appsession); err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully removed IP!", nil))
+}
+
+func ToggleAllowAnonymousIP(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.AllowAnonymousIPRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
+		return
+	}
+
+	// valdidate the emails
+	if !utils.ValidateEmails(request.Emails) || len(request.Emails) == 0 {
+		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+		return
+	}
+
+	// Toggle the allow anonymous IP status
+	if err := database.ToggleAllowAnonymousIP(ctx, appsession, request); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to toggle allow anonymous IP because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	// get logged users email from ctx
+	email, errv := AttemptToGetEmail(ctx, appsession)
+	if errv != nil {
+		configs.CaptureError(ctx, errv)
+		logrus.Error("Failed to get logged users email because: ", errv)
+		// we are more focused on the removing the ip address so we will not return an error
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status!", nil))
+		return
+	}
+
+	var receiverAllow string
+	var senderAllow string
+	if request.BlockAnonymousIPAddress {
+		receiverAllow = fmt.Sprintf(("%s has allowed anonymous IP addresses for you."), email)
+		senderAllow = fmt.Sprintf("You have successfully allowed anonymous IP addresses for %s.", utils.ConvertArrayToCommaDelimitedString(request.Emails))
+	} else {
+		receiverAllow = fmt.Sprintf(("%s has disallowed anonymous IP addresses for you."), email)
+		senderAllow = fmt.Sprintf("You have successfully disallowed anonymous IP addresses for %s.", utils.ConvertArrayToCommaDelimitedString(request.Emails))
+	}
+
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		email,
+		request.Emails,
+		"Allow anonymous IP address toggled",
+		receiverAllow,
+		senderAllow,
+	); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to send notification because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled allow anonymous IP status!", nil))
+}
+
+func GetAnalyticsOnBookings(ctx *gin.Context, appsession *models.AppSession, calculate string) {
+	var request models.RequestBooking
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		request.Creator = ctx.DefaultQuery("creator", "")
+		attendees := ctx.DefaultQuery("attendeeEmails", "")
+		if attendees == "" {
+			request.Attendees = []string{}
+		} else {
+			request.Attendees = strings.Split(attendees, ",")
+		}
+
+		// default time is since 1970
+		timeFromStr := ctx.DefaultQuery("timeFrom", "1970-01-01T00:00:00Z")
+		// default time is now
+		timeToStr := ctx.DefaultQuery("timeTo", time.Now().In(time.Local).Format(time.RFC3339))
+
+		timeFrom, err1 := time.Parse(time.RFC3339, timeFromStr)
+		timeTo, err2 := time.Parse(time.RFC3339, timeToStr)
+
+		if err1 != nil || err2 != nil {
+			configs.CaptureError(ctx, err1)
+			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+			return
+		}
+
+		request.TimeFrom = timeFrom
+		request.TimeTo = timeTo
+
+		limitStr := ctx.DefaultQuery("limit", "50")
+		limit, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+			return
+		}
+
+		request.Limit = limit
+
+		pageStr := ctx.DefaultQuery("page", "1")
+		page, err := strconv.ParseInt(pageStr, 10, 64)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+			return
+		}
+
+		request.Page = page
+
+		request.SiteID = ctx.DefaultQuery("siteId", "")
+		request.BuildingID = ctx.DefaultQuery("buildingId", "")
+		request.FloorNo = ctx.DefaultQuery("floorNo", "")
+	} else {
+		// ensure that the time from and time to are set else set them to default
+		if request.TimeFrom.IsZero() || request.TimeTo.IsZero() {
+			request.TimeFrom = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+			request.TimeTo = time.Now().In(time.Local)
+		}
+
+		// ensure that the limit is set else set it to default
+		if request.Limit == 0 {
+			request.Limit = 50
+		}
+
+	}
+
+	limit, page, skip := utils.ComputeLimitPageSkip(request.Limit, request.Page)
+
+	filter := models.AnalyticsFilterStruct{
+		Filter: bson.M{
+			"timeFrom":   request.TimeFrom,
+			"timeTo":     request.TimeTo,
+			"siteId":     request.SiteID,
+			"buildingId": request.BuildingID,
+			"floorNo":    request.FloorNo,
+		},
+		Limit: limit, // or whatever limit you want to apply
+		Skip:  skip,  // or whatever skip you want to apply
+	}
+
+	// Get the analytics
+	result, totalResults, err := database.GetAnalyticsOnBookings(ctx, appsession, request.Creator, request.Attendees, filter, calculate)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to get analytics because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
+			http.StatusInternalServerError,
+			"Failed to get analytics",
+			constants.InternalServerErrorCode,
+			"Failed to get analytics",
+			nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "Successfully fetched analytics!", result,
+		gin.H{"totalResults": len(result), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
 }
 
-func DownloadRoomImage(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.ImageRequest
+func ToggleAdminStatus(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RoleRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		request.ID = ctx.Param("id")
-		request.Quality = ctx.Query("quality")
-		if request.ID == "" {
-			configs.CaptureError(ctx, errors.New("id must be provided"))
+		email := ctx.Query("email")
+		role := ctx.Query("role")
+		if email == "" || role == "" {
+			configs.CaptureError(ctx, err)
 			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
 				http.StatusBadRequest,
 				"Invalid request payload",
 				constants.InvalidRequestPayloadCode,
-				"ID must be provided",
+				"Email and role must be provided",
 				nil))
 			return
+		} else {
+			request.Email = email
+			request.Role = role
 		}
 	}
 
-	if request.Quality != "" && request.Quality != constants.ThumbnailRes && request.Quality != constants.LowRes && request.Quality != constants.MidRes && request.Quality != constants.HighRes {
-		request.Quality = constants.MidRes
-	} else if request.Quality == "" {
-		request.Quality = constants.MidRes
+	// validate email
+	if !utils.ValidateEmail(request.Email) {
+		configs.CaptureError(ctx, errors.New("invalid email address"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid email address", nil))
+		return
 	}
 
-	if request.ID == "null" {
-		request.ID = "emproom.jpg"
-		request.Quality = ""
+	// Toggle the admin status
+	err := database.ToggleAdminStatus(ctx, appsession, request)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to toggle admin status because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
 	}
 
-	// redirect to the image on azure
-	blobURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s%s", configs.GetAzureAccountName(), configs.GetAzureRoomsContainerName(), request.ID, request.Quality)
-
-	http.Redirect(ctx.Writer, ctx.Request, blobURL, http.StatusSeeOther)
-}
-
-func UploadRoomImage(ctx *gin.Context, appsession *models.AppSession) {
-	file, err := ctx.FormFile("image")
+	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
 		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
 			http.StatusBadRequest,
 			"Invalid request payload",
 			constants.InvalidRequestPayloadCode,
-			"Invalid file format",
+			"Email must be provided",
 			nil))
 		return
 	}
 
-	uuid := utils.GenerateUUID()
-
-	files, err := ResizeImagesAndReturnAsFiles(ctx, appsession, file, uuid)
-
-	if err != nil {
-		return
-	}
-
-	// upload image associated with this email
-	if err := MultiUploadImages(ctx, appsession, configs.GetAzureRoomsContainerName(), files); err != nil {
-		return
-	}
-
-	// get room id from json body
-	roomid := ctx.Query("roomid")
-
-	if roomid == "" {
-		roomid = ctx.PostForm("roomid")
-		if roomid == "" {
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid JSON payload", nil))
-			return
-		}
-	}
-
-	// Update the room details with the image id
-	err = database.AddImageToRoom(ctx, appsession, roomid, uuid)
-
-	if err != nil {
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		email,
+		[]string{request.Email},
+		"Role status changed",
+		fmt.Sprintf("%s has changed your role status to %s", email, request.Role),
+		fmt.Sprintf("You have changed %s's role status to %s", request.Email, request.Role),
+	); err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.WithError(err).Error("Failed to update image id")
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update room image id", constants.InternalServerErrorCode, "Failed to update room image", nil))
+		logrus.Error("Failed to send notification because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully uploaded image!", gin.H{"id": uuid}))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status and sent notifications!", nil))
 }
 
-func DeleteRoomImage(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.ImageRequest
+func SendDownloadReportNotification(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestEmail
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		request.ID = ctx.Query("id")
-		request.RoomID = ctx.Query("roomid")
-		if request.ID == "" || request.RoomID == "" {
-			configs.CaptureError(ctx, errors.New("id and roomid must be provided"))
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Invalid request payload",
-				constants.InvalidRequestPayloadCode,
-				"ID and roomid must be provided",
-				nil))
-			return
-		}
-	}
-
-	// del user image if it exists
-	if err := MultiDeleteImages(ctx, appsession, configs.GetAzureRoomsContainerName(), []string{request.ID + constants.ThumbnailRes, request.ID + constants.LowRes, request.ID + constants.MidRes, request.ID + constants.HighRes}); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Invalid request payload",
+			constants.InvalidRequestPayloadCode,
+			"Invalid JSON payload",
+			nil))
 		return
 	}
 
-	// Update the room details with the image id
-	if err := database.DeleteImageFromRoom(ctx, appsession, request.RoomID, request.ID); err != nil {
-		configs.CaptureError(ctx, err)
-		logrus.WithError(err).Error("Failed to delete image id")
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to delete room image id", constants.InternalServerErrorCode, "Failed to delete room image", nil))
+	// valdidate the email
+	if !utils.ValidateEmail(request.Email) {
+		configs.CaptureError(ctx, errors.New("invalid email address"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid email address", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully deleted image!", nil))
-}
-
-func AddRoom(ctx *gin.Context, appsession *models.AppSession) {
-	var room models.RequestRoom
-	if err := ctx.ShouldBindJSON(&room); err != nil {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
 		configs.CaptureError(ctx, err)
 		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
 			http.StatusBadRequest,
 			"Invalid request payload",
 			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
+			"Email must be provided",
 			nil))
 		return
 	}
 
-	// Save the room to the database
-	roomID, err := database.AddRoom(ctx, appsession, room)
-	if err != nil {
-		var msg string
-		if err.Error() == "room already exists" {
-			msg = "Room already exists"
-		} else {
-			msg = "Failed to add room"
-		}
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		email,
+		[]string{request.Email},
+		"Report Downloaded",
+		email+" has downloaded your worker analytics report",
+		fmt.Sprintf("You have downloaded %s's worker analytics report", request.Email),
+	); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to add room",
-			constants.InternalServerErrorCode,
-			"Failed to add room",
-			gin.H{"message": msg}))
+		logrus.Error("Failed to send notification because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added room!", gin.H{"roomid": roomID}))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully sent notification!", nil))
 }
 
-func GetAvailableSlots(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestAvailableSlots
-
-	// Try binding from JSON payload
+func GetNotificationCount(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestEmail
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		configs.CaptureError(ctx, err)
-		// If JSON binding fails, try to bind from URL parameters
-		roomID := ctx.Query("roomId")
-		dateStr := ctx.Query("date")
-
-		// If URL parameters are not empty, try parsing them
-		if roomID != "" {
-			request.RoomID = roomID
-		}
-		if dateStr != "" {
-			// Parse the date string to time.Time
-			parsedDate, err := time.Parse(time.RFC3339, dateStr)
+		emailStr := ctx.Query("email")
+		if emailStr == "" {
+			email, err := AttemptToGetEmail(ctx, appsession)
 			if err != nil {
 				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+					http.StatusBadRequest,
+					"Invalid request payload",
+					constants.InvalidRequestPayloadCode,
+					"Email must be provided",
+					nil))
 				return
+			} else {
+				request.Email = email
 			}
-			request.Date = parsedDate
+		} else {
+			request.Email = emailStr
 		}
+	}
 
-		// Validate roomID and Date
-		if request.RoomID == "" || request.Date.IsZero() {
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Valid room id and date are required",
-				constants.BadRequestCode,
-				"You may have sent an empty room id or an invalid date",
-				nil))
-			return
+	// get count
+	unReadCount, totalCount, err := database.CountNotifications(ctx, appsession, request.Email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to get notification count because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched notification count!", gin.H{"unread": unReadCount, "total": totalCount}))
+}
+
+func GetUsersLocations(ctx *gin.Context, appsession *models.AppSession, ipPrivelege string) {
+	var email string
+	var order string
+	var limit int64
+	var page int64
+	var err error
+	order = ctx.Query("sort")
+	if order == "" || (order != "asc" && order != "desc") {
+		order = "asc"
+	}
+
+	limitStr := ctx.Query("limit")
+	if limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64) // Base 10, 64-bit size
+		if err != nil {
+			limit = 50 // Default limit
+		}
+	}
+
+	pageStr := ctx.Query("page")
+	if pageStr != "" {
+		page, err = strconv.ParseInt(pageStr, 10, 64) // Base 10, 64-bit size
+		if err != nil {
+			page = 1 // Default page
 		}
 	}
 
-	// Get the available slots
-	availableSlots, err := database.GetAvailableSlots(ctx, appsession, request)
+	// For email filter
+	email = ctx.Query("email")
+	// validate email
+	if email != "" && !utils.ValidateEmail(email) {
+		configs.CaptureError(ctx, errors.New("invalid email address"))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid email address", nil))
+		return
+	}
+
+	if limit <= 0 || limit > 50 {
+		limit = 50 // Default limit
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	skip := (page - 1) * limit
+
+	locations, totalResults, err := database.GetUsersLocations(ctx, appsession, limit, skip, order, email, ipPrivelege)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to get available slots",
-			constants.InternalServerErrorCode,
-			"Failed to get available slots",
-			nil))
+		logrus.Error("Failed to get users locations because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched available slots!", availableSlots))
+	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "Successfully fetched users locations!", locations, gin.H{
+		"totalResults": len(locations), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
 }
 
-func ToggleOnsite(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestOnsite
-	if err := ctx.ShouldBindJSON(&request); err != nil {
+// GetCalendarFeedURL returns the user's iCalendar subscription url, the url stays the same until it is reset
+func GetCalendarFeedURL(ctx *gin.Context, appsession *models.AppSession) {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
-			nil))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	// if email is not set, get it from the appsession
-	if request.Email == "" {
-		email, err := AttemptToGetEmail(ctx, appsession)
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Invalid request payload",
-				constants.InvalidRequestPayloadCode,
-				"Email must be provided",
-				nil))
-			return
-		}
-		request.Email = email
+	token, err := database.GetCalendarFeedToken(ctx, appsession, email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
 	}
 
-	// Toggle the onsite status
-	err := database.ToggleOnsite(ctx, appsession, request)
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched calendar feed url!", gin.H{"url": CalendarFeedURL(ctx, token)}))
+}
+
+// ResetCalendarFeedURL replaces the user's iCalendar subscription url, calendars subscribed to the old url stop updating
+func ResetCalendarFeedURL(ctx *gin.Context, appsession *models.AppSession) {
+	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to toggle onsite status because: ", err)
-		if err.Error() == "invalid status" || err.Error() == "user is already onsite" || err.Error() == "user is already offsite" {
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Failed to toggle onsite status",
-				constants.InvalidRequestPayloadCode,
-				"Failed due to invalid status or user is perhaps already onsite or offsite",
-				nil))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to toggle onsite status",
-			constants.InternalServerErrorCode,
-			"Failed to toggle onsite status",
-			nil))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled onsite status!", nil))
+	token, err := database.ResetCalendarFeedToken(ctx, appsession, email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully reset calendar feed url!", gin.H{"url": CalendarFeedURL(ctx, token)}))
 }
 
-func GetAnalyticsOnHours(ctx *gin.Context, appsession *models.AppSession, calculate string, forAllUsers bool) {
-	var request models.RequestHours
-	if err := ctx.ShouldBindJSON(&request); err != nil {
-		emailStr := ctx.DefaultQuery("email", "")
-		if emailStr == "" && !forAllUsers {
-			email, err := AttemptToGetEmail(ctx, appsession)
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-					http.StatusBadRequest,
-					"Invalid request payload",
-					constants.InvalidRequestPayloadCode,
-					"Email must be provided",
-					nil))
-				return
-			} else {
-				request.Email = email
-			}
-		} else {
-			request.Email = emailStr
-		}
+// ResetCalDAVPassword creates a new app password for calendar clients that use CalDAV, replacing the old one.
+// The password is only shown once since just its hash is stored.
+func ResetCalDAVPassword(ctx *gin.Context, appsession *models.AppSession) {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+		return
+	}
 
-		// default time is since 1970
-		timeFromStr := ctx.DefaultQuery("timeFrom", "1970-01-01T00:00:00Z")
-		// default time is now
-		timeToStr := ctx.DefaultQuery("timeTo", time.Now().In(time.Local).Format(time.RFC3339))
+	password, err := utils.GenerateFeedToken()
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
 
-		timeFrom, err1 := time.Parse(time.RFC3339, timeFromStr)
-		timeTo, err2 := time.Parse(time.RFC3339, timeToStr)
+	if err := database.ResetCalDAVPassword(ctx, appsession, email, utils.HashCalDAVPassword(password)); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
 
-		if err1 != nil || err2 != nil {
-			configs.CaptureError(ctx, err1)
-			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
-			return
-		}
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully reset caldav password!", gin.H{"username": email, "password": password}))
+}
 
-		request.TimeFrom = timeFrom
-		request.TimeTo = timeTo
+// GetCalendarFeed serves the upcoming bookings of the feed token's owner as an iCalendar file,
+// calendar clients cannot log in so the token in the url is what authenticates the request
+func GetCalendarFeed(ctx *gin.Context, appsession *models.AppSession) {
+	token := strings.TrimSuffix(ctx.Param("token"), ".ics")
 
-		limitStr := ctx.DefaultQuery("limit", "50")
-		limit, err := strconv.ParseInt(limitStr, 10, 64)
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+	email, err := database.GetCalendarFeedEmail(ctx, appsession, token)
+	if err != nil {
+		if err.Error() == "calendar feed not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Calendar feed not found", constants.BadRequestCode, "Calendar feed not found", nil))
 			return
 		}
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
 
-		request.Limit = limit
+	now := time.Now()
+	bookings, err := database.GetUpcomingBookings(ctx, appsession, email, now.AddDate(0, 0, constants.CalendarFeedDays))
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
 
-		pageStr := ctx.DefaultQuery("page", "1")
-		page, err := strconv.ParseInt(pageStr, 10, 64)
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
-			return
-		}
+	ctx.Header("Content-Disposition", "inline; filename=occupi.ics")
+	ctx.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(utils.FormatBookingFeed("Occupi bookings", bookings, now)))
+}
 
-		request.Page = page
-	} else {
-		// ensure that the time from and time to are set else set them to default
-		if request.TimeFrom.IsZero() || request.TimeTo.IsZero() {
-			request.TimeFrom = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
-			request.TimeTo = time.Now().In(time.Local)
-		}
+// ApproveBooking confirms a pending booking, or every pending occurrence of its series, and sends the booking emails
+func ApproveBooking(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestApproveBooking
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
 
-		// ensure that the limit is set else set it to default
-		if request.Limit == 0 {
-			request.Limit = 50
+	booking, approver, ok := GetBookingForApproval(ctx, appsession, request.BookingID)
+	if !ok {
+		return
+	}
+
+	bookings, err := database.ConfirmPendingBookings(ctx, appsession, booking)
+	if err != nil {
+		if err.Error() == "booking is not pending" {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking is not awaiting approval", constants.BadRequestCode, err.Error(), nil))
+			return
 		}
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
 
+	if !NotifyBookingConfirmed(ctx, appsession, bookings) {
+		return
 	}
 
-	limit, page, skip := utils.ComputeLimitPageSkip(request.Limit, request.Page)
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		approver,
+		[]string{booking.Creator},
+		"Booking Approved",
+		fmt.Sprintf("%s has approved your booking of %s.", approver, booking.RoomName),
+		fmt.Sprintf("You have approved the booking of %s by %s.", booking.RoomName, booking.Creator),
+	); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to send notification because: ", err)
+		return
+	}
 
-	filter := models.AnalyticsFilterStruct{
-		Filter: bson.M{
-			"timeFrom": request.TimeFrom,
-			"timeTo":   request.TimeTo,
-		},
-		Limit: limit, // or whatever limit you want to apply
-		Skip:  skip,  // or whatever skip you want to apply
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully approved booking!", bookings))
+}
+
+// RejectBooking removes a pending booking, or every pending occurrence of its series, and tells the creator why
+func RejectBooking(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestRejectBooking
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
 	}
 
-	// if for all users set email to empty string
-	if forAllUsers {
-		request.Email = ""
+	booking, approver, ok := GetBookingForApproval(ctx, appsession, request.BookingID)
+	if !ok {
+		return
 	}
 
-	// Get the user analytics
-	userHours, totalResults, err := database.GetAnalyticsOnHours(ctx, appsession, request.Email, filter, calculate)
+	rejected, err := database.RejectPendingBookings(ctx, appsession, booking)
 	if err != nil {
+		if err.Error() == "booking is not pending" {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking is not awaiting approval", constants.BadRequestCode, err.Error(), nil))
+			return
+		}
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to get user analytics because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to get user analytics",
-			constants.InternalServerErrorCode,
-			"Failed to get user analytics",
-			nil))
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "Successfully fetched user analytics! Note that all analytics are measured in hours.", userHours,
-		gin.H{"totalResults": len(userHours), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
-}
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		approver,
+		[]string{booking.Creator},
+		"Booking Rejected",
+		fmt.Sprintf("%s has rejected your booking of %s: %s", approver, booking.RoomName, request.Reason),
+		fmt.Sprintf("You have rejected the booking of %s by %s.", booking.RoomName, booking.Creator),
+	); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to send notification because: ", err)
+		return
+	}
 
-func CreateUser(ctx *gin.Context, appsession *models.AppSession) {
-	var user models.UserRequest
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+	body := utils.FormatBookingRejectedEmailBody(booking.OccupiID, booking.RoomName, booking.Start, request.Reason, approver)
+	if err := mail.SendMail(appsession, booking.Creator, "Booking Rejected - Occupi", body); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
-			nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "An error occurred", constants.InternalServerErrorCode, "Failed to send booking email", nil))
 		return
 	}
 
-	// if employee id is not set, generate a random one
-	if user.EmployeeID == "" {
-		user.EmployeeID = utils.GenerateEmployeeID()
+	// the freed up slots go to the next person on the waitlist
+	for _, occurrence := range rejected {
+		if err := database.OfferSlotToWaitlist(ctx, appsession, occurrence); err != nil {
+			configs.CaptureError(ctx, err)
+			logrus.Error("Failed to offer slot to waitlist because: ", err)
+		}
 	}
 
-	// check email does not exist
-	if exists := database.EmailExists(ctx, appsession, user.Email); exists {
-		configs.CaptureError(ctx, errors.New("email already exists"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Email already exists",
-			constants.InvalidRequestPayloadCode,
-			"Email already exists",
-			nil))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully rejected booking!", nil))
+}
+
+// ViewPendingBookings lists the bookings waiting for the user's approval
+func ViewPendingBookings(ctx *gin.Context, appsession *models.AppSession) {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+		return
+	}
+
+	isAdmin, err := database.CheckIfUserIsAdmin(ctx, appsession, email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	// hash the password
-	hashedPassword, err := utils.Argon2IDHash(user.Password)
+	bookings, err := database.GetPendingBookings(ctx, appsession, email, isAdmin)
 	if err != nil {
 		configs.CaptureError(ctx, err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	user.Password = hashedPassword
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched pending bookings!", bookings))
+}
 
-	// Create the user in the database
-	errv := database.CreateUser(ctx, appsession, user)
-	if errv != nil {
-		configs.CaptureError(ctx, errv)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to create user",
-			constants.InternalServerErrorCode,
-			"Failed to create user",
-			nil))
+// RespondToBooking accepts, declines or tentatively accepts a booking the user was invited to
+func RespondToBooking(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestRSVP
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully created user!", nil))
-}
-
-func GetIPInfo(ctx *gin.Context, appsession *models.AppSession) {
-	ipAddress := ctx.ClientIP()
-	info, err := configs.GetIPInfo(ipAddress, appsession.IPInfo)
+	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched IP information!", info))
+	booking, err := database.GetBooking(ctx, appsession, request.BookingID)
+	if err != nil {
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+		return
+	}
+
+	if !RecordRSVP(ctx, appsession, booking, email, request.Response, request.Series) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully responded to booking!", gin.H{"bookingId": booking.OccupiID, "response": request.Response}))
 }
 
-func AddIP(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestIP
-	if err := ctx.ShouldBindJSON(&request); err != nil {
+// ConfirmBookingLink shows the response from a signed link in a booking invitation with a button to save it,
+// nothing is saved here so mail scanners that open the link cannot respond for the attendee
+func ConfirmBookingLink(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestRSVPLink
+	if err := ctx.ShouldBindQuery(&request); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
-			nil))
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	// validate the IP
-	if !utils.ValidateIP(request.IP) {
-		configs.CaptureError(ctx, errors.New("invalid IP address"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid IP address",
-			nil))
+	booking, ok := VerifyBookingLink(ctx, appsession, request)
+	if !ok {
 		return
 	}
 
-	// valdidate the emails
-	if !utils.ValidateEmails(request.Emails) || len(request.Emails) == 0 {
-		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(utils.FormatRSVPConfirmPage(booking.RoomName, booking.Start, request)))
+}
+
+// RespondToBookingLink records the response posted from the confirmation page of a booking invitation link,
+// the signature stands in for signing in so attendees can respond straight from their email
+func RespondToBookingLink(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestRSVPLink
+	if err := ctx.ShouldBind(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	// Add the IP to the database
-	ipInfo, err := database.AddIP(ctx, appsession, request)
-	if err != nil {
-		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to add IP",
-			constants.InternalServerErrorCode,
-			"Failed to add IP",
-			nil))
+	booking, ok := VerifyBookingLink(ctx, appsession, request)
+	if !ok {
 		return
 	}
 
-	// remove the ip address from the blacklist for this user
-	err = database.WhiteListIP(ctx, appsession, request.Emails, request.IP)
-	if err != nil {
+	if !RecordRSVP(ctx, appsession, booking, request.Email, request.Response, request.Series) {
+		return
+	}
+
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(utils.FormatRSVPResponsePage(booking.RoomName, booking.Start, request.Response)))
+}
+
+// GetRoomQRCode renders the qr code shown on a room's door, the code rotates so it is never cached
+func GetRoomQRCode(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestRoomQRCode
+	if err := ctx.ShouldBindQuery(&request); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	// get logged users email from ctx
-	email, errv := AttemptToGetEmail(ctx, appsession)
-	if errv != nil {
-		configs.CaptureError(ctx, errv)
-		logrus.Error("Failed to get logged users email because: ", errv)
-		// we are more focused on theadding of the ip address so we will not return an error
-		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status!", nil))
+	room, err := database.GetRoom(ctx, appsession, request.RoomID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
 		return
 	}
 
-	if err := CreateAndSendNotificationLogic(
-		ctx,
-		appsession,
-		email,
-		request.Emails,
-		"IP address added",
-		fmt.Sprintf("%s has added %s to the list of allowed IP addresses for you. Check your email for more details.", email, request.IP),
-		fmt.Sprintf("You have successfully added %s to the list of allowed IP addresses for %s.", request.IP, utils.ConvertArrayToCommaDelimitedString(request.Emails)),
-	); err != nil {
-		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to send notification because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+	now := time.Now()
+	payload := utils.FormatRoomQRPayload(room.RoomID, now)
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("X-QR-Expires-At", utils.RoomQRExpiry(now).UTC().Format(time.RFC3339))
+
+	if request.Format == "svg" {
+		svg, err := utils.RenderQRCodeSVG(payload)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return
+		}
+		ctx.Data(http.StatusOK, "image/svg+xml", []byte(svg))
 		return
 	}
 
-	subject := "IP address added"
-	body := utils.FormatIPAddressAddedEmailBody(ipInfo, email)
+	size := request.Size
+	if size == 0 {
+		size = 256
+	}
 
-	if err := mail.SendBulkEmailWithBCC(request.Emails, subject, body, appsession); err != nil {
+	png, err := utils.RenderQRCodePNG(payload, size)
+	if err != nil {
+		configs.CaptureError(ctx, err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
-
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully added IP!", nil))
+	ctx.Data(http.StatusOK, "image/png", png)
 }
 
-func RemoveIP(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestIP
+// ScanRoomQRCode checks the user in to their booking of the room whose qr code they scanned, when they
+// have no booking of the room and it is free the time it is free until is returned so it can be booked on the spot
+func ScanRoomQRCode(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestScanRoomQR
 	if err := ctx.ShouldBindJSON(&request); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
-			nil))
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	// valdidate the emails
-	if !utils.ValidateEmails(request.Emails) || len(request.Emails) == 0 {
-		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+	now := time.Now()
+	roomID, err := utils.ParseRoomQRPayload(request.Payload, now)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid qr code", constants.BadRequestCode, err.Error(), nil))
 		return
 	}
 
-	// Remove the IP from the database
-	ipInfo, err := database.RemoveIP(ctx, appsession, request)
+	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to remove IP",
-			constants.InternalServerErrorCode,
-			"Failed to remove IP",
-			nil))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	// add this ip address to blacklist for this user
-	err = database.BlackListIP(ctx, appsession, request.Emails, request.IP)
+	booking, found, err := database.GetCurrentRoomBooking(ctx, appsession, roomID, email, now)
 	if err != nil {
 		configs.CaptureError(ctx, err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	// get logged users email from ctx
-	email, errv := AttemptToGetEmail(ctx, appsession)
-	if errv != nil {
-		configs.CaptureError(ctx, errv)
-		logrus.Error("Failed to get logged users email because: ", errv)
-		// we are more focused on the removing the ip address so we will not return an error
-		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status!", nil))
+	if found && booking.Status == constants.BookingPending {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking is awaiting approval", constants.BadRequestCode, "Bookings can only be checked into once they are approved", nil))
 		return
 	}
 
-	if err := CreateAndSendNotificationLogic(
-		ctx,
-		appsession,
-		email,
-		request.Emails,
-		"IP address removed",
-		fmt.Sprintf("%s has removed %s from the list of allowed IP addresses for you. Check your email for more details.", email, request.IP),
-		fmt.Sprintf("You have successfully removed %s from the list of allowed IP addresses for %s.", request.IP, utils.ConvertArrayToCommaDelimitedString(request.Emails)),
-	); err != nil {
-		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to send notification because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+	if found {
+		checkIn := models.CheckIn{
+			BookingID: booking.OccupiID,
+			Creator:   email,
+			Type:      constants.RoomCheckIn,
+			Method:    constants.CheckInQR,
+		}
+
+		if _, err := database.ConfirmCheckIn(ctx, appsession, checkIn); err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to check in", constants.InternalServerErrorCode, "Failed to check in", nil))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully checked in!", gin.H{"checkedIn": true, "bookingId": booking.OccupiID, "roomId": roomID}))
 		return
 	}
 
-	subject := "IP address removed"
-	body := utils.FormatIPAddressRemovedEmailBody(ipInfo, email)
+	room, err := database.GetRoom(ctx, appsession, roomID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return
+	}
 
-	if err := mail.SendBulkEmailWithBCC(request.Emails, subject, body, appsession); err != nil {
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+	slot, ok := GetAdHocSlot(ctx, appsession, room, now)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully removed IP!", nil))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Room is free to book now!", gin.H{
+		"checkedIn": false,
+		"roomId":    room.RoomID,
+		"roomName":  room.RoomName,
+		"freeFrom":  slot.Start,
+		"freeUntil": slot.End,
+	}))
 }
 
-func ToggleAllowAnonymousIP(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.AllowAnonymousIPRequest
+// GrantDelegation lets another user make bookings for the signed in user
+func GrantDelegation(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDelegation
 	if err := ctx.ShouldBindJSON(&request); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
-			nil))
-		return
-	}
-
-	// valdidate the emails
-	if !utils.ValidateEmails(request.Emails) || len(request.Emails) == 0 {
-		configs.CaptureError(ctx, errors.New("one or more of the emails are of invalid format"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "One or more of email addresses are of Invalid format", nil))
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
-	// Toggle the allow anonymous IP status
-	if err := database.ToggleAllowAnonymousIP(ctx, appsession, request); err != nil {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to toggle allow anonymous IP because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	// get logged users email from ctx
-	email, errv := AttemptToGetEmail(ctx, appsession)
-	if errv != nil {
-		configs.CaptureError(ctx, errv)
-		logrus.Error("Failed to get logged users email because: ", errv)
-		// we are more focused on the removing the ip address so we will not return an error
-		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status!", nil))
+	if request.Delegate == email {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "You cannot delegate booking to yourself", nil))
 		return
 	}
 
-	var receiverAllow string
-	var senderAllow string
-	if request.BlockAnonymousIPAddress {
-		receiverAllow = fmt.Sprintf(("%s has allowed anonymous IP addresses for you."), email)
-		senderAllow = fmt.Sprintf("You have successfully allowed anonymous IP addresses for %s.", utils.ConvertArrayToCommaDelimitedString(request.Emails))
-	} else {
-		receiverAllow = fmt.Sprintf(("%s has disallowed anonymous IP addresses for you."), email)
-		senderAllow = fmt.Sprintf("You have successfully disallowed anonymous IP addresses for %s.", utils.ConvertArrayToCommaDelimitedString(request.Emails))
+	if !database.EmailExists(ctx, appsession, request.Delegate) {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "User not found", constants.BadRequestCode, "User not found", nil))
+		return
 	}
 
-	if err := CreateAndSendNotificationLogic(
-		ctx,
-		appsession,
-		email,
-		request.Emails,
-		"Allow anonymous IP address toggled",
-		receiverAllow,
-		senderAllow,
-	); err != nil {
+	if err := database.GrantDelegation(ctx, appsession, email, request.Delegate); err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to send notification because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to grant delegation", constants.InternalServerErrorCode, "Failed to grant delegation", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled allow anonymous IP status!", nil))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully granted delegation!", gin.H{"principal": email, "delegate": request.Delegate}))
 }
 
-func GetAnalyticsOnBookings(ctx *gin.Context, appsession *models.AppSession, calculate string) {
-	var request models.RequestBooking
+// RevokeDelegation stops another user making bookings for the signed in user
+func RevokeDelegation(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDelegation
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		request.Creator = ctx.DefaultQuery("creator", "")
-		attendees := ctx.DefaultQuery("attendeeEmails", "")
-		if attendees == "" {
-			request.Attendees = []string{}
-		} else {
-			request.Attendees = strings.Split(attendees, ",")
-		}
-
-		// default time is since 1970
-		timeFromStr := ctx.DefaultQuery("timeFrom", "1970-01-01T00:00:00Z")
-		// default time is now
-		timeToStr := ctx.DefaultQuery("timeTo", time.Now().In(time.Local).Format(time.RFC3339))
-
-		timeFrom, err1 := time.Parse(time.RFC3339, timeFromStr)
-		timeTo, err2 := time.Parse(time.RFC3339, timeToStr)
-
-		if err1 != nil || err2 != nil {
-			configs.CaptureError(ctx, err1)
-			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
-			return
-		}
-
-		request.TimeFrom = timeFrom
-		request.TimeTo = timeTo
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
 
-		limitStr := ctx.DefaultQuery("limit", "50")
-		limit, err := strconv.ParseInt(limitStr, 10, 64)
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+		return
+	}
+
+	if err := database.RevokeDelegation(ctx, appsession, email, request.Delegate); err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "delegation not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Delegation not found", constants.BadRequestCode, err.Error(), nil))
 			return
 		}
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to revoke delegation", constants.InternalServerErrorCode, "Failed to revoke delegation", nil))
+		return
+	}
 
-		request.Limit = limit
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully revoked delegation!", nil))
+}
 
-		pageStr := ctx.DefaultQuery("page", "1")
-		page, err := strconv.ParseInt(pageStr, 10, 64)
-		if err != nil {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.InternalServerError())
-			return
-		}
+// ViewDelegations lists who can book for the signed in user and who they can book for
+func ViewDelegations(ctx *gin.Context, appsession *models.AppSession) {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+		return
+	}
 
-		request.Page = page
-	} else {
-		// ensure that the time from and time to are set else set them to default
-		if request.TimeFrom.IsZero() || request.TimeTo.IsZero() {
-			request.TimeFrom = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
-			request.TimeTo = time.Now().In(time.Local)
-		}
+	delegations, err := database.GetDelegations(ctx, appsession, email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get delegations", constants.InternalServerErrorCode, "Failed to get delegations", nil))
+		return
+	}
 
-		// ensure that the limit is set else set it to default
-		if request.Limit == 0 {
-			request.Limit = 50
+	delegates, principals := []string{}, []string{}
+	for _, delegation := range delegations {
+		if delegation.Principal == email {
+			delegates = append(delegates, delegation.Delegate)
+		} else {
+			principals = append(principals, delegation.Principal)
 		}
+	}
 
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched delegations!", gin.H{"delegates": delegates, "principals": principals}))
+}
+
+// GetFailedNotifications returns the notifications that were dead-lettered after running out of retries
+func GetFailedNotifications(ctx *gin.Context, appsession *models.AppSession) {
+	failed, err := database.GetFailedNotifications(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get failed notifications", constants.InternalServerErrorCode, "Failed to get failed notifications", nil))
+		return
 	}
 
-	limit, page, skip := utils.ComputeLimitPageSkip(request.Limit, request.Page)
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched failed notifications!", failed))
+}
 
-	filter := models.AnalyticsFilterStruct{
-		Filter: bson.M{
-			"timeFrom": request.TimeFrom,
-			"timeTo":   request.TimeTo,
-		},
-		Limit: limit, // or whatever limit you want to apply
-		Skip:  skip,  // or whatever skip you want to apply
+// GetPushDeliveryStats returns how the push notifications sent in the last days were delivered according to their
+// expo receipts, the last 7 days by default
+func GetPushDeliveryStats(ctx *gin.Context, appsession *models.AppSession) {
+	days := 7
+	if daysStr := ctx.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid days format", constants.InvalidRequestPayloadCode, "days must be a positive whole number", nil))
+			return
+		}
+		days = parsed
 	}
 
-	// Get the analytics
-	result, totalResults, err := database.GetAnalyticsOnBookings(ctx, appsession, request.Creator, request.Attendees, filter, calculate)
+	stats, err := database.GetPushDeliveryStats(ctx, appsession, time.Now().AddDate(0, 0, -days))
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to get analytics because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(
-			http.StatusInternalServerError,
-			"Failed to get analytics",
-			constants.InternalServerErrorCode,
-			"Failed to get analytics",
-			nil))
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get push delivery stats", constants.InternalServerErrorCode, "Failed to get push delivery stats", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "Successfully fetched analytics!", result,
-		gin.H{"totalResults": len(result), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched push delivery stats!", stats))
 }
 
-func ToggleAdminStatus(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RoleRequest
+// ReplayFailedNotification sends a dead-lettered notification again to the tokens it failed for
+func ReplayFailedNotification(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestReplayFailedNotification
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		email := ctx.Query("email")
-		role := ctx.Query("role")
-		if email == "" || role == "" {
-			configs.CaptureError(ctx, err)
-			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-				http.StatusBadRequest,
-				"Invalid request payload",
-				constants.InvalidRequestPayloadCode,
-				"Email and role must be provided",
-				nil))
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
+	failed, err := database.GetFailedNotification(ctx, appsession, request.ID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		if err.Error() == "failed notification not found" {
+			ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Failed notification not found", constants.BadRequestCode, err.Error(), nil))
 			return
-		} else {
-			request.Email = email
-			request.Role = role
 		}
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
 	}
 
-	// validate email
-	if !utils.ValidateEmail(request.Email) {
-		configs.CaptureError(ctx, errors.New("invalid email address"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid email address", nil))
+	// the notification was claimed when it was first sent, so it is replayed as a retry that is due now
+	if err := sender.PublishRetry(appsession, failed.Notification, 1, time.Now()); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to replay notification", constants.InternalServerErrorCode, "Failed to replay notification", nil))
 		return
 	}
 
-	// Toggle the admin status
-	err := database.ToggleAdminStatus(ctx, appsession, request)
-	if err != nil {
+	if err := database.DeleteFailedNotification(ctx, appsession, request.ID); err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to toggle admin status because: ", err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully replayed notification!", nil))
+}
+
+// GetWebPushPublicKey returns the VAPID public key browsers need to subscribe to web push notifications
+func GetWebPushPublicKey(ctx *gin.Context, appsession *models.AppSession) {
+	publicKey := configs.GetVAPIDPublicKey()
+	if publicKey == "" {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Web push is not configured", constants.BadRequestCode, "Web push is not configured", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched web push public key!", gin.H{"publicKey": publicKey}))
+}
+
+// SubscribeWebPush saves the web push subscription of the signed in user's browser
+func SubscribeWebPush(ctx *gin.Context, appsession *models.AppSession) {
+	var subscription models.WebPushSubscription
+	if err := ctx.ShouldBindJSON(&subscription); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
+	}
+
 	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Email must be provided",
-			nil))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	if err := CreateAndSendNotificationLogic(
-		ctx,
-		appsession,
-		email,
-		[]string{request.Email},
-		"Role status changed",
-		fmt.Sprintf("%s has changed your role status to %s", email, request.Role),
-		fmt.Sprintf("You have changed %s's role status to %s", request.Email, request.Role),
-	); err != nil {
+	if err := utils.ValidateWebPushEndpoint(subscription.Endpoint); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		return
+	}
+
+	if err := database.AddWebPushSubscription(ctx, appsession, email, subscription); err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to send notification because: ", err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully toggled admin status and sent notifications!", nil))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully subscribed to web push notifications!", nil))
 }
 
-func SendDownloadReportNotification(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestEmail
+// UnsubscribeWebPush removes the web push subscription of one of the signed in user's browsers
+func UnsubscribeWebPush(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestWebPushUnsubscribe
 	if err := ctx.ShouldBindJSON(&request); err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Invalid JSON payload",
-			nil))
-		return
-	}
-
-	// valdidate the email
-	if !utils.ValidateEmail(request.Email) {
-		configs.CaptureError(ctx, errors.New("invalid email address"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid email address", nil))
+		HandleValidationErrors(ctx, err)
 		return
 	}
 
 	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-			http.StatusBadRequest,
-			"Invalid request payload",
-			constants.InvalidRequestPayloadCode,
-			"Email must be provided",
-			nil))
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	if err := CreateAndSendNotificationLogic(
-		ctx,
-		appsession,
-		email,
-		[]string{request.Email},
-		"Report Downloaded",
-		email+" has downloaded your worker analytics report",
-		fmt.Sprintf("You have downloaded %s's worker analytics report", request.Email),
-	); err != nil {
+	if err := database.RemoveWebPushSubscription(ctx, appsession, email, request.Endpoint); err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to send notification because: ", err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully sent notification!", nil))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully unsubscribed from web push notifications!", nil))
 }
 
-func GetNotificationCount(ctx *gin.Context, appsession *models.AppSession) {
-	var request models.RequestEmail
+// RegisterDevice adds the device the request comes from to the devices the signed in user gets push notifications on
+func RegisterDevice(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDevice
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		emailStr := ctx.Query("email")
-		if emailStr == "" {
-			email, err := AttemptToGetEmail(ctx, appsession)
-			if err != nil {
-				configs.CaptureError(ctx, err)
-				ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
-					http.StatusBadRequest,
-					"Invalid request payload",
-					constants.InvalidRequestPayloadCode,
-					"Email must be provided",
-					nil))
-				return
-			} else {
-				request.Email = email
-			}
-		} else {
-			request.Email = emailStr
-		}
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
 	}
 
-	// get count
-	unReadCount, totalCount, err := database.CountNotifications(ctx, appsession, request.Email)
+	email, err := AttemptToGetEmail(ctx, appsession)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to get notification count because: ", err)
-		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched notification count!", gin.H{"unread": unReadCount, "total": totalCount}))
-}
-
-func GetUsersLocations(ctx *gin.Context, appsession *models.AppSession, ipPrivelege string) {
-	var email string
-	var order string
-	var limit int64
-	var page int64
-	var err error
-	order = ctx.Query("sort")
-	if order == "" || (order != "asc" && order != "desc") {
-		order = "asc"
+	device := models.Device{
+		Token:      request.ExpoPushToken,
+		Platform:   utils.DetectDeviceType(ctx.GetHeader("User-Agent")),
+		AppVersion: request.AppVersion,
+		LastSeen:   time.Now(),
 	}
 
-	limitStr := ctx.Query("limit")
-	if limitStr != "" {
-		limit, err = strconv.ParseInt(limitStr, 10, 64) // Base 10, 64-bit size
-		if err != nil {
-			limit = 50 // Default limit
-		}
+	if err := database.RegisterDevice(ctx, appsession, email, device); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
 	}
 
-	pageStr := ctx.Query("page")
-	if pageStr != "" {
-		page, err = strconv.ParseInt(pageStr, 10, 64) // Base 10, 64-bit size
-		if err != nil {
-			page = 1 // Default page
-		}
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully registered device!", nil))
+}
+
+// UnregisterDevice stops sending push notifications to one of the signed in user's devices
+func UnregisterDevice(ctx *gin.Context, appsession *models.AppSession) {
+	var request models.RequestDevice
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		configs.CaptureError(ctx, err)
+		HandleValidationErrors(ctx, err)
+		return
 	}
 
-	// For email filter
-	email = ctx.Query("email")
-	// validate email
-	if email != "" && !utils.ValidateEmail(email) {
-		configs.CaptureError(ctx, errors.New("invalid email address"))
-		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid email address", nil))
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
 		return
 	}
 
-	if limit <= 0 || limit > 50 {
-		limit = 50 // Default limit
+	if err := database.UnregisterDevice(ctx, appsession, email, request.ExpoPushToken); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
 	}
 
-	if page <= 0 {
-		page = 1
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully unregistered device!", nil))
+}
+
+// GetDevices returns the devices the signed in user gets push notifications on
+func GetDevices(ctx *gin.Context, appsession *models.AppSession) {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+		return
 	}
-	skip := (page - 1) * limit
 
-	locations, totalResults, err := database.GetUsersLocations(ctx, appsession, limit, skip, order, email, ipPrivelege)
+	devices, err := database.GetDevices(ctx, appsession, email)
 	if err != nil {
 		configs.CaptureError(ctx, err)
-		logrus.Error("Failed to get users locations because: ", err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.SuccessResponseWithMeta(http.StatusOK, "Successfully fetched users locations!", locations, gin.H{
-		"totalResults": len(locations), "totalPages": (totalResults + limit - 1) / limit, "currentPage": page}))
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully fetched devices!", devices))
 }
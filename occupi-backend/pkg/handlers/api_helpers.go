@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
@@ -16,13 +18,15 @@ import (
 	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/mail"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
-	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/receiver"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/notifier"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 	"github.com/ccoveille/go-safecast"
 	"github.com/gin-gonic/gin"
 	"github.com/nfnt/resize"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func MultiDeleteImages(ctx *gin.Context, appsession *models.AppSession, containerName string, ids []string) error {
@@ -281,6 +285,7 @@ func CreateAndSendNotificationLogic(ctx *gin.Context, appsession *models.AppSess
 		Emails:               receiverEmails,
 		UnsentExpoPushTokens: tokenArr,
 		UnreadEmails:         receiverEmails,
+		Type:                 constants.NotificationUpdate,
 	}
 
 	notificationSender := models.ScheduledNotification{
@@ -292,6 +297,7 @@ func CreateAndSendNotificationLogic(ctx *gin.Context, appsession *models.AppSess
 		Emails:               []string{senderEmail},
 		UnsentExpoPushTokens: []string{},
 		UnreadEmails:         []string{senderEmail},
+		Type:                 constants.NotificationUpdate,
 	}
 
 	// Save the notifications to the database
@@ -309,8 +315,8 @@ func CreateAndSendNotificationLogic(ctx *gin.Context, appsession *models.AppSess
 		return err
 	}
 
-	// send the notification
-	if err := receiver.SendPushNotification(notificationReciever, appsession); err != nil {
+	// send the notification on the channels each receiver chose
+	if _, err := notifier.Dispatch(ctx, appsession, notificationReciever); err != nil {
 		configs.CaptureError(ctx, err)
 		logrus.Error("Failed to send notification because: ", err)
 		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
@@ -319,3 +325,807 @@ func CreateAndSendNotificationLogic(ctx *gin.Context, appsession *models.AppSess
 
 	return nil
 }
+
+// ValidateRoomCapacity checks the attendees of a booking against the rooms occupancy bounds and writes an error response if they are out of bounds
+func ValidateRoomCapacity(ctx *gin.Context, appsession *models.AppSession, roomID string, emails []string, creator string) bool {
+	room, err := database.GetRoom(ctx, appsession, roomID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return false
+	}
+
+	attendees := database.CountAttendees(emails, creator)
+	if code, err := database.CheckRoomOccupancy(room, attendees); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Number of attendees is outside the room's occupancy",
+			code,
+			err.Error(),
+			gin.H{"minOccupancy": room.MinOccupancy, "maxOccupancy": room.MaxOccupancy, "attendees": attendees}))
+		return false
+	}
+
+	return true
+}
+
+// ValidateBookingRules checks that a room can be booked and that the booking fits its opening hours, slot length
+// and blackouts, writing an error response if it breaks any of them. The room is returned so the booking can be placed in
+// its site and building, and the settings so their buffer can be applied.
+func ValidateBookingRules(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) (models.Room, models.BookingSettings, bool) {
+	room, err := database.GetRoom(ctx, appsession, booking.RoomID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return room, models.BookingSettings{}, false
+	}
+
+	// inactive and archived rooms keep their bookings but cannot take new ones
+	if code, err := database.CheckRoomBookable(room); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Room is unavailable", code, err.Error(), gin.H{"status": room.Status}))
+		return room, models.BookingSettings{}, false
+	}
+
+	settings, err := database.GetBookingSettings(ctx, appsession, booking.RoomID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return room, settings, false
+	}
+
+	if code, err := database.CheckBookingAgainstSettings(booking, settings); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Booking is outside the room's available times",
+			code,
+			err.Error(),
+			gin.H{"openingTime": settings.OpeningTime, "closingTime": settings.ClosingTime, "slotLength": settings.SlotLength, "buffer": settings.Buffer}))
+		return room, settings, false
+	}
+
+	return room, settings, true
+}
+
+// SaveAndNotifyBooking saves a validated booking, emails the attendees and schedules its notifications.
+// An error response is written if any step fails.
+func SaveAndNotifyBooking(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) (models.Booking, bool) {
+	// Generate a unique ID for the booking
+	booking.ID = primitive.NewObjectID().Hex()
+	booking.OccupiID = utils.GenerateBookingID()
+	booking.CheckedIn = false
+	booking.SeriesID = ""
+	booking.Status = constants.BookingConfirmed
+
+	// Save the booking to the database
+	_, err := database.SaveBooking(ctx, appsession, booking)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to save booking", constants.InternalServerErrorCode, "Failed to save booking", nil))
+		return booking, false
+	}
+
+	return booking, NotifyBookingConfirmed(ctx, appsession, []models.Booking{booking})
+}
+
+// SaveOrRequestBooking saves a booking straight away, or as a pending booking when the room requires approval
+func SaveOrRequestBooking(ctx *gin.Context, appsession *models.AppSession, room models.Room, booking models.Booking) (models.Booking, bool) {
+	if !room.RequiresApproval {
+		return SaveAndNotifyBooking(ctx, appsession, booking)
+	}
+
+	booking.ID = primitive.NewObjectID().Hex()
+	booking.OccupiID = utils.GenerateBookingID()
+	booking.CheckedIn = false
+	booking.SeriesID = ""
+	booking.Status = constants.BookingPending
+
+	// a pending booking is saved so it holds the slot against other bookings while it waits for approval
+	if _, err := database.SaveBooking(ctx, appsession, booking); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to save booking", constants.InternalServerErrorCode, "Failed to save booking", nil))
+		return booking, false
+	}
+
+	return booking, RequestBookingApproval(ctx, appsession, room, []models.Booking{booking})
+}
+
+// RequestBookingApproval asks the room's approvers to approve or reject a pending booking, the bookings are the
+// occurrences of one series when there is more than one and the whole series is approved or rejected at once
+func RequestBookingApproval(ctx *gin.Context, appsession *models.AppSession, room models.Room, bookings []models.Booking) bool {
+	booking := bookings[0]
+	request := fmt.Sprintf("%s has requested to book %s on %s. Approve or reject booking %s.", booking.Creator, booking.RoomName, booking.Start.Format(time.RFC1123), booking.OccupiID)
+	if len(bookings) > 1 {
+		last := bookings[len(bookings)-1]
+		request = fmt.Sprintf("%s has requested to book %s %d times from %s to %s. Approve or reject booking %s to decide on every occurrence.",
+			booking.Creator, booking.RoomName, len(bookings), booking.Start.Format(time.RFC1123), last.Start.Format(time.RFC1123), booking.OccupiID)
+	}
+
+	approvers := room.Approvers
+	if len(approvers) == 0 {
+		admins, err := database.GetAdminEmails(ctx, appsession)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return false
+		}
+		approvers = admins
+	}
+
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		booking.Creator,
+		approvers,
+		"Booking Approval Requested",
+		request,
+		fmt.Sprintf("Your booking of %s is waiting for approval. You will be notified once it has been approved or rejected.", booking.RoomName),
+	); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to send notification because: ", err)
+		return false
+	}
+
+	return true
+}
+
+// NotifyBookingConfirmed sends the booking emails and schedules the notifications of confirmed bookings, the bookings
+// are the occurrences of one series when there is more than one
+func NotifyBookingConfirmed(ctx *gin.Context, appsession *models.AppSession, bookings []models.Booking) bool {
+	if err := mail.SendSeriesBookingEmails(bookings, appsession); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to send booking email", constants.InternalServerErrorCode, "Failed to send booking email", nil))
+		return false
+	}
+
+	tokens, err := database.GetUsersPushTokens(ctx, appsession, bookings[0].Emails)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to get push tokens", constants.InternalServerErrorCode, "Failed to get push tokens", nil))
+		return false
+	}
+
+	tokenArr, err := utils.ConvertTokensToStringArray(tokens, "expoPushToken")
+
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to convert tokens to string array because: ", err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return false
+	}
+
+	// each occurrence gets its own reminder but attendees are only invited once,
+	// bookings made on the spot have already started so they get no reminder
+	for _, booking := range bookings {
+		if !booking.Start.After(time.Now()) {
+			continue
+		}
+		if err := ScheduleBookingStartingSoonNotification(ctx, appsession, booking, tokenArr); err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+			return false
+		}
+	}
+
+	if err := SendBookingInvitationNotification(ctx, appsession, bookings[0], tokenArr); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to schedule notification", constants.InternalServerErrorCode, "Failed to schedule notification", nil))
+		return false
+	}
+
+	return true
+}
+
+// ScheduleBookingStartingSoonNotification schedules the "Booking Starting Soon" push notification for a booking
+func ScheduleBookingStartingSoonNotification(ctx *gin.Context, appsession *models.AppSession, booking models.Booking, tokenArr []string) error {
+	scheduledNotification := models.ScheduledNotification{
+		NotiID:               utils.GenerateUUID(),
+		Title:                "Booking Starting Soon",
+		Message:              utils.ConstructBookingStartingInScheduledString(booking.Emails, "3 mins"),
+		Sent:                 false,
+		SendTime:             booking.Start.Add(-3 * time.Minute),
+		Emails:               booking.Emails,
+		UnsentExpoPushTokens: tokenArr,
+		UnreadEmails:         booking.Emails,
+		BookingID:            booking.OccupiID,
+		Type:                 constants.NotificationReminder,
+	}
+
+	success, err := database.AddNotification(ctx, appsession, scheduledNotification, true)
+	if err != nil {
+		return err
+	}
+
+	if !success {
+		return errors.New("failed to schedule notification booking starting soon")
+	}
+
+	return nil
+}
+
+// SendBookingInvitationNotification saves the "Booking Invitation" notification for the attendees of a booking
+func SendBookingInvitationNotification(ctx *gin.Context, appsession *models.AppSession, booking models.Booking, tokenArr []string) error {
+	notification := models.ScheduledNotification{
+		NotiID:               utils.GenerateUUID(),
+		Title:                "Booking Invitation",
+		Message:              utils.ConstructBookingScheduledString(booking.Emails),
+		Sent:                 true,
+		SendTime:             utils.GetClientTime(ctx),
+		Emails:               booking.Emails,
+		UnsentExpoPushTokens: tokenArr,
+		UnreadEmails:         booking.Emails,
+		Type:                 constants.NotificationInvite,
+	}
+
+	success, err := database.AddNotification(ctx, appsession, notification, false)
+	if err != nil {
+		return err
+	}
+
+	if !success {
+		return errors.New("failed to schedule notification booking invitation")
+	}
+
+	return nil
+}
+
+// ApplyBookingUpdate returns the booking with the fields that were provided in the update request changed
+func ApplyBookingUpdate(booking models.Booking, request models.RequestUpdateBooking) models.Booking {
+	if request.RoomID != "" {
+		booking.RoomID = request.RoomID
+	}
+	if request.RoomName != "" {
+		booking.RoomName = request.RoomName
+	}
+	if request.FloorNo != "" {
+		booking.FloorNo = request.FloorNo
+	}
+	if request.Emails != nil {
+		booking.Emails = request.Emails
+	}
+	if !request.Date.IsZero() {
+		booking.Date = request.Date
+	}
+	if !request.Start.IsZero() {
+		booking.Start = request.Start
+	}
+	if !request.End.IsZero() {
+		booking.End = request.End
+	}
+	// removed attendees take their responses with them
+	booking.Attendees = utils.KeepAttendeeResponses(booking.Attendees, booking.Emails)
+	return booking
+}
+
+// RescheduleBookingNotifications replaces the pending reminder of a booking and invites any newly added attendees
+func RescheduleBookingNotifications(ctx *gin.Context, appsession *models.AppSession, booking models.Booking, added []string) error {
+	if err := database.DeleteScheduledBookingNotifications(ctx, appsession, booking.OccupiID); err != nil {
+		return err
+	}
+
+	tokens, err := database.GetUsersPushTokens(ctx, appsession, booking.Emails)
+	if err != nil {
+		return err
+	}
+
+	tokenArr, err := utils.ConvertTokensToStringArray(tokens, "expoPushToken")
+	if err != nil {
+		return err
+	}
+
+	// only schedule a reminder if the booking has not started yet
+	if booking.Start.After(time.Now()) {
+		if err := ScheduleBookingStartingSoonNotification(ctx, appsession, booking, tokenArr); err != nil {
+			return err
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	addedTokens, err := database.GetUsersPushTokens(ctx, appsession, added)
+	if err != nil {
+		return err
+	}
+
+	addedTokenArr, err := utils.ConvertTokensToStringArray(addedTokens, "expoPushToken")
+	if err != nil {
+		return err
+	}
+
+	invited := booking
+	invited.Emails = added
+	return SendBookingInvitationNotification(ctx, appsession, invited, addedTokenArr)
+}
+
+// BookRecurringRoom expands a recurring booking into its occurrences, validates them and saves them as a series
+func BookRecurringRoom(ctx *gin.Context, appsession *models.AppSession, room models.Room, booking models.Booking, rule models.RecurrenceRule, settings models.BookingSettings) {
+	occurrences, err := utils.ExpandRecurringBooking(booking, rule)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid recurrence rule", constants.BadRequestCode, err.Error(), nil))
+		return
+	}
+
+	// the first occurrence has already been checked but later ones may fall in a blackout
+	buffered := make([]models.Booking, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		if code, err := database.CheckBookingAgainstSettings(occurrence, settings); err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				http.StatusBadRequest,
+				"Booking is outside the room's available times",
+				code,
+				err.Error(),
+				gin.H{"occurrence": occurrence.Start}))
+			return
+		}
+		buffered = append(buffered, database.ApplyBookingBuffer(occurrence, settings.Buffer))
+	}
+
+	// every occurrence is held to the policies, with the earlier ones counting toward the quotas
+	if !ValidateBookingPolicies(ctx, appsession, occurrences...) {
+		return
+	}
+
+	// check every occurrence against existing bookings
+	conflicts, err := database.CheckCoincidingOccurrences(ctx, appsession, buffered)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book", constants.InternalServerErrorCode, "Failed to book", nil))
+		return
+	}
+
+	// report the occurrence start rather than the start of its buffer
+	for i := range conflicts {
+		conflicts[i] = conflicts[i].Add(time.Duration(settings.Buffer) * time.Minute)
+	}
+
+	if len(conflicts) > 0 {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Booking coincides with another booking",
+			constants.BadRequestCode,
+			"One or more occurrences coincide with another booking",
+			gin.H{"conflictingDates": conflicts}))
+		return
+	}
+
+	seriesID := utils.GenerateUUID()
+	occupiIDs := make([]string, 0, len(occurrences))
+	for i := range occurrences {
+		occurrences[i].ID = primitive.NewObjectID().Hex()
+		occurrences[i].OccupiID = utils.GenerateBookingID()
+		occurrences[i].CheckedIn = false
+		occurrences[i].SeriesID = seriesID
+		occurrences[i].OccurrenceStart = occurrences[i].Start
+		occurrences[i].Status = constants.BookingConfirmed
+		if room.RequiresApproval {
+			occurrences[i].Status = constants.BookingPending
+		}
+		occupiIDs = append(occupiIDs, occurrences[i].OccupiID)
+	}
+
+	series := models.BookingSeries{
+		SeriesID:   seriesID,
+		RoomID:     booking.RoomID,
+		RoomName:   booking.RoomName,
+		Emails:     booking.Emails,
+		Creator:    booking.Creator,
+		FloorNo:    booking.FloorNo,
+		Start:      booking.Start,
+		End:        booking.End,
+		Recurrence: rule,
+	}
+
+	if _, err := database.SaveBookingSeries(ctx, appsession, series, occurrences); err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to save booking", constants.InternalServerErrorCode, "Failed to save booking", nil))
+		return
+	}
+
+	// the whole series is approved or rejected at once
+	if room.RequiresApproval {
+		if !RequestBookingApproval(ctx, appsession, room, occurrences) {
+			return
+		}
+		ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Booking is awaiting approval!", gin.H{"seriesId": seriesID, "occupiIds": occupiIDs, "status": constants.BookingPending}))
+		return
+	}
+
+	if !NotifyBookingConfirmed(ctx, appsession, occurrences) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Successfully booked!", gin.H{"seriesId": seriesID, "occupiIds": occupiIDs}))
+}
+
+// LocationErrorResponse writes the response for errors from managing sites, buildings and floors
+func LocationErrorResponse(ctx *gin.Context, err error, message string) {
+	configs.CaptureError(ctx, err)
+	switch err.Error() {
+	case "site not found", "building not found", "floor not found":
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, message, constants.BadRequestCode, err.Error(), nil))
+	case "site has buildings", "building has floors", "floor has rooms", "floor already exists", "building is not part of the site":
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, message, constants.BadRequestCode, err.Error(), nil))
+	default:
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, message, constants.InternalServerErrorCode, message, nil))
+	}
+}
+
+// DeskSlot works out when a desk slot starts and ends using the booking settings of the desk's building,
+// writing the error response if the desk cannot be booked then
+func DeskSlot(ctx *gin.Context, appsession *models.AppSession, desk models.Desk, date time.Time, slot string) (time.Time, time.Time, bool) {
+	settings, err := database.GetLocationBookingSettings(ctx, appsession, "", desk.BuildingID, desk.SiteID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book desk", constants.InternalServerErrorCode, "Failed to get booking settings", nil))
+		return time.Time{}, time.Time{}, false
+	}
+
+	start, end, err := database.DeskSlotTimes(date, slot, settings)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		return time.Time{}, time.Time{}, false
+	}
+
+	if blackout, found := database.BlackoutDuring(start, end, settings.Blackouts); found {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Desk is unavailable", constants.BlackoutPeriodCode, "Desks are unavailable then: "+blackout.Reason, gin.H{"start": blackout.Start, "end": blackout.End}))
+		return time.Time{}, time.Time{}, false
+	}
+
+	return start, end, true
+}
+
+// builds the public url of a calendar feed from the host the request was made to
+func CalendarFeedURL(ctx *gin.Context, token string) string {
+	scheme := "https"
+	if ctx.Request.TLS == nil && ctx.GetHeader("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+	return scheme + "://" + ctx.Request.Host + "/calendar/" + token + ".ics"
+}
+
+// GetBookingForApproval gets a booking and checks that the signed in user may approve or reject it, writing an error
+// response if not. The user's email is returned so the creator can be told who decided.
+func GetBookingForApproval(ctx *gin.Context, appsession *models.AppSession, bookingID string) (models.Booking, string, bool) {
+	email, err := AttemptToGetEmail(ctx, appsession)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Email must be provided", nil))
+		return models.Booking{}, "", false
+	}
+
+	booking, err := database.GetBooking(ctx, appsession, bookingID)
+	if err != nil {
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+		return booking, email, false
+	}
+
+	room, err := database.GetRoom(ctx, appsession, booking.RoomID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return booking, email, false
+	}
+
+	isAdmin, err := database.CheckIfUserIsAdmin(ctx, appsession, email)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return booking, email, false
+	}
+
+	if !database.CanApproveBooking(room, email, isAdmin) {
+		ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Forbidden", constants.ForbiddenCode, "Only the room's approvers can approve or reject its bookings", nil))
+		return booking, email, false
+	}
+
+	return booking, email, true
+}
+
+// RecordRSVP saves an attendee's response to a booking and lets the creator know when they decline,
+// writing an error response if the response could not be saved
+func RecordRSVP(ctx *gin.Context, appsession *models.AppSession, booking models.Booking, email string, response string, series bool) bool {
+	if _, err := database.RespondToBooking(ctx, appsession, booking, email, response, series); err != nil {
+		if err.Error() == "not invited to booking" {
+			ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Forbidden", constants.ForbiddenCode, "Only attendees of a booking can respond to it", nil))
+			return false
+		}
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return false
+	}
+
+	if response != constants.RSVPDeclined || email == booking.Creator {
+		return true
+	}
+
+	if err := CreateAndSendNotificationLogic(
+		ctx,
+		appsession,
+		email,
+		[]string{booking.Creator},
+		"Booking Declined",
+		fmt.Sprintf("%s has declined your booking of %s.", email, booking.RoomName),
+		fmt.Sprintf("You have declined the booking of %s by %s.", booking.RoomName, booking.Creator),
+	); err != nil {
+		configs.CaptureError(ctx, err)
+		logrus.Error("Failed to send notification because: ", err)
+		return false
+	}
+
+	return true
+}
+
+// VerifyBookingLink checks the signature and expiry of a booking invitation link and gets the booking it is for,
+// writing an error response if the link is not valid
+func VerifyBookingLink(ctx *gin.Context, appsession *models.AppSession, request models.RequestRSVPLink) (models.Booking, bool) {
+	if err := utils.VerifyRSVP(request.BookingID, request.Email, request.Expires, request.Signature, time.Now()); err != nil {
+		ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Forbidden", constants.ForbiddenCode, err.Error(), nil))
+		return models.Booking{}, false
+	}
+
+	booking, err := database.GetBooking(ctx, appsession, request.BookingID)
+	if err != nil {
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+		return models.Booking{}, false
+	}
+
+	return booking, true
+}
+
+// ValidateDeskCheckIn checks that the person checking in is sitting at the booked desk and that the booking's
+// check-in window is open at now, writing an error response if not
+func ValidateDeskCheckIn(ctx *gin.Context, appsession *models.AppSession, checkIn models.CheckIn, now time.Time) bool {
+	booking, err := database.GetDeskBooking(ctx, appsession, checkIn.BookingID)
+	if err != nil {
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+		return false
+	}
+
+	if booking.Email != checkIn.Creator {
+		ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Forbidden", constants.ForbiddenCode, "Email not associated with booking", nil))
+		return false
+	}
+
+	if !database.CheckInWindowOpen(booking.Start, booking.End, now) {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(
+			http.StatusBadRequest,
+			"Check-in is not open",
+			constants.BadRequestCode,
+			fmt.Sprintf("Desks can be checked into from %d minutes before the booking starts until it ends", constants.QRCheckInEarlyMinutes),
+			gin.H{"start": booking.Start, "end": booking.End}))
+		return false
+	}
+
+	return true
+}
+
+// ValidateRoomCheckIn checks that the person checking in is an attendee of the booking and, for qr, nfc and ble
+// check-ins, that the token they read belongs to the booked room, writing an error response if not
+func ValidateRoomCheckIn(ctx *gin.Context, appsession *models.AppSession, checkIn models.CheckIn) bool {
+	switch checkIn.Method {
+	case "", constants.CheckInApp, constants.CheckInQR, constants.CheckInNFC, constants.CheckInBLE:
+	default:
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.BadRequestCode, "method must be app, qr, nfc or ble", nil))
+		return false
+	}
+
+	booking, err := database.GetBooking(ctx, appsession, checkIn.BookingID)
+	if err != nil {
+		configs.CaptureMessage(ctx, "booking not found")
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.InternalServerErrorCode, "Booking not found", nil))
+		return false
+	}
+
+	if !utils.IsBookingAttendee(booking, checkIn.Creator) {
+		ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Forbidden", constants.ForbiddenCode, "Email not associated with booking", nil))
+		return false
+	}
+
+	// only confirmed bookings hold the room
+	if booking.Status == constants.BookingPending {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking is awaiting approval", constants.BadRequestCode, "Bookings can only be checked into once they are approved", nil))
+		return false
+	}
+
+	if checkIn.Method == "" || checkIn.Method == constants.CheckInApp {
+		return true
+	}
+
+	room, err := database.GetRoom(ctx, appsession, booking.RoomID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return false
+	}
+
+	// qr codes are either a fixed token stuck to the room or the rotating code shown on its door
+	if checkIn.Method == constants.CheckInQR {
+		if roomID, err := utils.ParseRoomQRPayload(checkIn.TokenID, time.Now()); err == nil && roomID == room.RoomID {
+			return true
+		}
+	}
+
+	if checkIn.TokenID == "" || !utils.Contains(room.CheckInTokens, checkIn.TokenID) {
+		ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Forbidden", constants.ForbiddenCode, "Check-in token does not belong to the booked room", nil))
+		return false
+	}
+
+	return true
+}
+
+// finds how long a room is free from now so it can be booked on the spot, writes the error response
+// when the room cannot be booked or is in use
+func GetAdHocSlot(ctx *gin.Context, appsession *models.AppSession, room models.Room, now time.Time) (models.Slot, bool) {
+	if code, err := database.CheckRoomBookable(room); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Room is unavailable", code, err.Error(), gin.H{"status": room.Status}))
+		return models.Slot{}, false
+	}
+
+	settings, err := database.GetBookingSettings(ctx, appsession, room.RoomID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return models.Slot{}, false
+	}
+
+	_, closing := database.BusinessHours(now.In(database.LoadTimeZone(settings.TimeZone)), settings)
+	buffer := time.Duration(settings.Buffer) * time.Minute
+	bookings, err := database.GetRoomBookingsInRange(ctx, appsession, room.RoomID, now.Add(-buffer), closing.Add(buffer))
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return models.Slot{}, false
+	}
+
+	until, free := database.FreeUntil(bookings, now, settings)
+	if !free {
+		ctx.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, "Room is not free right now", constants.BadRequestCode, "Room is in use, closed or blacked out right now", nil))
+		return models.Slot{}, false
+	}
+
+	return models.Slot{Start: now, End: until}, true
+}
+
+// ValidateBookingPolicies checks new bookings against the policies of their creator's role and department
+// and of their room, writing the violation to the response when a booking breaks one. Several bookings are
+// the occurrences of a new series, which counts as one active booking while every occurrence counts toward
+// the weekly hours of its week.
+func ValidateBookingPolicies(ctx *gin.Context, appsession *models.AppSession, bookings ...models.Booking) bool {
+	return validateBookingPolicies(ctx, appsession, models.Booking{}, bookings)
+}
+
+// ValidateBookingChangePolicies checks a changed booking against the same policies as a new one,
+// the booking it replaces no longer counts toward the quotas
+func ValidateBookingChangePolicies(ctx *gin.Context, appsession *models.AppSession, existing models.Booking, booking models.Booking) bool {
+	return validateBookingPolicies(ctx, appsession, existing, []models.Booking{booking})
+}
+
+func validateBookingPolicies(ctx *gin.Context, appsession *models.AppSession, existing models.Booking, bookings []models.Booking) bool {
+	if len(bookings) == 0 {
+		return true
+	}
+
+	policies, err := database.GetApplicableBookingPolicies(ctx, appsession, bookings[0].Creator, bookings[0].RoomID)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return false
+	}
+
+	policy := database.MergeBookingPolicies(policies)
+	now := time.Now()
+
+	// the quotas are only looked up when a policy sets them
+	var activeBookings int64
+	if policy.MaxActiveBookings > 0 {
+		activeBookings, err = database.CountActiveBookings(ctx, appsession, bookings[0].Creator, now)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return false
+		}
+		if existing.OccupiID != "" && existing.End.After(now) {
+			activeBookings--
+		}
+	}
+
+	bookedMinutes := make(map[time.Time]int)
+	for _, booking := range bookings {
+		details := gin.H{"maxDuration": policy.MaxDuration, "maxAdvanceDays": policy.MaxAdvanceDays}
+		if len(bookings) > 1 {
+			details["occurrence"] = booking.Start
+		}
+
+		if code, err := database.CheckBookingAgainstPolicy(booking, policy, now); err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking violates a booking policy", code, err.Error(), details))
+			return false
+		}
+
+		weekStart, weekEnd := database.WeekBounds(booking.Start)
+		if _, ok := bookedMinutes[weekStart]; !ok && policy.WeeklyHours > 0 {
+			minutes, err := database.GetBookedMinutes(ctx, appsession, booking.Creator, weekStart, weekEnd)
+			if err != nil {
+				configs.CaptureError(ctx, err)
+				ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+				return false
+			}
+			if existing.OccupiID != "" {
+				minutes -= database.BookedMinutesBetween([]models.Booking{existing}, weekStart, weekEnd)
+			}
+			bookedMinutes[weekStart] = minutes
+		}
+
+		details = gin.H{"maxActiveBookings": policy.MaxActiveBookings, "weeklyHours": policy.WeeklyHours}
+		if len(bookings) > 1 {
+			details["occurrence"] = booking.Start
+		}
+
+		if code, err := database.CheckBookingQuota(booking, policy, activeBookings, bookedMinutes[weekStart]); err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Booking violates a booking policy", code, err.Error(), details))
+			return false
+		}
+
+		// earlier occurrences count toward the week of the later ones
+		bookedMinutes[weekStart] += database.BookedMinutesBetween([]models.Booking{booking}, weekStart, weekEnd)
+	}
+
+	return true
+}
+
+// AuthorizeBookingAction checks that the signed in user may act on a booking belonging to owner and returns their email.
+// Users may act on their own bookings and admins on anyone's, delegates of the owner are also allowed when allowDelegates
+// is set. Rejected attempts are logged for auditing and answered with a forbidden response.
+func AuthorizeBookingAction(ctx *gin.Context, appsession *models.AppSession, owner string, action string, allowDelegates bool) (string, bool) {
+	claims, err := utils.GetClaimsFromCTX(ctx)
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "Bad Request", constants.InvalidAuthCode, "User not authorized or Invalid auth token", nil))
+		return "", false
+	}
+
+	if claims.Email == owner || claims.Role == constants.Admin {
+		return claims.Email, true
+	}
+
+	if allowDelegates {
+		delegated, err := database.IsDelegate(ctx, appsession, owner, claims.Email)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return "", false
+		}
+		if delegated {
+			return claims.Email, true
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"email":  claims.Email,
+		"owner":  owner,
+		"action": action,
+		"path":   ctx.Request.URL.Path,
+		"ip":     utils.GetClientIP(ctx),
+	}).Warn("Rejected attempt to act on another user's booking")
+
+	ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Forbidden", constants.ForbiddenCode, fmt.Sprintf("You are not allowed to %s for %s", action, owner), nil))
+	return "", false
+}
+
+// ResolveBookingCreator records who made a booking, the creator must be the signed in user, a user who delegated
+// booking to them or anyone when they are an admin, writing a forbidden response otherwise
+func ResolveBookingCreator(ctx *gin.Context, appsession *models.AppSession, booking models.Booking) (models.Booking, bool) {
+	email, ok := AuthorizeBookingAction(ctx, appsession, booking.Creator, "book", true)
+	if !ok {
+		return booking, false
+	}
+
+	booking.CreatedBy = email
+	booking.OnBehalfOf = ""
+	if booking.Creator != email {
+		booking.OnBehalfOf = booking.Creator
+	}
+	return booking, true
+}
@@ -97,6 +97,8 @@ func Login(ctx *gin.Context, appsession *models.AppSession, role string, cookies
 
 	AddMobileUser(ctx, appsession, requestUser.Email, token)
 
+	RegisterLoginDevice(ctx, appsession, requestUser.Email, requestUser.ExpoPushToken, requestUser.AppVersion)
+
 	// Use AllocateAuthTokens to handle the response
 	AllocateAuthTokens(ctx, token, expirationTime, cookies)
 }
@@ -568,6 +570,8 @@ func VerifyOTP(ctx *gin.Context, appsession *models.AppSession, login bool, role
 
 	AddMobileUser(ctx, appsession, userotp.Email, token)
 
+	RegisterLoginDevice(ctx, appsession, userotp.Email, userotp.ExpoPushToken, userotp.AppVersion)
+
 	// Use AllocateAuthTokens to handle the response
 	AllocateAuthTokens(ctx, token, expirationTime, cookies)
 }
@@ -777,6 +781,15 @@ func Logout(ctx *gin.Context, appsession *models.AppSession) {
 	// clear token from cache
 	cache.DeleteMobileUser(appsession, claims.Email)
 
+	// stop sending push notifications to the device being logged out of
+	var device models.RequestDevice
+	if err := ctx.ShouldBindJSON(&device); err == nil {
+		if err := database.UnregisterDevice(ctx, appsession, claims.Email, device.ExpoPushToken); err != nil {
+			configs.CaptureError(ctx, err)
+			logrus.WithError(err).Error("Error unregistering device")
+		}
+	}
+
 	_ = utils.ClearSession(ctx)
 
 	// Clear the Authorization header
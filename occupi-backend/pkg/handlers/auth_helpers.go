@@ -12,6 +12,7 @@ import (
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 	"github.com/ipinfo/go/v2/ipinfo"
+	"github.com/sirupsen/logrus"
 
 	"github.com/gin-gonic/gin"
 )
@@ -623,3 +624,21 @@ func AddMobileUser(ctx *gin.Context, appsession *models.AppSession, email string
 	// add the user to the mobile user cache(or overwrite the user if they already exist)
 	cache.SetMobileUser(appsession, mobileUser)
 }
+
+// registers the device a user logged in on for push notifications, logging in still succeeds if it cannot be registered
+func RegisterLoginDevice(ctx *gin.Context, appsession *models.AppSession, email string, token string, appVersion string) {
+	if token == "" {
+		return
+	}
+
+	device := models.Device{
+		Token:      token,
+		Platform:   utils.DetectDeviceType(ctx.GetHeader("User-Agent")),
+		AppVersion: appVersion,
+		LastSeen:   time.Now(),
+	}
+
+	if err := database.RegisterDevice(ctx, appsession, email, device); err != nil {
+		logrus.WithError(err).Error("Error registering device")
+	}
+}
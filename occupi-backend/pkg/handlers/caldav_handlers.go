@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CalDAVOptions tells calendar clients which DAV features the server supports
+func CalDAVOptions(ctx *gin.Context) {
+	ctx.Header("DAV", "1, calendar-access")
+	ctx.Header("Allow", "OPTIONS, GET, PUT, PROPFIND, REPORT")
+	ctx.Status(http.StatusOK)
+}
+
+// CalDAVWellKnown points clients that discover the server through /.well-known/caldav to the principal
+func CalDAVWellKnown(ctx *gin.Context) {
+	ctx.Redirect(http.StatusMovedPermanently, caldavRoot)
+}
+
+// CalDAVPropfindPrincipal describes the signed in user, whose calendar home holds the room calendars
+func CalDAVPropfindPrincipal(ctx *gin.Context) {
+	WriteMultiStatus(ctx, []models.DAVResponse{PrincipalResponse()})
+}
+
+// CalDAVPropfindHome lists every room as a calendar collection
+func CalDAVPropfindHome(ctx *gin.Context, appsession *models.AppSession) {
+	responses := []models.DAVResponse{CalendarHomeResponse()}
+
+	if WantsMembers(ctx) {
+		rooms, err := database.FindRooms(ctx, appsession)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return
+		}
+
+		for _, room := range rooms {
+			responses = append(responses, RoomCalendarResponse(room, ""))
+		}
+	}
+
+	WriteMultiStatus(ctx, responses)
+}
+
+// CalDAVPropfindCalendar describes a room's calendar and lists its recent and upcoming bookings
+func CalDAVPropfindCalendar(ctx *gin.Context, appsession *models.AppSession) {
+	room, err := database.GetRoom(ctx, appsession, ctx.Param("roomId"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return
+	}
+
+	bookings, err := database.GetRoomBookingsInRange(ctx, appsession, room.RoomID, time.Now().AddDate(0, 0, -constants.CalDAVPastDays), time.Time{})
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	responses := []models.DAVResponse{RoomCalendarResponse(room, utils.CalendarCTag(bookings))}
+	if WantsMembers(ctx) {
+		for _, booking := range bookings {
+			responses = append(responses, BookingResponse(ctx, booking, false))
+		}
+	}
+
+	WriteMultiStatus(ctx, responses)
+}
+
+// CalDAVPropfindEvent describes a single booking in a room's calendar
+func CalDAVPropfindEvent(ctx *gin.Context, appsession *models.AppSession) {
+	booking, ok := GetCalDAVBooking(ctx, appsession)
+	if !ok {
+		return
+	}
+
+	WriteMultiStatus(ctx, []models.DAVResponse{BookingResponse(ctx, booking, false)})
+}
+
+// CalDAVReport answers calendar-query, calendar-multiget and free-busy-query reports on a room's calendar
+func CalDAVReport(ctx *gin.Context, appsession *models.AppSession) {
+	room, err := database.GetRoom(ctx, appsession, ctx.Param("roomId"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid report", nil))
+		return
+	}
+
+	report, err := utils.ParseCalDAVReport(body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		return
+	}
+
+	withData := len(report.Props) == 0 || utils.Contains(report.Props, "calendar-data")
+
+	switch report.Name {
+	case "calendar-query":
+		start := report.Start
+		if start.IsZero() {
+			start = time.Now().AddDate(0, 0, -constants.CalDAVPastDays)
+		}
+
+		bookings, err := database.GetRoomBookingsInRange(ctx, appsession, room.RoomID, start, report.End)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return
+		}
+
+		responses := []models.DAVResponse{}
+		for _, booking := range bookings {
+			responses = append(responses, BookingResponse(ctx, booking, withData))
+		}
+		WriteMultiStatus(ctx, responses)
+	case "calendar-multiget":
+		responses := []models.DAVResponse{}
+		for _, href := range report.Hrefs {
+			booking, err := database.GetBooking(ctx, appsession, BookingIDFromHref(href))
+			if err != nil || booking.RoomID != room.RoomID {
+				responses = append(responses, models.DAVResponse{Href: href, Status: caldavNotFound})
+				continue
+			}
+			responses = append(responses, BookingResponse(ctx, booking, withData))
+		}
+		WriteMultiStatus(ctx, responses)
+	case "free-busy-query":
+		if report.Start.IsZero() || report.End.IsZero() {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "free-busy-query needs a time-range with a start and an end", nil))
+			return
+		}
+
+		bookings, err := database.GetRoomBookingsInRange(ctx, appsession, room.RoomID, report.Start, report.End)
+		if err != nil {
+			configs.CaptureError(ctx, err)
+			ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+			return
+		}
+
+		ctx.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(utils.FormatFreeBusy(bookings, report.Start, report.End, time.Now())))
+	default:
+		ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Unsupported report", constants.BadRequestCode, "Only calendar-query, calendar-multiget and free-busy-query reports are supported", nil))
+	}
+}
+
+// CalDAVGetEvent returns a booking as an iCalendar object
+func CalDAVGetEvent(ctx *gin.Context, appsession *models.AppSession) {
+	booking, ok := GetCalDAVBooking(ctx, appsession)
+	if !ok {
+		return
+	}
+
+	booking = CalDAVVisibleBooking(booking, ctx.GetString("caldavEmail"))
+	ctx.Header("ETag", utils.CalendarETag(booking))
+	ctx.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(utils.FormatCalendarObject([]models.Booking{booking})))
+}
+
+// CalDAVPutEvent creates a booking from an event a calendar client added to a room's calendar. The booking goes through
+// the same capacity, opening hours, booking policy and coinciding booking checks as /api/book-room. Bookings are stored under their own
+// occupi id, so the Location header tells the client where the new event lives.
+func CalDAVPutEvent(ctx *gin.Context, appsession *models.AppSession) {
+	// existing bookings are changed through /api/update-booking so their emails and waitlist are handled
+	if database.BookingExists(ctx, appsession, BookingIDFromHref(ctx.Param("event"))) {
+		ctx.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, "Bookings cannot be changed through CalDAV", constants.BadRequestCode, "Bookings cannot be changed through CalDAV", nil))
+		return
+	}
+
+	room, err := database.GetRoom(ctx, appsession, ctx.Param("roomId"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Room not found", constants.BadRequestCode, "Room not found", nil))
+		return
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Invalid event", nil))
+		return
+	}
+
+	event, err := utils.ParseICalendarEvent(string(body))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, err.Error(), nil))
+		return
+	}
+
+	if event.Recurring {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request payload", constants.InvalidRequestPayloadCode, "Recurring events cannot be booked through CalDAV", nil))
+		return
+	}
+
+	booking := CalDAVEventToBooking(event, room, ctx.GetString("caldavEmail"))
+
+	if !ValidateRoomCapacity(ctx, appsession, booking.RoomID, booking.Emails, booking.Creator) {
+		return
+	}
+
+	room, settings, ok := ValidateBookingRules(ctx, appsession, booking)
+	if !ok {
+		return
+	}
+	booking.SiteID = room.SiteID
+	booking.BuildingID = room.BuildingID
+
+	if !ValidateBookingPolicies(ctx, appsession, booking) {
+		return
+	}
+
+	coinciding, err := database.CheckCoincidingBookings(ctx, appsession, database.ApplyBookingBuffer(booking, settings.Buffer))
+	if err != nil {
+		configs.CaptureError(ctx, err)
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to book", constants.InternalServerErrorCode, "Failed to book", nil))
+		return
+	}
+
+	if coinciding {
+		ctx.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, "Booking coincides with another booking", constants.BadRequestCode, "Booking coincides with another booking", nil))
+		return
+	}
+
+	booking, ok = SaveOrRequestBooking(ctx, appsession, room, booking)
+	if !ok {
+		return
+	}
+
+	ctx.Header("Location", BookingHref(booking))
+	ctx.Header("ETag", utils.CalendarETag(booking))
+	ctx.Status(http.StatusCreated)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	caldavRoot     = "/caldav/"
+	caldavHome     = "/caldav/rooms/"
+	caldavOK       = "HTTP/1.1 200 OK"
+	caldavNotFound = "HTTP/1.1 404 Not Found"
+)
+
+// the href of a room's calendar collection
+func RoomCalendarHref(roomID string) string {
+	return caldavHome + url.PathEscape(roomID) + "/"
+}
+
+// the href of a booking in its room's calendar, bookings are named after their occupi id
+func BookingHref(booking models.Booking) string {
+	return RoomCalendarHref(booking.RoomID) + url.PathEscape(booking.OccupiID) + ".ics"
+}
+
+// the occupi id of the booking an event href or path segment points to
+func BookingIDFromHref(href string) string {
+	name := href[strings.LastIndex(href, "/")+1:]
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return strings.TrimSuffix(name, ".ics")
+}
+
+// the response describing the principal, which points clients to the room calendars
+func PrincipalResponse() models.DAVResponse {
+	return models.DAVResponse{
+		Href: caldavRoot,
+		Propstat: []models.DAVPropstat{{
+			Prop: models.DAVProp{
+				ResourceType:         &models.DAVResourceType{Collection: &struct{}{}},
+				DisplayName:          "Occupi",
+				CurrentUserPrincipal: &models.DAVHref{Href: caldavRoot},
+				CalendarHomeSet:      &models.DAVHref{Href: caldavHome},
+			},
+			Status: caldavOK,
+		}},
+	}
+}
+
+// the response describing the collection that holds the room calendars
+func CalendarHomeResponse() models.DAVResponse {
+	return models.DAVResponse{
+		Href: caldavHome,
+		Propstat: []models.DAVPropstat{{
+			Prop: models.DAVProp{
+				ResourceType:         &models.DAVResourceType{Collection: &struct{}{}},
+				DisplayName:          "Rooms",
+				CurrentUserPrincipal: &models.DAVHref{Href: caldavRoot},
+			},
+			Status: caldavOK,
+		}},
+	}
+}
+
+// the response describing a room's calendar, the ctag is left out when the room's bookings were not fetched
+func RoomCalendarResponse(room models.Room, ctag string) models.DAVResponse {
+	prop := models.DAVProp{
+		ResourceType:        &models.DAVResourceType{Collection: &struct{}{}, Calendar: &struct{}{}},
+		DisplayName:         room.RoomName,
+		CalendarDescription: room.Description,
+		SupportedComponents: &models.DAVComponentSet{Components: []models.DAVComponent{{Name: "VEVENT"}}},
+		CTag:                ctag,
+	}
+
+	return models.DAVResponse{
+		Href:     RoomCalendarHref(room.RoomID),
+		Propstat: []models.DAVPropstat{{Prop: prop, Status: caldavOK}},
+	}
+}
+
+// CalDAVVisibleBooking hides who made and attends a booking from users that are not on it, room calendars show every
+// booking of the room so others can see when it is busy but not who is meeting
+func CalDAVVisibleBooking(booking models.Booking, email string) models.Booking {
+	if strings.EqualFold(booking.Creator, email) {
+		return booking
+	}
+	for _, attendee := range booking.Emails {
+		if strings.EqualFold(attendee, email) {
+			return booking
+		}
+	}
+
+	hidden := booking
+	hidden.Creator, hidden.CreatedBy, hidden.OnBehalfOf = "", "", ""
+	hidden.Emails, hidden.Attendees, hidden.CheckIns = nil, nil, nil
+	return hidden
+}
+
+// the response describing a booking as the signed in user may see it, with its calendar data when the client asked for it
+func BookingResponse(ctx *gin.Context, booking models.Booking, withData bool) models.DAVResponse {
+	booking = CalDAVVisibleBooking(booking, ctx.GetString("caldavEmail"))
+
+	prop := models.DAVProp{
+		ETag:        utils.CalendarETag(booking),
+		ContentType: "text/calendar; charset=utf-8; component=VEVENT",
+	}
+	if withData {
+		prop.CalendarData = utils.FormatCalendarObject([]models.Booking{booking})
+	}
+
+	return models.DAVResponse{
+		Href:     BookingHref(booking),
+		Propstat: []models.DAVPropstat{{Prop: prop, Status: caldavOK}},
+	}
+}
+
+// WriteMultiStatus writes a WebDAV 207 Multi-Status response
+func WriteMultiStatus(ctx *gin.Context, responses []models.DAVResponse) {
+	body, err := xml.Marshal(models.DAVMultiStatus{
+		DAV:       "DAV:",
+		CalDAV:    "urn:ietf:params:xml:ns:caldav",
+		CS:        "http://calendarserver.org/ns/",
+		Responses: responses,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.InternalServerError())
+		return
+	}
+
+	ctx.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// whether a PROPFIND should also describe the members of the collection
+func WantsMembers(ctx *gin.Context) bool {
+	return ctx.GetHeader("Depth") != "0"
+}
+
+// GetCalDAVBooking gets the booking an event path points to, writing a 404 if it is not in the room's calendar
+func GetCalDAVBooking(ctx *gin.Context, appsession *models.AppSession) (models.Booking, bool) {
+	booking, err := database.GetBooking(ctx, appsession, BookingIDFromHref(ctx.Param("event")))
+	if err != nil || booking.RoomID != ctx.Param("roomId") {
+		ctx.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Booking not found", constants.BadRequestCode, "Booking not found", nil))
+		return booking, false
+	}
+
+	return booking, true
+}
+
+// CalDAVEventToBooking turns an event added to a room's calendar into a booking of the room made by the signed in user
+func CalDAVEventToBooking(event models.CalDAVEvent, room models.Room, creator string) models.Booking {
+	emails := []string{}
+	for _, email := range event.Attendees {
+		if email != "" && !utils.Contains(emails, email) {
+			emails = append(emails, email)
+		}
+	}
+	if len(emails) == 0 {
+		emails = append(emails, creator)
+	}
+
+	return models.Booking{
+		RoomID:    room.RoomID,
+		RoomName:  room.RoomName,
+		FloorNo:   room.FloorNo,
+		Emails:    emails,
+		Creator:   creator,
+		CreatedBy: creator,
+		Date:      utils.StartOfDay(event.Start),
+		Start:     event.Start,
+		End:       event.End,
+	}
+}
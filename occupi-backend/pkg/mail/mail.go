@@ -2,9 +2,12 @@ package mail
 
 import (
 	"errors"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 	"gopkg.in/gomail.v2"
@@ -50,6 +53,60 @@ func SendMailBCC(appsession *models.AppSession, subject, body, bcc string) error
 	return nil
 }
 
+// SendMailWithInvite sends an email with an iCalendar invite so calendar clients can add, update or remove the booking
+func SendMailWithInvite(appsession *models.AppSession, to string, subject string, body string, method string, invite string) error {
+	if configs.GetGinRunMode() == test {
+		return nil // Do not send emails in test mode
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", configs.GetSystemEmail())
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
+	AttachInvite(m, method, invite)
+
+	if err := appsession.MailConn.DialAndSend(m); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SendMailBCCWithInvite sends an email with an iCalendar invite using BCC
+func SendMailBCCWithInvite(appsession *models.AppSession, subject, body, bcc, method, invite string) error {
+	if configs.GetGinRunMode() == test {
+		return nil // Do not send emails in test mode
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", configs.GetSystemEmail())
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
+	m.SetHeader("Bcc", bcc)
+	AttachInvite(m, method, invite)
+
+	if err := appsession.MailConn.DialAndSend(m); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AttachInvite adds the invite both inline, which mail clients use to show accept/decline buttons,
+// and as an invite.ics attachment for clients that only import files
+func AttachInvite(m *gomail.Message, method string, invite string) {
+	contentType := "text/calendar; method=" + method + "; charset=UTF-8"
+	m.AddAlternative(contentType, invite)
+	m.Attach("invite.ics",
+		gomail.SetHeader(map[string][]string{"Content-Type": {contentType}}),
+		gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := io.WriteString(w, invite)
+			return err
+		}),
+	)
+}
+
 // SendBulkEmailWithBCC sends an email to multiple recipients using BCC
 func SendBulkEmailWithBCC(emails []string, subject, body string, appsession *models.AppSession) error {
 	// if no emails to send to, return
@@ -78,15 +135,40 @@ func SendBulkEmailWithBCC(emails []string, subject, body string, appsession *mod
 	return nil
 }
 
+// SendBulkEmailWithInvite sends an email with an iCalendar invite to multiple recipients using BCC
+func SendBulkEmailWithInvite(emails []string, subject, body, method, invite string, appsession *models.AppSession) error {
+	// if no emails to send to, return
+	if len(emails) == 0 || configs.GetGinRunMode() == test {
+		return nil
+	}
+
+	// if only one email to send to, send directly
+	if len(emails) == 1 {
+		return SendMailWithInvite(appsession, emails[0], subject, body, method, invite)
+	}
+
+	return SendMailBCCWithInvite(appsession, subject, body, strings.Join(emails, ","), method, invite)
+}
+
 func SendBookingEmails(booking models.Booking, appsession *models.AppSession) error {
+	return sendBookingEmails(booking, []models.Booking{booking}, appsession)
+}
+
+// SendSeriesBookingEmails sends one set of booking emails for a recurring series, the invite holds every occurrence
+func SendSeriesBookingEmails(occurrences []models.Booking, appsession *models.AppSession) error {
+	if len(occurrences) == 0 {
+		return nil
+	}
+	return sendBookingEmails(occurrences[0], occurrences, appsession)
+}
+
+func sendBookingEmails(booking models.Booking, bookings []models.Booking, appsession *models.AppSession) error {
+	invite := utils.FormatBookingInvite(constants.ICalendarRequest, bookings, time.Now())
+
 	// Prepare the email content
 	creatorSubject := "Booking Confirmation - Occupi"
 	creatorBody := utils.FormatBookingEmailBodyForBooker(booking.ID, booking.RoomID, 0, booking.Emails, booking.Creator)
 
-	// Prepare the email content for attendees
-	attendeesSubject := "You're invited to a Booking - Occupi"
-	attendeesBody := utils.FormatBookingEmailBodyForAttendees(booking.ID, booking.RoomID, 0, booking.Creator)
-
 	var attendeesEmails []string
 	for _, email := range booking.Emails {
 		if email != booking.Creator {
@@ -94,22 +176,81 @@ func SendBookingEmails(booking models.Booking, appsession *models.AppSession) er
 		}
 	}
 
-	creatorEmailError := SendMail(appsession, booking.Creator, creatorSubject, creatorBody)
+	creatorEmailError := SendMailWithInvite(appsession, booking.Creator, creatorSubject, creatorBody, constants.ICalendarRequest, invite)
 	if creatorEmailError != nil {
 		return creatorEmailError
 	}
 
-	// Send the confirmation email using bcc headers to all recipients
-	err := SendBulkEmailWithBCC(attendeesEmails, attendeesSubject, attendeesBody, appsession)
+	return SendInvitations(booking, attendeesEmails, invite, appsession)
+}
 
-	if err != nil {
-		return err
+// SendInvitations emails each attendee on their own so the rsvp links in the email are signed for them,
+// attendees of a series respond to every occurrence at once
+func SendInvitations(booking models.Booking, emails []string, invite string, appsession *models.AppSession) error {
+	subject := "You're invited to a Booking - Occupi"
+	series := booking.SeriesID != ""
+	baseURL := configs.GetRSVPBaseURL()
+	expires := time.Now().Add(time.Duration(configs.GetRSVPLinkExpiry()) * time.Second)
+
+	for _, email := range emails {
+		body := utils.FormatBookingInvitationEmailBody(booking.OccupiID, booking.RoomName, booking.Start, booking.End, booking.Creator,
+			utils.FormatRSVPLink(baseURL, booking.OccupiID, email, constants.RSVPAccepted, series, expires),
+			utils.FormatRSVPLink(baseURL, booking.OccupiID, email, constants.RSVPTentative, series, expires),
+			utils.FormatRSVPLink(baseURL, booking.OccupiID, email, constants.RSVPDeclined, series, expires),
+		)
+		if err := SendMailWithInvite(appsession, email, subject, body, constants.ICalendarRequest, invite); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func SendCancellationEmails(cancel models.Cancel, appsession *models.AppSession) error {
+// SendBookingUpdateEmails only emails the changes of a booking, added attendees are invited, removed attendees are told
+// the booking was cancelled and everyone else is only emailed if the room or time changed
+func SendBookingUpdateEmails(oldBooking models.Booking, booking models.Booking, appsession *models.AppSession) error {
+	added, removed := utils.DiffEmails(oldBooking.Emails, booking.Emails)
+
+	// the occupi id is kept on update so the invite replaces the event already in each calendar
+	now := time.Now()
+	invite := utils.FormatBookingInvite(constants.ICalendarRequest, []models.Booking{booking}, now)
+	cancelInvite := utils.FormatBookingInvite(constants.ICalendarCancel, []models.Booking{oldBooking}, now)
+
+	if err := SendInvitations(booking, added, invite, appsession); err != nil {
+		return err
+	}
+
+	removedSubject := "Booking Cancelled - Occupi"
+	removedBody := utils.FormatCancellationEmailBodyForAttendees(oldBooking.OccupiID, oldBooking.RoomID, 0, booking.Creator)
+	if err := SendBulkEmailWithInvite(removed, removedSubject, removedBody, constants.ICalendarCancel, cancelInvite, appsession); err != nil {
+		return err
+	}
+
+	if oldBooking.RoomID == booking.RoomID && oldBooking.Start.Equal(booking.Start) && oldBooking.End.Equal(booking.End) {
+		return nil
+	}
+
+	// attendees that were just invited already have the new details
+	var unchangedEmails []string
+	for _, email := range booking.Emails {
+		if !utils.Contains(added, email) {
+			unchangedEmails = append(unchangedEmails, email)
+		}
+	}
+	if !utils.Contains(unchangedEmails, booking.Creator) {
+		unchangedEmails = append(unchangedEmails, booking.Creator)
+	}
+
+	updatedSubject := "Booking Updated - Occupi"
+	updatedBody := utils.FormatBookingUpdatedEmailBody(booking.OccupiID, booking.RoomID, booking.Start, booking.End, booking.Creator)
+	return SendBulkEmailWithInvite(unchangedEmails, updatedSubject, updatedBody, constants.ICalendarRequest, invite, appsession)
+}
+
+// SendCancellationEmails tells the creator and attendees about a cancellation, the invite cancels every
+// cancelled booking so a whole series is removed from calendars at once
+func SendCancellationEmails(cancel models.Cancel, cancelled []models.Booking, appsession *models.AppSession) error {
+	invite := utils.FormatBookingInvite(constants.ICalendarCancel, cancelled, time.Now())
+
 	// Prepare the email content
 	creatorSubject := "Booking Cancelled - Occupi"
 	creatorBody := utils.FormatCancellationEmailBodyForBooker(cancel.BookingID, cancel.RoomID, 0, cancel.Creator)
@@ -125,13 +266,13 @@ func SendCancellationEmails(cancel models.Cancel, appsession *models.AppSession)
 		}
 	}
 
-	creatorEmailError := SendMail(appsession, cancel.Creator, creatorSubject, creatorBody)
+	creatorEmailError := SendMailWithInvite(appsession, cancel.Creator, creatorSubject, creatorBody, constants.ICalendarCancel, invite)
 	if creatorEmailError != nil {
 		return creatorEmailError
 	}
 
 	// Send the confirmation email using bcc headers to all recipients
-	err := SendBulkEmailWithBCC(attendeesEmails, attendeesSubject, attendeesBody, appsession)
+	err := SendBulkEmailWithInvite(attendeesEmails, attendeesSubject, attendeesBody, constants.ICalendarCancel, invite, appsession)
 
 	if err != nil {
 		return errors.New("failed to send booking emails")
@@ -9,6 +9,7 @@ import (
 
 	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -211,6 +212,36 @@ func AttachRateLimitMiddleware(ginRouter *gin.Engine) {
 	ginRouter.Use(middleware)
 }
 
+// CalDAVAuth is a middleware that authenticates calendar clients, which only support basic auth.
+// The username is the user's email and the password is their caldav app password, the calendar feed token
+// is not accepted since feed urls are shared with calendar apps that should only read bookings.
+func CalDAVAuth(ctx *gin.Context, appsession *models.AppSession) {
+	email, password, ok := ctx.Request.BasicAuth()
+
+	var owner string
+	if ok {
+		var err error
+		owner, err = database.GetCalDAVPasswordEmail(ctx, appsession, utils.HashCalDAVPassword(password))
+		ok = err == nil && strings.EqualFold(owner, email)
+	}
+
+	if !ok {
+		ctx.Header("WWW-Authenticate", `Basic realm="Occupi CalDAV", charset="UTF-8"`)
+		ctx.JSON(http.StatusUnauthorized,
+			utils.ErrorResponse(
+				http.StatusUnauthorized,
+				"Bad Request",
+				constants.InvalidAuthCode,
+				"Invalid calendar credentials",
+				nil))
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("caldavEmail", owner)
+	ctx.Next()
+}
+
 // TimezoneMiddleware is a middleware that sets the timezone for the request.
 func TimezoneMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// structure of a VEVENT sent by a CalDAV client to create a booking
+type CalDAVEvent struct {
+	UID       string
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	Attendees []string
+	Recurring bool
+}
+
+// structure of a REPORT request, only the parts used to answer calendar-query,
+// calendar-multiget and free-busy-query reports are kept
+type CalDAVReport struct {
+	Name  string    // local name of the root element, e.g. calendar-query
+	Props []string  // local names of the requested properties
+	Hrefs []string  // resources requested by a calendar-multiget
+	Start time.Time // start of the time-range filter, zero when there is none
+	End   time.Time // end of the time-range filter, zero when there is none
+}
+
+// structure of a WebDAV multistatus response, the prefixes are declared on the root element
+type DAVMultiStatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	DAV       string        `xml:"xmlns:D,attr"`
+	CalDAV    string        `xml:"xmlns:C,attr"`
+	CS        string        `xml:"xmlns:CS,attr"`
+	Responses []DAVResponse `xml:"D:response"`
+}
+
+type DAVResponse struct {
+	Href     string        `xml:"D:href"`
+	Status   string        `xml:"D:status,omitempty"`
+	Propstat []DAVPropstat `xml:"D:propstat,omitempty"`
+}
+
+type DAVPropstat struct {
+	Prop   DAVProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+type DAVProp struct {
+	ResourceType         *DAVResourceType `xml:"D:resourcetype,omitempty"`
+	DisplayName          string           `xml:"D:displayname,omitempty"`
+	CurrentUserPrincipal *DAVHref         `xml:"D:current-user-principal,omitempty"`
+	CalendarHomeSet      *DAVHref         `xml:"C:calendar-home-set,omitempty"`
+	CalendarDescription  string           `xml:"C:calendar-description,omitempty"`
+	SupportedComponents  *DAVComponentSet `xml:"C:supported-calendar-component-set,omitempty"`
+	CTag                 string           `xml:"CS:getctag,omitempty"`
+	ETag                 string           `xml:"D:getetag,omitempty"`
+	ContentType          string           `xml:"D:getcontenttype,omitempty"`
+	CalendarData         string           `xml:"C:calendar-data,omitempty"`
+}
+
+type DAVHref struct {
+	Href string `xml:"D:href"`
+}
+
+type DAVResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+	Calendar   *struct{} `xml:"C:calendar,omitempty"`
+}
+
+type DAVComponentSet struct {
+	Components []DAVComponent `xml:"C:comp"`
+}
+
+type DAVComponent struct {
+	Name string `xml:"name,attr"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"os"
 	"time"
 
@@ -28,9 +29,19 @@ type User struct {
 	Status                  string        `json:"status" bson:"status, omitempty"`
 	Position                string        `json:"position" bson:"position, omitempty"`
 	DepartmentNo            string        `json:"departmentNo" bson:"departmentNo, omitempty"`
-	ExpoPushToken           string        `json:"expoPushToken" bson:"expoPushToken"`
+	ExpoPushToken           string        `json:"expoPushToken" bson:"expoPushToken"` // the token given when signing up, devices are registered on login
+	Devices                 []Device      `json:"devices,omitempty" bson:"devices,omitempty"`
 	ResetPassword           bool          `json:"resetPassword" bson:"resetPassword"`
 	BlockAnonymousIPAddress bool          `json:"blockAnonymousIPAddress" bson:"blockAnonymousIPAddress"`
+	NoShows                 int           `json:"noShows" bson:"noShows"`
+}
+
+// structure of a device a user gets push notifications on
+type Device struct {
+	Token      string    `json:"token" bson:"token"`
+	Platform   string    `json:"platform" bson:"platform"`
+	AppVersion string    `json:"appVersion,omitempty" bson:"appVersion,omitempty"`
+	LastSeen   time.Time `json:"lastSeen" bson:"lastSeen"`
 }
 
 type FilterUsers struct {
@@ -49,8 +60,22 @@ type Details struct {
 }
 
 type Notifications struct {
-	Invites         bool `json:"invites" bson:"invites"`
-	BookingReminder bool `json:"bookingReminder" bson:"bookingReminder"`
+	Invites         bool                  `json:"invites" bson:"invites"`
+	BookingReminder bool                  `json:"bookingReminder" bson:"bookingReminder"`
+	Channels        map[string][]string   `json:"channels,omitempty" bson:"channels,omitempty"` // the channels each notification type goes out on, push when a type is left out
+	WebhookURL      string                `json:"webhookUrl,omitempty" bson:"webhookUrl,omitempty"`
+	WebPush         []WebPushSubscription `json:"webPush,omitempty" bson:"webPush,omitempty"`
+}
+
+// structure of a browser's web push subscription
+type WebPushSubscription struct {
+	Endpoint string      `json:"endpoint" bson:"endpoint" binding:"required,url"`
+	Keys     WebPushKeys `json:"keys" bson:"keys" binding:"required"`
+}
+
+type WebPushKeys struct {
+	P256dh string `json:"p256dh" bson:"p256dh" binding:"required"`
+	Auth   string `json:"auth" bson:"auth" binding:"required"`
 }
 
 type Security struct {
@@ -70,17 +95,127 @@ type Location struct {
 
 // structure of booking
 type Booking struct {
+	ID         string             `json:"_id" bson:"_id,omitempty"`
+	OccupiID   string             `json:"occupiId" bson:"occupiId,omitempty"`
+	RoomID     string             `json:"roomId" bson:"roomId" binding:"required"`
+	RoomName   string             `json:"roomName" bson:"roomName" binding:"required"`
+	Emails     []string           `json:"emails" bson:"emails" binding:"required,dive,email"`
+	CheckedIn  bool               `json:"checkedIn" bson:"checkedIn"`
+	Creator    string             `json:"creator" bson:"creator" binding:"required,email"`
+	FloorNo    string             `json:"floorNo" bson:"floorNo" binding:"required"`
+	Date       time.Time          `json:"date" bson:"date" binding:"required"`
+	Start      time.Time          `json:"start" bson:"start" binding:"required"`
+	End        time.Time          `json:"end" bson:"end" binding:"required"`
+	SeriesID   string             `json:"seriesId" bson:"seriesId,omitempty"`
+	SiteID     string             `json:"siteId" bson:"siteId,omitempty"`
+	BuildingID string             `json:"buildingId" bson:"buildingId,omitempty"`
+	Status     string             `json:"status" bson:"status,omitempty"`         // bookings saved before approvals were added are confirmed
+	Attendees  []AttendeeResponse `json:"attendees" bson:"attendees,omitempty"`   // only attendees that responded are stored
+	CheckIns   []AttendeeCheckIn  `json:"checkIns" bson:"checkIns,omitempty"`     // checkedIn is set by the first check-in
+	CreatedBy  string             `json:"createdBy" bson:"createdBy,omitempty"`   // the user that made the booking
+	OnBehalfOf string             `json:"onBehalfOf" bson:"onBehalfOf,omitempty"` // the creator, when a delegate made the booking for them
+	// the start an occurrence of a series was created with, calendars match later changes to the occurrence by it
+	OccurrenceStart time.Time `json:"occurrenceStart" bson:"occurrenceStart,omitempty"`
+}
+
+// structure of a user letting another user make bookings for them
+type Delegation struct {
 	ID        string    `json:"_id" bson:"_id,omitempty"`
-	OccupiID  string    `json:"occupiId" bson:"occupiId,omitempty"`
-	RoomID    string    `json:"roomId" bson:"roomId" binding:"required"`
-	RoomName  string    `json:"roomName" bson:"roomName" binding:"required"`
-	Emails    []string  `json:"emails" bson:"emails" binding:"required,dive,email"`
-	CheckedIn bool      `json:"checkedIn" bson:"checkedIn"`
-	Creator   string    `json:"creator" bson:"creator" binding:"required,email"`
-	FloorNo   string    `json:"floorNo" bson:"floorNo" binding:"required"`
-	Date      time.Time `json:"date" bson:"date" binding:"required"`
-	Start     time.Time `json:"start" bson:"start" binding:"required"`
-	End       time.Time `json:"end" bson:"end" binding:"required"`
+	Principal string    `json:"principal" bson:"principal"` // the user bookings are made for
+	Delegate  string    `json:"delegate" bson:"delegate"`   // the user allowed to make them
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// structure of an attendee checking in to a booking
+type AttendeeCheckIn struct {
+	Email       string    `json:"email" bson:"email"`
+	Method      string    `json:"method" bson:"method"` // app, qr, nfc or ble
+	CheckedInAt time.Time `json:"checkedInAt" bson:"checkedInAt"`
+}
+
+// structure of an attendee's response to a booking invitation
+type AttendeeResponse struct {
+	Email       string    `json:"email" bson:"email"`
+	Response    string    `json:"response" bson:"response"`
+	RespondedAt time.Time `json:"respondedAt" bson:"respondedAt"`
+}
+
+// structure of a desk that can be booked for a morning, afternoon or the whole day
+type Desk struct {
+	ID            string   `json:"_id" bson:"_id,omitempty"`
+	DeskID        string   `json:"deskId" bson:"deskId"`
+	DeskName      string   `json:"deskName" bson:"deskName"`
+	FloorNo       string   `json:"floorNo" bson:"floorNo"`
+	BuildingID    string   `json:"buildingId" bson:"buildingId,omitempty"`
+	SiteID        string   `json:"siteId" bson:"siteId,omitempty"`
+	Neighbourhood string   `json:"neighbourhood" bson:"neighbourhood,omitempty"`
+	Amenities     []string `json:"amenities" bson:"amenities,omitempty"`
+}
+
+// structure of a desk booking, each booking holds one desk for one person
+type DeskBooking struct {
+	ID            string    `json:"_id" bson:"_id,omitempty"`
+	OccupiID      string    `json:"occupiId" bson:"occupiId"`
+	DeskID        string    `json:"deskId" bson:"deskId"`
+	DeskName      string    `json:"deskName" bson:"deskName"`
+	Email         string    `json:"email" bson:"email"`
+	Creator       string    `json:"creator" bson:"creator"`
+	FloorNo       string    `json:"floorNo" bson:"floorNo"`
+	BuildingID    string    `json:"buildingId" bson:"buildingId,omitempty"`
+	SiteID        string    `json:"siteId" bson:"siteId,omitempty"`
+	Neighbourhood string    `json:"neighbourhood" bson:"neighbourhood,omitempty"`
+	GroupID       string    `json:"groupId" bson:"groupId,omitempty"` // shared by the desks of a neighbourhood booking
+	Slot          string    `json:"slot" bson:"slot"`
+	Date          time.Time `json:"date" bson:"date"`
+	Start         time.Time `json:"start" bson:"start"`
+	End           time.Time `json:"end" bson:"end"`
+	CheckedIn     bool      `json:"checkedIn" bson:"checkedIn"`
+}
+
+// structure of a user's calendar subscription, the token is the only credential in the feed url
+type CalendarFeed struct {
+	ID        string    `json:"_id" bson:"_id,omitempty"`
+	Email     string    `json:"email" bson:"email"`
+	Token     string    `json:"token" bson:"token"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// structure of a user's caldav app password, it is kept apart from the read only calendar feed token so a shared
+// feed url cannot be used to make bookings, only a hash of the password is stored
+type CalDAVPassword struct {
+	ID           string    `json:"_id" bson:"_id,omitempty"`
+	Email        string    `json:"email" bson:"email"`
+	PasswordHash string    `json:"-" bson:"passwordHash"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// structure of a booking that was released because nobody checked in
+type NoShowBooking struct {
+	Booking    `bson:",inline"`
+	ReleasedAt time.Time `json:"releasedAt" bson:"releasedAt"`
+}
+
+// structure of a recurrence rule, modelled on the iCalendar RRULE
+type RecurrenceRule struct {
+	Frequency  string      `json:"frequency" bson:"frequency"` // daily, weekly or monthly
+	Interval   int         `json:"interval" bson:"interval"`
+	Count      int         `json:"count" bson:"count"`
+	Until      time.Time   `json:"until" bson:"until"`
+	Exceptions []time.Time `json:"exceptions" bson:"exceptions"`
+}
+
+// structure of a recurring booking series, each occurrence is stored as a booking with the series id
+type BookingSeries struct {
+	ID         string         `json:"_id" bson:"_id,omitempty"`
+	SeriesID   string         `json:"seriesId" bson:"seriesId"`
+	RoomID     string         `json:"roomId" bson:"roomId"`
+	RoomName   string         `json:"roomName" bson:"roomName"`
+	Emails     []string       `json:"emails" bson:"emails"`
+	Creator    string         `json:"creator" bson:"creator"`
+	FloorNo    string         `json:"floorNo" bson:"floorNo"`
+	Start      time.Time      `json:"start" bson:"start"`
+	End        time.Time      `json:"end" bson:"end"`
+	Recurrence RecurrenceRule `json:"recurrence" bson:"recurrence"`
 }
 
 type Cancel struct {
@@ -93,12 +228,16 @@ type Cancel struct {
 	Date      time.Time `json:"date" bson:"date" binding:"required"`
 	Start     time.Time `json:"start" bson:"start" binding:"required"`
 	End       time.Time `json:"end" bson:"end" binding:"required"`
+	Scope     string    `json:"scope" bson:"scope" binding:"omitempty,oneof=occurrence following series"`
 }
 
 // structure of CheckIn
 type CheckIn struct {
 	BookingID string `json:"bookingId" bson:"bookingId" binding:"required"`
 	Creator   string `json:"creator" bson:"creator" binding:"required,email"`
+	Type      string `json:"type" bson:"type"`       // room or desk, room bookings are checked in when left out
+	Method    string `json:"method" bson:"method"`   // app when left out, qr, nfc and ble check-ins need the room's token
+	TokenID   string `json:"tokenId" bson:"tokenId"` // the id read from the room's nfc tag or ble beacon, or the payload of its qr code
 }
 
 type OTP struct {
@@ -122,6 +261,15 @@ type Room struct {
 	Description  string    `json:"description" bson:"description"`
 	RoomName     string    `json:"roomName" bson:"roomName"`
 	RoomImage    RoomImage `json:"roomImage" bson:"roomImage"`
+	BuildingID   string    `json:"buildingId" bson:"buildingId,omitempty"`
+	SiteID       string    `json:"siteId" bson:"siteId,omitempty"`
+	Amenities    []string  `json:"amenities" bson:"amenities,omitempty"`
+	Status       string    `json:"status" bson:"status,omitempty"`
+	// bookings of rooms that require approval are pending until an approver confirms them,
+	// the approvers are the room's owners or every admin when the room has none
+	RequiresApproval bool     `json:"requiresApproval" bson:"requiresApproval,omitempty"`
+	Approvers        []string `json:"approvers" bson:"approvers,omitempty"`
+	CheckInTokens    []string `json:"-" bson:"checkInTokens,omitempty"` // ids of the qr codes, nfc tags and ble beacons in the room, only shown to admins
 }
 
 type RoomImage struct {
@@ -139,16 +287,93 @@ type ResetToken struct {
 	ExpireWhen time.Time `bson:"expireWhen"`
 }
 
+// structure of a user waiting for a room and time window to become available
+type WaitlistEntry struct {
+	ID           string    `json:"_id" bson:"_id,omitempty"`
+	WaitlistID   string    `json:"waitlistId" bson:"waitlistId"`
+	RoomID       string    `json:"roomId" bson:"roomId"`
+	RoomName     string    `json:"roomName" bson:"roomName"`
+	FloorNo      string    `json:"floorNo" bson:"floorNo"`
+	Creator      string    `json:"creator" bson:"creator"`
+	Emails       []string  `json:"emails" bson:"emails"`
+	Date         time.Time `json:"date" bson:"date"`
+	Start        time.Time `json:"start" bson:"start"`
+	End          time.Time `json:"end" bson:"end"`
+	Status       string    `json:"status" bson:"status"` // waiting, offered, accepted or expired
+	OfferExpires time.Time `json:"offerExpires" bson:"offerExpires"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
+
 type ScheduledNotification struct {
-	ID                   string    `json:"_id" bson:"_id,omitempty"`
-	NotiID               string    `json:"notiId" bson:"notiId,omitempty"`
-	Title                string    `json:"title" bson:"title"`
-	Message              string    `json:"message" bson:"message"`
-	Sent                 bool      `json:"sent" bson:"sent"`
-	SendTime             time.Time `json:"send_time" bson:"send_time"`
-	UnsentExpoPushTokens []string  `json:"unsentExpoPushTokens" bson:"unsentExpoPushTokens"`
-	Emails               []string  `json:"emails" bson:"emails"`
-	UnreadEmails         []string  `json:"unreadEmails" bson:"unreadEmails"`
+	ID                   string                 `json:"_id" bson:"_id,omitempty"`
+	NotiID               string                 `json:"notiId" bson:"notiId,omitempty"`
+	Title                string                 `json:"title" bson:"title"`
+	Message              string                 `json:"message" bson:"message"`
+	Sent                 bool                   `json:"sent" bson:"sent"`
+	SendTime             time.Time              `json:"send_time" bson:"send_time"`
+	UnsentExpoPushTokens []string               `json:"unsentExpoPushTokens" bson:"unsentExpoPushTokens"`
+	Emails               []string               `json:"emails" bson:"emails"`
+	UnreadEmails         []string               `json:"unreadEmails" bson:"unreadEmails"`
+	BookingID            string                 `json:"bookingId" bson:"bookingId,omitempty"`
+	Type                 string                 `json:"type" bson:"type,omitempty"`
+	Deliveries           []NotificationDelivery `json:"deliveries,omitempty" bson:"deliveries,omitempty"`
+}
+
+// structure of the outcome of sending a notification to a user through one channel
+type NotificationDelivery struct {
+	Channel string    `json:"channel" bson:"channel"`
+	Email   string    `json:"email,omitempty" bson:"email,omitempty"`
+	Status  string    `json:"status" bson:"status"`
+	Error   string    `json:"error,omitempty" bson:"error,omitempty"`
+	Attempt int       `json:"attempt" bson:"attempt"`
+	At      time.Time `json:"at" bson:"at"`
+}
+
+// structure of a notification that could not be sent after all its retries
+type FailedNotification struct {
+	ID           string                `json:"_id" bson:"_id,omitempty"`
+	Notification ScheduledNotification `json:"notification" bson:"notification"` // only the tokens that failed are kept
+	Reason       string                `json:"reason" bson:"reason"`
+	Attempts     int                   `json:"attempts" bson:"attempts"`
+	FailedAt     time.Time             `json:"failedAt" bson:"failedAt"`
+}
+
+// structure of the ticket expo gives for a push notification, its receipt says whether the notification reached the device
+type PushTicket struct {
+	ID        string    `json:"_id" bson:"_id,omitempty"`
+	TicketID  string    `json:"ticketId" bson:"ticketId"`
+	NotiID    string    `json:"notiId" bson:"notiId"`
+	Token     string    `json:"token" bson:"token"`
+	Status    string    `json:"status" bson:"status"`
+	Error     string    `json:"error,omitempty" bson:"error,omitempty"`
+	Message   string    `json:"message,omitempty" bson:"message,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	CheckedAt time.Time `json:"checkedAt,omitempty" bson:"checkedAt,omitempty"`
+}
+
+// structure of a receipt expo returns for a push ticket
+type PushReceipt struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details"`
+}
+
+// structure of how the push notifications sent since a time were delivered, errors are counted by the expo error code
+type PushDeliveryStats struct {
+	Since     time.Time      `json:"since"`
+	Total     int            `json:"total"`
+	Pending   int            `json:"pending"`
+	Delivered int            `json:"delivered"`
+	Failed    int            `json:"failed"`
+	Expired   int            `json:"expired"`
+	Errors    map[string]int `json:"errors"`
+}
+
+// envelope notifications are published to the queue in, the version and type say how to read the payload
+type NotificationMessage struct {
+	Version int             `json:"version"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
 }
 
 type FilterStruct struct {
@@ -165,9 +390,12 @@ type File struct {
 }
 
 type OfficeHours struct {
-	Email   string    `json:"email" bson:"email"`
-	Entered time.Time `json:"entered" bson:"entered"`
-	Exited  time.Time `json:"exited" bson:"exited"`
+	Email      string    `json:"email" bson:"email"`
+	Entered    time.Time `json:"entered" bson:"entered"`
+	Exited     time.Time `json:"exited" bson:"exited"`
+	SiteID     string    `json:"siteId" bson:"siteId,omitempty"`
+	BuildingID string    `json:"buildingId" bson:"buildingId,omitempty"`
+	FloorNo    string    `json:"floorNo" bson:"floorNo,omitempty"`
 }
 
 type AnalyticsFilterStruct struct {
@@ -192,3 +420,77 @@ type MobileUser struct {
 	Email string `json:"email" bson:"email"`
 	JWT   string `json:"jwt" bson:"jwt"`
 }
+
+// booking rules for a room or building, a document with neither id set holds the defaults for every room
+type BookingSettings struct {
+	ID          string     `json:"_id" bson:"_id,omitempty"`
+	BuildingID  string     `json:"buildingId" bson:"buildingId"`
+	RoomID      string     `json:"roomId" bson:"roomId"`
+	OpeningTime string     `json:"openingTime" bson:"openingTime"` // HH:MM
+	ClosingTime string     `json:"closingTime" bson:"closingTime"` // HH:MM
+	SlotLength  int        `json:"slotLength" bson:"slotLength"`   // minutes, 0 leaves free time unsplit
+	Buffer      int        `json:"buffer" bson:"buffer"`           // minutes kept free between bookings
+	Blackouts   []Blackout `json:"blackouts" bson:"blackouts"`
+	TimeZone    string     `json:"timeZone" bson:"-"` // timezone of the room's site, the hours are in this timezone
+}
+
+// limits on the bookings of a role, department or room, when several policies apply to a booking the
+// strictest of each limit is enforced and a limit of 0 leaves it unlimited
+type BookingPolicy struct {
+	ID                string `json:"_id" bson:"_id,omitempty"`
+	Scope             string `json:"scope" bson:"scope"`                         // role, department or room
+	Target            string `json:"target" bson:"target"`                       // the role, department number or room id
+	MaxDuration       int    `json:"maxDuration" bson:"maxDuration"`             // minutes
+	MaxAdvanceDays    int    `json:"maxAdvanceDays" bson:"maxAdvanceDays"`       // how many days ahead a booking can start
+	MaxActiveBookings int    `json:"maxActiveBookings" bson:"maxActiveBookings"` // bookings of a user that have not ended
+	WeeklyHours       int    `json:"weeklyHours" bson:"weeklyHours"`             // hours a user can book per week
+}
+
+type Blackout struct {
+	Start  time.Time `json:"start" bson:"start"`
+	End    time.Time `json:"end" bson:"end"`
+	Reason string    `json:"reason" bson:"reason"`
+}
+
+// the floor a department sits on, used to prefer nearby rooms when searching
+type Department struct {
+	ID           string `json:"_id" bson:"_id,omitempty"`
+	DepartmentNo string `json:"departmentNo" bson:"departmentNo"`
+	FloorNo      string `json:"floorNo" bson:"floorNo"`
+}
+
+// a room returned by the room search along with the slots in which it can be booked
+type RoomAvailability struct {
+	Room        `bson:",inline"`
+	Slots       []Slot            `json:"slots" bson:"-"`
+	CapacityGap int               `json:"capacityGap" bson:"capacityGap"`
+	SameFloor   bool              `json:"sameFloor" bson:"sameFloor"`
+	Bookings    []Booking         `json:"-" bson:"bookings"`
+	Settings    []BookingSettings `json:"-" bson:"settings"`
+	Site        []Site            `json:"-" bson:"site"`
+}
+
+// an office of the company, the times of everything in a site are in its timezone
+type Site struct {
+	ID       string `json:"_id" bson:"_id,omitempty"`
+	SiteID   string `json:"siteId" bson:"siteId"`
+	Name     string `json:"name" bson:"name"`
+	Address  string `json:"address" bson:"address"`
+	TimeZone string `json:"timeZone" bson:"timeZone"`
+}
+
+type Building struct {
+	ID         string `json:"_id" bson:"_id,omitempty"`
+	BuildingID string `json:"buildingId" bson:"buildingId"`
+	SiteID     string `json:"siteId" bson:"siteId"`
+	Name       string `json:"name" bson:"name"`
+	Address    string `json:"address" bson:"address"`
+}
+
+// floors are identified by their number within a building
+type Floor struct {
+	ID         string `json:"_id" bson:"_id,omitempty"`
+	BuildingID string `json:"buildingId" bson:"buildingId"`
+	FloorNo    string `json:"floorNo" bson:"floorNo"`
+	Name       string `json:"name" bson:"name"`
+}
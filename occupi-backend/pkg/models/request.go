@@ -16,16 +16,26 @@ type RegisterUser struct {
 
 // expected user structure from api requests
 type RequestUser struct {
-	Email      string `json:"email" binding:"required,email"`
-	Password   string `json:"password" binding:"required,min=8"`
-	EmployeeID string `json:"employee_id" binding:"omitempty,startswith=OCCUPI"`
-	IsTest     string `json:"test"`
+	Email         string `json:"email" binding:"required,email"`
+	Password      string `json:"password" binding:"required,min=8"`
+	EmployeeID    string `json:"employee_id" binding:"omitempty,startswith=OCCUPI"`
+	IsTest        string `json:"test"`
+	ExpoPushToken string `json:"expoPushToken" binding:"omitempty"` // registers the device being logged in on
+	AppVersion    string `json:"appVersion" binding:"omitempty"`
 }
 
 // expected structure of otp from api requests
 type RequestUserOTP struct {
-	Email string `json:"email" binding:"required,email"`
-	OTP   string `json:"otp" binding:"required,len=6"`
+	Email         string `json:"email" binding:"required,email"`
+	OTP           string `json:"otp" binding:"required,len=6"`
+	ExpoPushToken string `json:"expoPushToken" binding:"omitempty"` // registers the device being logged in on
+	AppVersion    string `json:"appVersion" binding:"omitempty"`
+}
+
+// expected structure of a device registered or unregistered for push notifications
+type RequestDevice struct {
+	ExpoPushToken string `json:"expoPushToken" binding:"required"`
+	AppVersion    string `json:"appVersion" binding:"omitempty"`
 }
 
 type RoomRequest struct {
@@ -45,6 +55,7 @@ type QueryInput struct {
 	Projection []string               `json:"projection"`
 	Limit      int64                  `json:"limit"`
 	Page       int64                  `json:"page"`
+	Tags       []string               `json:"tags"` // only used when filtering rooms
 }
 
 type ResetPassword struct {
@@ -64,6 +75,41 @@ type RequestEmails struct {
 	Emails []string `json:"emails" binding:"required"`
 }
 
+type RequestAddAttendees struct {
+	BookingID string   `json:"bookingId" binding:"required"`
+	Creator   string   `json:"creator" binding:"omitempty,email"`
+	Emails    []string `json:"emails" binding:"required,dive,email"`
+}
+
+// expected structure when updating a booking, fields that are left out are not changed
+type RequestUpdateBooking struct {
+	BookingID string    `json:"bookingId" binding:"required"`
+	Creator   string    `json:"creator" binding:"omitempty,email"`
+	RoomID    string    `json:"roomId" binding:"omitempty"`
+	RoomName  string    `json:"roomName" binding:"omitempty"`
+	FloorNo   string    `json:"floorNo" binding:"omitempty"`
+	Emails    []string  `json:"emails" binding:"omitempty,dive,email"`
+	Date      time.Time `json:"date" binding:"omitempty"`
+	Start     time.Time `json:"start" binding:"omitempty"`
+	End       time.Time `json:"end" binding:"omitempty"`
+}
+
+type RequestJoinWaitlist struct {
+	RoomID   string    `json:"roomId" binding:"required"`
+	RoomName string    `json:"roomName" binding:"required"`
+	FloorNo  string    `json:"floorNo" binding:"required"`
+	Creator  string    `json:"creator" binding:"omitempty,email"`
+	Emails   []string  `json:"emails" binding:"required,dive,email"`
+	Date     time.Time `json:"date" binding:"required"`
+	Start    time.Time `json:"start" binding:"required"`
+	End      time.Time `json:"end" binding:"required"`
+}
+
+type RequestWaitlistEntry struct {
+	WaitlistID string `json:"waitlistId" binding:"required"`
+	Creator    string `json:"creator" binding:"omitempty,email"`
+}
+
 type SecuritySettingsRequest struct {
 	Email              string `json:"email" binding:"omitempty,email"`
 	Mfa                string `json:"mfa"`
@@ -85,9 +131,11 @@ type UserDetailsRequest struct {
 }
 
 type NotificationsRequest struct {
-	Email           string `json:"email" binding:"required,email"`
-	Invites         string `json:"invites"`
-	BookingReminder string `json:"bookingReminder"`
+	Email           string              `json:"email" binding:"required,email"`
+	Invites         string              `json:"invites"`
+	BookingReminder string              `json:"bookingReminder"`
+	Channels        map[string][]string `json:"channels,omitempty"`
+	WebhookURL      *string             `json:"webhookUrl,omitempty"`
 }
 
 type ProfileImageRequest struct {
@@ -102,13 +150,18 @@ type ImageRequest struct {
 }
 
 type RequestRoom struct {
-	RoomID       string `json:"roomId" binding:"required,startswith=RM"`
-	RoomNo       string `json:"roomNo" binding:"required"`
-	FloorNo      string `json:"floorNo" binding:"required"`
-	MinOccupancy int    `json:"minOccupancy" binding:"required"`
-	MaxOccupancy int    `json:"maxOccupancy" binding:"required"`
-	Description  string `json:"description" binding:"required"`
-	RoomName     string `json:"roomName" binding:"required"`
+	RoomID           string   `json:"roomId" binding:"required,startswith=RM"`
+	RoomNo           string   `json:"roomNo" binding:"required"`
+	FloorNo          string   `json:"floorNo" binding:"required"`
+	MinOccupancy     int      `json:"minOccupancy" binding:"required"`
+	MaxOccupancy     int      `json:"maxOccupancy" binding:"required"`
+	Description      string   `json:"description" binding:"required"`
+	RoomName         string   `json:"roomName" binding:"required"`
+	Amenities        []string `json:"amenities"`
+	BuildingID       string   `json:"buildingId"`
+	RequiresApproval bool     `json:"requiresApproval"`
+	Approvers        []string `json:"approvers" binding:"omitempty,dive,email"`
+	CheckInTokens    []string `json:"checkInTokens"`
 }
 
 type WebAuthnSession struct {
@@ -129,25 +182,34 @@ type Slot struct {
 }
 
 type RequestOnsite struct {
-	Email  string `json:"email" binding:"omitempty,email"`
-	OnSite string `json:"onSite" binding:"required"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	OnSite     string `json:"onSite" binding:"required"`
+	SiteID     string `json:"siteId"`
+	BuildingID string `json:"buildingId"`
+	FloorNo    string `json:"floorNo"`
 }
 
 type RequestHours struct {
-	Email    string    `json:"email" binding:"omitempty,email"`
-	TimeFrom time.Time `json:"timeFrom" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
-	TimeTo   time.Time `json:"timeTo" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
-	Limit    int64     `json:"limit"`
-	Page     int64     `json:"page"`
+	Email      string    `json:"email" binding:"omitempty,email"`
+	TimeFrom   time.Time `json:"timeFrom" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
+	TimeTo     time.Time `json:"timeTo" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit      int64     `json:"limit"`
+	Page       int64     `json:"page"`
+	SiteID     string    `json:"siteId"`
+	BuildingID string    `json:"buildingId"`
+	FloorNo    string    `json:"floorNo"`
 }
 
 type RequestBooking struct {
-	Creator   string    `json:"creator" binding:"omitempty,email"`
-	Attendees []string  `json:"attendees" binding:"omitempty,email"`
-	TimeFrom  time.Time `json:"timeFrom" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
-	TimeTo    time.Time `json:"timeTo" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
-	Limit     int64     `json:"limit"`
-	Page      int64     `json:"page"`
+	Creator    string    `json:"creator" binding:"omitempty,email"`
+	Attendees  []string  `json:"attendees" binding:"omitempty,email"`
+	TimeFrom   time.Time `json:"timeFrom" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
+	TimeTo     time.Time `json:"timeTo" binding:"omitempty" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit      int64     `json:"limit"`
+	Page       int64     `json:"page"`
+	SiteID     string    `json:"siteId"`
+	BuildingID string    `json:"buildingId"`
+	FloorNo    string    `json:"floorNo"`
 }
 
 type RequestSpecialEvent struct {
@@ -201,3 +263,190 @@ type RoleRequest struct {
 	Email string `json:"email" binding:"required,email"`
 	Role  string `json:"role" binding:"required"`
 }
+
+type RequestBookingSettings struct {
+	BuildingID  string     `json:"buildingId"`
+	RoomID      string     `json:"roomId"`
+	OpeningTime string     `json:"openingTime" binding:"required"`
+	ClosingTime string     `json:"closingTime" binding:"required"`
+	SlotLength  int        `json:"slotLength" binding:"min=0"`
+	Buffer      int        `json:"buffer" binding:"min=0"`
+	Blackouts   []Blackout `json:"blackouts"`
+}
+
+type RequestBookingPolicy struct {
+	Scope             string `json:"scope" binding:"required,oneof=role department room"`
+	Target            string `json:"target" binding:"required"`
+	MaxDuration       int    `json:"maxDuration" binding:"min=0"`
+	MaxAdvanceDays    int    `json:"maxAdvanceDays" binding:"min=0"`
+	MaxActiveBookings int    `json:"maxActiveBookings" binding:"min=0"`
+	WeeklyHours       int    `json:"weeklyHours" binding:"min=0"`
+}
+
+type RequestDeleteBookingPolicy struct {
+	Scope  string `json:"scope" binding:"required,oneof=role department room"`
+	Target string `json:"target" binding:"required"`
+}
+
+type RequestDepartment struct {
+	DepartmentNo string `json:"departmentNo" binding:"required"`
+	FloorNo      string `json:"floorNo" binding:"required"`
+}
+
+type RequestDeleteDepartment struct {
+	DepartmentNo string `json:"departmentNo" binding:"required"`
+}
+
+type RequestRoomSearch struct {
+	Start     time.Time `json:"start" binding:"required"`
+	End       time.Time `json:"end" binding:"required"`
+	Duration  int       `json:"duration" binding:"required,min=1"` // minutes
+	Attendees int       `json:"attendees" binding:"required,min=1"`
+	FloorNo   string    `json:"floorNo"`
+	Amenities []string  `json:"amenities"`
+}
+
+type RequestUpdateRoom struct {
+	RoomID           string    `json:"roomId" binding:"required,startswith=RM"`
+	RoomNo           string    `json:"roomNo"`
+	FloorNo          string    `json:"floorNo"`
+	MinOccupancy     *int      `json:"minOccupancy"`
+	MaxOccupancy     *int      `json:"maxOccupancy"`
+	Description      string    `json:"description"`
+	RoomName         string    `json:"roomName"`
+	Amenities        *[]string `json:"amenities"`
+	BuildingID       string    `json:"buildingId"`
+	RequiresApproval *bool     `json:"requiresApproval"`
+	Approvers        *[]string `json:"approvers" binding:"omitempty,dive,email"`
+	CheckInTokens    *[]string `json:"checkInTokens"`
+}
+
+type RequestRSVP struct {
+	BookingID string `json:"bookingId" binding:"required"`
+	Response  string `json:"response" binding:"required,oneof=accepted declined tentative"`
+	Series    bool   `json:"series"` // also respond to the following occurrences of a recurring booking
+}
+
+// structure of the query of a signed rsvp link in a booking email, the confirmation page posts the same fields as a form
+type RequestRSVPLink struct {
+	BookingID string `form:"bookingId" binding:"required"`
+	Email     string `form:"email" binding:"required,email"`
+	Response  string `form:"response" binding:"required,oneof=accepted declined tentative"`
+	Series    bool   `form:"series"`
+	Expires   int64  `form:"exp" binding:"required"`
+	Signature string `form:"sig" binding:"required"`
+}
+
+// structure of the query for the qr code shown on a room's door
+type RequestRoomQRCode struct {
+	RoomID string `form:"roomId" binding:"required"`
+	Format string `form:"format" binding:"omitempty,oneof=png svg"`
+	Size   int    `form:"size" binding:"omitempty,min=64,max=1024"`
+}
+
+type RequestScanRoomQR struct {
+	Payload string `json:"payload" binding:"required"`
+}
+
+// expected structure when booking a free room on the spot, the booking starts now
+type RequestBookNow struct {
+	RoomID   string   `json:"roomId" binding:"required"`
+	Emails   []string `json:"emails" binding:"omitempty,dive,email"`
+	Duration int      `json:"duration" binding:"omitempty,min=1"` // minutes, until the room is next needed when left out
+}
+
+// expected structure when extending a booking that is in progress
+type RequestExtendBooking struct {
+	BookingID string `json:"bookingId" binding:"required"`
+	Minutes   int    `json:"minutes" binding:"required,min=1,max=1440"`
+}
+
+type RequestDelegation struct {
+	Delegate string `json:"delegate" binding:"required,email"`
+}
+
+type RequestApproveBooking struct {
+	BookingID string `json:"bookingId" binding:"required"`
+}
+
+type RequestRejectBooking struct {
+	BookingID string `json:"bookingId" binding:"required"`
+	Reason    string `json:"reason" binding:"required"`
+}
+
+type RequestRoomStatus struct {
+	RoomID string `json:"roomId" binding:"required,startswith=RM"`
+	Status string `json:"status" binding:"required,oneof=active inactive archived"`
+}
+
+// the id is generated when adding a site and required when updating one
+type RequestSite struct {
+	SiteID   string `json:"siteId"`
+	Name     string `json:"name" binding:"required"`
+	Address  string `json:"address"`
+	TimeZone string `json:"timeZone" binding:"required"`
+}
+
+// the id is generated when adding a building and required when updating one
+type RequestBuilding struct {
+	BuildingID string `json:"buildingId"`
+	SiteID     string `json:"siteId" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	Address    string `json:"address"`
+}
+
+type RequestFloor struct {
+	BuildingID string `json:"buildingId" binding:"required"`
+	FloorNo    string `json:"floorNo" binding:"required"`
+	Name       string `json:"name"`
+}
+
+type RequestSiteID struct {
+	SiteID string `json:"siteId" binding:"required"`
+}
+
+type RequestBuildingID struct {
+	BuildingID string `json:"buildingId" binding:"required"`
+}
+
+type RequestDesk struct {
+	DeskID        string   `json:"deskId" binding:"required"`
+	DeskName      string   `json:"deskName" binding:"required"`
+	FloorNo       string   `json:"floorNo" binding:"required"`
+	BuildingID    string   `json:"buildingId"`
+	Neighbourhood string   `json:"neighbourhood"`
+	Amenities     []string `json:"amenities"`
+}
+
+type RequestDeskID struct {
+	DeskID string `json:"deskId" binding:"required"`
+}
+
+type RequestDeskBooking struct {
+	DeskID string    `json:"deskId"`
+	Usual  bool      `json:"usual"` // books the desk the user has booked most often lately when no desk is given
+	Email  string    `json:"email" binding:"omitempty,email"`
+	Date   time.Time `json:"date" binding:"required"`
+	Slot   string    `json:"slot" binding:"required,oneof=morning afternoon fullday"`
+}
+
+type RequestNeighbourhoodBooking struct {
+	Neighbourhood string    `json:"neighbourhood" binding:"required"`
+	BuildingID    string    `json:"buildingId"`
+	FloorNo       string    `json:"floorNo"`
+	Emails        []string  `json:"emails" binding:"required,min=1,unique,dive,email"`
+	Date          time.Time `json:"date" binding:"required"`
+	Slot          string    `json:"slot" binding:"required,oneof=morning afternoon fullday"`
+}
+
+type RequestDeskBookingID struct {
+	BookingID string `json:"bookingId" binding:"required"`
+}
+
+type RequestReplayFailedNotification struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type RequestWebPushUnsubscribe struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
@@ -0,0 +1,319 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/SherClockHolmes/webpush-go"
+	expo "github.com/oliveroneill/exponent-server-sdk-golang/sdk"
+	"github.com/sirupsen/logrus"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/mail"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
+)
+
+// ExpoNotifier sends push notifications through Expo to each of the user's active devices
+type ExpoNotifier struct{}
+
+func (ExpoNotifier) Channel() string {
+	return constants.ChannelPush
+}
+
+func (ExpoNotifier) Notify(appsession *models.AppSession, user models.User, notification models.ScheduledNotification) error {
+	tokens := utils.ActivePushTokens(user, time.Now().AddDate(0, 0, -configs.GetDeviceActiveDays()))
+	if len(tokens) == 0 {
+		return ErrNoRecipient
+	}
+
+	retry := []string{}
+	var errs []error
+	for _, token := range tokens {
+		if err := pushToToken(appsession, notification, token); err != nil {
+			if isRetryable(err) {
+				retry = append(retry, token)
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	if len(retry) > 0 {
+		return &RetryError{Tokens: retry, Err: errors.Join(errs...)}
+	}
+	return errors.Join(errs...)
+}
+
+// PushToExpoTokens sends a notification to each of its expo tokens and returns the tokens that are worth retrying,
+// which are the ones expo could not be reached for or that were rate limited, with the last error
+func PushToExpoTokens(notification models.ScheduledNotification, appsession *models.AppSession) ([]string, error) {
+	failed := []string{}
+	var lastErr error
+
+	for _, token := range notification.UnsentExpoPushTokens {
+		err := pushToToken(appsession, notification, token)
+		if err != nil && isRetryable(err) {
+			failed = append(failed, token)
+			lastErr = err
+		}
+	}
+
+	return failed, lastErr
+}
+
+func pushToToken(appsession *models.AppSession, notification models.ScheduledNotification, token string) error {
+	// To check the token is valid
+	pushToken, err := expo.NewExponentPushToken(token)
+	if err != nil {
+		logrus.Error("Failed to create push token: ", err)
+		return err
+	}
+
+	// Publish message
+	response, err := appsession.ExpoClient.Publish(
+		&expo.PushMessage{
+			To:       []expo.ExponentPushToken{pushToken},
+			Body:     notification.Message,
+			Sound:    "default",
+			Title:    notification.Title,
+			Priority: expo.DefaultPriority,
+		},
+	)
+
+	// Check errors
+	if err != nil {
+		logrus.Error("Failed to send notification: ", err)
+		return &expoUnreachableError{err: err}
+	}
+
+	// Validate responses
+	if err := response.ValidateResponse(); err != nil {
+		logrus.Error("Failed to validate response: ", response.PushMessage.To, " failed")
+		// expo will never deliver to the device again, so stop sending to it
+		var notRegistered *expo.DeviceNotRegisteredError
+		if errors.As(err, &notRegistered) {
+			if err := database.RemoveExpoPushToken(context.Background(), appsession, token); err != nil {
+				logrus.Error("Failed to remove unregistered push token: ", err)
+			}
+		}
+		return err
+	}
+
+	// keep the ticket so the receipt poller can check the notification reached the device
+	if response.ID != "" {
+		ticket := models.PushTicket{
+			TicketID:  response.ID,
+			NotiID:    notification.NotiID,
+			Token:     token,
+			Status:    constants.TicketPending,
+			CreatedAt: time.Now(),
+		}
+		if err := database.AddPushTicket(context.Background(), appsession, ticket); err != nil {
+			logrus.Error("Failed to save push ticket: ", err)
+		}
+	}
+
+	return nil
+}
+
+// wraps the errors of requests that never got a response from expo so they can be told apart from rejected tokens
+type expoUnreachableError struct {
+	err error
+}
+
+func (e *expoUnreachableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *expoUnreachableError) Unwrap() error {
+	return e.err
+}
+
+// expo could not be reached or rate limited the device, other failures such as unregistered devices will fail again
+func isRetryable(err error) bool {
+	var unreachable *expoUnreachableError
+	var rateLimited *expo.MessageRateExceededError
+	return errors.As(err, &unreachable) || errors.As(err, &rateLimited)
+}
+
+// EmailNotifier sends notifications to the user's email address
+type EmailNotifier struct{}
+
+func (EmailNotifier) Channel() string {
+	return constants.ChannelEmail
+}
+
+func (EmailNotifier) Notify(appsession *models.AppSession, user models.User, notification models.ScheduledNotification) error {
+	if user.Email == "" {
+		return ErrNoRecipient
+	}
+
+	return mail.SendMail(appsession, user.Email, notification.Title, utils.FormatNotificationEmailBody(notification.Title, notification.Message))
+}
+
+// WebhookNotifier posts notifications as json to the url the user set in their notification settings
+type WebhookNotifier struct {
+	// the client webhooks are posted with, webhookClient when nil
+	Client *http.Client
+}
+
+// only lets webhooks reach public addresses so users cannot make the server call its own network
+var webhookClient = NewWebhookClient(utils.IsPublicIP)
+
+// returned when a webhook url resolves to an address it may not be posted to
+var ErrWebhookAddress = errors.New("webhook address is not allowed")
+
+// NewWebhookClient returns a client that does not follow redirects or use a proxy and only connects to the
+// addresses allowed accepts, the address is checked after it is resolved so hostnames cannot point elsewhere
+func NewWebhookClient(allowed func(ip net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return ErrWebhookAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (WebhookNotifier) Channel() string {
+	return constants.ChannelWebhook
+}
+
+func (n WebhookNotifier) Notify(appsession *models.AppSession, user models.User, notification models.ScheduledNotification) error {
+	if user.Notifications.WebhookURL == "" {
+		return ErrNoRecipient
+	}
+
+	body, err := json.Marshal(webhookPayload(user, notification))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(configs.GetWebhookTimeout())*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, user.Notifications.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Occupi-Webhook")
+
+	client := n.Client
+	if client == nil {
+		client = webhookClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+// the json posted to a user's webhook
+func webhookPayload(user models.User, notification models.ScheduledNotification) map[string]interface{} {
+	return map[string]interface{}{
+		"notiId":    notification.NotiID,
+		"type":      notification.Type,
+		"title":     notification.Title,
+		"message":   notification.Message,
+		"email":     user.Email,
+		"bookingId": notification.BookingID,
+		"sendTime":  notification.SendTime,
+	}
+}
+
+// WebPushNotifier sends notifications to the browsers the user subscribed with web push, signed with the VAPID keys
+type WebPushNotifier struct{}
+
+func (WebPushNotifier) Channel() string {
+	return constants.ChannelWebPush
+}
+
+func (WebPushNotifier) Notify(appsession *models.AppSession, user models.User, notification models.ScheduledNotification) error {
+	if len(user.Notifications.WebPush) == 0 {
+		return ErrNoRecipient
+	}
+
+	if configs.GetVAPIDPublicKey() == "" || configs.GetVAPIDPrivateKey() == "" {
+		return errors.New("web push is not configured")
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"title":     notification.Title,
+		"body":      notification.Message,
+		"type":      notification.Type,
+		"bookingId": notification.BookingID,
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, subscription := range user.Notifications.WebPush {
+		response, err := webpush.SendNotification(payload, &webpush.Subscription{
+			Endpoint: subscription.Endpoint,
+			Keys:     webpush.Keys{Auth: subscription.Keys.Auth, P256dh: subscription.Keys.P256dh},
+		}, &webpush.Options{
+			Subscriber:      configs.GetVAPIDSubject(),
+			VAPIDPublicKey:  configs.GetVAPIDPublicKey(),
+			VAPIDPrivateKey: configs.GetVAPIDPrivateKey(),
+			TTL:             3600,
+			// endpoints come from the browser, so they get the same address checks as webhooks
+			HTTPClient: webhookClient,
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		response.Body.Close()
+
+		switch {
+		case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+			// the browser unsubscribed, so stop sending to it
+			if err := database.RemoveWebPushSubscription(context.Background(), appsession, user.Email, subscription.Endpoint); err != nil {
+				logrus.Error("Failed to remove expired web push subscription: ", err)
+			}
+			errs = append(errs, errors.New("web push subscription has expired"))
+		case response.StatusCode < 200 || response.StatusCode > 299:
+			errs = append(errs, fmt.Errorf("push service responded with status %d", response.StatusCode))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
+)
+
+// Notifier delivers notifications to users through one channel
+type Notifier interface {
+	// the channel users choose in their notification settings
+	Channel() string
+	// sends a notification to a user, ErrNoRecipient means the user has nowhere to get it on this channel
+	Notify(appsession *models.AppSession, user models.User, notification models.ScheduledNotification) error
+}
+
+// returned by a notifier when a user has not set up the channel, for example has no webhook url
+var ErrNoRecipient = errors.New("user has not set up this channel")
+
+// returned by a notifier when sending to some of a user's devices failed but is worth retrying
+type RetryError struct {
+	Tokens []string
+	Err    error
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+var notifiers = map[string]Notifier{}
+
+func init() {
+	Register(ExpoNotifier{})
+	Register(EmailNotifier{})
+	Register(WebhookNotifier{})
+	Register(WebPushNotifier{})
+}
+
+// Register adds a notifier, replacing the one registered for the same channel
+func Register(notifier Notifier) {
+	notifiers[notifier.Channel()] = notifier
+}
+
+// Dispatch sends a notification to each of its recipients on the channels they chose for its type and records
+// how each delivery went, it returns the push tokens that failed but are worth retrying
+func Dispatch(ctx context.Context, appsession *models.AppSession, notification models.ScheduledNotification) ([]string, error) {
+	users, err := database.GetNotificationRecipients(ctx, appsession, notification.Emails)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := []string{}
+	deliveries := []models.NotificationDelivery{}
+	for _, user := range users {
+		for _, channel := range utils.NotificationChannels(user, notification.Type) {
+			notifier, ok := notifiers[channel]
+			if !ok {
+				continue
+			}
+
+			delivery := models.NotificationDelivery{
+				Channel: channel,
+				Email:   user.Email,
+				Status:  constants.DeliverySent,
+				Attempt: 1,
+				At:      time.Now(),
+			}
+
+			var retryErr *RetryError
+			err := notifier.Notify(appsession, user, notification)
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrNoRecipient):
+				delivery.Status = constants.DeliverySkipped
+			case errors.As(err, &retryErr):
+				delivery.Status = constants.DeliveryRetrying
+				delivery.Error = err.Error()
+				retry = append(retry, retryErr.Tokens...)
+			default:
+				logrus.Error("Failed to send ", channel, " notification: ", err)
+				delivery.Status = constants.DeliveryFailed
+				delivery.Error = err.Error()
+			}
+
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	if err := database.RecordNotificationDeliveries(ctx, appsession, notification.NotiID, deliveries); err != nil {
+		logrus.Error("Failed to record notification deliveries: ", err)
+	}
+
+	return retry, nil
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+)
+
+// FetchPushReceipts gets the receipts expo has for push tickets by their ticket id, tickets expo has no receipt
+// for yet are left out
+func FetchPushReceipts(ctx context.Context, ticketIDs []string) (map[string]models.PushReceipt, error) {
+	body, err := json.Marshal(map[string][]string{"ids": ticketIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, configs.GetExpoReceiptsURL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("expo responded with status %d", response.StatusCode)
+	}
+
+	var result struct {
+		Data   map[string]models.PushReceipt `json:"data"`
+		Errors []map[string]interface{}      `json:"errors"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("expo rejected the receipts request: %v", result.Errors)
+	}
+
+	if result.Data == nil {
+		result.Data = map[string]models.PushReceipt{}
+	}
+	return result.Data, nil
+}
@@ -0,0 +1,112 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	expo "github.com/oliveroneill/exponent-server-sdk-golang/sdk"
+	"github.com/sirupsen/logrus"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/notifier"
+)
+
+// expo has most receipts ready 15 minutes after sending and keeps them for a day
+var (
+	receiptDelay  = 15 * time.Minute
+	receiptExpiry = 24 * time.Hour
+)
+
+// StartReceiptPoller periodically checks the receipts of the push notifications sent through expo
+func StartReceiptPoller(appsession *models.AppSession) {
+	ticker := time.NewTicker(time.Duration(configs.GetReceiptPollInterval()) * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		PollPushReceipts(appsession)
+	}
+}
+
+// PollPushReceipts reconciles the push tickets old enough to have a receipt with what expo says happened to them
+func PollPushReceipts(appsession *models.AppSession) {
+	ctx := context.Background()
+
+	tickets, err := database.GetPendingPushTickets(ctx, appsession, time.Now().Add(-receiptDelay))
+	if err != nil {
+		logrus.Error("Failed to get pending push tickets: ", err)
+		return
+	}
+
+	for start := 0; start < len(tickets); start += constants.ExpoReceiptBatch {
+		batch := tickets[start:min(start+constants.ExpoReceiptBatch, len(tickets))]
+
+		ids := make([]string, 0, len(batch))
+		for _, ticket := range batch {
+			ids = append(ids, ticket.TicketID)
+		}
+
+		receipts, err := notifier.FetchPushReceipts(ctx, ids)
+		if err != nil {
+			// the tickets stay pending and are checked on the next poll
+			logrus.Error("Failed to fetch push receipts: ", err)
+			return
+		}
+
+		for _, ticket := range batch {
+			receipt, ok := receipts[ticket.TicketID]
+			ReconcilePushTicket(ctx, appsession, ticket, receipt, ok)
+		}
+	}
+}
+
+// ReconcilePushTicket records the receipt of a push ticket, a delivered notification no longer counts the token as
+// unsent, a failed one is recorded on the notification and a token whose device is gone is removed from its user
+func ReconcilePushTicket(ctx context.Context, appsession *models.AppSession, ticket models.PushTicket, receipt models.PushReceipt, found bool) {
+	if !found {
+		// expo drops receipts after a day, so one that never turned up will not
+		if time.Since(ticket.CreatedAt) > receiptExpiry {
+			if err := database.ResolvePushTicket(ctx, appsession, ticket.TicketID, constants.TicketExpired, "", ""); err != nil {
+				logrus.Error("Failed to expire push ticket: ", err)
+			}
+		}
+		return
+	}
+
+	if receipt.Status == expo.SuccessStatus {
+		if err := database.ResolvePushTicket(ctx, appsession, ticket.TicketID, constants.TicketDelivered, "", ""); err != nil {
+			logrus.Error("Failed to resolve push ticket: ", err)
+		}
+		if err := database.PullUnsentPushToken(ctx, appsession, ticket.NotiID, ticket.Token); err != nil {
+			logrus.Error("Failed to update notification: ", err)
+		}
+		return
+	}
+
+	errorCode, _ := receipt.Details["error"].(string)
+	if err := database.ResolvePushTicket(ctx, appsession, ticket.TicketID, constants.TicketFailed, errorCode, receipt.Message); err != nil {
+		logrus.Error("Failed to resolve push ticket: ", err)
+	}
+
+	delivery := models.NotificationDelivery{
+		Channel: constants.ChannelPush,
+		Status:  constants.DeliveryFailed,
+		Error:   receipt.Message,
+		At:      time.Now(),
+	}
+	if errorCode != "" {
+		delivery.Error = fmt.Sprintf("%s: %s", errorCode, receipt.Message)
+	}
+	if err := database.RecordNotificationDeliveries(ctx, appsession, ticket.NotiID, []models.NotificationDelivery{delivery}); err != nil {
+		logrus.Error("Failed to record notification delivery: ", err)
+	}
+
+	if errorCode == expo.ErrorDeviceNotRegistered {
+		if err := database.RemoveExpoPushToken(ctx, appsession, ticket.Token); err != nil {
+			logrus.Error("Failed to remove unregistered push token: ", err)
+		}
+	}
+}
@@ -2,22 +2,48 @@ package receiver
 
 import (
 	"context"
-	"strings"
+	"errors"
 	"time"
 
-	expo "github.com/oliveroneill/exponent-server-sdk-golang/sdk"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
 
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/notifier"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/sender"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 )
 
+// a notification that is due and waiting for a worker to send it
+type notificationJob struct {
+	delivery     amqp.Delivery
+	notification models.ScheduledNotification
+	attempt      int
+}
+
 func StartConsumeMessage(appsession *models.AppSession) {
+	workers := configs.GetNotificationWorkers()
+
+	// messages held until they are due are unacknowledged, so leave room for them on top of the ones being sent
+	if err := appsession.RabbitCh.Qos(workers*10, 0, false); err != nil {
+		logrus.Error("Failed to set prefetch: ", err)
+		return
+	}
+
+	// notifications that were still queued by an older version of the server are moved to the notification queue
+	if moved, err := sender.MoveLegacyMessages(appsession); err != nil {
+		logrus.Error("Failed to move notifications from the legacy queue: ", err)
+	} else if moved > 0 {
+		logrus.Info("Moved ", moved, " notifications from the legacy queue")
+	}
+
 	msgs, err := appsession.RabbitCh.Consume(
 		appsession.RabbitQ.Name,
 		"",
-		true,
+		false,
 		false,
 		false,
 		false,
@@ -28,7 +54,8 @@ func StartConsumeMessage(appsession *models.AppSession) {
 		return
 	}
 
-	// check if there are any unsent notifications in the database
+	// check if there are any unsent notifications in the database, they are published again in case their
+	// message was lost and claiming them makes sure one that is still queued is not sent twice
 	notifications, err := database.GetScheduledNotifications(context.Background(), appsession)
 
 	if err != nil {
@@ -37,97 +64,180 @@ func StartConsumeMessage(appsession *models.AppSession) {
 
 	go func() {
 		for _, notification := range notifications {
-			NotificationSendingLogic(notification, appsession)
+			if err := sender.PublishMessage(appsession, notification); err != nil {
+				logrus.Error("Failed to publish message because: ", err)
+			}
 		}
 	}()
 
+	jobs := make(chan notificationJob)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				processNotification(appsession, job)
+			}
+		}()
+	}
+
 	go func() {
 		for d := range msgs {
-			parts := strings.Split(string(d.Body), "|")
-			if len(parts) != 8 {
-				continue
-			}
-			ID, notiID, title, message, sendTimeStr, unsentExpoTokens, emails, unreadEmails := parts[0], parts[1], parts[2], parts[3], parts[4], parts[5], parts[6], parts[7]
-			sendTime, err := time.Parse(time.RFC3339, sendTimeStr)
-			if err != nil {
-				continue
-			}
+			scheduleNotification(appsession, d, jobs)
+		}
+	}()
+}
 
-			notification := models.ScheduledNotification{
-				ID:                   ID,
-				NotiID:               notiID,
-				Title:                title,
-				Message:              message,
-				SendTime:             sendTime,
-				UnsentExpoPushTokens: utils.ConvertCommaDelimitedStringToArray(unsentExpoTokens),
-				Emails:               utils.ConvertCommaDelimitedStringToArray(emails),
-				UnreadEmails:         utils.ConvertCommaDelimitedStringToArray(unreadEmails),
-			}
+// scheduleNotification moves a message that is not due yet to a delay queue, holds one that is due within the
+// shortest delay for the rest of the wait and hands one that is due to the workers
+func scheduleNotification(appsession *models.AppSession, d amqp.Delivery, jobs chan<- notificationJob) {
+	notification, err := utils.DecodeNotificationMessage(d.Body, d.ContentType)
+	if err != nil {
+		// a message that cannot be read is dropped rather than delivered with corrupted fields
+		logrus.WithFields(logrus.Fields{
+			"messageId":   d.MessageId,
+			"contentType": d.ContentType,
+			"size":        len(d.Body),
+		}).Error("Rejected malformed notification message: ", err)
+		if err := d.Reject(false); err != nil {
+			logrus.Error("Failed to reject message: ", err)
+		}
+		return
+	}
+
+	attempt, notBefore := utils.DecodeNotificationHeaders(d.Headers)
+	due := notification.SendTime
+	if notBefore.After(due) {
+		due = notBefore
+	}
+	wait := time.Until(due)
 
-			NotificationSendingLogic(notification, appsession)
+	if step := utils.NotificationDelayStep(wait, configs.NotificationDelaySteps); step > 0 {
+		err := sender.DelayMessage(appsession, d, step)
+		if err == nil {
+			if err := d.Ack(false); err != nil {
+				logrus.Error("Failed to acknowledge message: ", err)
+			}
+			return
 		}
-	}()
+		// keep the message and wait for it in memory rather than lose it
+		logrus.Error("Failed to delay notification: ", err)
+	}
+
+	job := notificationJob{delivery: d, notification: notification, attempt: attempt}
+	if wait > 0 {
+		time.AfterFunc(wait, func() { jobs <- job })
+		return
+	}
+	jobs <- job
 }
 
-func NotificationSendingLogic(notification models.ScheduledNotification, appsession *models.AppSession) {
-	// to account for discrepancies in time, we should allow for a range of 5 seconds before and after the scheduled time
-	// whereby we can send the notification, after that, we should discard the notification, else if there
-	// is still more than 5 seconds before the scheduled time, we should wait until the time is right
-	now := time.Now().In(time.Local)
+// processNotification sends a due notification, retrying the tokens that failed or dead-lettering the
+// notification once it has run out of attempts, the message is only acknowledged once this is done
+func processNotification(appsession *models.AppSession, job notificationJob) {
+	ctx := context.Background()
+	notification := job.notification
 
-	switch {
-	case now.After(notification.SendTime.Add(-5*time.Second)) && now.Before(notification.SendTime.Add(5*time.Second)):
-		err := SendPushNotification(notification, appsession)
-		if err != nil {
-			logrus.Error("Failed to send push notification: ", err)
+	defer func() {
+		if err := job.delivery.Ack(false); err != nil {
+			logrus.Error("Failed to acknowledge message: ", err)
 		}
-	case now.Before(notification.SendTime.Add(-5 * time.Second)):
-		// wait until the time is right
-		time.Sleep(time.Until(notification.SendTime))
-		err := SendPushNotification(notification, appsession)
-		if err != nil {
-			logrus.Error("Failed to send push notification: ", err)
+	}()
+
+	// retries were claimed when they were first sent
+	if job.attempt == 0 {
+		// the notification may have been sent already, rescheduled or removed
+		if notification.ID != "" {
+			claimed, err := database.ClaimNotification(ctx, appsession, notification.ID)
+			if err != nil {
+				logrus.Error("Failed to claim notification: ", err)
+			} else if !claimed {
+				return
+			}
 		}
-	default:
-		// just mark the notification as sent
-		err := database.MarkNotificationAsSent(context.Background(), appsession, notification.ID)
-		if err != nil {
-			logrus.Error("Failed to update notification: ", err)
+
+		// late notifications, for example ones that were queued while the server was down, are still sent unless
+		// they are past the staleness window, then they are dead-lettered so an admin can replay them
+		staleAfter := time.Duration(configs.GetNotificationStaleAfter()) * time.Second
+		if utils.IsNotificationStale(notification, time.Now(), staleAfter) {
+			failedNotification := models.FailedNotification{
+				Notification: notification,
+				Reason:       "notification is stale",
+				Attempts:     0,
+				FailedAt:     time.Now(),
+			}
+			if err := database.AddFailedNotification(ctx, appsession, failedNotification); err != nil {
+				logrus.Error("Failed to dead-letter notification: ", err)
+			}
+			return
 		}
 	}
-}
 
-func SendPushNotification(notification models.ScheduledNotification, appsession *models.AppSession) error {
-	for _, token := range notification.UnsentExpoPushTokens {
-		// To check the token is valid
-		pushToken, err := expo.NewExponentPushToken(token)
+	// a notification with a type goes out on the channels each recipient chose on its first attempt, retries and
+	// notifications from before channels existed only go to the push tokens they carry
+	dispatched := false
+	var failed []string
+	var err error
+	if job.attempt == 0 && notification.Type != "" {
+		failed, err = notifier.Dispatch(ctx, appsession, notification)
 		if err != nil {
-			logrus.Error("Failed to create push token: ", err)
-			continue
+			logrus.Error("Failed to dispatch notification, falling back to push: ", err)
+		} else {
+			dispatched = true
 		}
+	}
+	if !dispatched {
+		failed, err = notifier.PushToExpoTokens(notification, appsession)
+	}
 
-		// Publish message
-		response, err := appsession.ExpoClient.Publish(
-			&expo.PushMessage{
-				To:       []expo.ExponentPushToken{pushToken},
-				Body:     notification.Message,
-				Sound:    "default",
-				Title:    notification.Title,
-				Priority: expo.DefaultPriority,
-			},
-		)
-
-		// Check errors
+	attempt := job.attempt + 1
+	status := constants.DeliverySent
+	defer func() {
+		// deliveries of a dispatched notification were recorded per recipient by the dispatch
+		if dispatched && status != constants.DeliveryFailed {
+			return
+		}
+		delivery := models.NotificationDelivery{Channel: constants.ChannelPush, Status: status, Attempt: attempt, At: time.Now()}
 		if err != nil {
-			logrus.Error("Failed to send notification: ", err)
-			continue
+			delivery.Error = err.Error()
 		}
+		if err := database.RecordNotificationDeliveries(ctx, appsession, notification.NotiID, []models.NotificationDelivery{delivery}); err != nil {
+			logrus.Error("Failed to record notification delivery: ", err)
+		}
+	}()
+
+	if len(failed) == 0 {
+		return
+	}
+	if err == nil {
+		err = errors.New("failed to send push notification")
+	}
 
-		// Validate responses
-		if response.ValidateResponse() != nil {
-			logrus.Error("Failed to validate response: ", response.PushMessage.To, " failed")
-			continue
+	notification.UnsentExpoPushTokens = failed
+	if attempt < configs.GetNotificationMaxAttempts() {
+		delay := utils.NotificationRetryDelay(attempt, time.Duration(configs.GetNotificationRetryBase())*time.Second)
+		retryErr := sender.PublishRetry(appsession, notification, attempt, time.Now().Add(delay))
+		if retryErr == nil {
+			status = constants.DeliveryRetrying
+			return
 		}
+		logrus.Error("Failed to schedule notification retry: ", retryErr)
+		err = retryErr
+	}
+
+	status = constants.DeliveryFailed
+	failedNotification := models.FailedNotification{
+		Notification: notification,
+		Reason:       err.Error(),
+		Attempts:     attempt,
+		FailedAt:     time.Now(),
+	}
+	if err := database.AddFailedNotification(ctx, appsession, failedNotification); err != nil {
+		logrus.Error("Failed to dead-letter notification: ", err)
+	}
+}
+
+func SendPushNotification(notification models.ScheduledNotification, appsession *models.AppSession) error {
+	if failed, err := notifier.PushToExpoTokens(notification, appsession); len(failed) > 0 {
+		logrus.Error("Failed to send notification to ", len(failed), " devices: ", err)
 	}
 
 	// if notification id is invalid or empty, return
@@ -0,0 +1,80 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
+)
+
+// StartNoShowSweeper periodically releases bookings that nobody checked into
+func StartNoShowSweeper(appsession *models.AppSession) {
+	ticker := time.NewTicker(time.Duration(configs.GetNoShowSweepInterval()) * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		SweepNoShows(appsession)
+	}
+}
+
+// SweepNoShows releases the no-show bookings, lets their creators know and offers the freed up slots to the waitlist,
+// slots whose waitlist offers ran out are offered to the next person in line
+func SweepNoShows(appsession *models.AppSession) {
+	if err := database.ReofferExpiredWaitlistOffers(context.Background(), appsession); err != nil {
+		logrus.Error("Failed to offer expired waitlist offers again because: ", err)
+	}
+
+	gracePeriod := time.Duration(configs.GetNoShowGracePeriod()) * time.Second
+
+	released, err := database.ReleaseNoShowBookings(context.Background(), appsession, gracePeriod)
+	if err != nil {
+		logrus.Error("Failed to release no-show bookings: ", err)
+	}
+
+	for _, booking := range released {
+		if err := NotifyNoShow(appsession, booking, gracePeriod); err != nil {
+			logrus.Error("Failed to notify creator of no-show: ", err)
+		}
+
+		if err := database.OfferSlotToWaitlist(context.Background(), appsession, booking); err != nil {
+			logrus.Error("Failed to offer slot to waitlist because: ", err)
+		}
+	}
+}
+
+// NotifyNoShow sends the creator of a released booking a push notification
+func NotifyNoShow(appsession *models.AppSession, booking models.Booking, gracePeriod time.Duration) error {
+	ctx := context.Background()
+
+	tokens, err := database.GetUsersPushTokens(ctx, appsession, []string{booking.Creator})
+	if err != nil {
+		return err
+	}
+
+	tokenArr, err := utils.ConvertTokensToStringArray(tokens, "expoPushToken")
+	if err != nil {
+		return err
+	}
+
+	notification := models.ScheduledNotification{
+		NotiID:               utils.GenerateUUID(),
+		Title:                "Booking Released",
+		Message:              utils.ConstructNoShowString(booking.RoomName, fmt.Sprintf("%d mins", int(gracePeriod.Minutes()))),
+		Sent:                 false,
+		SendTime:             time.Now(),
+		Emails:               []string{booking.Creator},
+		UnsentExpoPushTokens: tokenArr,
+		UnreadEmails:         []string{booking.Creator},
+		Type:                 constants.NotificationReleased,
+	}
+
+	_, err = database.AddNotification(ctx, appsession, notification, true)
+	return err
+}
@@ -37,6 +37,11 @@ func OccupiRouter(router *gin.Engine, appsession *models.AppSession) {
 		api.POST("/book-room", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.BookRoom(ctx, appsession) })
 		api.POST("/check-in", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.CheckIn(ctx, appsession) })
 		api.POST("/cancel-booking", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.CancelBooking(ctx, appsession) })
+		api.POST("/update-booking", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.UpdateBooking(ctx, appsession) })
+		api.POST("/join-waitlist", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.JoinWaitlist(ctx, appsession) })
+		api.POST("/leave-waitlist", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.LeaveWaitlist(ctx, appsession) })
+		api.POST("/accept-waitlist-offer", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.AcceptWaitlistOffer(ctx, appsession) })
+		api.POST("/add-attendees", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.AddAttendees(ctx, appsession) })
 		api.GET("/view-bookings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.FilterCollection(ctx, appsession, "RoomBooking") })
 		api.GET("/view-rooms", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.FilterCollection(ctx, appsession, "Rooms") })
 		api.GET("/user-details", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetUserDetails(ctx, appsession) })
@@ -49,6 +54,12 @@ func OccupiRouter(router *gin.Engine, appsession *models.AppSession) {
 		api.GET("/update-notification-settings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.UpdateNotificationSettings(ctx, appsession) })
 		api.GET("/get-security-settings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetSecuritySettings(ctx, appsession) })
 		api.GET("/get-notification-settings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetNotificationSettings(ctx, appsession) })
+		api.GET("/web-push-public-key", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetWebPushPublicKey(ctx, appsession) })
+		api.POST("/subscribe-web-push", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.SubscribeWebPush(ctx, appsession) })
+		api.DELETE("/unsubscribe-web-push", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.UnsubscribeWebPush(ctx, appsession) })
+		api.POST("/register-device", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.RegisterDevice(ctx, appsession) })
+		api.DELETE("/unregister-device", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.UnregisterDevice(ctx, appsession) })
+		api.GET("/get-devices", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetDevices(ctx, appsession) })
 		// limit request body size to 16MB when uploading profile image due to mongoDB document size limit
 		api.POST("/upload-profile-image", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.LimitRequestBodySize(16<<20), func(ctx *gin.Context) { handlers.UploadProfileImage(ctx, appsession) })
 		api.GET("/download-profile-image", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.DownloadProfileImage(ctx, appsession) })
@@ -57,7 +68,53 @@ func OccupiRouter(router *gin.Engine, appsession *models.AppSession) {
 		api.POST("/upload-room-image", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, middleware.LimitRequestBodySize(16<<20), func(ctx *gin.Context) { handlers.UploadRoomImage(ctx, appsession) })
 		api.DELETE("/delete-room-image", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.DeleteRoomImage(ctx, appsession) })
 		api.PUT("/add-room", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.AddRoom(ctx, appsession) })
+		api.PUT("/update-room", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateRoom(ctx, appsession) })
+		api.PUT("/update-room-status", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateRoomStatus(ctx, appsession) })
+		api.GET("/get-room-check-in-tokens", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetRoomCheckInTokens(ctx, appsession) })
+		api.PUT("/add-site", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.AddSite(ctx, appsession) })
+		api.PUT("/update-site", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateSite(ctx, appsession) })
+		api.DELETE("/delete-site", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.DeleteSite(ctx, appsession) })
+		api.PUT("/add-building", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.AddBuilding(ctx, appsession) })
+		api.PUT("/update-building", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateBuilding(ctx, appsession) })
+		api.DELETE("/delete-building", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.DeleteBuilding(ctx, appsession) })
+		api.PUT("/add-floor", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.AddFloor(ctx, appsession) })
+		api.PUT("/update-floor", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateFloor(ctx, appsession) })
+		api.DELETE("/delete-floor", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.DeleteFloor(ctx, appsession) })
+		api.GET("/view-sites", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.FilterCollection(ctx, appsession, "Sites") })
+		api.GET("/view-buildings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.FilterCollection(ctx, appsession, "Buildings") })
+		api.GET("/view-floors", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.FilterCollection(ctx, appsession, "Floors") })
+		api.PUT("/add-desk", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.AddDesk(ctx, appsession) })
+		api.DELETE("/delete-desk", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.DeleteDesk(ctx, appsession) })
+		api.GET("/view-desks", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.FilterCollection(ctx, appsession, "Desks") })
+		api.GET("/view-desk-bookings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.FilterCollection(ctx, appsession, "DeskBooking") })
+		api.GET("/available-desks", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetAvailableDesks(ctx, appsession) })
+		api.POST("/book-desk", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.BookDesk(ctx, appsession) })
+		api.POST("/book-neighbourhood", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.BookNeighbourhood(ctx, appsession) })
+		api.POST("/cancel-desk-booking", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.CancelDeskBooking(ctx, appsession) })
+		api.POST("/respond-to-booking", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.RespondToBooking(ctx, appsession) })
+		api.POST("/approve-booking", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.ApproveBooking(ctx, appsession) })
+		api.POST("/reject-booking", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.RejectBooking(ctx, appsession) })
+		api.GET("/view-pending-bookings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.ViewPendingBookings(ctx, appsession) })
+		api.GET("/room-qr-code", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetRoomQRCode(ctx, appsession) })
+		api.POST("/grant-delegation", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GrantDelegation(ctx, appsession) })
+		api.POST("/revoke-delegation", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.RevokeDelegation(ctx, appsession) })
+		api.GET("/view-delegations", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.ViewDelegations(ctx, appsession) })
+		api.POST("/book-now", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.BookNow(ctx, appsession) })
+		api.POST("/extend-booking", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.ExtendBooking(ctx, appsession) })
+		api.POST("/scan-room-qr", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.ScanRoomQRCode(ctx, appsession) })
+		api.GET("/calendar-feed-url", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetCalendarFeedURL(ctx, appsession) })
+		api.POST("/reset-calendar-feed-url", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.ResetCalendarFeedURL(ctx, appsession) })
+		api.POST("/reset-caldav-password", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.ResetCalDAVPassword(ctx, appsession) })
 		api.GET("/available-slots", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetAvailableSlots(ctx, appsession) })
+		api.GET("/search-rooms", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.SearchAvailableRooms(ctx, appsession) })
+		api.POST("/update-booking-settings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateBookingSettings(ctx, appsession) })
+		api.GET("/get-booking-settings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetBookingSettings(ctx, appsession) })
+		api.POST("/update-booking-policy", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateBookingPolicy(ctx, appsession) })
+		api.GET("/get-booking-policies", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetBookingPolicies(ctx, appsession) })
+		api.DELETE("/delete-booking-policy", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.DeleteBookingPolicy(ctx, appsession) })
+		api.POST("/update-department", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.UpdateDepartment(ctx, appsession) })
+		api.GET("/get-departments", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetDepartments(ctx, appsession) })
+		api.DELETE("/delete-department", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.DeleteDepartment(ctx, appsession) })
 		api.PUT("/toggle-onsite", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.BlockAfterHours(), func(ctx *gin.Context) { handlers.ToggleOnsite(ctx, appsession) })
 		api.POST("/create-user", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.CreateUser(ctx, appsession) })
 		api.GET("/get-ip-info", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetIPInfo(ctx, appsession) })
@@ -66,6 +123,9 @@ func OccupiRouter(router *gin.Engine, appsession *models.AppSession) {
 		api.PUT("/toggle-allow-anonymous-ip", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.ToggleAllowAnonymousIP(ctx, appsession) })
 		api.PUT("/toggle-admin-status", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.ToggleAdminStatus(ctx, appsession) })
 		api.PUT("/notify-report-download", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.SendDownloadReportNotification(ctx, appsession) })
+		api.GET("/failed-notifications", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetFailedNotifications(ctx, appsession) })
+		api.POST("/replay-failed-notification", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.ReplayFailedNotification(ctx, appsession) })
+		api.GET("/push-delivery-stats", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetPushDeliveryStats(ctx, appsession) })
 		api.GET("/get-notifications-count", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetNotificationCount(ctx, appsession) })
 		api.GET("/get-users-locations", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetUsersLocations(ctx, appsession, "whitelist") })
 		api.GET("/get-blacklist", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetUsersLocations(ctx, appsession, "blacklist") })
@@ -90,6 +150,9 @@ func OccupiRouter(router *gin.Engine, appsession *models.AppSession) {
 
 		analytics.GET("/top-bookings", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetAnalyticsOnBookings(ctx, appsession, "top3") })
 		analytics.GET("/bookings-historical", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetAnalyticsOnBookings(ctx, appsession, "historical") })
+		analytics.GET("/no-shows", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetAnalyticsOnBookings(ctx, appsession, "noshows") })
+		analytics.GET("/booking-responses", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetAnalyticsOnBookings(ctx, appsession, "rsvp") })
+		analytics.GET("/room-occupancy", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, middleware.AdminRoute, func(ctx *gin.Context) { handlers.GetAnalyticsOnBookings(ctx, appsession, "occupancy") })
 		analytics.GET("/bookings-current", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.GetAnalyticsOnBookings(ctx, appsession, "upcoming") })
 	}
 	auth := router.Group("/auth")
@@ -124,6 +187,29 @@ func OccupiRouter(router *gin.Engine, appsession *models.AppSession) {
 		})
 		auth.POST("/is-verified", middleware.UnProtectedRoute, func(ctx *gin.Context) { handlers.IsEmailVerified(ctx, appsession) })
 	}
+	calendar := router.Group("/calendar")
+	{
+		// feeds are fetched by calendar clients that cannot log in, the token in the url authenticates them
+		calendar.GET("/:token", func(ctx *gin.Context) { handlers.GetCalendarFeed(ctx, appsession) })
+	}
+	// rsvp links are followed from booking emails, the signature in the link authenticates the attendee,
+	// opening the link only shows a confirmation page and the response is saved when it is posted back
+	router.GET("/rsvp", func(ctx *gin.Context) { handlers.ConfirmBookingLink(ctx, appsession) })
+	router.POST("/rsvp", func(ctx *gin.Context) { handlers.RespondToBookingLink(ctx, appsession) })
+	// calendar clients only support basic auth, so caldav routes use the user's caldav app password instead of the session
+	router.GET("/.well-known/caldav", handlers.CalDAVWellKnown)
+	router.Handle("PROPFIND", "/.well-known/caldav", handlers.CalDAVWellKnown)
+	caldav := router.Group("/caldav")
+	{
+		caldav.OPTIONS("/*path", handlers.CalDAVOptions)
+		caldav.Handle("PROPFIND", "/", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, handlers.CalDAVPropfindPrincipal)
+		caldav.Handle("PROPFIND", "/rooms/", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, func(ctx *gin.Context) { handlers.CalDAVPropfindHome(ctx, appsession) })
+		caldav.Handle("PROPFIND", "/rooms/:roomId/", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, func(ctx *gin.Context) { handlers.CalDAVPropfindCalendar(ctx, appsession) })
+		caldav.Handle("PROPFIND", "/rooms/:roomId/:event", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, func(ctx *gin.Context) { handlers.CalDAVPropfindEvent(ctx, appsession) })
+		caldav.Handle("REPORT", "/rooms/:roomId/", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, func(ctx *gin.Context) { handlers.CalDAVReport(ctx, appsession) })
+		caldav.GET("/rooms/:roomId/:event", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, func(ctx *gin.Context) { handlers.CalDAVGetEvent(ctx, appsession) })
+		caldav.PUT("/rooms/:roomId/:event", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, func(ctx *gin.Context) { handlers.CalDAVPutEvent(ctx, appsession) })
+	}
 	rtc := router.Group("/rtc")
 	{
 		rtc.GET("/enter", middleware.ProtectedRoute, func(ctx *gin.Context) { middleware.VerifyMobileUser(ctx, appsession) }, func(ctx *gin.Context) { handlers.Enter(ctx, appsession) })
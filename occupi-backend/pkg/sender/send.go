@@ -2,44 +2,137 @@ package sender
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
 	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 )
 
 func PublishMessage(appsession *models.AppSession, notification models.ScheduledNotification) error {
-	// if gin run mode is test randomly return error
+	return publishNotification(appsession, notification, nil)
+}
+
+// publishes a notification that has already been sent attempt times so it is sent again at notBefore
+func PublishRetry(appsession *models.AppSession, notification models.ScheduledNotification, attempt int, notBefore time.Time) error {
+	return publishNotification(appsession, notification, utils.EncodeNotificationHeaders(attempt, notBefore))
+}
+
+// moves a message to the delay queue that holds it for step seconds before it comes back to the notification queue
+func DelayMessage(appsession *models.AppSession, delivery amqp.Delivery, step int) error {
+	// nothing is published to rabbitmq when running tests
 	if configs.GetGinRunMode() == "test" {
 		return nil
 	}
 
+	return publish(appsession, configs.NotificationDelayQueue(appsession.RabbitQ.Name, step), amqp.Publishing{
+		ContentType:  delivery.ContentType,
+		Type:         delivery.Type,
+		Headers:      delivery.Headers,
+		DeliveryMode: amqp.Persistent,
+		Body:         delivery.Body,
+	})
+}
+
+// moves the messages left in the legacy notification queue to the notification queue and deletes the legacy queue
+// once it is empty. The queue is drained on its own channel so a failure does not close the one notifications use.
+func MoveLegacyMessages(appsession *models.AppSession) (int, error) {
+	// nothing is published to rabbitmq when running tests
+	if configs.GetGinRunMode() == "test" {
+		return 0, nil
+	}
+
+	ch, err := appsession.RabbitMQ.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	// the legacy queue was not durable, declaring it the same way leaves it untouched when it exists
+	if _, err := ch.QueueDeclare(configs.LegacyNotificationQueue, false, false, false, false, nil); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for {
+		delivery, ok, err := ch.Get(configs.LegacyNotificationQueue, false)
+		if err != nil {
+			return moved, err
+		}
+		if !ok {
+			break
+		}
+
+		err = publish(appsession, appsession.RabbitQ.Name, amqp.Publishing{
+			ContentType:  delivery.ContentType,
+			Type:         delivery.Type,
+			Headers:      delivery.Headers,
+			DeliveryMode: amqp.Persistent,
+			Body:         delivery.Body,
+		})
+		if err != nil {
+			// leave the message in the legacy queue for the next start
+			_ = delivery.Nack(false, true)
+			return moved, err
+		}
+
+		if err := delivery.Ack(false); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+
+	// a server that has not been upgraded may still publish to the legacy queue, so it is only deleted when empty
+	_, err = ch.QueueDelete(configs.LegacyNotificationQueue, false, true, false)
+	return moved, err
+}
+
+func publishNotification(appsession *models.AppSession, notification models.ScheduledNotification, headers amqp.Table) error {
+	// nothing is published to rabbitmq when running tests
+	if configs.GetGinRunMode() == "test" {
+		return nil
+	}
+
+	body, err := utils.EncodeNotificationMessage(notification)
+	if err != nil {
+		return err
+	}
+
+	return publish(appsession, appsession.RabbitQ.Name, amqp.Publishing{
+		ContentType:  constants.NotificationContentType,
+		Type:         constants.NotificationMsgType,
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+}
+
+// publishes a message and waits for the broker to confirm it, so a message that is moved between queues
+// is only acknowledged in the queue it came from once the broker has taken it
+func publish(appsession *models.AppSession, queue string, message amqp.Publishing) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	body := fmt.Sprintf(
-		"%s|%s|%s|%s|%s|%s|%s|%s",
-		notification.ID,
-		notification.NotiID,
-		notification.Title,
-		notification.Message,
-		notification.SendTime.Format(time.RFC3339),
-		utils.ConvertArrayToCommaDelimitedString(notification.UnsentExpoPushTokens),
-		utils.ConvertArrayToCommaDelimitedString(notification.Emails),
-		utils.ConvertArrayToCommaDelimitedString(notification.UnreadEmails),
-	)
-	err := appsession.RabbitCh.PublishWithContext(ctx,
+	confirmation, err := appsession.RabbitCh.PublishWithDeferredConfirmWithContext(ctx,
 		"",
-		appsession.RabbitQ.Name,
+		queue,
 		false,
 		false,
-		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        []byte(body),
-		})
-	return err
+		message)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("message was not confirmed by rabbitmq")
+	}
+	return nil
 }
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+)
+
+// reads the parts of a CalDAV REPORT body that are needed to answer it
+func ParseCalDAVReport(body []byte) (models.CalDAVReport, error) {
+	var report models.CalDAVReport
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	var path []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			name := element.Name.Local
+			if len(path) == 0 {
+				report.Name = name
+			}
+			// properties are the direct children of the report's prop element
+			if len(path) == 2 && path[1] == "prop" {
+				report.Props = append(report.Props, name)
+			}
+			if name == "time-range" {
+				for _, attr := range element.Attr {
+					value, err := time.Parse("20060102T150405Z", attr.Value)
+					if err != nil {
+						return report, errors.New("invalid time-range")
+					}
+					switch attr.Name.Local {
+					case "start":
+						report.Start = value
+					case "end":
+						report.End = value
+					}
+				}
+			}
+			path = append(path, name)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		case xml.CharData:
+			if len(path) == 2 && path[1] == "href" {
+				report.Hrefs = append(report.Hrefs, string(bytes.TrimSpace(element)))
+			}
+		}
+	}
+
+	if report.Name == "" {
+		return report, errors.New("empty report")
+	}
+
+	return report, nil
+}
+
+// hashes a caldav app password for storing, the passwords are random so a plain hash is enough to look them up by
+func HashCalDAVPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// the etag of a booking's calendar object, it changes whenever the calendar data does
+func CalendarETag(booking models.Booking) string {
+	sum := sha256.Sum256([]byte(FormatCalendarObject([]models.Booking{booking})))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// the ctag of a room calendar, it changes whenever a booking in the calendar is added, changed or removed
+func CalendarCTag(bookings []models.Booking) string {
+	hash := sha256.New()
+	for _, booking := range bookings {
+		hash.Write([]byte(CalendarETag(booking)))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
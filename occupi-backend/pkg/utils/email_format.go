@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"html"
 	"strconv"
+	"time"
 
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 	"github.com/ipinfo/go/v2/ipinfo"
 )
 
@@ -129,6 +132,74 @@ func FormatBookingEmailBodyForAttendees(bookingID string, roomID string, slot in
 		</div>` + AppendFooter()
 }
 
+// formats the invitation sent to each attendee of a booking, the links let them respond without signing in
+func FormatBookingInvitationEmailBody(bookingID string, roomName string, start time.Time, end time.Time, creator string, acceptLink string, tentativeLink string, declineLink string) string {
+	return AppendHeader("Booking") + `
+		<div class="content">
+			<p>Dear attendee,</p>
+			<p>
+				` + creator + ` has booked an office space and invited you to join. Here are the booking details:<br><br>
+				<b>Booking ID:</b> ` + bookingID + `<br>
+				<b>Room:</b> ` + roomName + `<br>
+				<b>Start:</b> ` + start.Format(time.RFC1123) + `<br>
+				<b>End:</b> ` + end.Format(time.RFC1123) + `<br><br>
+				Will you attend?
+				<a href="` + html.EscapeString(acceptLink) + `">Yes</a> |
+				<a href="` + html.EscapeString(tentativeLink) + `">Maybe</a> |
+				<a href="` + html.EscapeString(declineLink) + `">No</a><br><br>
+				If you have any questions, feel free to contact us.<br><br>
+				Thank you,<br>
+				<b>The Occupi Team</b><br>
+			</p>
+		</div>` + AppendFooter()
+}
+
+// formats the page an rsvp link in an invitation opens, the response is only saved once the attendee presses the button
+func FormatRSVPConfirmPage(roomName string, start time.Time, request models.RequestRSVPLink) string {
+	occurrences := "the booking"
+	if request.Series {
+		occurrences = "this and the following occurrences of the booking"
+	}
+
+	fields := ""
+	for _, field := range [][2]string{
+		{"bookingId", request.BookingID},
+		{"email", request.Email},
+		{"response", request.Response},
+		{"series", strconv.FormatBool(request.Series)},
+		{"exp", strconv.FormatInt(request.Expires, 10)},
+		{"sig", request.Signature},
+	} {
+		fields += `
+					<input type="hidden" name="` + field[0] + `" value="` + html.EscapeString(field[1]) + `">`
+	}
+
+	return AppendHeader("Booking") + `
+		<div class="content">
+			<p>
+				Respond <b>` + html.EscapeString(request.Response) + `</b> to ` + occurrences + ` of ` + html.EscapeString(roomName) + ` on ` + start.Format(time.RFC1123) + `?<br><br>
+				<form method="post">` + fields + `
+					<button type="submit">Confirm</button>
+				</form><br>
+				Thank you,<br>
+				<b>The Occupi Team</b><br>
+			</p>
+		</div>` + AppendFooter()
+}
+
+// formats the page shown after an attendee responds through a link in their invitation
+func FormatRSVPResponsePage(roomName string, start time.Time, response string) string {
+	return AppendHeader("Booking") + `
+		<div class="content">
+			<p>
+				Your response to the booking of ` + roomName + ` on ` + start.Format(time.RFC1123) + ` was saved as <b>` + response + `</b>.<br><br>
+				You can change your response at any time from the Occupi app or the links in your invitation.<br><br>
+				Thank you,<br>
+				<b>The Occupi Team</b><br>
+			</p>
+		</div>` + AppendFooter()
+}
+
 // formats cancellation email body to send attendees
 func FormatCancellationEmailBodyForAttendees(bookingID string, roomID string, slot int, email string) string {
 	return AppendHeader("Booking") + `
@@ -146,6 +217,55 @@ func FormatCancellationEmailBodyForAttendees(bookingID string, roomID string, sl
 		</div>` + AppendFooter()
 }
 
+// formats booking updated email body to send attendees when the room or time of a booking changes
+func FormatBookingUpdatedEmailBody(bookingID string, roomID string, start time.Time, end time.Time, email string) string {
+	return AppendHeader("Booking") + `
+		<div class="content">
+			<p>Dear attendees,</p>
+			<p>
+				` + email + ` has made changes to a booked office space. Here are the updated booking details:<br><br>
+				<b>Booking ID:</b> ` + bookingID + `<br>
+				<b>Room ID:</b> ` + roomID + `<br>
+				<b>Start:</b> ` + start.Format(time.RFC1123) + `<br>
+				<b>End:</b> ` + end.Format(time.RFC1123) + `<br><br>
+				If you have any questions, feel free to contact us.<br><br>
+				Thank you,<br>
+				<b>The Occupi Team</b><br>
+			</p>
+		</div>` + AppendFooter()
+}
+
+// formats the email body telling the creator of a booking that it was rejected
+func FormatBookingRejectedEmailBody(bookingID string, roomName string, start time.Time, reason string, approver string) string {
+	return AppendHeader("Booking") + `
+		<div class="content">
+			<p>Dear booker,</p>
+			<p>
+				Your request to book ` + roomName + ` was rejected by ` + approver + `.<br><br>
+				<b>Booking ID:</b> ` + bookingID + `<br>
+				<b>Start:</b> ` + start.Format(time.RFC1123) + `<br>
+				<b>Reason:</b> ` + html.EscapeString(reason) + `<br><br>
+				The room is free again for other bookings. If you have any questions, feel free to contact us.<br><br>
+				Thank you,<br>
+				<b>The Occupi Team</b><br>
+			</p>
+		</div>` + AppendFooter()
+}
+
+// formats the email body of a notification sent to a user who chose to get it by email
+func FormatNotificationEmailBody(title string, message string) string {
+	return AppendHeader(title) + `
+		<div class="content">
+			<p>Dear user,</p>
+			<p>
+				` + html.EscapeString(message) + `<br><br>
+				You are getting this email because you chose to get these notifications by email, you can change this in your notification settings.<br><br>
+				Thank you,<br>
+				<b>The Occupi Team</b><br>
+			</p>
+		</div>` + AppendFooter()
+}
+
 // formats verification email body
 func FormatEmailVerificationBody(otp string, email string) string {
 	return AppendHeader("Registration") + `
@@ -0,0 +1,334 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+)
+
+// sequence numbers count seconds from this date so every later change of a booking has a higher sequence
+var sequenceEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// formats an iCalendar invite for bookings, METHOD:REQUEST adds or updates the bookings in a calendar
+// and METHOD:CANCEL removes them, the booking's occupi id is used as the event uid. An invite may only
+// hold one event, so occurrences of a series share the series uid: a new series is sent as one event
+// with an RDATE for each later occurrence and changes to occurrences name them with a RECURRENCE-ID
+func FormatBookingInvite(method string, bookings []models.Booking, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"PRODID:" + constants.ICalendarProdID,
+		"VERSION:2.0",
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + method,
+	}
+
+	if method == constants.ICalendarRequest && len(bookings) > 1 && bookings[0].SeriesID != "" {
+		lines = append(lines, FormatSeriesEvent(bookings, now)...)
+	} else {
+		for _, booking := range bookings {
+			event := FormatBookingEvent(booking, method, now)
+			if booking.SeriesID != "" {
+				event = asSeriesOccurrence(event, booking)
+			}
+			lines = append(lines, event...)
+		}
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+	return JoinICalendarLines(lines)
+}
+
+// formats the occurrences of a new series as a single VEVENT under the series uid, the first occurrence
+// gives the details and every later occurrence is added with an RDATE
+func FormatSeriesEvent(occurrences []models.Booking, now time.Time) []string {
+	event := FormatBookingEvent(occurrences[0], constants.ICalendarRequest, now)
+	event[1] = "UID:" + BookingUID(occurrences[0].SeriesID)
+
+	starts := make([]string, 0, len(occurrences)-1)
+	for _, occurrence := range occurrences[1:] {
+		starts = append(starts, FormatICalendarTime(RecurrenceID(occurrence)))
+	}
+
+	// the RDATE goes straight after DTSTART and DTEND
+	lines := append([]string{}, event[:6]...)
+	lines = append(lines, "RDATE:"+strings.Join(starts, ","))
+	return append(lines, event[6:]...)
+}
+
+// gives an occurrence's event the series uid and the RECURRENCE-ID of the occurrence it changes
+func asSeriesOccurrence(event []string, booking models.Booking) []string {
+	lines := append([]string{}, event[:1]...)
+	lines = append(lines, "UID:"+BookingUID(booking.SeriesID), "RECURRENCE-ID:"+FormatICalendarTime(RecurrenceID(booking)))
+	return append(lines, event[2:]...)
+}
+
+// the start calendars know an occurrence of a series by, occurrences saved before it was recorded use their start
+func RecurrenceID(booking models.Booking) time.Time {
+	if booking.OccurrenceStart.IsZero() {
+		return booking.Start
+	}
+	return booking.OccurrenceStart
+}
+
+// formats a calendar subscription feed of bookings
+func FormatBookingFeed(name string, bookings []models.Booking, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"PRODID:" + constants.ICalendarProdID,
+		"VERSION:2.0",
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + constants.ICalendarPublish,
+		"X-WR-CALNAME:" + EscapeICalendarText(name),
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+	}
+
+	for _, booking := range bookings {
+		lines = append(lines, FormatBookingEvent(booking, constants.ICalendarPublish, now)...)
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+	return JoinICalendarLines(lines)
+}
+
+// formats bookings as a CalDAV calendar object, which must not have a METHOD, the stamp is fixed so the
+// same booking always gives the same data and etag
+func FormatCalendarObject(bookings []models.Booking) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"PRODID:" + constants.ICalendarProdID,
+		"VERSION:2.0",
+		"CALSCALE:GREGORIAN",
+	}
+
+	for _, booking := range bookings {
+		lines = append(lines, FormatBookingEvent(booking, constants.ICalendarPublish, sequenceEpoch)...)
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+	return JoinICalendarLines(lines)
+}
+
+// formats the busy times of bookings between start and end as a VFREEBUSY reply to a free-busy-query
+func FormatFreeBusy(bookings []models.Booking, start time.Time, end time.Time, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"PRODID:" + constants.ICalendarProdID,
+		"VERSION:2.0",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VFREEBUSY",
+		"DTSTAMP:" + FormatICalendarTime(now),
+		"DTSTART:" + FormatICalendarTime(start),
+		"DTEND:" + FormatICalendarTime(end),
+	}
+
+	for _, booking := range bookings {
+		lines = append(lines, "FREEBUSY;FBTYPE=BUSY:"+FormatICalendarTime(booking.Start)+"/"+FormatICalendarTime(booking.End))
+	}
+
+	lines = append(lines, "END:VFREEBUSY", "END:VCALENDAR")
+	return JoinICalendarLines(lines)
+}
+
+// formats a single booking as a VEVENT
+func FormatBookingEvent(booking models.Booking, method string, now time.Time) []string {
+	status := "CONFIRMED"
+	switch {
+	case method == constants.ICalendarCancel:
+		status = "CANCELLED"
+	case booking.Status == constants.BookingPending:
+		status = "TENTATIVE"
+	}
+
+	location := booking.RoomName
+	if booking.FloorNo != "" {
+		location += ", Floor " + booking.FloorNo
+	}
+
+	lines := []string{
+		"BEGIN:VEVENT",
+		"UID:" + BookingUID(booking.OccupiID),
+		"DTSTAMP:" + FormatICalendarTime(now),
+		"SEQUENCE:" + strconv.FormatInt(int64(now.Sub(sequenceEpoch)/time.Second), 10),
+		"DTSTART:" + FormatICalendarTime(booking.Start),
+		"DTEND:" + FormatICalendarTime(booking.End),
+		"SUMMARY:" + EscapeICalendarText("Occupi booking: "+booking.RoomName),
+		"LOCATION:" + EscapeICalendarText(location),
+		"STATUS:" + status,
+	}
+
+	if booking.Creator != "" {
+		lines = append(lines, "ORGANIZER:mailto:"+booking.Creator)
+	}
+	for _, email := range booking.Emails {
+		partstat := ICalendarPartStat(AttendeeResponseOf(booking, email))
+		lines = append(lines, "ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT="+partstat+";RSVP=TRUE:mailto:"+email)
+	}
+
+	return append(lines, "END:VEVENT")
+}
+
+// the uid calendars use to match updates and cancellations to the booking they already have
+func BookingUID(occupiID string) string {
+	return occupiID + "@occupi.tech"
+}
+
+// formats a time in UTC as an iCalendar date-time
+func FormatICalendarTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapes commas, semicolons, backslashes and newlines in iCalendar text values
+func EscapeICalendarText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// joins content lines with CRLF, folding lines longer than 75 octets as required by RFC 5545
+func JoinICalendarLines(lines []string) string {
+	var builder strings.Builder
+	for _, line := range lines {
+		for len(line) > 75 {
+			cut := 75
+			// never split a multi-byte character
+			for cut > 0 && line[cut]&0xC0 == 0x80 {
+				cut--
+			}
+			builder.WriteString(line[:cut] + "\r\n")
+			line = " " + line[cut:]
+		}
+		builder.WriteString(line + "\r\n")
+	}
+	return builder.String()
+}
+
+// parses the first VEVENT of an iCalendar object sent by a calendar client
+func ParseICalendarEvent(data string) (models.CalDAVEvent, error) {
+	// unfold lines that were folded at 75 octets before reading the properties
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var event models.CalDAVEvent
+	inEvent, found := false, false
+	for _, line := range strings.Split(data, "\n") {
+		name, params, value, ok := splitICalendarLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			if found {
+				return event, nil
+			}
+			inEvent = true
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			inEvent, found = false, true
+			continue
+		}
+		if !inEvent {
+			continue
+		}
+
+		var err error
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = value
+		case "DTSTART":
+			event.Start, err = parseICalendarTime(params, value)
+		case "DTEND":
+			event.End, err = parseICalendarTime(params, value)
+		case "RRULE", "RDATE":
+			event.Recurring = true
+		case "ATTENDEE":
+			if len(value) > 7 && strings.EqualFold(value[:7], "mailto:") {
+				event.Attendees = append(event.Attendees, strings.ToLower(value[7:]))
+			}
+		}
+		if err != nil {
+			return event, err
+		}
+	}
+
+	if !found {
+		return event, errors.New("no event found")
+	}
+	if event.Start.IsZero() || event.End.IsZero() {
+		return event, errors.New("event must have a start and an end")
+	}
+	if !event.End.After(event.Start) {
+		return event, errors.New("event must end after it starts")
+	}
+
+	return event, nil
+}
+
+// splits a content line into its name, parameters and value
+func splitICalendarLine(line string) (string, map[string]string, string, bool) {
+	line = strings.TrimRight(line, "\r")
+
+	// quoted parameter values such as CN="Doe: Jane" may contain colons
+	colon, quoted := -1, false
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := map[string]string{}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+// parses a DTSTART or DTEND value, times without a Z are read in their TZID or else in UTC
+func parseICalendarTime(params map[string]string, value string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		return time.Time{}, errors.New("all day events cannot be booked")
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
+	location := time.UTC
+	if tzid, ok := params["TZID"]; ok {
+		loaded, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, errors.New("unknown time zone " + tzid)
+		}
+		location = loaded
+	}
+
+	return time.ParseInLocation("20060102T150405", value, location)
+}
+
+// generates a random token for a calendar subscription url
+func GenerateFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
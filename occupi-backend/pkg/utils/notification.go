@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+)
+
+// encodes a notification as the versioned json envelope published to the notification queue
+func EncodeNotificationMessage(notification models.ScheduledNotification) ([]byte, error) {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(models.NotificationMessage{
+		Version: constants.NotificationMsgVersion,
+		Type:    constants.NotificationMsgType,
+		Payload: payload,
+	})
+}
+
+// decodes a message from the notification queue, json envelopes are read by their version and anything else
+// is read as the legacy | delimited format so messages published before the rollout are still delivered
+func DecodeNotificationMessage(body []byte, contentType string) (models.ScheduledNotification, error) {
+	trimmed := bytes.TrimSpace(body)
+	if contentType == constants.NotificationContentType || bytes.HasPrefix(trimmed, []byte("{")) {
+		return decodeNotificationEnvelope(trimmed)
+	}
+
+	return decodeLegacyNotification(string(body))
+}
+
+func decodeNotificationEnvelope(body []byte) (models.ScheduledNotification, error) {
+	var notification models.ScheduledNotification
+
+	var message models.NotificationMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return notification, fmt.Errorf("invalid notification message: %w", err)
+	}
+
+	if message.Version < 1 || message.Version > constants.NotificationMsgVersion {
+		return notification, fmt.Errorf("unsupported notification message version %d", message.Version)
+	}
+	if message.Type != constants.NotificationMsgType {
+		return notification, fmt.Errorf("unknown notification message type %q", message.Type)
+	}
+	if len(message.Payload) == 0 {
+		return notification, errors.New("notification message has no payload")
+	}
+
+	if err := json.Unmarshal(message.Payload, &notification); err != nil {
+		return notification, fmt.Errorf("invalid notification payload: %w", err)
+	}
+	if notification.SendTime.IsZero() {
+		return notification, errors.New("notification message has no send time")
+	}
+
+	return notification, nil
+}
+
+// reads the id|notiId|title|message|sendTime|tokens|emails|unreadEmails format with comma joined arrays
+func decodeLegacyNotification(body string) (models.ScheduledNotification, error) {
+	var notification models.ScheduledNotification
+
+	parts := strings.Split(body, "|")
+	if len(parts) != constants.LegacyNotificationFields {
+		return notification, fmt.Errorf("legacy notification message has %d fields, expected %d", len(parts), constants.LegacyNotificationFields)
+	}
+
+	sendTime, err := time.Parse(time.RFC3339, parts[4])
+	if err != nil {
+		return notification, fmt.Errorf("legacy notification message has an invalid send time: %w", err)
+	}
+
+	notification = models.ScheduledNotification{
+		ID:                   parts[0],
+		NotiID:               parts[1],
+		Title:                parts[2],
+		Message:              parts[3],
+		SendTime:             sendTime,
+		UnsentExpoPushTokens: ConvertCommaDelimitedStringToArray(parts[5]),
+		Emails:               ConvertCommaDelimitedStringToArray(parts[6]),
+		UnreadEmails:         ConvertCommaDelimitedStringToArray(parts[7]),
+	}
+
+	return notification, nil
+}
+
+// the headers of a notification that is being retried, attempt is how many times it has already been sent
+// and it is not sent again before notBefore
+func EncodeNotificationHeaders(attempt int, notBefore time.Time) amqp.Table {
+	return amqp.Table{
+		constants.NotificationAttemptHeader: int32(attempt),
+		constants.NotificationNotBefore:     notBefore.UTC().Format(time.RFC3339),
+	}
+}
+
+// reads the attempt and not before headers of a notification, a notification without them has not been sent yet
+func DecodeNotificationHeaders(headers amqp.Table) (int, time.Time) {
+	attempt := 0
+	switch value := headers[constants.NotificationAttemptHeader].(type) {
+	case int32:
+		attempt = int(value)
+	case int64:
+		attempt = int(value)
+	case int:
+		attempt = value
+	}
+
+	var notBefore time.Time
+	if value, ok := headers[constants.NotificationNotBefore].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			notBefore = parsed
+		}
+	}
+
+	return attempt, notBefore
+}
+
+// how long to wait before sending a notification again, the wait doubles after every attempt up to an hour
+func NotificationRetryDelay(attempt int, base time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// whether a notification is found too long after its send time to still be useful to its recipients
+func IsNotificationStale(notification models.ScheduledNotification, now time.Time, staleAfter time.Duration) bool {
+	return now.Sub(notification.SendTime) > staleAfter
+}
+
+// picks the longest delay queue step in seconds that is no longer than wait, 0 means the wait is too short
+// for any delay queue and the notification should be held until it is due
+func NotificationDelayStep(wait time.Duration, steps []int) int {
+	chosen := 0
+	for _, step := range steps {
+		if time.Duration(step)*time.Second <= wait && step > chosen {
+			chosen = step
+		}
+	}
+	return chosen
+}
+
+var notificationTypes = []string{
+	constants.NotificationInvite,
+	constants.NotificationReminder,
+	constants.NotificationWaitlist,
+	constants.NotificationReleased,
+	constants.NotificationUpdate,
+}
+
+var notificationChannels = []string{
+	constants.ChannelPush,
+	constants.ChannelEmail,
+	constants.ChannelWebhook,
+	constants.ChannelWebPush,
+}
+
+// the channels a user gets a type of notification on, nothing when they turned off invites, or booking reminders
+// for reminders, and push when they have not chosen channels for the type
+func NotificationChannels(user models.User, notificationType string) []string {
+	if !user.Notifications.Invites {
+		return nil
+	}
+	if notificationType == constants.NotificationReminder && !user.Notifications.BookingReminder {
+		return nil
+	}
+
+	channels, ok := user.Notifications.Channels[notificationType]
+	if !ok {
+		return []string{constants.ChannelPush}
+	}
+	return channels
+}
+
+// the push tokens of a user's devices seen since the given time along with the token they signed up with
+func ActivePushTokens(user models.User, since time.Time) []string {
+	tokens := []string{}
+	if user.ExpoPushToken != "" {
+		tokens = append(tokens, user.ExpoPushToken)
+	}
+	for _, device := range user.Devices {
+		if device.Token != "" && !device.LastSeen.Before(since) && !Contains(tokens, device.Token) {
+			tokens = append(tokens, device.Token)
+		}
+	}
+	return tokens
+}
+
+// checks that channel choices only name known notification types and channels
+func ValidateNotificationChannels(channels map[string][]string) error {
+	for notificationType, chosen := range channels {
+		if !Contains(notificationTypes, notificationType) {
+			return fmt.Errorf("unknown notification type %q", notificationType)
+		}
+		for _, channel := range chosen {
+			if !Contains(notificationChannels, channel) {
+				return fmt.Errorf("unknown notification channel %q", channel)
+			}
+		}
+	}
+	return nil
+}
+
+// checks a webhook url a user wants notifications posted to, an empty url turns the webhook off
+func ValidateWebhookURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	return validatePublicURL(raw, "webhookUrl")
+}
+
+// checks the endpoint a browser gave when subscribing to web push, it is posted to the same way as webhooks
+func ValidateWebPushEndpoint(raw string) error {
+	return validatePublicURL(raw, "endpoint")
+}
+
+// checks that a url uses https and does not name a private address, field is the name used in the errors
+func validatePublicURL(raw string, field string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%s must be a valid url", field)
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("%s must use https", field)
+	}
+	// addresses behind hostnames are checked again when the url is called
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); strings.EqualFold(host, "localhost") || (ip != nil && !IsPublicIP(ip)) {
+		return fmt.Errorf("%s must not point to a private address", field)
+	}
+	return nil
+}
+
+// reports whether an ip address is reachable on the internet rather than loopback, private, link local or unspecified
+func IsPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
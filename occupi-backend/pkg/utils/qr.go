@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/skip2/go-qrcode"
+)
+
+const roomQRPrefix = "occupi:room:"
+
+// the rotation window a time falls in, a room's qr code changes at the start of every window
+func RoomQRWindow(now time.Time) int64 {
+	return now.Unix() / int64(configs.GetQRRotationInterval())
+}
+
+// the time the qr code shown at now stops being the current one
+func RoomQRExpiry(now time.Time) time.Time {
+	interval := int64(configs.GetQRRotationInterval())
+	return time.Unix((RoomQRWindow(now)+1)*interval, 0)
+}
+
+// signs a room id and rotation window so a photo of a room's qr code stops working once it has rotated
+func signRoomQR(roomID string, window int64) string {
+	mac := hmac.New(sha256.New, []byte(configs.GetJWTSecret()))
+	mac.Write([]byte("room-qr\n" + roomID + "\n" + strconv.FormatInt(window, 10)))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// formats the payload of the qr code shown on a room's door at now
+func FormatRoomQRPayload(roomID string, now time.Time) string {
+	window := RoomQRWindow(now)
+	return roomQRPrefix + roomID + ":" + strconv.FormatInt(window, 10) + ":" + signRoomQR(roomID, window)
+}
+
+// checks a scanned qr code payload and returns the room it belongs to, the previous window is also accepted
+// so a code scanned just before it rotated still works
+func ParseRoomQRPayload(payload string, now time.Time) (string, error) {
+	if !strings.HasPrefix(payload, roomQRPrefix) {
+		return "", errors.New("invalid qr code")
+	}
+
+	// the room id is everything between the prefix and the last two fields
+	fields := strings.Split(strings.TrimPrefix(payload, roomQRPrefix), ":")
+	if len(fields) < 3 {
+		return "", errors.New("invalid qr code")
+	}
+	roomID := strings.Join(fields[:len(fields)-2], ":")
+	signature := fields[len(fields)-1]
+
+	window, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil || roomID == "" {
+		return "", errors.New("invalid qr code")
+	}
+
+	if !hmac.Equal([]byte(signRoomQR(roomID, window)), []byte(signature)) {
+		return "", errors.New("invalid qr code")
+	}
+
+	current := RoomQRWindow(now)
+	if window != current && window != current-1 {
+		return "", errors.New("qr code has expired")
+	}
+
+	return roomID, nil
+}
+
+// renders a qr code as a png of size by size pixels
+func RenderQRCodePNG(payload string, size int) ([]byte, error) {
+	return qrcode.Encode(payload, qrcode.Medium, size)
+}
+
+// renders a qr code as an svg, one unit per module so it scales to any size
+func RenderQRCodeSVG(payload string) (string, error) {
+	code, err := qrcode.New(payload, qrcode.Medium)
+	if err != nil {
+		return "", err
+	}
+
+	bitmap := code.Bitmap()
+	size := strconv.Itoa(len(bitmap))
+
+	var builder strings.Builder
+	builder.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 ` + size + " " + size + `" shape-rendering="crispEdges">`)
+	builder.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/><path fill="#000000" d="`)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				builder.WriteString("M" + strconv.Itoa(x) + " " + strconv.Itoa(y) + "h1v1h-1z")
+			}
+		}
+	}
+	builder.WriteString(`"/></svg>`)
+
+	return builder.String(), nil
+}
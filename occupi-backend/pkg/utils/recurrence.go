@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+)
+
+// ExtractRecurrenceRule pulls the optional recurrence rule out of a booking request
+func ExtractRecurrenceRule(data map[string]interface{}) (models.RecurrenceRule, bool, error) {
+	value, exists := data["recurrence"]
+	if !exists || value == nil {
+		return models.RecurrenceRule{}, false, nil
+	}
+
+	ruleBytes, err := json.Marshal(value)
+	if err != nil {
+		return models.RecurrenceRule{}, false, err
+	}
+
+	var rule models.RecurrenceRule
+	if err := json.Unmarshal(ruleBytes, &rule); err != nil {
+		return models.RecurrenceRule{}, false, errors.New("field recurrence is of incorrect format")
+	}
+
+	return rule, true, nil
+}
+
+// ValidateRecurrenceRule checks that the rule has a valid frequency and a terminating count or until date
+func ValidateRecurrenceRule(rule models.RecurrenceRule) error {
+	if rule.Frequency != constants.Daily && rule.Frequency != constants.Weekly && rule.Frequency != constants.Monthly {
+		return fmt.Errorf("frequency must be one of %s, %s or %s", constants.Daily, constants.Weekly, constants.Monthly)
+	}
+
+	if rule.Interval < 0 {
+		return errors.New("interval must be a positive number")
+	}
+
+	if rule.Count < 0 {
+		return errors.New("count must be a positive number")
+	}
+
+	if rule.Count == 0 && rule.Until.IsZero() {
+		return errors.New("either count or until must be provided")
+	}
+
+	if rule.Count > constants.MaxRecurringOccurrences {
+		return fmt.Errorf("a series cannot have more than %d occurrences", constants.MaxRecurringOccurrences)
+	}
+
+	return nil
+}
+
+// ExpandRecurringBooking generates every occurrence of a recurring booking.
+// As with RRULE, count includes occurrences that are later removed by the exceptions
+// and until is inclusive of the whole day it falls on.
+func ExpandRecurringBooking(booking models.Booking, rule models.RecurrenceRule) ([]models.Booking, error) {
+	if err := ValidateRecurrenceRule(rule); err != nil {
+		return nil, err
+	}
+
+	interval := rule.Interval
+	if interval == 0 {
+		interval = 1
+	}
+
+	var untilDay time.Time
+	if !rule.Until.IsZero() {
+		untilDay = StartOfDay(rule.Until.In(booking.Start.Location()))
+	}
+
+	occurrences := make([]models.Booking, 0)
+	generated := 0
+
+	for i := 0; ; i++ {
+		var months, days int
+		switch rule.Frequency {
+		case constants.Daily:
+			days = i * interval
+		case constants.Weekly:
+			days = i * interval * 7
+		case constants.Monthly:
+			months = i * interval
+		}
+
+		start := booking.Start.AddDate(0, months, days)
+
+		// monthly occurrences that don't exist in a month (eg the 31st) are skipped rather than rolled over
+		if rule.Frequency == constants.Monthly && start.Day() != booking.Start.Day() {
+			continue
+		}
+
+		if !untilDay.IsZero() && StartOfDay(start).After(untilDay) {
+			break
+		}
+
+		if rule.Count > 0 && generated >= rule.Count {
+			break
+		}
+
+		generated++
+
+		if generated > constants.MaxRecurringOccurrences {
+			return nil, fmt.Errorf("a series cannot have more than %d occurrences", constants.MaxRecurringOccurrences)
+		}
+
+		if IsExceptionDate(start, rule.Exceptions) {
+			continue
+		}
+
+		occurrence := booking
+		occurrence.Date = booking.Date.AddDate(0, months, days)
+		occurrence.Start = start
+		occurrence.End = booking.End.AddDate(0, months, days)
+		occurrences = append(occurrences, occurrence)
+	}
+
+	if len(occurrences) == 0 {
+		return nil, errors.New("recurrence rule does not produce any occurrences")
+	}
+
+	return occurrences, nil
+}
+
+// IsExceptionDate checks whether the date falls on the same day as any of the exceptions
+func IsExceptionDate(date time.Time, exceptions []time.Time) bool {
+	for _, exception := range exceptions {
+		if SameDay(date, exception.In(date.Location())) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartOfDay returns midnight of the given date in the dates location
+func StartOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+// SameDay checks whether two times fall on the same calendar day
+func SameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
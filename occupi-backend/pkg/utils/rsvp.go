@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+)
+
+// the key rsvp links are signed with, derived from the jwt secret so a link signature can never pass as a token signature
+func rsvpKey() []byte {
+	mac := hmac.New(sha256.New, []byte(configs.GetJWTSecret()))
+	mac.Write([]byte("occupi rsvp links"))
+	return mac.Sum(nil)
+}
+
+// signs a booking id, attendee email and expiry so rsvp links in booking emails can be used without signing in,
+// the response is left out so one signature covers the accept, tentative and decline links
+func SignRSVP(bookingID string, email string, expires int64) string {
+	mac := hmac.New(sha256.New, rsvpKey())
+	mac.Write([]byte(bookingID + "\n" + strings.ToLower(email) + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checks the signature of an rsvp link and that it has not expired, expires is in unix seconds
+func VerifyRSVP(bookingID string, email string, expires int64, signature string, now time.Time) error {
+	if !hmac.Equal([]byte(SignRSVP(bookingID, email, expires)), []byte(signature)) {
+		return errors.New("invalid rsvp link")
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return errors.New("rsvp link has expired")
+	}
+	return nil
+}
+
+// builds the signed link an attendee follows to respond to a booking, the link stops working at expires
+func FormatRSVPLink(baseURL string, bookingID string, email string, response string, series bool, expires time.Time) string {
+	query := url.Values{}
+	query.Set("bookingId", bookingID)
+	query.Set("email", email)
+	query.Set("response", response)
+	if series {
+		query.Set("series", "true")
+	}
+	query.Set("exp", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("sig", SignRSVP(bookingID, email, expires.Unix()))
+
+	return strings.TrimSuffix(baseURL, "/") + "/rsvp?" + query.Encode()
+}
+
+// gets an attendee's response to a booking, the creator has accepted unless they said otherwise
+func AttendeeResponseOf(booking models.Booking, email string) string {
+	for _, attendee := range booking.Attendees {
+		if attendee.Email == email {
+			return attendee.Response
+		}
+	}
+
+	if email == booking.Creator {
+		return constants.RSVPAccepted
+	}
+	return constants.RSVPNeedsAction
+}
+
+// the iCalendar participation status of an rsvp response
+func ICalendarPartStat(response string) string {
+	switch response {
+	case constants.RSVPAccepted:
+		return "ACCEPTED"
+	case constants.RSVPDeclined:
+		return "DECLINED"
+	case constants.RSVPTentative:
+		return "TENTATIVE"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+// keeps the responses of the attendees that are still invited to a booking
+func KeepAttendeeResponses(attendees []models.AttendeeResponse, emails []string) []models.AttendeeResponse {
+	kept := []models.AttendeeResponse{}
+	for _, attendee := range attendees {
+		if Contains(emails, attendee.Email) {
+			kept = append(kept, attendee)
+		}
+	}
+	return kept
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/authenticator"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 )
 
@@ -454,11 +455,38 @@ func ConstructBookingStartingInScheduledString(emails []string, startTime string
 	}
 }
 
+func ConstructWaitlistOfferString(roomName string, window string) string {
+	return fmt.Sprintf("%s is now available for the time you waitlisted, accept within %s to book it", roomName, window)
+}
+
+func ConstructNoShowString(roomName string, gracePeriod string) string {
+	return fmt.Sprintf("Your booking of %s was released because nobody checked in within %s", roomName, gracePeriod)
+}
+
 func PrependEmailtoSlice(emails []string, email string) []string {
 	emails = append([]string{email}, emails...)
 	return emails
 }
 
+// returns the emails that were added to and removed from a list of emails
+func DiffEmails(oldEmails []string, newEmails []string) ([]string, []string) {
+	var added []string
+	for _, email := range newEmails {
+		if !Contains(oldEmails, email) && !Contains(added, email) {
+			added = append(added, email)
+		}
+	}
+
+	var removed []string
+	for _, email := range oldEmails {
+		if !Contains(newEmails, email) && !Contains(removed, email) {
+			removed = append(removed, email)
+		}
+	}
+
+	return added, removed
+}
+
 func ConvertToStringArray(input interface{}) []string {
 	// Convert the input to a slice of strings
 	var stringArray []string
@@ -717,3 +745,83 @@ func DetectDeviceType(userAgent string) string {
 		return "Unknown"
 	}
 }
+
+// NormalizeTags lowercases and trims tags, dropping empty and duplicate tags
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ApplyRoomFilters limits a room filter to rooms with all of the tags and hides archived rooms unless a status is asked for
+func ApplyRoomFilters(filter primitive.M, tags []string) primitive.M {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	if tags = NormalizeTags(tags); len(tags) > 0 {
+		filter["amenities"] = bson.M{"$all": tags}
+	}
+
+	if _, ok := filter["status"]; !ok {
+		filter["status"] = bson.M{"$ne": constants.RoomArchived}
+	}
+
+	return filter
+}
+
+// keeps the check in tokens of rooms out of a room query so they can neither be read nor guessed through filters,
+// only admins may see them since anyone holding a token can check in without being in the room
+func HideCheckInTokens(filter models.FilterStruct) models.FilterStruct {
+	for key := range filter.Filter {
+		if strings.HasPrefix(key, "checkInTokens") {
+			delete(filter.Filter, key)
+		}
+	}
+	for key := range filter.Sort {
+		if strings.HasPrefix(key, "checkInTokens") {
+			delete(filter.Sort, key)
+		}
+	}
+
+	if filter.Projection == nil {
+		filter.Projection = bson.M{}
+	}
+	// an inclusive projection leaves out every field it does not name, mongo does not allow mixing in an exclusion
+	inclusive := false
+	for key, value := range filter.Projection {
+		if key != "_id" && value == 1 {
+			inclusive = true
+		}
+	}
+	if inclusive {
+		delete(filter.Projection, "checkInTokens")
+	} else {
+		filter.Projection["checkInTokens"] = 0
+	}
+
+	return filter
+}
+
+// checks whether an attendee has checked in to a booking
+func HasCheckedIn(booking models.Booking, email string) bool {
+	for _, checkIn := range booking.CheckIns {
+		if checkIn.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// checks whether an email belongs to the creator or an invited attendee of a booking
+func IsBookingAttendee(booking models.Booking, email string) bool {
+	return email == booking.Creator || Contains(booking.Emails, email)
+}
@@ -336,3 +336,81 @@ func TestGetBlacklistPipeLineDesc(t *testing.T) {
 		t.Errorf("GetBlacklistPipeLine() = %v, want greater than 0", res)
 	}
 }
+
+func TestAggregateNoShowsByUser(t *testing.T) {
+	creatorEmail := "test@example.com"
+	attendeeEmails := []string{"test@example.com"}
+	filter := models.AnalyticsFilterStruct{Filter: bson.M{}, Limit: 10}
+
+	res := analytics.AggregateNoShowsByUser(creatorEmail, attendeeEmails, filter, "start")
+
+	// check len is greater than 0
+	if len(res) == 0 {
+		t.Errorf("AggregateNoShowsByUser() = %v, want greater than 0", res)
+	}
+}
+
+func TestAggregateRSVPsByRoom(t *testing.T) {
+	creatorEmail := "test@example.com"
+	attendeeEmails := []string{"test@example.com"}
+	filter := models.AnalyticsFilterStruct{Filter: bson.M{}, Limit: 10}
+
+	res := analytics.AggregateRSVPsByRoom(creatorEmail, attendeeEmails, filter, "start")
+
+	// check len is greater than 0
+	if len(res) == 0 {
+		t.Errorf("AggregateRSVPsByRoom() = %v, want greater than 0", res)
+	}
+}
+
+func TestAggregateOccupancyByRoom(t *testing.T) {
+	creatorEmail := "test@example.com"
+	attendeeEmails := []string{"test@example.com"}
+	filter := models.AnalyticsFilterStruct{Filter: bson.M{}, Limit: 10}
+
+	res := analytics.AggregateOccupancyByRoom(creatorEmail, attendeeEmails, filter, "start")
+
+	// check len is greater than 0
+	if len(res) == 0 {
+		t.Errorf("AggregateOccupancyByRoom() = %v, want greater than 0", res)
+	}
+}
+
+func TestAppendLocationFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   models.AnalyticsFilterStruct
+		expected bson.D
+	}{
+		{
+			name:     "no location given",
+			filter:   models.AnalyticsFilterStruct{Filter: bson.M{}},
+			expected: bson.D{},
+		},
+		{
+			name:   "site only",
+			filter: models.AnalyticsFilterStruct{Filter: bson.M{"siteId": "SITE1", "buildingId": ""}},
+			expected: bson.D{
+				{Key: "siteId", Value: bson.D{{Key: "$eq", Value: "SITE1"}}},
+			},
+		},
+		{
+			name:   "site, building and floor",
+			filter: models.AnalyticsFilterStruct{Filter: bson.M{"siteId": "SITE1", "buildingId": "BLD1", "floorNo": "3"}},
+			expected: bson.D{
+				{Key: "siteId", Value: bson.D{{Key: "$eq", Value: "SITE1"}}},
+				{Key: "buildingId", Value: bson.D{{Key: "$eq", Value: "BLD1"}}},
+				{Key: "floorNo", Value: bson.D{{Key: "$eq", Value: "3"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analytics.AppendLocationFilter(bson.D{}, tt.filter)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("AppendLocationFilter() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
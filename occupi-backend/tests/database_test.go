@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -16,6 +17,7 @@ import (
 	"github.com/go-redis/redismock/v9"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/ipinfo/go/v2/ipinfo"
+	expo "github.com/oliveroneill/exponent-server-sdk-golang/sdk"
 
 	"github.com/stretchr/testify/assert"
 
@@ -8073,6 +8075,33 @@ func TestToggleOnsite(t *testing.T) {
 		assert.Contains(t, err.Error(), "update error")
 	})
 
+	mt.Run("Unknown building leaves the user offsite", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch, bson.D{
+				{Key: "email", Value: email},
+				{Key: "onSite", Value: false},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch),
+		)
+
+		// Call the function under test
+		appsession := &models.AppSession{
+			DB: mt.Client,
+		}
+
+		err := database.ToggleOnsite(ctx, appsession, models.RequestOnsite{
+			Email:      email,
+			OnSite:     "Yes",
+			BuildingID: "B404",
+		})
+
+		// Validate the result
+		assert.EqualError(t, err, "building not found")
+		for _, event := range mt.GetAllStartedEvents() {
+			assert.NotEqual(t, "update", event.CommandName)
+		}
+	})
+
 	mt.Run("Toggle onsite to true and add office hours successfully", func(mt *mtest.T) {
 		mt.AddMockResponses(mtest.CreateCursorResponse(1, configs.GetMongoDBName()+".Users", mtest.FirstBatch, bson.D{
 			{Key: "email", Value: email},
@@ -11740,3 +11769,4051 @@ func TestCheckCoincidingBookings(t *testing.T) {
 		assert.False(t, result)
 	})
 }
+
+func TestFindCoincidingOccurrences(t *testing.T) {
+	occurrences := []models.Booking{
+		{RoomID: "Room1", Start: time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 10, 20, 10, 0, 0, 0, time.UTC)},
+		{RoomID: "Room1", Start: time.Date(2024, 10, 27, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 10, 27, 10, 0, 0, 0, time.UTC)},
+		{RoomID: "Room1", Start: time.Date(2024, 11, 3, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 11, 3, 10, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("No existing bookings", func(t *testing.T) {
+		conflicts := database.FindCoincidingOccurrences(occurrences, []models.Booking{})
+		assert.Empty(t, conflicts)
+	})
+
+	t.Run("Adjacent bookings do not coincide", func(t *testing.T) {
+		existing := []models.Booking{
+			{RoomID: "Room1", Start: time.Date(2024, 10, 20, 10, 0, 0, 0, time.UTC), End: time.Date(2024, 10, 20, 11, 0, 0, 0, time.UTC)},
+		}
+		conflicts := database.FindCoincidingOccurrences(occurrences, existing)
+		assert.Empty(t, conflicts)
+	})
+
+	t.Run("Overlapping bookings return the conflicting dates", func(t *testing.T) {
+		existing := []models.Booking{
+			{RoomID: "Room1", Start: time.Date(2024, 10, 27, 9, 30, 0, 0, time.UTC), End: time.Date(2024, 10, 27, 11, 0, 0, 0, time.UTC)},
+			{RoomID: "Room1", Start: time.Date(2024, 11, 3, 8, 0, 0, 0, time.UTC), End: time.Date(2024, 11, 3, 12, 0, 0, 0, time.UTC)},
+		}
+		conflicts := database.FindCoincidingOccurrences(occurrences, existing)
+		assert.Equal(t, []time.Time{occurrences[1].Start, occurrences[2].Start}, conflicts)
+	})
+}
+
+func TestCheckCoincidingOccurrences(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// Set gin run mode
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	occurrences := []models.Booking{
+		{RoomID: "Room1", Start: time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 10, 20, 10, 0, 0, 0, time.UTC)},
+		{RoomID: "Room1", Start: time.Date(2024, 10, 27, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 10, 27, 10, 0, 0, 0, time.UTC)},
+	}
+
+	mt.Run("Database Is Nil", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: nil}
+
+		conflicts, err := database.CheckCoincidingOccurrences(ctx, appsession, occurrences)
+
+		assert.Error(t, err)
+		assert.Equal(t, "database is nil", err.Error())
+		assert.Nil(t, conflicts)
+	})
+
+	mt.Run("NoConflict", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		conflicts, err := database.CheckCoincidingOccurrences(ctx, appsession, occurrences)
+
+		assert.NoError(t, err)
+		assert.Empty(t, conflicts)
+	})
+
+	mt.Run("WithConflict", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, bson.D{
+			{Key: "roomId", Value: "Room1"},
+			{Key: "start", Value: time.Date(2024, 10, 27, 9, 30, 0, 0, time.UTC)},
+			{Key: "end", Value: time.Date(2024, 10, 27, 10, 30, 0, 0, time.UTC)},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		conflicts, err := database.CheckCoincidingOccurrences(ctx, appsession, occurrences)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{occurrences[1].Start}, conflicts)
+	})
+
+	mt.Run("Find Error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "find error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		conflicts, err := database.CheckCoincidingOccurrences(ctx, appsession, occurrences)
+
+		assert.Error(t, err)
+		assert.Nil(t, conflicts)
+	})
+}
+
+func TestSaveBookingSeries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	series := models.BookingSeries{
+		SeriesID: "series1",
+		RoomID:   "Room1",
+		Creator:  "test@example.com",
+		Recurrence: models.RecurrenceRule{
+			Frequency: constants.Weekly,
+			Count:     2,
+		},
+	}
+	occurrences := []models.Booking{
+		{OccupiID: "OCCUPI01", SeriesID: "series1", RoomID: "Room1", Creator: "test@example.com"},
+		{OccupiID: "OCCUPI02", SeriesID: "series1", RoomID: "Room1", Creator: "test@example.com"},
+	}
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		success, err := database.SaveBookingSeries(ctx, appsession, series, occurrences)
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+
+	mt.Run("Save booking series successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.SaveBookingSeries(ctx, appsession, series, occurrences)
+
+		assert.NoError(t, err)
+		assert.True(t, success)
+	})
+
+	mt.Run("Save booking series successfully and cache occurrences", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		Cache, mock := redismock.NewClientMock()
+
+		for _, occurrence := range occurrences {
+			bookingData, _ := bson.Marshal(occurrence)
+			mock.ExpectSet(cache.RoomBookingKey(occurrence.OccupiID), bookingData, time.Duration(configs.GetCacheEviction())*time.Second).SetVal(string(bookingData))
+		}
+
+		appsession := &models.AppSession{DB: mt.Client, Cache: Cache}
+
+		success, err := database.SaveBookingSeries(ctx, appsession, series, occurrences)
+
+		assert.NoError(t, err)
+		assert.True(t, success)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	mt.Run("Insert series error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "duplicate key error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.SaveBookingSeries(ctx, appsession, series, occurrences)
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+
+	mt.Run("Insert occurrences error removes the series", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   1,
+				Code:    11000,
+				Message: "duplicate key error",
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.SaveBookingSeries(ctx, appsession, series, occurrences)
+
+		assert.Error(t, err)
+		assert.False(t, success)
+
+		events := mt.GetAllStartedEvents()
+		assert.Len(t, events, 4)
+		assert.Equal(t, "delete", events[2].CommandName)
+		assert.Equal(t, "RoomBooking", events[2].Command.Lookup("delete").StringValue())
+		assert.Equal(t, "delete", events[3].CommandName)
+		assert.Equal(t, "BookingSeries", events[3].Command.Lookup("delete").StringValue())
+	})
+}
+
+func TestCancelBookingSeries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	from := time.Date(2024, 10, 27, 9, 0, 0, 0, time.UTC)
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		count, err := database.CancelBookingSeries(ctx, appsession, "series1", "test@example.com", from, constants.WholeSeries)
+
+		assert.Error(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	mt.Run("Invalid scope", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: mt.Client}
+
+		count, err := database.CancelBookingSeries(ctx, appsession, "series1", "test@example.com", from, constants.ThisOccurrence)
+
+		assert.Error(t, err)
+		assert.Equal(t, "invalid cancellation scope", err.Error())
+		assert.Equal(t, int64(0), count)
+	})
+
+	mt.Run("Cancel whole series successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI01"}},
+				bson.D{{Key: "occupiId", Value: "OCCUPI02"}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+			mtest.CreateSuccessResponse(),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		count, err := database.CancelBookingSeries(ctx, appsession, "series1", "test@example.com", from, constants.WholeSeries)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	mt.Run("Cancel this and following successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI02"}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			mtest.CreateSuccessResponse(),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		count, err := database.CancelBookingSeries(ctx, appsession, "series1", "test@example.com", from, constants.ThisAndFollowing)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	mt.Run("Find error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "find error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		count, err := database.CancelBookingSeries(ctx, appsession, "series1", "test@example.com", from, constants.WholeSeries)
+
+		assert.Error(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestCountAttendees(t *testing.T) {
+	tests := []struct {
+		name     string
+		emails   []string
+		creator  string
+		expected int
+	}{
+		{"Creator not in emails", []string{"a@example.com", "b@example.com"}, "c@example.com", 3},
+		{"Creator in emails", []string{"a@example.com", "c@example.com"}, "c@example.com", 2},
+		{"Duplicate emails", []string{"a@example.com", "A@example.com"}, "", 1},
+		{"No emails", []string{}, "c@example.com", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, database.CountAttendees(tt.emails, tt.creator))
+		})
+	}
+}
+
+func TestCheckRoomOccupancy(t *testing.T) {
+	room := models.Room{RoomID: "RM001", MinOccupancy: 2, MaxOccupancy: 4}
+
+	tests := []struct {
+		name         string
+		room         models.Room
+		attendees    int
+		expectedCode string
+		expectErr    bool
+	}{
+		{"Within bounds", room, 3, "", false},
+		{"At maximum", room, 4, "", false},
+		{"Above maximum", room, 5, constants.RoomCapacityExceededCode, true},
+		{"Below minimum", room, 1, constants.RoomUnderOccupiedCode, true},
+		{"Unbounded room", models.Room{RoomID: "RM002"}, 30, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := database.CheckRoomOccupancy(tt.room, tt.attendees)
+			assert.Equal(t, tt.expectedCode, code)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetRoom(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		room, err := database.GetRoom(ctx, appsession, "RM001")
+
+		assert.Error(t, err)
+		assert.Equal(t, models.Room{}, room)
+	})
+
+	mt.Run("Room found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch, bson.D{
+			{Key: "roomId", Value: "RM001"},
+			{Key: "floorNo", Value: "1"},
+			{Key: "minOccupancy", Value: 2},
+			{Key: "maxOccupancy", Value: 4},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		room, err := database.GetRoom(ctx, appsession, "RM001")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "RM001", room.RoomID)
+		assert.Equal(t, 2, room.MinOccupancy)
+		assert.Equal(t, 4, room.MaxOccupancy)
+	})
+
+	mt.Run("Room not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetRoom(ctx, appsession, "RM001")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestAddAttendeesToBooking(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		err := database.AddAttendeesToBooking(ctx, appsession, "OCCUPI01", []string{"a@example.com"})
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Add attendees successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		Cache, mock := redismock.NewClientMock()
+		mock.ExpectDel(cache.RoomBookingKey("OCCUPI01")).SetVal(1)
+
+		appsession := &models.AppSession{DB: mt.Client, Cache: Cache}
+
+		err := database.AddAttendeesToBooking(ctx, appsession, "OCCUPI01", []string{"a@example.com"})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	mt.Run("Update error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "update error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddAttendeesToBooking(ctx, appsession, "OCCUPI01", []string{"a@example.com"})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestUpdateBooking(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	booking := models.Booking{
+		OccupiID: "OCCUPI01",
+		RoomID:   "RM001",
+		Creator:  "test@example.com",
+		Emails:   []string{"test@example.com"},
+		Start:    time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 10, 20, 10, 0, 0, 0, time.UTC),
+	}
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		success, err := database.UpdateBooking(ctx, appsession, booking)
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+
+	mt.Run("Update booking successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		Cache, mock := redismock.NewClientMock()
+		bookingData, _ := bson.Marshal(booking)
+		mock.ExpectSet(cache.RoomBookingKey(booking.OccupiID), bookingData, time.Duration(configs.GetCacheEviction())*time.Second).SetVal(string(bookingData))
+
+		appsession := &models.AppSession{DB: mt.Client, Cache: Cache}
+
+		success, err := database.UpdateBooking(ctx, appsession, booking)
+
+		assert.NoError(t, err)
+		assert.True(t, success)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	mt.Run("Booking not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.UpdateBooking(ctx, appsession, booking)
+
+		assert.Error(t, err)
+		assert.Equal(t, "booking not found", err.Error())
+		assert.False(t, success)
+	})
+
+	mt.Run("Update error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "update error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.UpdateBooking(ctx, appsession, booking)
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+}
+
+func TestIsNotificationScheduled(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	id := primitive.NewObjectID().Hex()
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		assert.False(t, database.IsNotificationScheduled(context.Background(), appsession, id))
+	})
+
+	mt.Run("Invalid id", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.False(t, database.IsNotificationScheduled(context.Background(), appsession, "invalid"))
+	})
+
+	mt.Run("Notification is scheduled", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, configs.GetMongoDBName()+".Notifications", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.True(t, database.IsNotificationScheduled(context.Background(), appsession, id))
+	})
+
+	mt.Run("Notification is not scheduled", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, configs.GetMongoDBName()+".Notifications", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.False(t, database.IsNotificationScheduled(context.Background(), appsession, id))
+	})
+}
+
+func TestDeleteScheduledBookingNotifications(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		err := database.DeleteScheduledBookingNotifications(ctx, appsession, "OCCUPI01")
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Delete successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteScheduledBookingNotifications(ctx, appsession, "OCCUPI01")
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Delete error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "delete error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteScheduledBookingNotifications(ctx, appsession, "OCCUPI01")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestAddToWaitlist(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	entry := models.WaitlistEntry{
+		WaitlistID: "waitlist1",
+		RoomID:     "RM001",
+		Creator:    "test@example.com",
+		Status:     constants.WaitlistWaiting,
+	}
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		success, err := database.AddToWaitlist(ctx, appsession, entry)
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+
+	mt.Run("Add to waitlist successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.AddToWaitlist(ctx, appsession, entry)
+
+		assert.NoError(t, err)
+		assert.True(t, success)
+	})
+
+	mt.Run("Insert error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "duplicate key error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.AddToWaitlist(ctx, appsession, entry)
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+}
+
+func TestGetWaitlistEntry(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		_, err := database.GetWaitlistEntry(ctx, appsession, "waitlist1")
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Entry found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch, bson.D{
+			{Key: "waitlistId", Value: "waitlist1"},
+			{Key: "creator", Value: "test@example.com"},
+			{Key: "status", Value: constants.WaitlistOffered},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		entry, err := database.GetWaitlistEntry(ctx, appsession, "waitlist1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", entry.Creator)
+		assert.Equal(t, constants.WaitlistOffered, entry.Status)
+	})
+
+	mt.Run("Entry not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetWaitlistEntry(ctx, appsession, "waitlist1")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRemoveFromWaitlist(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		success, err := database.RemoveFromWaitlist(ctx, appsession, "waitlist1", "test@example.com")
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+
+	mt.Run("Remove successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.RemoveFromWaitlist(ctx, appsession, "waitlist1", "test@example.com")
+
+		assert.NoError(t, err)
+		assert.True(t, success)
+	})
+
+	mt.Run("Entry not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.RemoveFromWaitlist(ctx, appsession, "waitlist1", "test@example.com")
+
+		assert.Error(t, err)
+		assert.False(t, success)
+	})
+}
+
+func TestGetWaitlistForSlot(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	start := time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		entries, err := database.GetWaitlistForSlot(ctx, appsession, "RM001", start, end)
+
+		assert.Error(t, err)
+		assert.Nil(t, entries)
+	})
+
+	mt.Run("Entries found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch,
+			bson.D{{Key: "waitlistId", Value: "waitlist1"}, {Key: "status", Value: constants.WaitlistWaiting}},
+			bson.D{{Key: "waitlistId", Value: "waitlist2"}, {Key: "status", Value: constants.WaitlistWaiting}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		entries, err := database.GetWaitlistForSlot(ctx, appsession, "RM001", start, end)
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "waitlist1", entries[0].WaitlistID)
+	})
+
+	mt.Run("Find error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "find error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		entries, err := database.GetWaitlistForSlot(ctx, appsession, "RM001", start, end)
+
+		assert.Error(t, err)
+		assert.Nil(t, entries)
+	})
+}
+
+func TestUpdateWaitlistStatus(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		err := database.UpdateWaitlistStatus(ctx, appsession, "waitlist1", constants.WaitlistOffered, time.Now())
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Update successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.UpdateWaitlistStatus(ctx, appsession, "waitlist1", constants.WaitlistOffered, time.Now())
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Expire offers successfully", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch,
+				bson.D{{Key: "waitlistId", Value: "waitlist1"}, {Key: "status", Value: constants.WaitlistOffered}},
+				bson.D{{Key: "waitlistId", Value: "waitlist2"}, {Key: "status", Value: constants.WaitlistOffered}},
+			),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			// the second offer was expired by another call in the meantime
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		expired, err := database.ExpireWaitlistOffers(ctx, appsession)
+
+		assert.NoError(t, err)
+		assert.Len(t, expired, 1)
+		assert.Equal(t, "waitlist1", expired[0].WaitlistID)
+	})
+
+	mt.Run("Update error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "update error",
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.UpdateWaitlistStatus(ctx, appsession, "waitlist1", constants.WaitlistAccepted, time.Time{})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestOfferSlotToWaitlistKeepsBuffer(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	end := start.Add(time.Hour)
+
+	mt.Run("The window is checked with the room's buffer", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch, bson.D{
+				{Key: "waitlistId", Value: "W1"},
+				{Key: "roomId", Value: "R1"},
+				{Key: "creator", Value: "test@example.com"},
+				{Key: "start", Value: start},
+				{Key: "end", Value: end},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingSettings", mtest.FirstBatch, bson.D{
+				{Key: "roomId", Value: "R1"},
+				{Key: "buffer", Value: 15},
+			}),
+			// a booking ends 10 minutes before the window, inside the buffer, so nobody is offered the slot
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, bson.D{
+				{Key: "occupiId", Value: "B1"},
+				{Key: "roomId", Value: "R1"},
+				{Key: "start", Value: start.Add(-time.Hour)},
+				{Key: "end", Value: start.Add(-10 * time.Minute)},
+			}),
+		)
+
+		err := database.OfferSlotToWaitlist(context.Background(), &models.AppSession{DB: mt.Client}, models.Booking{RoomID: "R1", Start: start, End: end})
+
+		assert.NoError(t, err)
+
+		events := mt.GetAllStartedEvents()
+		last := events[len(events)-1]
+		assert.Equal(t, "find", last.CommandName)
+
+		var command struct {
+			Filter bson.M `bson:"filter"`
+		}
+		assert.NoError(t, bson.Unmarshal(last.Command, &command))
+		window := command.Filter["$and"].(bson.A)
+		assert.Equal(t, primitive.NewDateTimeFromTime(end.Add(15*time.Minute)), window[0].(bson.M)["start"].(bson.M)["$lt"])
+		assert.Equal(t, primitive.NewDateTimeFromTime(start.Add(-15*time.Minute)), window[1].(bson.M)["end"].(bson.M)["$gt"])
+	})
+}
+
+func TestReofferExpiredWaitlistOffers(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	end := start.Add(time.Hour)
+
+	mt.Run("The slot of an expired offer goes to the next person", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch, bson.D{
+				{Key: "waitlistId", Value: "W1"},
+				{Key: "roomId", Value: "R1"},
+				{Key: "status", Value: constants.WaitlistOffered},
+				{Key: "start", Value: start},
+				{Key: "end", Value: end},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch, bson.D{
+				{Key: "waitlistId", Value: "W2"},
+				{Key: "roomId", Value: "R1"},
+				{Key: "creator", Value: "next@example.com"},
+				{Key: "start", Value: start},
+				{Key: "end", Value: end},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingSettings", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		// the notification to the next person is not mocked, only the offer is checked
+		_ = database.ReofferExpiredWaitlistOffers(context.Background(), &models.AppSession{DB: mt.Client})
+
+		var offered bool
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName != "update" {
+				continue
+			}
+			var command struct {
+				Updates []struct {
+					Q bson.M `bson:"q"`
+					U bson.M `bson:"u"`
+				} `bson:"updates"`
+			}
+			assert.NoError(t, bson.Unmarshal(event.Command, &command))
+			if command.Updates[0].Q["waitlistId"] == "W2" {
+				offered = command.Updates[0].U["$set"].(bson.M)["status"] == constants.WaitlistOffered
+			}
+		}
+		assert.True(t, offered)
+	})
+}
+
+func TestReleaseNoShowBookings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gracePeriod := 15 * time.Minute
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		released, err := database.ReleaseNoShowBookings(context.Background(), appsession, gracePeriod)
+
+		assert.Error(t, err)
+		assert.Nil(t, released)
+	})
+
+	mt.Run("No bookings to release", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		released, err := database.ReleaseNoShowBookings(context.Background(), appsession, gracePeriod)
+
+		assert.NoError(t, err)
+		assert.Empty(t, released)
+
+		// bookings awaiting approval are never released
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, constants.BookingPending, filter.Lookup("status", "$ne").StringValue())
+	})
+
+	mt.Run("Release booking successfully", func(mt *mtest.T) {
+		booking := bson.D{
+			{Key: "occupiId", Value: "OCCUPI01"},
+			{Key: "roomId", Value: "RM001"},
+			{Key: "creator", Value: "test@example.com"},
+			{Key: "checkedIn", Value: false},
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, booking),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: booking}},
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		Cache, mock := redismock.NewClientMock()
+		mock.ExpectDel(cache.RoomBookingKey("OCCUPI01")).SetVal(1)
+		mock.ExpectDel(cache.UserKey("test@example.com")).SetVal(1)
+
+		appsession := &models.AppSession{DB: mt.Client, Cache: Cache}
+
+		released, err := database.ReleaseNoShowBookings(context.Background(), appsession, gracePeriod)
+
+		assert.NoError(t, err)
+		assert.Len(t, released, 1)
+		assert.Equal(t, "OCCUPI01", released[0].OccupiID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	mt.Run("Booking checked in before release", func(mt *mtest.T) {
+		booking := bson.D{
+			{Key: "occupiId", Value: "OCCUPI01"},
+			{Key: "checkedIn", Value: false},
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, booking),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		released, err := database.ReleaseNoShowBookings(context.Background(), appsession, gracePeriod)
+
+		assert.NoError(t, err)
+		assert.Empty(t, released)
+	})
+}
+
+func TestComputeAvailableSlotsWithSettings(t *testing.T) {
+	date := time.Date(2024, 7, 22, 0, 0, 0, 0, time.Local)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 7, 22, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		bookings []models.Booking
+		settings models.BookingSettings
+		expected []models.Slot
+	}{
+		{
+			name:     "Custom opening hours",
+			bookings: []models.Booking{},
+			settings: models.BookingSettings{OpeningTime: "07:30", ClosingTime: "12:00"},
+			expected: []models.Slot{{Start: at(7, 30), End: at(12, 0)}},
+		},
+		{
+			name:     "Buffer around bookings",
+			bookings: []models.Booking{{Start: at(10, 0), End: at(11, 0)}},
+			settings: models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00", Buffer: 15},
+			expected: []models.Slot{
+				{Start: at(8, 0), End: at(9, 45)},
+				{Start: at(11, 15), End: at(17, 0)},
+			},
+		},
+		{
+			name:     "Slots of fixed length aligned to opening",
+			bookings: []models.Booking{{Start: at(9, 0), End: at(9, 45)}},
+			settings: models.BookingSettings{OpeningTime: "08:00", ClosingTime: "11:00", SlotLength: 30},
+			expected: []models.Slot{
+				{Start: at(8, 0), End: at(8, 30)},
+				{Start: at(8, 30), End: at(9, 0)},
+				{Start: at(10, 0), End: at(10, 30)},
+				{Start: at(10, 30), End: at(11, 0)},
+			},
+		},
+		{
+			name:     "Blackouts are skipped",
+			bookings: []models.Booking{},
+			settings: models.BookingSettings{
+				OpeningTime: "08:00",
+				ClosingTime: "17:00",
+				Blackouts:   []models.Blackout{{Start: at(12, 0), End: at(13, 0), Reason: "Cleaning"}},
+			},
+			expected: []models.Slot{
+				{Start: at(8, 0), End: at(12, 0)},
+				{Start: at(13, 0), End: at(17, 0)},
+			},
+		},
+		{
+			name: "Unsorted and overlapping bookings",
+			bookings: []models.Booking{
+				{Start: at(14, 0), End: at(15, 0)},
+				{Start: at(9, 0), End: at(12, 0)},
+				{Start: at(10, 0), End: at(11, 0)},
+			},
+			settings: models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00"},
+			expected: []models.Slot{
+				{Start: at(8, 0), End: at(9, 0)},
+				{Start: at(12, 0), End: at(14, 0)},
+				{Start: at(15, 0), End: at(17, 0)},
+			},
+		},
+		{
+			name:     "Invalid hours fall back to the defaults",
+			bookings: []models.Booking{},
+			settings: models.BookingSettings{OpeningTime: "late", ClosingTime: "17:00"},
+			expected: []models.Slot{{Start: at(8, 0), End: at(17, 0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := database.ComputeAvailableSlotsWithSettings(tt.bookings, date, tt.settings)
+			assert.Equal(t, tt.expected, slots)
+		})
+	}
+}
+
+func TestResolveBookingSettings(t *testing.T) {
+	defaults := models.BookingSettings{OpeningTime: "07:00", ClosingTime: "18:00", Blackouts: []models.Blackout{{Reason: "Public holiday"}}}
+	building := models.BookingSettings{BuildingID: "BLD1", OpeningTime: "08:00", ClosingTime: "16:00", Buffer: 10}
+	room := models.BookingSettings{RoomID: "RM001", OpeningTime: "09:00", ClosingTime: "15:00", SlotLength: 30, Blackouts: []models.Blackout{{Reason: "Maintenance"}}}
+	otherRoom := models.BookingSettings{RoomID: "RM002", OpeningTime: "10:00", ClosingTime: "11:00"}
+
+	t.Run("No settings", func(t *testing.T) {
+		resolved := database.ResolveBookingSettings(nil, "RM001", "BLD1")
+		assert.Equal(t, database.DefaultBookingSettings(), resolved)
+	})
+
+	t.Run("Room settings take precedence", func(t *testing.T) {
+		resolved := database.ResolveBookingSettings([]models.BookingSettings{defaults, room, building, otherRoom}, "RM001", "BLD1")
+		assert.Equal(t, "09:00", resolved.OpeningTime)
+		assert.Equal(t, 30, resolved.SlotLength)
+		assert.Len(t, resolved.Blackouts, 2)
+	})
+
+	t.Run("Building settings override defaults", func(t *testing.T) {
+		resolved := database.ResolveBookingSettings([]models.BookingSettings{defaults, building, otherRoom}, "RM003", "BLD1")
+		assert.Equal(t, "08:00", resolved.OpeningTime)
+		assert.Equal(t, 10, resolved.Buffer)
+		assert.Equal(t, []models.Blackout{{Reason: "Public holiday"}}, resolved.Blackouts)
+	})
+
+	t.Run("Other buildings are ignored", func(t *testing.T) {
+		resolved := database.ResolveBookingSettings([]models.BookingSettings{defaults, building}, "RM003", "BLD2")
+		assert.Equal(t, "07:00", resolved.OpeningTime)
+	})
+}
+
+func TestValidateBookingSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.BookingSettings
+		wantErr  bool
+	}{
+		{"Valid settings", models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00", SlotLength: 30, Buffer: 10}, false},
+		{"Invalid opening time", models.BookingSettings{OpeningTime: "8am", ClosingTime: "17:00"}, true},
+		{"Closing before opening", models.BookingSettings{OpeningTime: "17:00", ClosingTime: "08:00"}, true},
+		{"Negative buffer", models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00", Buffer: -5}, true},
+		{"Slot longer than opening hours", models.BookingSettings{OpeningTime: "08:00", ClosingTime: "09:00", SlotLength: 90}, true},
+		{"Blackout ends before it starts", models.BookingSettings{
+			OpeningTime: "08:00",
+			ClosingTime: "17:00",
+			Blackouts:   []models.Blackout{{Start: time.Now().Add(time.Hour), End: time.Now()}},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := database.ValidateBookingSettings(tt.settings)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckBookingAgainstSettings(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 7, 22, hour, minute, 0, 0, time.Local)
+	}
+	settings := models.BookingSettings{
+		OpeningTime: "08:00",
+		ClosingTime: "17:00",
+		SlotLength:  30,
+		Blackouts:   []models.Blackout{{Start: at(12, 0), End: at(13, 0), Reason: "Cleaning"}},
+	}
+
+	tests := []struct {
+		name         string
+		booking      models.Booking
+		expectedCode string
+	}{
+		{"Valid booking", models.Booking{Start: at(9, 0), End: at(10, 30)}, ""},
+		{"Starts before opening", models.Booking{Start: at(7, 30), End: at(9, 0)}, constants.OutsideBusinessHoursCode},
+		{"Ends after closing", models.Booking{Start: at(16, 30), End: at(17, 30)}, constants.OutsideBusinessHoursCode},
+		{"Misaligned start", models.Booking{Start: at(9, 15), End: at(10, 0)}, constants.SlotMisalignedCode},
+		{"Overlaps blackout", models.Booking{Start: at(11, 30), End: at(12, 30)}, constants.BlackoutPeriodCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := database.CheckBookingAgainstSettings(tt.booking, settings)
+			assert.Equal(t, tt.expectedCode, code)
+			if tt.expectedCode == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestApplyBookingBuffer(t *testing.T) {
+	start := time.Date(2024, 7, 22, 10, 0, 0, 0, time.Local)
+	booking := models.Booking{RoomID: "RM001", Start: start, End: start.Add(time.Hour)}
+
+	buffered := database.ApplyBookingBuffer(booking, 15)
+
+	assert.Equal(t, start.Add(-15*time.Minute), buffered.Start)
+	assert.Equal(t, start.Add(75*time.Minute), buffered.End)
+	assert.Equal(t, start, booking.Start)
+}
+
+func TestGetBookingSettings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		_, err := database.GetBookingSettings(ctx, appsession, "RM001")
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Room and building settings", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch, bson.D{
+				{Key: "roomId", Value: "RM001"},
+				{Key: "buildingId", Value: "BLD1"},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingSettings", mtest.FirstBatch,
+				bson.D{
+					{Key: "buildingId", Value: "BLD1"},
+					{Key: "roomId", Value: ""},
+					{Key: "openingTime", Value: "08:00"},
+					{Key: "closingTime", Value: "16:00"},
+					{Key: "buffer", Value: 10},
+				},
+				bson.D{
+					{Key: "buildingId", Value: ""},
+					{Key: "roomId", Value: "RM001"},
+					{Key: "openingTime", Value: "09:00"},
+					{Key: "closingTime", Value: "15:00"},
+					{Key: "slotLength", Value: 30},
+				},
+			),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		settings, err := database.GetBookingSettings(ctx, appsession, "RM001")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "09:00", settings.OpeningTime)
+		assert.Equal(t, 30, settings.SlotLength)
+	})
+
+	mt.Run("Room not found uses defaults", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingSettings", mtest.FirstBatch),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		settings, err := database.GetBookingSettings(ctx, appsession, "RM001")
+
+		assert.NoError(t, err)
+		assert.Equal(t, database.DefaultBookingSettings(), settings)
+	})
+
+	mt.Run("Find error", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "find error"}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetBookingSettings(ctx, appsession, "RM001")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSetBookingSettings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	settings := models.BookingSettings{RoomID: "RM001", OpeningTime: "08:00", ClosingTime: "17:00", SlotLength: 30}
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		err := database.SetBookingSettings(ctx, appsession, settings)
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Settings saved", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.SetBookingSettings(ctx, appsession, settings)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Update error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "update error"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.SetBookingSettings(ctx, appsession, settings)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRoomSearchPipeline(t *testing.T) {
+	request := models.RequestRoomSearch{
+		Start:     time.Date(2024, 7, 22, 9, 0, 0, 0, time.Local),
+		End:       time.Date(2024, 7, 22, 12, 0, 0, 0, time.Local),
+		Duration:  60,
+		Attendees: 4,
+	}
+
+	t.Run("Matches on capacity only", func(t *testing.T) {
+		pipeline := database.RoomSearchPipeline(request, "")
+
+		match := pipeline[0].(bson.M)["$match"].(bson.M)
+		assert.Equal(t, bson.A{
+			bson.M{"maxOccupancy": bson.M{"$gte": 4}},
+			bson.M{"maxOccupancy": 0},
+			bson.M{"maxOccupancy": bson.M{"$exists": false}},
+		}, match["$or"])
+		assert.NotContains(t, match, "floorNo")
+		assert.NotContains(t, match, "amenities")
+
+		addFields := pipeline[3].(bson.M)["$addFields"].(bson.M)
+		assert.Equal(t, bson.M{"$literal": false}, addFields["sameFloor"])
+		// rooms without a maximum occupancy are ranked after the rooms that fit the group
+		assert.Equal(t, math.MaxInt32, addFields["capacityGap"].(bson.M)["$cond"].(bson.A)[2])
+	})
+
+	t.Run("Looks up room, building and global settings", func(t *testing.T) {
+		pipeline := database.RoomSearchPipeline(request, "")
+
+		lookup := pipeline[2].(bson.M)["$lookup"].(bson.M)
+		match := lookup["pipeline"].(bson.A)[0].(bson.M)["$match"].(bson.M)
+		levels := match["$expr"].(bson.M)["$or"].(bson.A)
+		assert.Equal(t, "BookingSettings", lookup["from"])
+		assert.Len(t, levels, 3)
+		assert.Contains(t, levels, bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$roomId", ""}},
+			bson.M{"$eq": bson.A{"$buildingId", ""}},
+		}})
+	})
+
+	t.Run("Filters on floor and amenities", func(t *testing.T) {
+		filtered := request
+		filtered.FloorNo = "3"
+		filtered.Amenities = []string{" Projector", "whiteboard", "projector", ""}
+
+		pipeline := database.RoomSearchPipeline(filtered, "3")
+
+		match := pipeline[0].(bson.M)["$match"].(bson.M)
+		assert.Equal(t, "3", match["floorNo"])
+		assert.Equal(t, bson.M{"$all": []string{"projector", "whiteboard"}}, match["amenities"])
+
+		addFields := pipeline[3].(bson.M)["$addFields"].(bson.M)
+		assert.Equal(t, bson.M{"$eq": bson.A{"$floorNo", "3"}}, addFields["sameFloor"])
+	})
+}
+
+func TestFindSlotsInWindow(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 7, day, hour, minute, 0, 0, time.Local)
+	}
+	settings := models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00"}
+
+	t.Run("Free time is clipped to the window", func(t *testing.T) {
+		bookings := []models.Booking{{Start: at(22, 10, 0), End: at(22, 11, 0)}}
+
+		slots := database.FindSlotsInWindow(bookings, settings, at(22, 9, 0), at(22, 12, 0), 60)
+
+		assert.Equal(t, []models.Slot{
+			{Start: at(22, 9, 0), End: at(22, 10, 0)},
+			{Start: at(22, 11, 0), End: at(22, 12, 0)},
+		}, slots)
+	})
+
+	t.Run("Short gaps are dropped", func(t *testing.T) {
+		bookings := []models.Booking{{Start: at(22, 9, 30), End: at(22, 11, 0)}}
+
+		slots := database.FindSlotsInWindow(bookings, settings, at(22, 9, 0), at(22, 12, 0), 60)
+
+		assert.Equal(t, []models.Slot{{Start: at(22, 11, 0), End: at(22, 12, 0)}}, slots)
+	})
+
+	t.Run("Slots are joined and rounded up", func(t *testing.T) {
+		slotted := models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00", SlotLength: 30}
+		bookings := []models.Booking{{Start: at(22, 10, 0), End: at(22, 10, 30)}}
+
+		slots := database.FindSlotsInWindow(bookings, slotted, at(22, 8, 45), at(22, 12, 0), 50)
+
+		assert.Equal(t, []models.Slot{
+			{Start: at(22, 9, 0), End: at(22, 10, 0)},
+			{Start: at(22, 10, 30), End: at(22, 12, 0)},
+		}, slots)
+	})
+
+	t.Run("Windows span several days", func(t *testing.T) {
+		bookings := []models.Booking{{Start: at(22, 8, 0), End: at(22, 17, 0)}}
+
+		slots := database.FindSlotsInWindow(bookings, settings, at(22, 8, 0), at(23, 10, 0), 60)
+
+		assert.Equal(t, []models.Slot{{Start: at(23, 8, 0), End: at(23, 10, 0)}}, slots)
+	})
+}
+
+func TestSearchAvailableRooms(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	request := models.RequestRoomSearch{
+		Start:     time.Date(2024, 7, 22, 9, 0, 0, 0, time.Local),
+		End:       time.Date(2024, 7, 22, 12, 0, 0, 0, time.Local),
+		Duration:  60,
+		Attendees: 4,
+	}
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		rooms, err := database.SearchAvailableRooms(ctx, appsession, request, "")
+
+		assert.Error(t, err)
+		assert.Nil(t, rooms)
+	})
+
+	mt.Run("Rooms without free slots are dropped", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch,
+			bson.D{
+				{Key: "roomId", Value: "RM001"},
+				{Key: "floorNo", Value: "1"},
+				{Key: "maxOccupancy", Value: 4},
+				{Key: "capacityGap", Value: 0},
+				{Key: "sameFloor", Value: false},
+				{Key: "bookings", Value: bson.A{
+					bson.D{{Key: "start", Value: request.Start}, {Key: "end", Value: request.End}},
+				}},
+				{Key: "settings", Value: bson.A{}},
+			},
+			bson.D{
+				{Key: "roomId", Value: "RM002"},
+				{Key: "floorNo", Value: "2"},
+				{Key: "maxOccupancy", Value: 6},
+				{Key: "capacityGap", Value: 2},
+				{Key: "sameFloor", Value: true},
+				{Key: "bookings", Value: bson.A{}},
+				{Key: "settings", Value: bson.A{}},
+			},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		rooms, err := database.SearchAvailableRooms(ctx, appsession, request, "2")
+
+		assert.NoError(t, err)
+		assert.Len(t, rooms, 1)
+		assert.Equal(t, "RM002", rooms[0].RoomID)
+		assert.True(t, rooms[0].SameFloor)
+		assert.Equal(t, []models.Slot{{Start: request.Start, End: request.End}}, rooms[0].Slots)
+	})
+
+	mt.Run("Aggregate error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "aggregate error"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		rooms, err := database.SearchAvailableRooms(ctx, appsession, request, "")
+
+		assert.Error(t, err)
+		assert.Nil(t, rooms)
+	})
+}
+
+func TestGetDepartmentFloor(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		_, err := database.GetDepartmentFloor(ctx, appsession, "test@example.com")
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Department floor found", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch, bson.D{
+				{Key: "departmentNo", Value: "Engineering"},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Departments", mtest.FirstBatch, bson.D{
+				{Key: "departmentNo", Value: "Engineering"},
+				{Key: "floorNo", Value: "3"},
+			}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		floor, err := database.GetDepartmentFloor(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "3", floor)
+	})
+
+	mt.Run("User without a department", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch, bson.D{
+			{Key: "email", Value: "test@example.com"},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		floor, err := database.GetDepartmentFloor(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", floor)
+	})
+
+	mt.Run("Department not placed on a floor", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch, bson.D{
+				{Key: "departmentNo", Value: "Engineering"},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Departments", mtest.FirstBatch),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		floor, err := database.GetDepartmentFloor(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", floor)
+	})
+
+	mt.Run("User not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetDepartmentFloor(ctx, appsession, "test@example.com")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSetDepartment(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	department := models.Department{DepartmentNo: "Engineering", FloorNo: "3"}
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		assert.Error(t, database.SetDepartment(ctx, appsession, department))
+	})
+
+	mt.Run("Department placed on a floor", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.NoError(t, database.SetDepartment(ctx, appsession, department))
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "Engineering", update.Lookup("q", "departmentNo").StringValue())
+		assert.Equal(t, "3", update.Lookup("u", "$set", "floorNo").StringValue())
+		assert.True(t, update.Lookup("upsert").Boolean())
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "update failed"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.Error(t, database.SetDepartment(ctx, appsession, department))
+	})
+}
+
+func TestGetDepartments(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Departments returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Departments", mtest.FirstBatch,
+			bson.D{{Key: "departmentNo", Value: "Engineering"}, {Key: "floorNo", Value: "3"}},
+			bson.D{{Key: "departmentNo", Value: "Finance"}, {Key: "floorNo", Value: "1"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		departments, err := database.GetDepartments(ctx, appsession)
+
+		assert.NoError(t, err)
+		assert.Len(t, departments, 2)
+		assert.Equal(t, "3", departments[0].FloorNo)
+	})
+
+	mt.Run("No departments", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Departments", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		departments, err := database.GetDepartments(ctx, appsession)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, departments)
+		assert.Empty(t, departments)
+	})
+}
+
+func TestDeleteDepartment(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Department deleted", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.NoError(t, database.DeleteDepartment(ctx, appsession, "Engineering"))
+	})
+
+	mt.Run("Department not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.EqualError(t, database.DeleteDepartment(ctx, appsession, "Engineering"), "department not found")
+	})
+}
+
+func TestApplyRoomUpdate(t *testing.T) {
+	room := models.Room{RoomID: "RM001", RoomNo: "1", FloorNo: "1", MinOccupancy: 1, MaxOccupancy: 4, RoomName: "Room 1"}
+	two, six, zero := 2, 6, 0
+	amenities := []string{"Projector", "projector", "Whiteboard"}
+
+	t.Run("Only set fields are updated", func(t *testing.T) {
+		updated, err := database.ApplyRoomUpdate(room, models.RequestUpdateRoom{RoomID: "RM001", RoomName: "Boardroom", MaxOccupancy: &six, Amenities: &amenities})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Boardroom", updated.RoomName)
+		assert.Equal(t, "1", updated.FloorNo)
+		assert.Equal(t, 1, updated.MinOccupancy)
+		assert.Equal(t, 6, updated.MaxOccupancy)
+		assert.Equal(t, []string{"projector", "whiteboard"}, updated.Amenities)
+	})
+
+	t.Run("Minimum above maximum", func(t *testing.T) {
+		small := models.Room{MinOccupancy: 1, MaxOccupancy: 1}
+
+		_, err := database.ApplyRoomUpdate(small, models.RequestUpdateRoom{RoomID: "RM001", MinOccupancy: &two})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Maximum must be positive", func(t *testing.T) {
+		_, err := database.ApplyRoomUpdate(models.Room{}, models.RequestUpdateRoom{RoomID: "RM001", MinOccupancy: &zero, MaxOccupancy: &zero})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Check-in tokens replaced", func(t *testing.T) {
+		withTokens := room
+		withTokens.CheckInTokens = []string{"NFC-OLD"}
+		tokens := []string{"NFC-1", "BLE-1"}
+
+		updated, err := database.ApplyRoomUpdate(withTokens, models.RequestUpdateRoom{RoomID: "RM001", CheckInTokens: &tokens})
+
+		assert.NoError(t, err)
+		assert.Equal(t, tokens, updated.CheckInTokens)
+	})
+
+	t.Run("Approval settings updated", func(t *testing.T) {
+		requiresApproval := true
+		approvers := []string{"owner@example.com"}
+
+		updated, err := database.ApplyRoomUpdate(room, models.RequestUpdateRoom{RoomID: "RM001", RequiresApproval: &requiresApproval, Approvers: &approvers})
+
+		assert.NoError(t, err)
+		assert.True(t, updated.RequiresApproval)
+		assert.Equal(t, approvers, updated.Approvers)
+		assert.Equal(t, "Room 1", updated.RoomName)
+	})
+}
+
+func TestCheckRoomBookable(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       string
+		expectedCode string
+	}{
+		{"Room without a status", "", ""},
+		{"Active room", constants.RoomActive, ""},
+		{"Inactive room", constants.RoomInactive, constants.RoomUnavailableCode},
+		{"Archived room", constants.RoomArchived, constants.RoomUnavailableCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := database.CheckRoomBookable(models.Room{RoomID: "RM001", Status: tt.status})
+
+			assert.Equal(t, tt.expectedCode, code)
+			if tt.expectedCode == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestUpdateRoom(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	six := 6
+	roomDoc := bson.D{
+		{Key: "roomId", Value: "RM001"},
+		{Key: "roomNo", Value: "1"},
+		{Key: "floorNo", Value: "1"},
+		{Key: "minOccupancy", Value: 1},
+		{Key: "maxOccupancy", Value: 4},
+	}
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		_, err := database.UpdateRoom(ctx, appsession, models.RequestUpdateRoom{RoomID: "RM001"})
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Room updated", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch, roomDoc),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		room, err := database.UpdateRoom(ctx, appsession, models.RequestUpdateRoom{RoomID: "RM001", MaxOccupancy: &six})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 6, room.MaxOccupancy)
+	})
+
+	mt.Run("Room not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.UpdateRoom(ctx, appsession, models.RequestUpdateRoom{RoomID: "RM001"})
+
+		assert.EqualError(t, err, "room not found")
+	})
+
+	mt.Run("Room number taken", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch, roomDoc),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch, bson.D{
+				{Key: "roomId", Value: "RM002"},
+				{Key: "roomNo", Value: "2"},
+			}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.UpdateRoom(ctx, appsession, models.RequestUpdateRoom{RoomID: "RM001", RoomNo: "2"})
+
+		assert.EqualError(t, err, "room already exists")
+	})
+}
+
+func TestSetRoomStatus(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		err := database.SetRoomStatus(ctx, appsession, "RM001", constants.RoomInactive)
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Status updated", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.SetRoomStatus(ctx, appsession, "RM001", constants.RoomArchived)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Room not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.SetRoomStatus(ctx, appsession, "RM001", constants.RoomArchived)
+
+		assert.EqualError(t, err, "room not found")
+	})
+}
+
+func TestLoadTimeZone(t *testing.T) {
+	assert.Equal(t, time.Local, database.LoadTimeZone(""))
+	assert.Equal(t, time.Local, database.LoadTimeZone("Not/AZone"))
+	assert.Equal(t, "Africa/Johannesburg", database.LoadTimeZone("Africa/Johannesburg").String())
+}
+
+func TestCapTimeRangeIn(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	capped := database.CapTimeRangeIn(location)
+
+	assert.Equal(t, location, capped.Location())
+	assert.True(t, capped.Hour() >= 7 && capped.Hour() <= 17)
+}
+
+func TestCheckBookingAgainstSettingsInSiteTimeZone(t *testing.T) {
+	settings := database.DefaultBookingSettings()
+	settings.TimeZone = "America/New_York"
+
+	location, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	// 09:00 in New York is inside business hours even though it is 13:00 or 14:00 in UTC
+	start := time.Date(2024, 9, 16, 9, 0, 0, 0, location)
+	booking := models.Booking{Start: start.UTC(), End: start.Add(time.Hour).UTC()}
+	code, err := database.CheckBookingAgainstSettings(booking, settings)
+	assert.NoError(t, err)
+	assert.Equal(t, "", code)
+
+	// 07:00 in New York is before opening
+	start = time.Date(2024, 9, 16, 7, 0, 0, 0, location)
+	booking = models.Booking{Start: start.UTC(), End: start.Add(time.Hour).UTC()}
+	code, err = database.CheckBookingAgainstSettings(booking, settings)
+	assert.Error(t, err)
+	assert.Equal(t, constants.OutsideBusinessHoursCode, code)
+}
+
+func TestAddSite(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		_, err := database.AddSite(ctx, appsession, models.Site{Name: "Pretoria", TimeZone: "Africa/Johannesburg"})
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("Site added", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		siteID, err := database.AddSite(ctx, appsession, models.Site{Name: "Pretoria", TimeZone: "Africa/Johannesburg"})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, siteID)
+	})
+}
+
+func TestUpdateSite(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	site := models.Site{SiteID: "SITE1", Name: "Pretoria", TimeZone: "Africa/Johannesburg"}
+
+	mt.Run("Site updated", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.UpdateSite(ctx, appsession, site)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Site not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.UpdateSite(ctx, appsession, site)
+
+		assert.EqualError(t, err, "site not found")
+	})
+}
+
+func TestDeleteSite(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Site still has buildings", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch, bson.D{
+			{Key: "n", Value: int64(2)},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteSite(ctx, appsession, "SITE1")
+
+		assert.EqualError(t, err, "site has buildings")
+	})
+
+	mt.Run("Site deleted", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteSite(ctx, appsession, "SITE1")
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Site not found", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteSite(ctx, appsession, "SITE1")
+
+		assert.EqualError(t, err, "site not found")
+	})
+}
+
+func TestAddBuilding(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	building := models.Building{SiteID: "SITE1", Name: "Main Building"}
+
+	mt.Run("Site not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Sites", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.AddBuilding(ctx, appsession, building)
+
+		assert.EqualError(t, err, "site not found")
+	})
+
+	mt.Run("Building added", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Sites", mtest.FirstBatch, bson.D{
+				{Key: "siteId", Value: "SITE1"},
+				{Key: "timeZone", Value: "Africa/Johannesburg"},
+			}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		buildingID, err := database.AddBuilding(ctx, appsession, building)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, buildingID)
+	})
+}
+
+func TestUpdateBuilding(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	building := models.Building{BuildingID: "BLD1", SiteID: "SITE2", Name: "Main Building"}
+	site := mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Sites", mtest.FirstBatch, bson.D{
+		{Key: "siteId", Value: "SITE2"},
+	})
+
+	mt.Run("Building moved with its rooms", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			site,
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 3}, {Key: "nModified", Value: 3}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.UpdateBuilding(ctx, appsession, building)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Building not found", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			site,
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.UpdateBuilding(ctx, appsession, building)
+
+		assert.EqualError(t, err, "building not found")
+	})
+}
+
+func TestDeleteBuilding(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Building still has floors", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Floors", mtest.FirstBatch, bson.D{
+			{Key: "n", Value: int64(1)},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteBuilding(ctx, appsession, "BLD1")
+
+		assert.EqualError(t, err, "building has floors")
+	})
+
+	mt.Run("Building deleted", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Floors", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteBuilding(ctx, appsession, "BLD1")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestAddFloor(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	floor := models.Floor{BuildingID: "BLD1", FloorNo: "3", Name: "Third Floor"}
+	building := mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch, bson.D{
+		{Key: "buildingId", Value: "BLD1"},
+		{Key: "siteId", Value: "SITE1"},
+	})
+
+	mt.Run("Building not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddFloor(ctx, appsession, floor)
+
+		assert.EqualError(t, err, "building not found")
+	})
+
+	mt.Run("Floor already exists", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			building,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Floors", mtest.FirstBatch, bson.D{
+				{Key: "n", Value: int64(1)},
+			}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddFloor(ctx, appsession, floor)
+
+		assert.EqualError(t, err, "floor already exists")
+	})
+
+	mt.Run("Floor added", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			building,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Floors", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddFloor(ctx, appsession, floor)
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestDeleteFloor(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Floor still has rooms", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch, bson.D{
+			{Key: "n", Value: int64(4)},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteFloor(ctx, appsession, "BLD1", "3")
+
+		assert.EqualError(t, err, "floor has rooms")
+	})
+
+	mt.Run("Floor not found", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteFloor(ctx, appsession, "BLD1", "3")
+
+		assert.EqualError(t, err, "floor not found")
+	})
+}
+
+func TestPlaceRoomInBuilding(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	room := models.Room{RoomID: "RM001", FloorNo: "3"}
+	building := mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch, bson.D{
+		{Key: "buildingId", Value: "BLD1"},
+		{Key: "siteId", Value: "SITE1"},
+	})
+
+	mt.Run("Room placed", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			building,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Floors", mtest.FirstBatch, bson.D{
+				{Key: "n", Value: int64(1)},
+			}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		placed, err := database.PlaceRoomInBuilding(ctx, appsession, room, "BLD1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "BLD1", placed.BuildingID)
+		assert.Equal(t, "SITE1", placed.SiteID)
+	})
+
+	mt.Run("Floor not in building", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			building,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Floors", mtest.FirstBatch),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.PlaceRoomInBuilding(ctx, appsession, room, "BLD1")
+
+		assert.EqualError(t, err, "floor not found")
+	})
+
+	mt.Run("Building not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.PlaceRoomInBuilding(ctx, appsession, room, "BLD1")
+
+		assert.EqualError(t, err, "building not found")
+	})
+}
+
+func TestResolveOfficeLocation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	building := mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch, bson.D{
+		{Key: "buildingId", Value: "BLD1"},
+		{Key: "siteId", Value: "SITE1"},
+	})
+	site := mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Sites", mtest.FirstBatch, bson.D{
+		{Key: "siteId", Value: "SITE1"},
+		{Key: "timeZone", Value: "Europe/London"},
+	})
+
+	mt.Run("No location given", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: mt.Client}
+
+		officeHours, location, err := database.ResolveOfficeLocation(ctx, appsession, models.RequestOnsite{Email: "test@example.com", OnSite: "Yes"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Local, location)
+		assert.Equal(t, "test@example.com", officeHours.Email)
+	})
+
+	mt.Run("Site taken from building", func(mt *mtest.T) {
+		mt.AddMockResponses(building, site)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		officeHours, location, err := database.ResolveOfficeLocation(ctx, appsession, models.RequestOnsite{Email: "test@example.com", OnSite: "Yes", BuildingID: "BLD1", FloorNo: "3"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Europe/London", location.String())
+		assert.Equal(t, "SITE1", officeHours.SiteID)
+		assert.Equal(t, "3", officeHours.FloorNo)
+	})
+
+	mt.Run("Building in another site", func(mt *mtest.T) {
+		mt.AddMockResponses(building)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, _, err := database.ResolveOfficeLocation(ctx, appsession, models.RequestOnsite{Email: "test@example.com", OnSite: "Yes", SiteID: "SITE2", BuildingID: "BLD1"})
+
+		assert.EqualError(t, err, "building is not part of the site")
+	})
+
+	mt.Run("Site not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Sites", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, _, err := database.ResolveOfficeLocation(ctx, appsession, models.RequestOnsite{Email: "test@example.com", OnSite: "Yes", SiteID: "SITE9"})
+
+		assert.EqualError(t, err, "site not found")
+	})
+}
+
+func TestDeskSlotTimes(t *testing.T) {
+	settings := database.DefaultBookingSettings()
+	date := time.Date(2024, 9, 16, 0, 0, 0, 0, time.Local)
+	at := func(hour int) time.Time {
+		return time.Date(2024, 9, 16, hour, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		slot  string
+		start time.Time
+		end   time.Time
+	}{
+		{constants.DeskMorning, at(8), at(12)},
+		{constants.DeskAfternoon, at(12), at(17)},
+		{constants.DeskFullDay, at(8), at(17)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slot, func(t *testing.T) {
+			start, end, err := database.DeskSlotTimes(date, tt.slot, settings)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+		})
+	}
+
+	t.Run("Uses the calendar day in the site's timezone", func(t *testing.T) {
+		tokyo := settings
+		tokyo.TimeZone = "Asia/Tokyo"
+		location, _ := time.LoadLocation("Asia/Tokyo")
+
+		start, end, err := database.DeskSlotTimes(time.Date(2024, 9, 16, 0, 0, 0, 0, time.UTC), constants.DeskMorning, tokyo)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 9, 16, 8, 0, 0, 0, location), start)
+		assert.Equal(t, time.Date(2024, 9, 16, 12, 0, 0, 0, location), end)
+	})
+
+	t.Run("No half days when the office opens after midday", func(t *testing.T) {
+		late := settings
+		late.OpeningTime = "13:00"
+		late.ClosingTime = "21:00"
+
+		_, _, err := database.DeskSlotTimes(date, constants.DeskAfternoon, late)
+		assert.Error(t, err)
+
+		start, _, err := database.DeskSlotTimes(date, constants.DeskFullDay, late)
+		assert.NoError(t, err)
+		assert.Equal(t, at(13), start)
+	})
+
+	t.Run("Unknown slot", func(t *testing.T) {
+		_, _, err := database.DeskSlotTimes(date, "evening", settings)
+		assert.Error(t, err)
+	})
+}
+
+func TestBlackoutDuring(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2024, 9, 16, hour, 0, 0, 0, time.Local)
+	}
+	blackouts := []models.Blackout{{Start: at(12), End: at(14), Reason: "Cleaning"}}
+
+	blackout, found := database.BlackoutDuring(at(8), at(13), blackouts)
+	assert.True(t, found)
+	assert.Equal(t, "Cleaning", blackout.Reason)
+
+	_, found = database.BlackoutDuring(at(8), at(12), blackouts)
+	assert.False(t, found)
+}
+
+func TestUsualDeskPipeline(t *testing.T) {
+	since := time.Date(2024, 8, 17, 0, 0, 0, 0, time.Local)
+
+	pipeline := database.UsualDeskPipeline("test@example.com", since)
+
+	assert.Equal(t, bson.M{"email": "test@example.com", "start": bson.M{"$gte": since}}, pipeline[0].(bson.M)["$match"])
+	assert.Equal(t, "$deskId", pipeline[1].(bson.M)["$group"].(bson.M)["_id"])
+	assert.Equal(t, bson.D{{Key: "count", Value: -1}, {Key: "lastBooked", Value: -1}}, pipeline[2].(bson.M)["$sort"])
+	assert.Equal(t, 1, pipeline[3].(bson.M)["$limit"])
+}
+
+func TestFreeDesks(t *testing.T) {
+	desks := []models.Desk{{DeskID: "D1"}, {DeskID: "D2"}, {DeskID: "D3"}}
+
+	assert.Equal(t, []models.Desk{{DeskID: "D1"}, {DeskID: "D3"}}, database.FreeDesks(desks, []string{"D2"}))
+	assert.Equal(t, desks, database.FreeDesks(desks, []string{}))
+}
+
+func TestAssignNeighbourhoodDesks(t *testing.T) {
+	free := []models.Desk{
+		{DeskID: "D1", DeskName: "Desk 1", FloorNo: "3", Neighbourhood: "engineering"},
+		{DeskID: "D2", DeskName: "Desk 2", FloorNo: "3", Neighbourhood: "engineering"},
+	}
+	template := models.DeskBooking{Creator: "lead@example.com", GroupID: "GROUP1", Slot: constants.DeskFullDay}
+
+	t.Run("Everyone gets a desk", func(t *testing.T) {
+		bookings, err := database.AssignNeighbourhoodDesks(free, []string{"a@example.com", "b@example.com"}, template)
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 2)
+		assert.Equal(t, "D1", bookings[0].DeskID)
+		assert.Equal(t, "a@example.com", bookings[0].Email)
+		assert.Equal(t, "D2", bookings[1].DeskID)
+		assert.Equal(t, "GROUP1", bookings[1].GroupID)
+		assert.Equal(t, "lead@example.com", bookings[1].Creator)
+		assert.NotEqual(t, bookings[0].OccupiID, bookings[1].OccupiID)
+	})
+
+	t.Run("Not enough desks", func(t *testing.T) {
+		_, err := database.AssignNeighbourhoodDesks(free, []string{"a@example.com", "b@example.com", "c@example.com"}, template)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestConfirmDeskCheckIn(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	checkIn := models.CheckIn{BookingID: "DESK1", Creator: "test@example.com", Type: constants.DeskCheckIn}
+
+	mt.Run("Checked in and attendance recorded", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".attendance", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.ConfirmCheckIn(ctx, appsession, checkIn)
+
+		assert.NoError(t, err)
+		assert.True(t, success)
+	})
+
+	mt.Run("Desk not booked for the user", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		success, err := database.ConfirmCheckIn(ctx, appsession, checkIn)
+
+		assert.EqualError(t, err, "booking not found")
+		assert.False(t, success)
+	})
+}
+
+func TestCheckInWindowOpen(t *testing.T) {
+	start := time.Date(2024, 7, 22, 8, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 7, 22, 12, 0, 0, 0, time.UTC)
+
+	assert.False(t, database.CheckInWindowOpen(start, end, start.Add(-16*time.Minute)))
+	assert.True(t, database.CheckInWindowOpen(start, end, start.Add(-15*time.Minute)))
+	assert.True(t, database.CheckInWindowOpen(start, end, start.Add(3*time.Hour)))
+	assert.False(t, database.CheckInWindowOpen(start, end, end))
+	assert.False(t, database.CheckInWindowOpen(start, end, start.AddDate(0, 0, -1)))
+}
+
+func TestAddDesk(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	desk := models.Desk{DeskID: "D1", DeskName: "Desk 1", FloorNo: "3", Neighbourhood: "engineering"}
+
+	mt.Run("Desk added", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Desks", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddDesk(ctx, appsession, desk)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Desk already exists", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Desks", mtest.FirstBatch, bson.D{
+			{Key: "n", Value: int64(1)},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddDesk(ctx, appsession, desk)
+
+		assert.EqualError(t, err, "desk already exists")
+	})
+
+	mt.Run("Floor not in building", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Buildings", mtest.FirstBatch, bson.D{
+				{Key: "buildingId", Value: "BLD1"},
+				{Key: "siteId", Value: "SITE1"},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Floors", mtest.FirstBatch),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		placed := desk
+		placed.BuildingID = "BLD1"
+		err := database.AddDesk(ctx, appsession, placed)
+
+		assert.EqualError(t, err, "floor not found")
+	})
+}
+
+func TestBookedDeskIDs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	start := time.Date(2024, 9, 16, 8, 0, 0, 0, time.Local)
+
+	mt.Run("Booked desks returned", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{"D2"}}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		booked, err := database.BookedDeskIDs(ctx, appsession, []string{"D1", "D2"}, start, start.Add(4*time.Hour))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"D2"}, booked)
+	})
+}
+
+func TestCheckCoincidingDeskBookings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	start := time.Date(2024, 9, 16, 8, 0, 0, 0, time.Local)
+
+	mt.Run("No clash", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, coinciding, err := database.CheckCoincidingDeskBookings(ctx, appsession, []string{"test@example.com"}, []string{"D1"}, start, start.Add(4*time.Hour))
+
+		assert.NoError(t, err)
+		assert.False(t, coinciding)
+	})
+
+	mt.Run("Desk already booked", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch, bson.D{
+			{Key: "occupiId", Value: "DESK1"},
+			{Key: "deskId", Value: "D1"},
+			{Key: "email", Value: "other@example.com"},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		existing, coinciding, err := database.CheckCoincidingDeskBookings(ctx, appsession, []string{"test@example.com"}, []string{"D1"}, start, start.Add(4*time.Hour))
+
+		assert.NoError(t, err)
+		assert.True(t, coinciding)
+		assert.Equal(t, "D1", existing.DeskID)
+	})
+}
+
+func TestGetUsualDesk(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Most booked desk returned", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: "D2"},
+				{Key: "count", Value: 5},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Desks", mtest.FirstBatch, bson.D{
+				{Key: "deskId", Value: "D2"},
+				{Key: "deskName", Value: "Desk 2"},
+				{Key: "floorNo", Value: "3"},
+			}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		desk, err := database.GetUsualDesk(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "D2", desk.DeskID)
+	})
+
+	mt.Run("No recent desk bookings", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetUsualDesk(ctx, appsession, "test@example.com")
+
+		assert.EqualError(t, err, "no usual desk")
+	})
+}
+
+func TestCancelDeskBooking(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Booking cancelled", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.CancelDeskBooking(ctx, appsession, "DESK1", "test@example.com")
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Booking not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.CancelDeskBooking(ctx, appsession, "DESK1", "test@example.com")
+
+		assert.EqualError(t, err, "booking not found")
+	})
+}
+
+func TestSeriesCancellationFilter(t *testing.T) {
+	from := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Whole series", func(t *testing.T) {
+		filter, err := database.SeriesCancellationFilter("series1", "test@example.com", from, constants.WholeSeries)
+
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"seriesId": "series1", "creator": "test@example.com"}, filter)
+	})
+
+	t.Run("This and following", func(t *testing.T) {
+		filter, err := database.SeriesCancellationFilter("series1", "test@example.com", from, constants.ThisAndFollowing)
+
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$gte": from}, filter["start"])
+	})
+
+	t.Run("Invalid scope", func(t *testing.T) {
+		_, err := database.SeriesCancellationFilter("series1", "test@example.com", from, constants.ThisOccurrence)
+
+		assert.EqualError(t, err, "invalid cancellation scope")
+	})
+}
+
+func TestFindSeriesOccurrences(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Occurrences returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+			bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "seriesId", Value: "series1"}},
+			bson.D{{Key: "occupiId", Value: "OCCUPI2"}, {Key: "seriesId", Value: "series1"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		occurrences, err := database.FindSeriesOccurrences(ctx, appsession, "series1", "test@example.com", time.Now(), constants.WholeSeries)
+
+		assert.NoError(t, err)
+		assert.Len(t, occurrences, 2)
+		assert.Equal(t, "OCCUPI2", occurrences[1].OccupiID)
+	})
+
+	mt.Run("Invalid scope", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.FindSeriesOccurrences(ctx, appsession, "series1", "test@example.com", time.Now(), "")
+
+		assert.EqualError(t, err, "invalid cancellation scope")
+	})
+}
+
+func TestGetCalendarFeedToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Existing token returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalendarFeeds", mtest.FirstBatch, bson.D{
+			{Key: "email", Value: "test@example.com"},
+			{Key: "token", Value: "abc123"},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		token, err := database.GetCalendarFeedToken(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", token)
+	})
+
+	mt.Run("Token created on first request", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalendarFeeds", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		token, err := database.GetCalendarFeedToken(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Len(t, token, 64)
+	})
+
+	t.Run("Nil database", func(t *testing.T) {
+		_, err := database.GetCalendarFeedToken(ctx, &models.AppSession{}, "test@example.com")
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestResetCalendarFeedToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Token replaced", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		token, err := database.ResetCalendarFeedToken(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Len(t, token, 64)
+	})
+}
+
+func TestGetCalendarFeedEmail(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Token found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalendarFeeds", mtest.FirstBatch, bson.D{
+			{Key: "email", Value: "test@example.com"},
+			{Key: "token", Value: "abc123"},
+		}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		email, err := database.GetCalendarFeedEmail(ctx, appsession, "abc123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", email)
+	})
+
+	mt.Run("Token not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalendarFeeds", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetCalendarFeedEmail(ctx, appsession, "missing")
+
+		assert.EqualError(t, err, "calendar feed not found")
+	})
+}
+
+func TestResetCalDAVPassword(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		err := database.ResetCalDAVPassword(ctx, &models.AppSession{}, "test@example.com", "hash")
+
+		assert.EqualError(t, err, "database is nil")
+	})
+
+	mt.Run("Password replaced", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		err := database.ResetCalDAVPassword(ctx, &models.AppSession{DB: mt.Client}, "test@example.com", "hash")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestGetCalDAVPasswordEmail(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Password found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalDAVPasswords", mtest.FirstBatch, bson.D{
+			{Key: "email", Value: "test@example.com"},
+			{Key: "passwordHash", Value: "hash"},
+		}))
+
+		email, err := database.GetCalDAVPasswordEmail(ctx, &models.AppSession{DB: mt.Client}, "hash")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", email)
+	})
+
+	mt.Run("Password not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalDAVPasswords", mtest.FirstBatch))
+
+		_, err := database.GetCalDAVPasswordEmail(ctx, &models.AppSession{DB: mt.Client}, "missing")
+
+		assert.EqualError(t, err, "caldav password not found")
+	})
+}
+
+func TestGetUpcomingBookings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Bookings returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+			bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "creator", Value: "test@example.com"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.GetUpcomingBookings(ctx, appsession, "test@example.com", time.Now().AddDate(0, 0, constants.CalendarFeedDays))
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 1)
+		assert.Equal(t, "OCCUPI1", bookings[0].OccupiID)
+	})
+}
+
+func TestFindRooms(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Rooms returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch,
+			bson.D{{Key: "roomId", Value: "R1"}, {Key: "roomName", Value: "Boardroom"}},
+			bson.D{{Key: "roomId", Value: "R2"}, {Key: "roomName", Value: "Huddle"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		rooms, err := database.FindRooms(ctx, appsession)
+
+		assert.NoError(t, err)
+		assert.Len(t, rooms, 2)
+		assert.Equal(t, "Huddle", rooms[1].RoomName)
+	})
+
+	t.Run("Nil database", func(t *testing.T) {
+		_, err := database.FindRooms(ctx, &models.AppSession{})
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestGetRoomBookingsInRange(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Bookings returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+			bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "roomId", Value: "R1"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.GetRoomBookingsInRange(ctx, appsession, "R1", time.Now(), time.Time{})
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 1)
+		assert.Equal(t, "OCCUPI1", bookings[0].OccupiID)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "query failed"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetRoomBookingsInRange(ctx, appsession, "R1", time.Now(), time.Now().Add(time.Hour))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestPendingBookingsFilter(t *testing.T) {
+	t.Run("Single booking", func(t *testing.T) {
+		filter := database.PendingBookingsFilter(models.Booking{OccupiID: "OCCUPI1"})
+
+		assert.Equal(t, bson.M{"occupiId": "OCCUPI1", "status": constants.BookingPending}, filter)
+	})
+
+	t.Run("Booking in a series", func(t *testing.T) {
+		filter := database.PendingBookingsFilter(models.Booking{OccupiID: "OCCUPI1", SeriesID: "SERIES1"})
+
+		assert.Equal(t, bson.M{"seriesId": "SERIES1", "status": constants.BookingPending}, filter)
+	})
+}
+
+func TestCanApproveBooking(t *testing.T) {
+	room := models.Room{RoomID: "R1", RequiresApproval: true, Approvers: []string{"owner@example.com"}}
+
+	tests := []struct {
+		name     string
+		email    string
+		isAdmin  bool
+		expected bool
+	}{
+		{"Admin", "admin@example.com", true, true},
+		{"Room owner", "owner@example.com", false, true},
+		{"Other user", "test@example.com", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, database.CanApproveBooking(room, tt.email, tt.isAdmin))
+		})
+	}
+}
+
+func TestGetAdminEmails(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Admins returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+			bson.D{{Key: "email", Value: "admin1@example.com"}},
+			bson.D{{Key: "email", Value: "admin2@example.com"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		emails, err := database.GetAdminEmails(ctx, appsession)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin1@example.com", "admin2@example.com"}, emails)
+	})
+
+	t.Run("Nil database", func(t *testing.T) {
+		_, err := database.GetAdminEmails(ctx, &models.AppSession{})
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestConfirmPendingBookings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Series confirmed", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "seriesId", Value: "SERIES1"}, {Key: "status", Value: constants.BookingPending}},
+				bson.D{{Key: "occupiId", Value: "OCCUPI2"}, {Key: "seriesId", Value: "SERIES1"}, {Key: "status", Value: constants.BookingPending}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}, {Key: "nModified", Value: 2}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.ConfirmPendingBookings(ctx, appsession, models.Booking{OccupiID: "OCCUPI1", SeriesID: "SERIES1"})
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 2)
+		for _, booking := range bookings {
+			assert.Equal(t, constants.BookingConfirmed, booking.Status)
+		}
+	})
+
+	mt.Run("Booking not pending", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.ConfirmPendingBookings(ctx, appsession, models.Booking{OccupiID: "OCCUPI1"})
+
+		assert.EqualError(t, err, "booking is not pending")
+	})
+
+	t.Run("Nil database", func(t *testing.T) {
+		_, err := database.ConfirmPendingBookings(ctx, &models.AppSession{}, models.Booking{OccupiID: "OCCUPI1"})
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestRejectPendingBookings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Single booking rejected", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "status", Value: constants.BookingPending}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.RejectPendingBookings(ctx, appsession, models.Booking{OccupiID: "OCCUPI1"})
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 1)
+	})
+
+	mt.Run("Series rejected", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "seriesId", Value: "SERIES1"}},
+				bson.D{{Key: "occupiId", Value: "OCCUPI2"}, {Key: "seriesId", Value: "SERIES1"}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.RejectPendingBookings(ctx, appsession, models.Booking{OccupiID: "OCCUPI1", SeriesID: "SERIES1"})
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 2)
+	})
+
+	mt.Run("Booking not pending", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.RejectPendingBookings(ctx, appsession, models.Booking{OccupiID: "OCCUPI1"})
+
+		assert.EqualError(t, err, "booking is not pending")
+	})
+}
+
+func TestGetPendingBookings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Admin sees every pending booking", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+			bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "status", Value: constants.BookingPending}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.GetPendingBookings(ctx, appsession, "admin@example.com", true)
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 1)
+	})
+
+	mt.Run("Approver sees their rooms' bookings", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{"R1"}}},
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "roomId", Value: "R1"}},
+			),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.GetPendingBookings(ctx, appsession, "owner@example.com", false)
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 1)
+		assert.Equal(t, "R1", bookings[0].RoomID)
+	})
+
+	mt.Run("User approves no rooms", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{}}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.GetPendingBookings(ctx, appsession, "test@example.com", false)
+
+		assert.NoError(t, err)
+		assert.Empty(t, bookings)
+	})
+}
+
+func TestRSVPFilter(t *testing.T) {
+	start := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	booking := models.Booking{OccupiID: "OCCUPI1", SeriesID: "SERIES1", Start: start}
+
+	t.Run("Single occurrence", func(t *testing.T) {
+		filter := database.RSVPFilter(booking, "guest@example.com", false)
+
+		assert.Equal(t, bson.M{"occupiId": "OCCUPI1", "emails": "guest@example.com"}, filter)
+	})
+
+	t.Run("Following occurrences of a series", func(t *testing.T) {
+		filter := database.RSVPFilter(booking, "guest@example.com", true)
+
+		assert.Equal(t, bson.M{"seriesId": "SERIES1", "start": bson.M{"$gte": start}, "emails": "guest@example.com"}, filter)
+	})
+
+	t.Run("Series response to a single booking", func(t *testing.T) {
+		filter := database.RSVPFilter(models.Booking{OccupiID: "OCCUPI1"}, "guest@example.com", true)
+
+		assert.Equal(t, bson.M{"occupiId": "OCCUPI1", "emails": "guest@example.com"}, filter)
+	})
+}
+
+func TestRespondToBooking(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Response recorded", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI1"}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		bookings, err := database.RespondToBooking(ctx, appsession, models.Booking{OccupiID: "OCCUPI1"}, "guest@example.com", constants.RSVPAccepted, false)
+
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 1)
+	})
+
+	mt.Run("Not invited", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.RespondToBooking(ctx, appsession, models.Booking{OccupiID: "OCCUPI1"}, "stranger@example.com", constants.RSVPDeclined, false)
+
+		assert.EqualError(t, err, "not invited to booking")
+	})
+
+	mt.Run("Update fails", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "occupiId", Value: "OCCUPI1"}},
+			),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "update failed"}),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.RespondToBooking(ctx, appsession, models.Booking{OccupiID: "OCCUPI1"}, "guest@example.com", constants.RSVPAccepted, false)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Nil database", func(t *testing.T) {
+		_, err := database.RespondToBooking(ctx, &models.AppSession{}, models.Booking{OccupiID: "OCCUPI1"}, "guest@example.com", constants.RSVPAccepted, false)
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestRoomCheckInFilter(t *testing.T) {
+	filter := database.RoomCheckInFilter("OCCUPI1", "guest@example.com")
+
+	assert.Equal(t, bson.M{
+		"occupiId": "OCCUPI1",
+		"$or": bson.A{
+			bson.M{"creator": "guest@example.com"},
+			bson.M{"emails": "guest@example.com"},
+		},
+		"checkIns.email": bson.M{"$ne": "guest@example.com"},
+	}, filter)
+}
+
+func TestGetCurrentRoomBooking(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Booking found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+			bson.D{{Key: "occupiId", Value: "OCCUPI1"}, {Key: "roomId", Value: "R1"}, {Key: "creator", Value: "test@example.com"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		booking, found, err := database.GetCurrentRoomBooking(ctx, appsession, "R1", "test@example.com", time.Now())
+
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "OCCUPI1", booking.OccupiID)
+	})
+
+	mt.Run("No booking", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, found, err := database.GetCurrentRoomBooking(ctx, appsession, "R1", "test@example.com", time.Now())
+
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "query failed"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, _, err := database.GetCurrentRoomBooking(ctx, appsession, "R1", "test@example.com", time.Now())
+
+		assert.Error(t, err)
+	})
+}
+
+func TestFreeUntil(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 7, 22, hour, minute, 0, 0, time.Local)
+	}
+	settings := models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00", Buffer: 15}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		bookings []models.Booking
+		settings models.BookingSettings
+		now      time.Time
+		until    time.Time
+		free     bool
+	}{
+		{
+			name:     "Free until closing",
+			bookings: []models.Booking{},
+			settings: settings,
+			now:      at(10, 0),
+			until:    at(17, 0),
+			free:     true,
+		},
+		{
+			name:     "Free until the buffer of the next booking",
+			bookings: []models.Booking{{Start: at(12, 0), End: at(13, 0)}},
+			settings: settings,
+			now:      at(10, 0),
+			until:    at(11, 45),
+			free:     true,
+		},
+		{
+			name:     "Booking in progress",
+			bookings: []models.Booking{{Start: at(9, 0), End: at(10, 30)}},
+			settings: settings,
+			now:      at(10, 0),
+			free:     false,
+		},
+		{
+			name:     "Inside the buffer after a booking",
+			bookings: []models.Booking{{Start: at(9, 0), End: at(9, 50)}},
+			settings: settings,
+			now:      at(10, 0),
+			free:     false,
+		},
+		{
+			name:     "Blacked out",
+			bookings: []models.Booking{},
+			settings: models.BookingSettings{
+				OpeningTime: "08:00",
+				ClosingTime: "17:00",
+				Blackouts:   []models.Blackout{{Start: at(9, 0), End: at(11, 0), Reason: "Cleaning"}},
+			},
+			now:  at(10, 0),
+			free: false,
+		},
+		{
+			name:     "Closed",
+			bookings: []models.Booking{},
+			settings: settings,
+			now:      at(18, 0),
+			free:     false,
+		},
+		{
+			name:     "No settings use the default hours",
+			bookings: []models.Booking{},
+			settings: database.DefaultBookingSettings(),
+			now:      at(21, 0),
+			free:     false,
+		},
+		{
+			// 23:30 UTC on the 21st is 08:30 on the 22nd in Tokyo
+			name:     "Hours on the site's calendar day",
+			bookings: []models.Booking{},
+			settings: models.BookingSettings{OpeningTime: "08:00", ClosingTime: "17:00", TimeZone: "Asia/Tokyo"},
+			now:      time.Date(2024, 7, 21, 23, 30, 0, 0, time.UTC),
+			until:    time.Date(2024, 7, 22, 17, 0, 0, 0, tokyo),
+			free:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, free := database.FreeUntil(tt.bookings, tt.now, tt.settings)
+
+			assert.Equal(t, tt.free, free)
+			if tt.free {
+				assert.Equal(t, tt.until, until)
+			}
+		})
+	}
+}
+
+func TestAdHocBookingEnd(t *testing.T) {
+	now := time.Date(2024, 7, 22, 10, 0, 0, 0, time.Local)
+	slot := models.Slot{Start: now, End: now.Add(90 * time.Minute)}
+
+	tests := []struct {
+		name     string
+		slot     models.Slot
+		duration int
+		expected time.Time
+	}{
+		{"Until the maximum", slot, 0, now.Add(time.Hour)},
+		{"Requested duration", slot, 30, now.Add(30 * time.Minute)},
+		{"Requested duration longer than the maximum", slot, 120, now.Add(time.Hour)},
+		{"Until the room is next needed", models.Slot{Start: now, End: now.Add(20 * time.Minute)}, 30, now.Add(20 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, database.AdHocBookingEnd(tt.slot, tt.duration, time.Hour))
+		})
+	}
+}
+
+func TestSetBookingPolicy(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	policy := models.BookingPolicy{Scope: constants.PolicyScopeRole, Target: constants.Basic, MaxDuration: 120}
+
+	mt.Run("Policy saved", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.NoError(t, database.SetBookingPolicy(ctx, appsession, policy))
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "update failed"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.Error(t, database.SetBookingPolicy(ctx, appsession, policy))
+	})
+}
+
+func TestDeleteBookingPolicy(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Policy deleted", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.NoError(t, database.DeleteBookingPolicy(ctx, appsession, constants.PolicyScopeRoom, "R1"))
+	})
+
+	mt.Run("Policy not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.EqualError(t, database.DeleteBookingPolicy(ctx, appsession, constants.PolicyScopeRoom, "R1"), "booking policy not found")
+	})
+}
+
+func TestGetApplicableBookingPolicies(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Policies returned", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+				bson.D{{Key: "role", Value: constants.Basic}, {Key: "departmentNo", Value: "D1"}},
+			),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingPolicies", mtest.FirstBatch,
+				bson.D{{Key: "scope", Value: constants.PolicyScopeRole}, {Key: "target", Value: constants.Basic}, {Key: "maxDuration", Value: 120}},
+			),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		policies, err := database.GetApplicableBookingPolicies(ctx, appsession, "test@example.com", "R1")
+
+		assert.NoError(t, err)
+		assert.Len(t, policies, 1)
+		assert.Equal(t, 120, policies[0].MaxDuration)
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "query failed"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetApplicableBookingPolicies(ctx, appsession, "test@example.com", "R1")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestCountActiveBookings(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Bookings counted", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 3}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		count, err := database.CountActiveBookings(ctx, appsession, "test@example.com", time.Now())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		// the occurrences of a series are grouped by their series id
+		var command struct {
+			Pipeline []bson.M `bson:"pipeline"`
+		}
+		assert.NoError(t, bson.Unmarshal(mt.GetStartedEvent().Command, &command))
+		assert.Equal(t, bson.M{"_id": bson.M{"$ifNull": bson.A{"$seriesId", "$occupiId"}}}, command.Pipeline[1]["$group"])
+	})
+
+	mt.Run("No active bookings", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		count, err := database.CountActiveBookings(ctx, appsession, "test@example.com", time.Now())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestGetBookedMinutes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	weekStart := time.Date(2024, 7, 22, 0, 0, 0, 0, time.UTC)
+
+	mt.Run("Minutes added up", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+			bson.D{{Key: "start", Value: weekStart.Add(9 * time.Hour)}, {Key: "end", Value: weekStart.Add(10 * time.Hour)}},
+			bson.D{{Key: "start", Value: weekStart.Add(33 * time.Hour)}, {Key: "end", Value: weekStart.Add(33*time.Hour + 30*time.Minute)}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		minutes, err := database.GetBookedMinutes(ctx, appsession, "test@example.com", weekStart, weekStart.AddDate(0, 0, 7))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 90, minutes)
+	})
+}
+
+func TestMergeBookingPolicies(t *testing.T) {
+	policies := []models.BookingPolicy{
+		{Scope: constants.PolicyScopeRole, MaxDuration: 240, WeeklyHours: 10},
+		{Scope: constants.PolicyScopeDepartment, MaxDuration: 120, MaxAdvanceDays: 30},
+		{Scope: constants.PolicyScopeRoom, MaxActiveBookings: 2},
+	}
+
+	assert.Equal(t, models.BookingPolicy{MaxDuration: 120, MaxAdvanceDays: 30, MaxActiveBookings: 2, WeeklyHours: 10}, database.MergeBookingPolicies(policies))
+	assert.Equal(t, models.BookingPolicy{}, database.MergeBookingPolicies(nil))
+}
+
+func TestBookingPoliciesFilter(t *testing.T) {
+	filter := database.BookingPoliciesFilter(models.User{Role: constants.Basic, DepartmentNo: "D1"}, "R1")
+
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"scope": constants.PolicyScopeRoom, "target": "R1"},
+		bson.M{"scope": constants.PolicyScopeRole, "target": constants.Basic},
+		bson.M{"scope": constants.PolicyScopeDepartment, "target": "D1"},
+	}}, filter)
+}
+
+func TestCheckBookingAgainstPolicy(t *testing.T) {
+	now := time.Date(2024, 7, 22, 9, 0, 0, 0, time.UTC)
+	policy := models.BookingPolicy{MaxDuration: 120, MaxAdvanceDays: 14}
+
+	tests := []struct {
+		name     string
+		booking  models.Booking
+		expected string
+	}{
+		{"Within the policy", models.Booking{Start: now.Add(time.Hour), End: now.Add(3 * time.Hour)}, ""},
+		{"Too long", models.Booking{Start: now.Add(time.Hour), End: now.Add(4 * time.Hour)}, constants.PolicyMaxDurationCode},
+		{"Too far ahead", models.Booking{Start: now.AddDate(0, 0, 15), End: now.AddDate(0, 0, 15).Add(time.Hour)}, constants.PolicyAdvanceWindowCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := database.CheckBookingAgainstPolicy(tt.booking, policy, now)
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+}
+
+func TestCheckBookingQuota(t *testing.T) {
+	start := time.Date(2024, 7, 24, 9, 0, 0, 0, time.UTC)
+	booking := models.Booking{Start: start, End: start.Add(2 * time.Hour)}
+	policy := models.BookingPolicy{MaxActiveBookings: 3, WeeklyHours: 10}
+
+	tests := []struct {
+		name           string
+		activeBookings int64
+		bookedMinutes  int
+		expected       string
+	}{
+		{"Within the quotas", 1, 6 * 60, ""},
+		{"Too many active bookings", 3, 0, constants.PolicyActiveBookingsCode},
+		{"Weekly hours used up", 0, 9 * 60, constants.PolicyWeeklyQuotaCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := database.CheckBookingQuota(booking, policy, tt.activeBookings, tt.bookedMinutes)
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+}
+
+func TestWeekBounds(t *testing.T) {
+	start, end := database.WeekBounds(time.Date(2024, 7, 28, 15, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, time.Date(2024, 7, 22, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 7, 29, 0, 0, 0, 0, time.UTC), end)
+}
+
+func TestGrantDelegation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Delegation granted", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.NoError(t, database.GrantDelegation(ctx, appsession, "manager@example.com", "assistant@example.com"))
+	})
+
+	mt.Run("Database error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "update failed"}))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.Error(t, database.GrantDelegation(ctx, appsession, "manager@example.com", "assistant@example.com"))
+	})
+}
+
+func TestRevokeDelegation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Delegation revoked", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.NoError(t, database.RevokeDelegation(ctx, appsession, "manager@example.com", "assistant@example.com"))
+	})
+
+	mt.Run("Delegation not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		assert.EqualError(t, database.RevokeDelegation(ctx, appsession, "manager@example.com", "assistant@example.com"), "delegation not found")
+	})
+}
+
+func TestGetDelegations(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Delegations returned", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch,
+			bson.D{{Key: "principal", Value: "manager@example.com"}, {Key: "delegate", Value: "assistant@example.com"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		delegations, err := database.GetDelegations(ctx, appsession, "assistant@example.com")
+
+		assert.NoError(t, err)
+		assert.Len(t, delegations, 1)
+		assert.Equal(t, "manager@example.com", delegations[0].Principal)
+	})
+}
+
+func TestIsDelegate(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mt.Run("Delegate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 1}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		delegated, err := database.IsDelegate(ctx, appsession, "manager@example.com", "assistant@example.com")
+
+		assert.NoError(t, err)
+		assert.True(t, delegated)
+	})
+
+	mt.Run("Not a delegate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		delegated, err := database.IsDelegate(ctx, appsession, "manager@example.com", "other@example.com")
+
+		assert.NoError(t, err)
+		assert.False(t, delegated)
+	})
+}
+
+func TestClaimNotification(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ctx := context.Background()
+
+	mt.Run("Claimed", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		claimed, err := database.ClaimNotification(ctx, appsession, "60b725f10c9e9d63e5ecf26a")
+
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+
+	mt.Run("Already sent", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		claimed, err := database.ClaimNotification(ctx, appsession, "60b725f10c9e9d63e5ecf26a")
+
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+
+	mt.Run("Invalid id", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: mt.Client}
+
+		claimed, err := database.ClaimNotification(ctx, appsession, "invalid_id")
+
+		assert.Error(t, err)
+		assert.False(t, claimed)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		claimed, err := database.ClaimNotification(ctx, &models.AppSession{}, "60b725f10c9e9d63e5ecf26a")
+
+		assert.EqualError(t, err, "database is nil")
+		assert.False(t, claimed)
+	})
+}
+
+func TestFailedNotifications(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	id := primitive.NewObjectID()
+
+	mt.Run("Add", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddFailedNotification(context.Background(), appsession, models.FailedNotification{
+			Notification: models.ScheduledNotification{Title: "Booking Starting Soon"},
+			Reason:       "rate limited",
+			Attempts:     5,
+			FailedAt:     time.Now(),
+		})
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Get all", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".FailedNotifications", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: id}, {Key: "reason", Value: "rate limited"}, {Key: "attempts", Value: 5}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		failed, err := database.GetFailedNotifications(ctx, appsession)
+
+		assert.NoError(t, err)
+		assert.Len(t, failed, 1)
+		assert.Equal(t, "rate limited", failed[0].Reason)
+	})
+
+	mt.Run("Get one", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".FailedNotifications", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: id}, {Key: "attempts", Value: 5}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		failed, err := database.GetFailedNotification(ctx, appsession, id.Hex())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, failed.Attempts)
+	})
+
+	mt.Run("Not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".FailedNotifications", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		_, err := database.GetFailedNotification(ctx, appsession, id.Hex())
+
+		assert.EqualError(t, err, "failed notification not found")
+	})
+
+	mt.Run("Delete", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.DeleteFailedNotification(ctx, appsession, id.Hex())
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestGetNotificationRecipients(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ctx := context.Background()
+
+	mt.Run("Recipients", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+			bson.D{
+				{Key: "email", Value: "test@example.com"},
+				{Key: "notifications", Value: bson.D{
+					{Key: "invites", Value: true},
+					{Key: "channels", Value: bson.D{{Key: constants.NotificationInvite, Value: bson.A{constants.ChannelEmail}}}},
+				}},
+			},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		users, err := database.GetNotificationRecipients(ctx, appsession, []string{"test@example.com"})
+
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, []string{constants.ChannelEmail}, users[0].Notifications.Channels[constants.NotificationInvite])
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		users, err := database.GetNotificationRecipients(ctx, &models.AppSession{}, []string{"test@example.com"})
+
+		assert.EqualError(t, err, "database is nil")
+		assert.Nil(t, users)
+	})
+}
+
+func TestRecordNotificationDeliveries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ctx := context.Background()
+
+	mt.Run("Record", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.RecordNotificationDeliveries(ctx, appsession, "noti-1", []models.NotificationDelivery{
+			{Channel: constants.ChannelEmail, Email: "test@example.com", Status: constants.DeliverySent, Attempt: 1, At: time.Now()},
+		})
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Nothing to record", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.RecordNotificationDeliveries(ctx, appsession, "noti-1", nil)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		err := database.RecordNotificationDeliveries(ctx, &models.AppSession{}, "noti-1", nil)
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestWebPushSubscriptions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	subscription := models.WebPushSubscription{
+		Endpoint: "https://push.example.com/send/abc",
+		Keys:     models.WebPushKeys{P256dh: "p256dh", Auth: "auth"},
+	}
+
+	mt.Run("Subscribe", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddWebPushSubscription(ctx, appsession, "test@example.com", subscription)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Unsubscribe", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.RemoveWebPushSubscription(ctx, appsession, "test@example.com", subscription.Endpoint)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		assert.EqualError(t, database.AddWebPushSubscription(ctx, &models.AppSession{}, "test@example.com", subscription), "database is nil")
+		assert.EqualError(t, database.RemoveWebPushSubscription(ctx, &models.AppSession{}, "test@example.com", subscription.Endpoint), "database is nil")
+	})
+}
+
+func TestPushTickets(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ctx := context.Background()
+
+	mt.Run("Add", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.AddPushTicket(ctx, appsession, models.PushTicket{
+			TicketID:  "ticket-1",
+			NotiID:    "noti-1",
+			Token:     "ExponentPushToken[abc]",
+			Status:    constants.TicketPending,
+			CreatedAt: time.Now(),
+		})
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Get pending", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".PushTickets", mtest.FirstBatch,
+			bson.D{{Key: "ticketId", Value: "ticket-1"}, {Key: "token", Value: "ExponentPushToken[abc]"}, {Key: "status", Value: constants.TicketPending}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		tickets, err := database.GetPendingPushTickets(ctx, appsession, time.Now())
+
+		assert.NoError(t, err)
+		assert.Len(t, tickets, 1)
+		assert.Equal(t, "ticket-1", tickets[0].TicketID)
+	})
+
+	mt.Run("Resolve", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.ResolvePushTicket(ctx, appsession, "ticket-1", constants.TicketFailed, expo.ErrorDeviceNotRegistered, "not a registered device")
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Pull unsent token", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.PullUnsentPushToken(ctx, appsession, "noti-1", "ExponentPushToken[abc]")
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		assert.EqualError(t, database.AddPushTicket(ctx, appsession, models.PushTicket{}), "database is nil")
+		_, err := database.GetPendingPushTickets(ctx, appsession, time.Now())
+		assert.EqualError(t, err, "database is nil")
+		assert.EqualError(t, database.ResolvePushTicket(ctx, appsession, "ticket-1", constants.TicketDelivered, "", ""), "database is nil")
+		assert.EqualError(t, database.PullUnsentPushToken(ctx, appsession, "noti-1", "ExponentPushToken[abc]"), "database is nil")
+	})
+}
+
+func TestRemoveExpoPushToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ctx := context.Background()
+
+	mt.Run("Removed from users", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+				bson.D{{Key: "email", Value: "test@example.com"}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+		)
+
+		db, mock := redismock.NewClientMock()
+		mock.ExpectDel(cache.UserKey("test@example.com")).SetVal(1)
+
+		appsession := &models.AppSession{DB: mt.Client, Cache: db}
+
+		err := database.RemoveExpoPushToken(ctx, appsession, "ExponentPushToken[abc]")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	mt.Run("No users with the token", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.RemoveExpoPushToken(ctx, appsession, "ExponentPushToken[abc]")
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		err := database.RemoveExpoPushToken(ctx, &models.AppSession{}, "ExponentPushToken[abc]")
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestGetPushDeliveryStats(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	since := time.Now().AddDate(0, 0, -7)
+
+	mt.Run("Stats", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".PushTickets", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: bson.D{{Key: "status", Value: constants.TicketDelivered}}}, {Key: "count", Value: 40}},
+			bson.D{{Key: "_id", Value: bson.D{{Key: "status", Value: constants.TicketPending}}}, {Key: "count", Value: 5}},
+			bson.D{{Key: "_id", Value: bson.D{{Key: "status", Value: constants.TicketFailed}, {Key: "error", Value: expo.ErrorDeviceNotRegistered}}}, {Key: "count", Value: 3}},
+			bson.D{{Key: "_id", Value: bson.D{{Key: "status", Value: constants.TicketFailed}, {Key: "error", Value: expo.ErrorMessageRateExceeded}}}, {Key: "count", Value: 1}},
+			bson.D{{Key: "_id", Value: bson.D{{Key: "status", Value: constants.TicketExpired}}}, {Key: "count", Value: 2}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		stats, err := database.GetPushDeliveryStats(ctx, appsession, since)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PushDeliveryStats{
+			Since:     since,
+			Total:     51,
+			Pending:   5,
+			Delivered: 40,
+			Failed:    4,
+			Expired:   2,
+			Errors:    map[string]int{expo.ErrorDeviceNotRegistered: 3, expo.ErrorMessageRateExceeded: 1},
+		}, stats)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		_, err := database.GetPushDeliveryStats(ctx, &models.AppSession{}, since)
+
+		assert.EqualError(t, err, "database is nil")
+	})
+}
+
+func TestDevices(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	updated := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+	unmatched := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}}
+
+	device := models.Device{
+		Token:      "ExponentPushToken[tablet]",
+		Platform:   "Android",
+		AppVersion: "1.4.0",
+		LastSeen:   time.Now(),
+	}
+
+	mt.Run("Register new device", func(mt *mtest.T) {
+		mt.AddMockResponses(unmatched, unmatched, unmatched, updated)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.RegisterDevice(ctx, appsession, "test@example.com", device)
+
+		assert.NoError(t, err)
+		events := mt.GetAllStartedEvents()
+		assert.Len(t, events, 4)
+	})
+
+	mt.Run("Register device seen before", func(mt *mtest.T) {
+		mt.AddMockResponses(unmatched, unmatched, updated)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.RegisterDevice(ctx, appsession, "test@example.com", device)
+
+		assert.NoError(t, err)
+		assert.Len(t, mt.GetAllStartedEvents(), 3)
+	})
+
+	mt.Run("Unregister", func(mt *mtest.T) {
+		mt.AddMockResponses(updated, unmatched)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		err := database.UnregisterDevice(ctx, appsession, "test@example.com", device.Token)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("Get devices", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+			bson.D{{Key: "devices", Value: bson.A{
+				bson.D{{Key: "token", Value: device.Token}, {Key: "platform", Value: device.Platform}, {Key: "appVersion", Value: device.AppVersion}},
+			}}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		devices, err := database.GetDevices(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Len(t, devices, 1)
+		assert.Equal(t, device.Token, devices[0].Token)
+		assert.Equal(t, "Android", devices[0].Platform)
+	})
+
+	mt.Run("No devices", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+			bson.D{{Key: "email", Value: "test@example.com"}},
+		))
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		devices, err := database.GetDevices(ctx, appsession, "test@example.com")
+
+		assert.NoError(t, err)
+		assert.Empty(t, devices)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		appsession := &models.AppSession{}
+
+		assert.EqualError(t, database.RegisterDevice(ctx, appsession, "test@example.com", device), "database is nil")
+		assert.EqualError(t, database.UnregisterDevice(ctx, appsession, "test@example.com", device.Token), "database is nil")
+		_, err := database.GetDevices(ctx, appsession, "test@example.com")
+		assert.EqualError(t, err, "database is nil")
+	})
+}
@@ -9,10 +9,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -21,11 +24,13 @@ import (
 	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/authenticator"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/handlers"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 
-	// "github.com/COS301-SE-2024/occupi/occupi-backend/pkg/database"
 	// "github.com/COS301-SE-2024/occupi/occupi-backend/pkg/middleware"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/router"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 )
 
 /*
@@ -755,3 +760,566 @@ func TestPingRoute(t *testing.T) {
 //         })
 //     }
 // }
+
+// creates a test context for calling a handler directly, the body is sent as json and the token as the
+// authorization header when they are given
+func newTestContext(method, path, body, token string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest(method, path, bytes.NewBufferString(body))
+	if body != "" {
+		ctx.Request.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		ctx.Request.Header.Set("Authorization", token)
+	}
+	return ctx, w
+}
+
+func TestAuthorizeBookingAction(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	userToken, _, _, _ := authenticator.GenerateToken("assistant@example.com", constants.Basic)
+	adminToken, _, _, _ := authenticator.GenerateToken("admin@example.com", constants.Admin)
+
+	mt.Run("Own booking", func(mt *mtest.T) {
+		ctx, _ := newTestContext("POST", "/api/cancel-booking", "", userToken)
+
+		email, ok := handlers.AuthorizeBookingAction(ctx, &models.AppSession{DB: mt.Client}, "assistant@example.com", "cancel bookings", true)
+
+		assert.True(t, ok)
+		assert.Equal(t, "assistant@example.com", email)
+	})
+
+	mt.Run("Admin acting on another user's booking", func(mt *mtest.T) {
+		ctx, _ := newTestContext("POST", "/api/cancel-booking", "", adminToken)
+
+		email, ok := handlers.AuthorizeBookingAction(ctx, &models.AppSession{DB: mt.Client}, "manager@example.com", "cancel bookings", false)
+
+		assert.True(t, ok)
+		assert.Equal(t, "admin@example.com", email)
+	})
+
+	mt.Run("Delegate acting for their principal", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 1}},
+		))
+		ctx, _ := newTestContext("POST", "/api/cancel-booking", "", userToken)
+
+		_, ok := handlers.AuthorizeBookingAction(ctx, &models.AppSession{DB: mt.Client}, "manager@example.com", "cancel bookings", true)
+
+		assert.True(t, ok)
+	})
+
+	mt.Run("Another user's booking", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch))
+		ctx, w := newTestContext("POST", "/api/cancel-booking", "", userToken)
+
+		_, ok := handlers.AuthorizeBookingAction(ctx, &models.AppSession{DB: mt.Client}, "manager@example.com", "cancel bookings", true)
+
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), constants.ForbiddenCode)
+	})
+
+	mt.Run("Delegates cannot check in for their principal", func(mt *mtest.T) {
+		ctx, w := newTestContext("POST", "/api/cancel-booking", "", userToken)
+
+		_, ok := handlers.AuthorizeBookingAction(ctx, &models.AppSession{DB: mt.Client}, "manager@example.com", "check in", false)
+
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	mt.Run("No token", func(mt *mtest.T) {
+		ctx, w := newTestContext("POST", "/api/cancel-booking", "", "")
+
+		_, ok := handlers.AuthorizeBookingAction(ctx, &models.AppSession{DB: mt.Client}, "manager@example.com", "cancel bookings", true)
+
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestBookingOwnershipComesFromSession(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	userToken, _, _, _ := authenticator.GenerateToken("assistant@example.com", constants.Basic)
+
+	booking := bson.D{
+		{Key: "occupiId", Value: "OCCUPI20240001"},
+		{Key: "creator", Value: "manager@example.com"},
+		{Key: "emails", Value: bson.A{"manager@example.com"}},
+	}
+	entry := bson.D{
+		{Key: "waitlistId", Value: "WL001"},
+		{Key: "creator", Value: "manager@example.com"},
+		{Key: "status", Value: constants.WaitlistOffered},
+	}
+
+	mt.Run("Add attendees to another user's booking", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, booking),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch),
+		)
+		ctx, w := newTestContext("POST", "/api/add-attendees", `{"bookingId":"OCCUPI20240001","creator":"manager@example.com","emails":["guest@example.com"]}`, userToken)
+
+		handlers.AddAttendees(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	mt.Run("Leave another user's waitlist entry", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch, entry),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch),
+		)
+		ctx, w := newTestContext("POST", "/api/leave-waitlist", `{"waitlistId":"WL001","creator":"manager@example.com"}`, userToken)
+
+		handlers.LeaveWaitlist(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	mt.Run("Accept another user's waitlist offer", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Waitlist", mtest.FirstBatch, entry),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch),
+		)
+		ctx, w := newTestContext("POST", "/api/accept-waitlist-offer", `{"waitlistId":"WL001","creator":"manager@example.com"}`, userToken)
+
+		handlers.AcceptWaitlistOffer(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	mt.Run("Join a waitlist for another user", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch))
+		ctx, w := newTestContext("POST", "/api/join-waitlist", `{"roomId":"RM001","roomName":"Room","floorNo":"1","creator":"manager@example.com","emails":["manager@example.com"],"date":"2030-01-01T00:00:00Z","start":"2030-01-01T09:00:00Z","end":"2030-01-01T10:00:00Z"}`, userToken)
+
+		handlers.JoinWaitlist(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestDeskBookingCreatorComesFromSession(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	userToken, _, _, _ := authenticator.GenerateToken("assistant@example.com", constants.Basic)
+
+	mt.Run("Book a desk for another user", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch))
+		ctx, w := newTestContext("POST", "/api/book-desk", `{"deskId":"D1","email":"manager@example.com","date":"2030-01-01T00:00:00Z","slot":"fullday"}`, userToken)
+
+		handlers.BookDesk(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	mt.Run("Book a neighbourhood for a team the user is not part of", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Delegations", mtest.FirstBatch))
+		ctx, w := newTestContext("POST", "/api/book-neighbourhood", `{"neighbourhood":"N1","emails":["manager@example.com","dev@example.com"],"date":"2030-01-01T00:00:00Z","slot":"fullday"}`, userToken)
+
+		handlers.BookNeighbourhood(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestCheckInPendingBooking(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	userToken, _, _, _ := authenticator.GenerateToken("test@example.com", constants.Basic)
+
+	mt.Run("Pending booking", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, bson.D{
+			{Key: "occupiId", Value: "OCCUPI20240001"},
+			{Key: "creator", Value: "test@example.com"},
+			{Key: "emails", Value: bson.A{"test@example.com"}},
+			{Key: "status", Value: constants.BookingPending},
+		}))
+		ctx, w := newTestContext("POST", "/api/check-in", `{"bookingId":"OCCUPI20240001","creator":"test@example.com"}`, userToken)
+
+		handlers.CheckIn(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Booking is awaiting approval")
+	})
+}
+
+func TestCancelBookingInvalidScope(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	mt.Run("Scope is checked before the booking is looked up", func(mt *mtest.T) {
+		ctx, w := newTestContext("POST", "/api/cancel-booking", `{"bookingId":"OCCUPI20240001","roomId":"RM001","roomName":"Room","emails":["test@example.com"],"creator":"test@example.com","floorNo":"1","date":"2030-01-01T00:00:00Z","start":"2030-01-01T09:00:00Z","end":"2030-01-01T10:00:00Z","scope":"single"}`, "")
+
+		handlers.CancelBooking(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "scope must be one of")
+		assert.Empty(t, mt.GetAllStartedEvents())
+	})
+}
+
+func TestValidateDeskCheckIn(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	start := time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC)
+	booking := bson.D{
+		{Key: "occupiId", Value: "DESK1"},
+		{Key: "email", Value: "test@example.com"},
+		{Key: "start", Value: start},
+		{Key: "end", Value: start.Add(4 * time.Hour)},
+	}
+	checkIn := models.CheckIn{BookingID: "DESK1", Creator: "test@example.com", Type: constants.DeskCheckIn}
+
+	mt.Run("During the booking", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch, booking))
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		assert.True(t, handlers.ValidateDeskCheckIn(ctx, &models.AppSession{DB: mt.Client}, checkIn, start.Add(time.Hour)))
+	})
+
+	mt.Run("The day before", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch, booking))
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		assert.False(t, handlers.ValidateDeskCheckIn(ctx, &models.AppSession{DB: mt.Client}, checkIn, start.AddDate(0, 0, -1)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Check-in is not open")
+	})
+
+	mt.Run("After the booking ended", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch, booking))
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		assert.False(t, handlers.ValidateDeskCheckIn(ctx, &models.AppSession{DB: mt.Client}, checkIn, start.Add(5*time.Hour)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	mt.Run("Someone else's desk", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch, booking))
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		other := checkIn
+		other.Creator = "other@example.com"
+
+		assert.False(t, handlers.ValidateDeskCheckIn(ctx, &models.AppSession{DB: mt.Client}, other, start.Add(time.Hour)))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	mt.Run("Booking not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".DeskBooking", mtest.FirstBatch))
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		assert.False(t, handlers.ValidateDeskCheckIn(ctx, &models.AppSession{DB: mt.Client}, checkIn, start))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestSearchAvailableRoomsPrefersDepartmentFloor(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	mt.Run("Department placed on a floor", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch, bson.D{
+				{Key: "departmentNo", Value: "Engineering"},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Departments", mtest.FirstBatch, bson.D{
+				{Key: "departmentNo", Value: "Engineering"},
+				{Key: "floorNo", Value: "3"},
+			}),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Rooms", mtest.FirstBatch),
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		r := gin.New()
+		r.Use(sessions.Sessions("occupi-sessions-store", cookie.NewStore([]byte("secret"))))
+		r.POST("/api/search-rooms", func(ctx *gin.Context) {
+			session := sessions.Default(ctx)
+			session.Set("email", "test@example.com")
+			session.Set("role", constants.Basic)
+			handlers.SearchAvailableRooms(ctx, appsession)
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/search-rooms", bytes.NewBufferString(`{"start":"2030-01-01T08:00:00Z","end":"2030-01-01T17:00:00Z","duration":60,"attendees":2}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// the search ranks rooms on the department's floor first
+		events := mt.GetAllStartedEvents()
+		assert.Contains(t, events[len(events)-1].Command.Lookup("pipeline").String(), `"3"`)
+	})
+}
+
+func TestValidateBookingPolicies(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	weekStart, _ := database.WeekBounds(time.Now().AddDate(0, 0, 7))
+	occurrence := func(day int) models.Booking {
+		start := weekStart.AddDate(0, 0, day).Add(9 * time.Hour)
+		return models.Booking{OccupiID: fmt.Sprintf("B%d", day), Creator: "test@example.com", RoomID: "R1", Start: start, End: start.Add(time.Hour)}
+	}
+	policy := func(key string, value int) bson.D {
+		return bson.D{{Key: "scope", Value: constants.PolicyScopeRoom}, {Key: "target", Value: "R1"}, {Key: key, Value: value}}
+	}
+	users := mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch)
+
+	mt.Run("A new series counts as one active booking", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			users,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingPolicies", mtest.FirstBatch, policy("maxActiveBookings", 2)),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+		)
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		ok := handlers.ValidateBookingPolicies(ctx, &models.AppSession{DB: mt.Client}, occurrence(0), occurrence(1), occurrence(2))
+
+		assert.True(t, ok)
+	})
+
+	mt.Run("A new series over the active bookings", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			users,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingPolicies", mtest.FirstBatch, policy("maxActiveBookings", 2)),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, bson.D{{Key: "n", Value: 2}}),
+		)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		ok := handlers.ValidateBookingPolicies(ctx, &models.AppSession{DB: mt.Client}, occurrence(0), occurrence(1), occurrence(2))
+
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), constants.PolicyActiveBookingsCode)
+		assert.Contains(t, w.Body.String(), "occurrence")
+	})
+
+	mt.Run("Earlier occurrences count toward the weekly hours", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			users,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingPolicies", mtest.FirstBatch, policy("weeklyHours", 2)),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch),
+		)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		ok := handlers.ValidateBookingPolicies(ctx, &models.AppSession{DB: mt.Client}, occurrence(0), occurrence(1), occurrence(2))
+
+		assert.False(t, ok)
+		assert.Contains(t, w.Body.String(), constants.PolicyWeeklyQuotaCode)
+	})
+
+	mt.Run("Occurrences in different weeks", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			users,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingPolicies", mtest.FirstBatch, policy("weeklyHours", 1)),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch),
+		)
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		ok := handlers.ValidateBookingPolicies(ctx, &models.AppSession{DB: mt.Client}, occurrence(0), occurrence(7))
+
+		assert.True(t, ok)
+	})
+
+	mt.Run("A changed booking does not count itself", func(mt *mtest.T) {
+		existing := occurrence(0)
+		mt.AddMockResponses(
+			users,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".BookingPolicies", mtest.FirstBatch, policy("weeklyHours", 1)),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch,
+				bson.D{{Key: "start", Value: existing.Start}, {Key: "end", Value: existing.End}},
+			),
+		)
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		moved := existing
+		moved.Start = existing.Start.Add(2 * time.Hour)
+		moved.End = existing.End.Add(2 * time.Hour)
+
+		ok := handlers.ValidateBookingChangePolicies(ctx, &models.AppSession{DB: mt.Client}, existing, moved)
+
+		assert.True(t, ok)
+	})
+}
+
+func TestCalDAVVisibleBooking(t *testing.T) {
+	booking := models.Booking{
+		OccupiID:  "B1",
+		RoomID:    "R1",
+		Creator:   "creator@example.com",
+		CreatedBy: "assistant@example.com",
+		Emails:    []string{"creator@example.com", "guest@example.com"},
+		Attendees: []models.AttendeeResponse{{Email: "guest@example.com", Response: constants.RSVPAccepted}},
+	}
+
+	t.Run("Creator and attendees see who is meeting", func(t *testing.T) {
+		assert.Equal(t, booking, handlers.CalDAVVisibleBooking(booking, "creator@example.com"))
+		assert.Equal(t, booking, handlers.CalDAVVisibleBooking(booking, "Guest@Example.com"))
+	})
+
+	t.Run("Others only see that the room is busy", func(t *testing.T) {
+		hidden := handlers.CalDAVVisibleBooking(booking, "other@example.com")
+
+		assert.Equal(t, "B1", hidden.OccupiID)
+		assert.Empty(t, hidden.Creator)
+		assert.Empty(t, hidden.CreatedBy)
+		assert.Empty(t, hidden.Emails)
+		assert.Empty(t, hidden.Attendees)
+		assert.NotContains(t, utils.FormatCalendarObject([]models.Booking{hidden}), "example.com")
+	})
+}
+
+func TestBookingLink(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	link := utils.FormatRSVPLink("https://dev.occupi.tech", "OCCUPI20240001", "guest@example.com", constants.RSVPDeclined, false, time.Now().Add(time.Hour))
+	query := link[strings.Index(link, "?")+1:]
+
+	booking := bson.D{
+		{Key: "occupiId", Value: "OCCUPI20240001"},
+		{Key: "roomName", Value: "Boardroom"},
+		{Key: "creator", Value: "guest@example.com"},
+		{Key: "emails", Value: bson.A{"guest@example.com"}},
+	}
+
+	mt.Run("Opening the link does not respond", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, booking))
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest("GET", "/rsvp?"+query, nil)
+
+		handlers.ConfirmBookingLink(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `<form method="post">`)
+		assert.Contains(t, w.Body.String(), `name="response" value="declined"`)
+		assert.Len(t, mt.GetAllStartedEvents(), 1)
+		assert.Equal(t, "find", mt.GetStartedEvent().CommandName)
+	})
+
+	mt.Run("Posting the confirmation responds", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, booking),
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".RoomBooking", mtest.FirstBatch, booking),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest("POST", "/rsvp", strings.NewReader(query))
+		ctx.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		handlers.RespondToBookingLink(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "was saved as <b>declined</b>")
+	})
+
+	mt.Run("Posting with a changed response", func(mt *mtest.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest("POST", "/rsvp", strings.NewReader(strings.Replace(query, "email=guest%40example.com", "email=other%40example.com", 1)))
+		ctx.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		handlers.RespondToBookingLink(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, mt.GetAllStartedEvents())
+	})
+}
+
+func TestReplayFailedNotification(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	id := primitive.NewObjectID()
+
+	mt.Run("Not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".FailedNotifications", mtest.FirstBatch))
+		ctx, w := newTestContext("POST", "/api/replay-failed-notification", `{"id":"`+id.Hex()+`"}`, "")
+
+		handlers.ReplayFailedNotification(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	mt.Run("Missing id", func(mt *mtest.T) {
+		ctx, w := newTestContext("POST", "/api/replay-failed-notification", `{}`, "")
+
+		handlers.ReplayFailedNotification(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestGetPushDeliveryStatsHandler(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	mt.Run("Stats", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".PushTickets", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: bson.D{{Key: "status", Value: constants.TicketDelivered}}}, {Key: "count", Value: 3}},
+		))
+		ctx, w := newTestContext("GET", "/api/push-delivery-stats?days=30", "", "")
+
+		handlers.GetPushDeliveryStats(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"delivered":3`)
+	})
+
+	mt.Run("Invalid days", func(mt *mtest.T) {
+		ctx, w := newTestContext("GET", "/api/push-delivery-stats?days=-1", "", "")
+
+		handlers.GetPushDeliveryStats(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestRegisterDeviceHandler(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	mt.Run("Missing token", func(mt *mtest.T) {
+		ctx, w := newTestContext("POST", "/api/register-device", `{"appVersion":"1.4.0"}`, "")
+
+		handlers.RegisterDevice(ctx, &models.AppSession{DB: mt.Client})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -22,6 +24,7 @@ import (
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/middleware"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
 	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/router"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/utils"
 	// "github.com/stretchr/testify/mock"
 )
 
@@ -1351,3 +1354,70 @@ func TestVerifyMobileUser(t *testing.T) {
 
 }
 */
+
+func TestCalDAVAuth(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	gin.SetMode(configs.GetGinRunMode())
+
+	newRouter := func(appsession *models.AppSession) *gin.Engine {
+		r := gin.New()
+		r.GET("/caldav", func(ctx *gin.Context) { middleware.CalDAVAuth(ctx, appsession) }, func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, ctx.GetString("caldavEmail"))
+		})
+		return r
+	}
+
+	t.Run("No credentials", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/caldav", nil)
+		newRouter(&models.AppSession{}).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+	})
+
+	mt.Run("Valid caldav password", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalDAVPasswords", mtest.FirstBatch, bson.D{
+			{Key: "email", Value: "test@example.com"},
+			{Key: "passwordHash", Value: utils.HashCalDAVPassword("abc123")},
+		}))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/caldav", nil)
+		req.SetBasicAuth("Test@Example.com", "abc123")
+		newRouter(&models.AppSession{DB: mt.Client}).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "test@example.com", w.Body.String())
+
+		// the password is looked up by its hash, never stored as is
+		filter := mt.GetStartedEvent().Command.Lookup("filter", "passwordHash").StringValue()
+		assert.Equal(t, utils.HashCalDAVPassword("abc123"), filter)
+	})
+
+	mt.Run("Calendar feed token is not accepted", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalDAVPasswords", mtest.FirstBatch))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/caldav", nil)
+		req.SetBasicAuth("test@example.com", "feedtoken")
+		newRouter(&models.AppSession{DB: mt.Client}).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	mt.Run("Password of another user", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".CalDAVPasswords", mtest.FirstBatch, bson.D{
+			{Key: "email", Value: "other@example.com"},
+			{Key: "passwordHash", Value: utils.HashCalDAVPassword("abc123")},
+		}))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/caldav", nil)
+		req.SetBasicAuth("test@example.com", "abc123")
+		newRouter(&models.AppSession{DB: mt.Client}).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/notifier"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	notification := models.ScheduledNotification{
+		NotiID:    "noti-1",
+		Type:      constants.NotificationReminder,
+		Title:     "Booking Starting Soon",
+		Message:   "Your booking starts in 15 minutes",
+		BookingID: "booking-1",
+		SendTime:  time.Now(),
+	}
+
+	t.Run("Posts the notification", func(t *testing.T) {
+		var received map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		user := models.User{Email: "test@example.com", Notifications: models.Notifications{WebhookURL: server.URL}}
+
+		err := notifier.WebhookNotifier{Client: notifier.NewWebhookClient(allowAnyIP)}.Notify(&models.AppSession{}, user, notification)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "noti-1", received["notiId"])
+		assert.Equal(t, constants.NotificationReminder, received["type"])
+		assert.Equal(t, "test@example.com", received["email"])
+		assert.Equal(t, "booking-1", received["bookingId"])
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		user := models.User{Email: "test@example.com", Notifications: models.Notifications{WebhookURL: server.URL}}
+
+		err := notifier.WebhookNotifier{Client: notifier.NewWebhookClient(allowAnyIP)}.Notify(&models.AppSession{}, user, notification)
+
+		assert.EqualError(t, err, "webhook responded with status 500")
+	})
+
+	t.Run("Redirects are not followed", func(t *testing.T) {
+		followed := false
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			followed = true
+		}))
+		defer target.Close()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+		}))
+		defer server.Close()
+
+		user := models.User{Email: "test@example.com", Notifications: models.Notifications{WebhookURL: server.URL}}
+
+		err := notifier.WebhookNotifier{Client: notifier.NewWebhookClient(allowAnyIP)}.Notify(&models.AppSession{}, user, notification)
+
+		assert.EqualError(t, err, "webhook responded with status 307")
+		assert.False(t, followed)
+	})
+
+	t.Run("Loopback address", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		user := models.User{Email: "test@example.com", Notifications: models.Notifications{WebhookURL: server.URL}}
+
+		err := notifier.WebhookNotifier{}.Notify(&models.AppSession{}, user, notification)
+
+		assert.True(t, errors.Is(err, notifier.ErrWebhookAddress))
+		assert.False(t, called)
+	})
+
+	t.Run("No webhook", func(t *testing.T) {
+		err := notifier.WebhookNotifier{}.Notify(&models.AppSession{}, models.User{Email: "test@example.com"}, notification)
+
+		assert.True(t, errors.Is(err, notifier.ErrNoRecipient))
+	})
+}
+
+// lets the test servers on loopback receive webhooks
+func allowAnyIP(net.IP) bool {
+	return true
+}
+
+func TestChannelsWithoutRecipients(t *testing.T) {
+	notification := models.ScheduledNotification{Title: "Booking Starting Soon"}
+
+	assert.True(t, errors.Is(notifier.ExpoNotifier{}.Notify(&models.AppSession{}, models.User{Email: "test@example.com"}, notification), notifier.ErrNoRecipient))
+	assert.True(t, errors.Is(notifier.EmailNotifier{}.Notify(&models.AppSession{}, models.User{}, notification), notifier.ErrNoRecipient))
+	assert.True(t, errors.Is(notifier.WebPushNotifier{}.Notify(&models.AppSession{}, models.User{Email: "test@example.com"}, notification), notifier.ErrNoRecipient))
+}
+
+func TestDispatch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ctx := context.Background()
+
+	notification := models.ScheduledNotification{
+		NotiID:   "noti-1",
+		Type:     constants.NotificationReminder,
+		Title:    "Booking Starting Soon",
+		Message:  "Your booking starts in 15 minutes",
+		SendTime: time.Now(),
+		Emails:   []string{"test@example.com", "other@example.com"},
+	}
+
+	mt.Run("Sends on chosen channels", func(mt *mtest.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+				bson.D{
+					{Key: "email", Value: "test@example.com"},
+					{Key: "notifications", Value: bson.D{
+						{Key: "invites", Value: true},
+						{Key: "bookingReminder", Value: true},
+						{Key: "webhookUrl", Value: server.URL},
+						{Key: "channels", Value: bson.D{{Key: constants.NotificationReminder, Value: bson.A{constants.ChannelWebhook}}}},
+					}},
+				},
+				bson.D{
+					{Key: "email", Value: "other@example.com"},
+					{Key: "notifications", Value: bson.D{
+						{Key: "invites", Value: true},
+						{Key: "bookingReminder", Value: false},
+					}},
+				},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		retry, err := notifier.Dispatch(ctx, appsession, notification)
+
+		assert.NoError(t, err)
+		assert.Empty(t, retry)
+		assert.Equal(t, 1, calls)
+	})
+
+	mt.Run("Nil database", func(mt *mtest.T) {
+		retry, err := notifier.Dispatch(ctx, &models.AppSession{}, notification)
+
+		assert.EqualError(t, err, "database is nil")
+		assert.Nil(t, retry)
+	})
+}
+
+func TestFetchPushReceipts(t *testing.T) {
+	t.Cleanup(func() { viper.Set(configs.ExpoReceiptsURL, "") })
+
+	t.Run("Receipts", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var request map[string][]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal(t, []string{"ticket-1", "ticket-2", "ticket-3"}, request["ids"])
+
+			_, _ = w.Write([]byte(`{"data": {
+				"ticket-1": {"status": "ok"},
+				"ticket-2": {"status": "error", "message": "not a registered device", "details": {"error": "DeviceNotRegistered"}}
+			}}`))
+		}))
+		defer server.Close()
+		viper.Set(configs.ExpoReceiptsURL, server.URL)
+
+		receipts, err := notifier.FetchPushReceipts(context.Background(), []string{"ticket-1", "ticket-2", "ticket-3"})
+
+		assert.NoError(t, err)
+		assert.Len(t, receipts, 2)
+		assert.Equal(t, "ok", receipts["ticket-1"].Status)
+		assert.Equal(t, "DeviceNotRegistered", receipts["ticket-2"].Details["error"])
+	})
+
+	t.Run("Request rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"errors": [{"code": "VALIDATION_ERROR", "message": "ids must be an array"}]}`))
+		}))
+		defer server.Close()
+		viper.Set(configs.ExpoReceiptsURL, server.URL)
+
+		receipts, err := notifier.FetchPushReceipts(context.Background(), []string{"ticket-1"})
+
+		assert.Error(t, err)
+		assert.Nil(t, receipts)
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		viper.Set(configs.ExpoReceiptsURL, server.URL)
+
+		_, err := notifier.FetchPushReceipts(context.Background(), []string{"ticket-1"})
+
+		assert.EqualError(t, err, "expo responded with status 503")
+	})
+}
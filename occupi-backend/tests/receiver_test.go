@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	expo "github.com/oliveroneill/exponent-server-sdk-golang/sdk"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/COS301-SE-2024/occupi/occupi-backend/configs"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/constants"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/models"
+	"github.com/COS301-SE-2024/occupi/occupi-backend/pkg/receiver"
+)
+
+func TestReconcilePushTicket(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ctx := context.Background()
+
+	updated := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+
+	ticket := models.PushTicket{
+		TicketID:  "ticket-1",
+		NotiID:    "noti-1",
+		Token:     "ExponentPushToken[abc]",
+		Status:    constants.TicketPending,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+
+	mt.Run("Delivered", func(mt *mtest.T) {
+		mt.AddMockResponses(updated, updated)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		receiver.ReconcilePushTicket(ctx, appsession, ticket, models.PushReceipt{Status: expo.SuccessStatus}, true)
+
+		events := mt.GetAllStartedEvents()
+		assert.Len(t, events, 2)
+		assert.Equal(t, "PushTickets", events[0].Command.Lookup("update").StringValue())
+		assert.Equal(t, "Notifications", events[1].Command.Lookup("update").StringValue())
+	})
+
+	mt.Run("Device not registered", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			updated,
+			updated,
+			mtest.CreateCursorResponse(0, configs.GetMongoDBName()+".Users", mtest.FirstBatch,
+				bson.D{{Key: "email", Value: "test@example.com"}},
+			),
+			updated,
+			updated,
+		)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		receiver.ReconcilePushTicket(ctx, appsession, ticket, models.PushReceipt{
+			Status:  "error",
+			Message: "not a registered device",
+			Details: map[string]interface{}{"error": expo.ErrorDeviceNotRegistered},
+		}, true)
+
+		events := mt.GetAllStartedEvents()
+		assert.Len(t, events, 5)
+		assert.Equal(t, "Users", events[3].Command.Lookup("update").StringValue())
+		assert.Equal(t, "Users", events[4].Command.Lookup("update").StringValue())
+	})
+
+	mt.Run("Other failure keeps the token", func(mt *mtest.T) {
+		mt.AddMockResponses(updated, updated)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		receiver.ReconcilePushTicket(ctx, appsession, ticket, models.PushReceipt{
+			Status:  "error",
+			Message: "message too big",
+			Details: map[string]interface{}{"error": expo.ErrorMessageTooBig},
+		}, true)
+
+		assert.Len(t, mt.GetAllStartedEvents(), 2)
+	})
+
+	mt.Run("Receipt not ready", func(mt *mtest.T) {
+		appsession := &models.AppSession{DB: mt.Client}
+
+		receiver.ReconcilePushTicket(ctx, appsession, ticket, models.PushReceipt{}, false)
+
+		assert.Empty(t, mt.GetAllStartedEvents())
+	})
+
+	mt.Run("Receipt expired", func(mt *mtest.T) {
+		mt.AddMockResponses(updated)
+
+		appsession := &models.AppSession{DB: mt.Client}
+
+		expired := ticket
+		expired.CreatedAt = time.Now().Add(-25 * time.Hour)
+
+		receiver.ReconcilePushTicket(ctx, appsession, expired, models.PushReceipt{}, false)
+
+		events := mt.GetAllStartedEvents()
+		assert.Len(t, events, 1)
+		assert.Equal(t, "PushTickets", events[0].Command.Lookup("update").StringValue())
+	})
+}
@@ -1,16 +1,22 @@
 package tests
 
 import (
-	// "encoding/json"
-
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -1160,6 +1166,17 @@ func TestFormatCancellationEmailBodyForAttendees(t *testing.T) {
 	}
 }
 
+func TestFormatBookingRejectedEmailBody(t *testing.T) {
+	start := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+
+	actual := utils.FormatBookingRejectedEmailBody("B123", "Boardroom", start, "Reserved for <board> meeting", "owner@example.com")
+
+	assert.Contains(t, actual, "Your request to book Boardroom was rejected by owner@example.com.")
+	assert.Contains(t, actual, "<b>Booking ID:</b> B123<br>")
+	assert.Contains(t, actual, "<b>Start:</b> "+start.Format(time.RFC1123))
+	assert.Contains(t, actual, "<b>Reason:</b> Reserved for &lt;board&gt; meeting<br>")
+}
+
 func TestFormatEmailVerificationBody(t *testing.T) {
 	tests := []struct {
 		otp      string
@@ -3098,3 +3115,985 @@ func TestFormatIPAddressRemovedEmailBody(t *testing.T) {
 	assert.Contains(t, emailBody, privilegeGranterEmail)
 	assert.Contains(t, emailBody, "The Occupi Team")
 }
+
+func TestExtractRecurrenceRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       map[string]interface{}
+		expected    models.RecurrenceRule
+		isRecurring bool
+		expectErr   bool
+	}{
+		{
+			name:        "No recurrence",
+			input:       map[string]interface{}{"roomId": "RM001"},
+			expected:    models.RecurrenceRule{},
+			isRecurring: false,
+			expectErr:   false,
+		},
+		{
+			name: "Valid recurrence",
+			input: map[string]interface{}{
+				"recurrence": map[string]interface{}{
+					"frequency": "weekly",
+					"interval":  2,
+					"count":     5,
+				},
+			},
+			expected:    models.RecurrenceRule{Frequency: constants.Weekly, Interval: 2, Count: 5},
+			isRecurring: true,
+			expectErr:   false,
+		},
+		{
+			name: "Invalid recurrence",
+			input: map[string]interface{}{
+				"recurrence": map[string]interface{}{
+					"frequency": 1,
+				},
+			},
+			expected:    models.RecurrenceRule{},
+			isRecurring: false,
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, isRecurring, err := utils.ExtractRecurrenceRule(tt.input)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.isRecurring, isRecurring)
+			assert.Equal(t, tt.expected, rule)
+		})
+	}
+}
+
+func TestValidateRecurrenceRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      models.RecurrenceRule
+		expectErr bool
+	}{
+		{"Valid count", models.RecurrenceRule{Frequency: constants.Daily, Count: 3}, false},
+		{"Valid until", models.RecurrenceRule{Frequency: constants.Monthly, Until: time.Now().Add(24 * time.Hour)}, false},
+		{"Invalid frequency", models.RecurrenceRule{Frequency: "yearly", Count: 3}, true},
+		{"Negative interval", models.RecurrenceRule{Frequency: constants.Daily, Interval: -1, Count: 3}, true},
+		{"Negative count", models.RecurrenceRule{Frequency: constants.Daily, Count: -1}, true},
+		{"No count or until", models.RecurrenceRule{Frequency: constants.Weekly}, true},
+		{"Count too large", models.RecurrenceRule{Frequency: constants.Daily, Count: constants.MaxRecurringOccurrences + 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateRecurrenceRule(tt.rule)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExpandRecurringBooking(t *testing.T) {
+	booking := models.Booking{
+		RoomID:  "RM001",
+		Creator: "test@example.com",
+		Date:    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		Start:   time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+		End:     time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Daily with count", func(t *testing.T) {
+		occurrences, err := utils.ExpandRecurringBooking(booking, models.RecurrenceRule{Frequency: constants.Daily, Count: 3})
+		assert.NoError(t, err)
+		assert.Len(t, occurrences, 3)
+		assert.Equal(t, time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC), occurrences[2].Start)
+		assert.Equal(t, time.Date(2024, 2, 2, 10, 0, 0, 0, time.UTC), occurrences[2].End)
+		assert.Equal(t, "RM001", occurrences[2].RoomID)
+	})
+
+	t.Run("Weekly with interval and until", func(t *testing.T) {
+		occurrences, err := utils.ExpandRecurringBooking(booking, models.RecurrenceRule{
+			Frequency: constants.Weekly,
+			Interval:  2,
+			Until:     time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC),
+		})
+		assert.NoError(t, err)
+		assert.Len(t, occurrences, 3)
+		assert.Equal(t, time.Date(2024, 2, 28, 9, 0, 0, 0, time.UTC), occurrences[2].Start)
+	})
+
+	t.Run("Monthly skips missing days", func(t *testing.T) {
+		occurrences, err := utils.ExpandRecurringBooking(booking, models.RecurrenceRule{Frequency: constants.Monthly, Count: 3})
+		assert.NoError(t, err)
+		assert.Len(t, occurrences, 3)
+		assert.Equal(t, time.March, occurrences[1].Start.Month())
+		assert.Equal(t, time.May, occurrences[2].Start.Month())
+	})
+
+	t.Run("Exceptions are removed", func(t *testing.T) {
+		occurrences, err := utils.ExpandRecurringBooking(booking, models.RecurrenceRule{
+			Frequency:  constants.Daily,
+			Count:      3,
+			Exceptions: []time.Time{time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, occurrences, 2)
+		assert.Equal(t, 31, occurrences[0].Start.Day())
+		assert.Equal(t, 2, occurrences[1].Start.Day())
+	})
+
+	t.Run("Until exceeding maximum occurrences", func(t *testing.T) {
+		_, err := utils.ExpandRecurringBooking(booking, models.RecurrenceRule{
+			Frequency: constants.Daily,
+			Until:     booking.Start.AddDate(1, 0, 0),
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("No occurrences", func(t *testing.T) {
+		_, err := utils.ExpandRecurringBooking(booking, models.RecurrenceRule{
+			Frequency:  constants.Daily,
+			Count:      1,
+			Exceptions: []time.Time{booking.Start},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid rule", func(t *testing.T) {
+		_, err := utils.ExpandRecurringBooking(booking, models.RecurrenceRule{Frequency: "hourly", Count: 1})
+		assert.Error(t, err)
+	})
+}
+
+func TestDiffEmails(t *testing.T) {
+	tests := []struct {
+		name            string
+		oldEmails       []string
+		newEmails       []string
+		expectedAdded   []string
+		expectedRemoved []string
+	}{
+		{"No changes", []string{"a@example.com"}, []string{"a@example.com"}, nil, nil},
+		{"Added", []string{"a@example.com"}, []string{"a@example.com", "b@example.com"}, []string{"b@example.com"}, nil},
+		{"Removed", []string{"a@example.com", "b@example.com"}, []string{"a@example.com"}, nil, []string{"b@example.com"}},
+		{"Replaced", []string{"a@example.com"}, []string{"b@example.com", "b@example.com"}, []string{"b@example.com"}, []string{"a@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := utils.DiffEmails(tt.oldEmails, tt.newEmails)
+			assert.Equal(t, tt.expectedAdded, added)
+			assert.Equal(t, tt.expectedRemoved, removed)
+		})
+	}
+}
+
+func TestFormatBookingUpdatedEmailBody(t *testing.T) {
+	start := time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	expected := utils.AppendHeader("Booking") + `
+		<div class="content">
+			<p>Dear attendees,</p>
+			<p>
+				user@example.com has made changes to a booked office space. Here are the updated booking details:<br><br>
+				<b>Booking ID:</b> B123<br>
+				<b>Room ID:</b> R456<br>
+				<b>Start:</b> Sun, 20 Oct 2024 09:00:00 UTC<br>
+				<b>End:</b> Sun, 20 Oct 2024 10:00:00 UTC<br><br>
+				If you have any questions, feel free to contact us.<br><br>
+				Thank you,<br>
+				<b>The Occupi Team</b><br>
+			</p>
+		</div>` + utils.AppendFooter()
+
+	actual := utils.FormatBookingUpdatedEmailBody("B123", "R456", start, end, "user@example.com")
+	if strings.TrimSpace(actual) != strings.TrimSpace(expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestConstructWaitlistOfferString(t *testing.T) {
+	expected := "Boardroom is now available for the time you waitlisted, accept within 15 mins to book it"
+	assert.Equal(t, expected, utils.ConstructWaitlistOfferString("Boardroom", "15 mins"))
+}
+
+func TestConstructNoShowString(t *testing.T) {
+	expected := "Your booking of Boardroom was released because nobody checked in within 15 mins"
+	assert.Equal(t, expected, utils.ConstructNoShowString("Boardroom", "15 mins"))
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		expected []string
+	}{
+		{"Nil tags", nil, []string{}},
+		{"Lowercases and trims", []string{" Projector ", "WHITEBOARD"}, []string{"projector", "whiteboard"}},
+		{"Drops empty and duplicate tags", []string{"vc", "", "VC", "  "}, []string{"vc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.NormalizeTags(tt.tags))
+		})
+	}
+}
+
+func TestApplyRoomFilters(t *testing.T) {
+	t.Run("Archived rooms are hidden by default", func(t *testing.T) {
+		filter := utils.ApplyRoomFilters(nil, nil)
+
+		assert.Equal(t, bson.M{"status": bson.M{"$ne": constants.RoomArchived}}, filter)
+	})
+
+	t.Run("Rooms must have every tag", func(t *testing.T) {
+		filter := utils.ApplyRoomFilters(bson.M{"floorNo": "3"}, []string{"Projector", "vc"})
+
+		assert.Equal(t, "3", filter["floorNo"])
+		assert.Equal(t, bson.M{"$all": []string{"projector", "vc"}}, filter["amenities"])
+	})
+
+	t.Run("Status filter is kept", func(t *testing.T) {
+		filter := utils.ApplyRoomFilters(bson.M{"status": constants.RoomArchived}, nil)
+
+		assert.Equal(t, bson.M{"status": constants.RoomArchived}, filter)
+	})
+}
+
+func TestHideCheckInTokens(t *testing.T) {
+	t.Run("Tokens are left out by default", func(t *testing.T) {
+		filter := utils.HideCheckInTokens(models.FilterStruct{
+			Filter:     bson.M{"floorNo": "3", "checkInTokens": "NFC-1", "checkInTokens.0": "NFC-1"},
+			Projection: bson.M{"password": 0, "_id": 0},
+			Sort:       bson.M{"checkInTokens": 1},
+		})
+
+		assert.Equal(t, bson.M{"floorNo": "3"}, filter.Filter)
+		assert.Equal(t, bson.M{"password": 0, "_id": 0, "checkInTokens": 0}, filter.Projection)
+		assert.Equal(t, bson.M{}, filter.Sort)
+	})
+
+	t.Run("Tokens cannot be projected", func(t *testing.T) {
+		filter := utils.HideCheckInTokens(models.FilterStruct{
+			Projection: bson.M{"roomId": 1, "checkInTokens": 1, "_id": 0},
+		})
+
+		assert.Equal(t, bson.M{"roomId": 1, "_id": 0}, filter.Projection)
+	})
+
+	t.Run("Rooms are sent without their tokens", func(t *testing.T) {
+		body, err := json.Marshal(models.Room{RoomID: "RM001", CheckInTokens: []string{"NFC-1"}})
+
+		assert.NoError(t, err)
+		assert.NotContains(t, string(body), "NFC-1")
+	})
+}
+
+func TestFormatBookingInvite(t *testing.T) {
+	now := time.Date(2024, 9, 1, 8, 0, 0, 0, time.UTC)
+	booking := models.Booking{
+		OccupiID: "OCCUPI123",
+		RoomName: "Boardroom, East",
+		FloorNo:  "3",
+		Creator:  "creator@example.com",
+		Emails:   []string{"creator@example.com", "guest@example.com"},
+		Start:    time.Date(2024, 9, 2, 10, 0, 0, 0, time.FixedZone("SAST", 2*60*60)),
+		End:      time.Date(2024, 9, 2, 11, 0, 0, 0, time.FixedZone("SAST", 2*60*60)),
+	}
+
+	t.Run("Request invite", func(t *testing.T) {
+		invite := utils.FormatBookingInvite(constants.ICalendarRequest, []models.Booking{booking}, now)
+
+		assert.True(t, strings.HasPrefix(invite, "BEGIN:VCALENDAR\r\n"))
+		assert.True(t, strings.HasSuffix(invite, "END:VCALENDAR\r\n"))
+		assert.Contains(t, invite, "METHOD:REQUEST\r\n")
+		assert.Contains(t, invite, "UID:OCCUPI123@occupi.tech\r\n")
+		assert.Contains(t, invite, "DTSTART:20240902T080000Z\r\n")
+		assert.Contains(t, invite, "DTEND:20240902T090000Z\r\n")
+		assert.Contains(t, invite, "LOCATION:Boardroom\\, East\\, Floor 3\r\n")
+		assert.Contains(t, invite, "ORGANIZER:mailto:creator@example.com\r\n")
+		assert.Contains(t, invite, "STATUS:CONFIRMED\r\n")
+		assert.Equal(t, 2, strings.Count(invite, "ATTENDEE;"))
+	})
+
+	t.Run("Cancel invite keeps the uid", func(t *testing.T) {
+		invite := utils.FormatBookingInvite(constants.ICalendarCancel, []models.Booking{booking}, now)
+
+		assert.Contains(t, invite, "METHOD:CANCEL\r\n")
+		assert.Contains(t, invite, "UID:OCCUPI123@occupi.tech\r\n")
+		assert.Contains(t, invite, "STATUS:CANCELLED\r\n")
+	})
+
+	t.Run("Pending booking is tentative", func(t *testing.T) {
+		pending := booking
+		pending.Status = constants.BookingPending
+
+		invite := utils.FormatBookingInvite(constants.ICalendarRequest, []models.Booking{pending}, now)
+
+		assert.Contains(t, invite, "STATUS:TENTATIVE\r\n")
+	})
+
+	t.Run("Later invites have a higher sequence", func(t *testing.T) {
+		first := utils.FormatBookingEvent(booking, constants.ICalendarRequest, now)
+		second := utils.FormatBookingEvent(booking, constants.ICalendarRequest, now.Add(time.Minute))
+
+		assert.Less(t, first[3], second[3])
+	})
+
+	occurrence := func(occupiID string, days int) models.Booking {
+		occurrence := booking
+		occurrence.OccupiID = occupiID
+		occurrence.SeriesID = "SERIES1"
+		occurrence.Start = booking.Start.AddDate(0, 0, days)
+		occurrence.End = booking.End.AddDate(0, 0, days)
+		occurrence.OccurrenceStart = occurrence.Start
+		return occurrence
+	}
+
+	t.Run("New series is one event", func(t *testing.T) {
+		invite := utils.FormatBookingInvite(constants.ICalendarRequest, []models.Booking{occurrence("OCCUPI1", 0), occurrence("OCCUPI2", 7), occurrence("OCCUPI3", 14)}, now)
+
+		assert.Equal(t, 1, strings.Count(invite, "BEGIN:VEVENT"))
+		assert.Contains(t, invite, "UID:SERIES1@occupi.tech\r\n")
+		assert.Contains(t, invite, "DTSTART:20240902T080000Z\r\nDTEND:20240902T090000Z\r\nRDATE:20240909T080000Z,20240916T080000Z\r\n")
+		assert.NotContains(t, invite, "OCCUPI2")
+	})
+
+	t.Run("Cancelled occurrences share the series uid", func(t *testing.T) {
+		invite := utils.FormatBookingInvite(constants.ICalendarCancel, []models.Booking{occurrence("OCCUPI2", 7), occurrence("OCCUPI3", 14)}, now)
+
+		assert.Equal(t, 2, strings.Count(invite, "BEGIN:VEVENT"))
+		assert.Equal(t, 2, strings.Count(invite, "UID:SERIES1@occupi.tech\r\n"))
+		assert.Contains(t, invite, "RECURRENCE-ID:20240909T080000Z\r\n")
+		assert.Contains(t, invite, "RECURRENCE-ID:20240916T080000Z\r\n")
+	})
+
+	t.Run("Moved occurrence keeps its recurrence id", func(t *testing.T) {
+		moved := occurrence("OCCUPI2", 7)
+		moved.Start = moved.Start.Add(2 * time.Hour)
+		moved.End = moved.End.Add(2 * time.Hour)
+
+		invite := utils.FormatBookingInvite(constants.ICalendarRequest, []models.Booking{moved}, now)
+
+		assert.Contains(t, invite, "UID:SERIES1@occupi.tech\r\nRECURRENCE-ID:20240909T080000Z\r\n")
+		assert.Contains(t, invite, "DTSTART:20240909T100000Z\r\n")
+		assert.NotContains(t, invite, "RDATE")
+	})
+}
+
+func TestFormatBookingFeed(t *testing.T) {
+	feed := utils.FormatBookingFeed("Occupi bookings", nil, time.Now())
+
+	assert.Contains(t, feed, "METHOD:PUBLISH\r\n")
+	assert.Contains(t, feed, "X-WR-CALNAME:Occupi bookings\r\n")
+	assert.NotContains(t, feed, "BEGIN:VEVENT")
+}
+
+func TestEscapeICalendarText(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne`, utils.EscapeICalendarText("a\\b;c,d\ne"))
+}
+
+func TestJoinICalendarLines(t *testing.T) {
+	t.Run("Short lines are not folded", func(t *testing.T) {
+		assert.Equal(t, "A:b\r\nC:d\r\n", utils.JoinICalendarLines([]string{"A:b", "C:d"}))
+	})
+
+	t.Run("Long lines are folded at 75 octets", func(t *testing.T) {
+		joined := utils.JoinICalendarLines([]string{"SUMMARY:" + strings.Repeat("x", 150)})
+		lines := strings.Split(strings.TrimSuffix(joined, "\r\n"), "\r\n")
+
+		assert.Len(t, lines, 3)
+		for _, line := range lines {
+			assert.LessOrEqual(t, len(line), 75)
+		}
+		for _, line := range lines[1:] {
+			assert.True(t, strings.HasPrefix(line, " "))
+		}
+		assert.Equal(t, "SUMMARY:"+strings.Repeat("x", 150), strings.ReplaceAll(joined[:len(joined)-2], "\r\n ", ""))
+	})
+
+	t.Run("Multi-byte characters are not split", func(t *testing.T) {
+		joined := utils.JoinICalendarLines([]string{"SUMMARY:" + strings.Repeat("é", 60)})
+
+		for _, line := range strings.Split(joined, "\r\n") {
+			assert.True(t, utf8.ValidString(line))
+		}
+	})
+}
+
+func TestGenerateFeedToken(t *testing.T) {
+	first, err := utils.GenerateFeedToken()
+	assert.NoError(t, err)
+	second, err := utils.GenerateFeedToken()
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 64)
+	assert.NotEqual(t, first, second)
+}
+
+func TestParseICalendarEvent(t *testing.T) {
+	t.Run("UTC event with attendees", func(t *testing.T) {
+		data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:abc-123\r\nSUMMARY:Planning\r\n" +
+			"DTSTART:20240902T080000Z\r\nDTEND:20240902T090000Z\r\n" +
+			"ATTENDEE;CN=\"Doe: Jane\";ROLE=REQ-PARTICIPANT:mailto:Jane@Example.com\r\n" +
+			"ATTENDEE:MAILTO:john@example.com\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+		event, err := utils.ParseICalendarEvent(data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc-123", event.UID)
+		assert.Equal(t, "Planning", event.Summary)
+		assert.Equal(t, time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC), event.Start)
+		assert.Equal(t, time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC), event.End)
+		assert.Equal(t, []string{"jane@example.com", "john@example.com"}, event.Attendees)
+		assert.False(t, event.Recurring)
+	})
+
+	t.Run("Local time in a time zone and folded lines", func(t *testing.T) {
+		data := "BEGIN:VEVENT\nUID:abc\nSUMMARY:A very long summary that\n  is folded\n" +
+			"DTSTART;TZID=Africa/Johannesburg:20240902T100000\nDTEND;TZID=Africa/Johannesburg:20240902T110000\nRRULE:FREQ=DAILY\nEND:VEVENT\n"
+
+		event, err := utils.ParseICalendarEvent(data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "A very long summary that is folded", event.Summary)
+		assert.True(t, event.Start.Equal(time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC)))
+		assert.True(t, event.Recurring)
+	})
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"No event", "BEGIN:VCALENDAR\nEND:VCALENDAR\n", "no event found"},
+		{"All day event", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240902\nEND:VEVENT\n", "all day events cannot be booked"},
+		{"Missing end", "BEGIN:VEVENT\nDTSTART:20240902T080000Z\nEND:VEVENT\n", "event must have a start and an end"},
+		{"Ends before start", "BEGIN:VEVENT\nDTSTART:20240902T090000Z\nDTEND:20240902T080000Z\nEND:VEVENT\n", "event must end after it starts"},
+		{"Unknown time zone", "BEGIN:VEVENT\nDTSTART;TZID=Nowhere/Land:20240902T090000\nEND:VEVENT\n", "unknown time zone Nowhere/Land"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := utils.ParseICalendarEvent(tt.data)
+
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestFormatCalendarObject(t *testing.T) {
+	booking := models.Booking{
+		OccupiID: "OCCUPI123",
+		RoomName: "Boardroom",
+		Start:    time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC),
+	}
+
+	object := utils.FormatCalendarObject([]models.Booking{booking})
+
+	assert.NotContains(t, object, "METHOD:")
+	assert.Contains(t, object, "UID:OCCUPI123@occupi.tech\r\n")
+	assert.Equal(t, object, utils.FormatCalendarObject([]models.Booking{booking}))
+}
+
+func TestFormatFreeBusy(t *testing.T) {
+	start := time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+	bookings := []models.Booking{{
+		Start: time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC),
+	}}
+
+	freeBusy := utils.FormatFreeBusy(bookings, start, start.AddDate(0, 0, 1), time.Now())
+
+	assert.Contains(t, freeBusy, "BEGIN:VFREEBUSY\r\n")
+	assert.Contains(t, freeBusy, "DTSTART:20240902T000000Z\r\n")
+	assert.Contains(t, freeBusy, "DTEND:20240903T000000Z\r\n")
+	assert.Contains(t, freeBusy, "FREEBUSY;FBTYPE=BUSY:20240902T080000Z/20240902T090000Z\r\n")
+}
+
+func TestParseCalDAVReport(t *testing.T) {
+	t.Run("Calendar query", func(t *testing.T) {
+		body := `<?xml version="1.0" encoding="utf-8" ?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">
+    <C:time-range start="20240902T000000Z" end="20240903T000000Z"/>
+  </C:comp-filter></C:comp-filter></C:filter>
+</C:calendar-query>`
+
+		report, err := utils.ParseCalDAVReport([]byte(body))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "calendar-query", report.Name)
+		assert.Equal(t, []string{"getetag", "calendar-data"}, report.Props)
+		assert.Equal(t, time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC), report.Start)
+		assert.Equal(t, time.Date(2024, 9, 3, 0, 0, 0, 0, time.UTC), report.End)
+	})
+
+	t.Run("Calendar multiget", func(t *testing.T) {
+		body := `<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/></D:prop>
+  <D:href>/caldav/rooms/R1/OCCUPI1.ics</D:href>
+  <D:href> /caldav/rooms/R1/OCCUPI2.ics </D:href>
+</C:calendar-multiget>`
+
+		report, err := utils.ParseCalDAVReport([]byte(body))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "calendar-multiget", report.Name)
+		assert.Equal(t, []string{"/caldav/rooms/R1/OCCUPI1.ics", "/caldav/rooms/R1/OCCUPI2.ics"}, report.Hrefs)
+		assert.True(t, report.Start.IsZero())
+	})
+
+	t.Run("Invalid time range", func(t *testing.T) {
+		body := `<C:free-busy-query xmlns:C="urn:ietf:params:xml:ns:caldav"><C:time-range start="tomorrow"/></C:free-busy-query>`
+
+		_, err := utils.ParseCalDAVReport([]byte(body))
+
+		assert.EqualError(t, err, "invalid time-range")
+	})
+
+	t.Run("Empty body", func(t *testing.T) {
+		_, err := utils.ParseCalDAVReport(nil)
+
+		assert.EqualError(t, err, "empty report")
+	})
+}
+
+func TestCalendarETag(t *testing.T) {
+	booking := models.Booking{
+		OccupiID: "OCCUPI123",
+		Start:    time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC),
+	}
+	moved := booking
+	moved.End = moved.End.Add(30 * time.Minute)
+
+	assert.Equal(t, utils.CalendarETag(booking), utils.CalendarETag(booking))
+	assert.NotEqual(t, utils.CalendarETag(booking), utils.CalendarETag(moved))
+	assert.NotEqual(t, utils.CalendarCTag([]models.Booking{booking}), utils.CalendarCTag([]models.Booking{booking, moved}))
+}
+
+func TestSignRSVP(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(time.Hour).Unix()
+	signature := utils.SignRSVP("OCCUPI1", "guest@example.com", expires)
+
+	t.Run("Valid signature", func(t *testing.T) {
+		assert.NoError(t, utils.VerifyRSVP("OCCUPI1", "guest@example.com", expires, signature, now))
+	})
+
+	t.Run("Email case does not matter", func(t *testing.T) {
+		assert.NoError(t, utils.VerifyRSVP("OCCUPI1", "Guest@Example.com", expires, signature, now))
+	})
+
+	t.Run("Signature of another attendee", func(t *testing.T) {
+		assert.EqualError(t, utils.VerifyRSVP("OCCUPI1", "other@example.com", expires, signature, now), "invalid rsvp link")
+	})
+
+	t.Run("Signature of another booking", func(t *testing.T) {
+		assert.EqualError(t, utils.VerifyRSVP("OCCUPI2", "guest@example.com", expires, signature, now), "invalid rsvp link")
+	})
+
+	t.Run("Expiry cannot be extended", func(t *testing.T) {
+		assert.EqualError(t, utils.VerifyRSVP("OCCUPI1", "guest@example.com", expires+3600, signature, now), "invalid rsvp link")
+	})
+
+	t.Run("Expired link", func(t *testing.T) {
+		assert.EqualError(t, utils.VerifyRSVP("OCCUPI1", "guest@example.com", expires, signature, now.Add(2*time.Hour)), "rsvp link has expired")
+	})
+
+	t.Run("Not signed with the jwt secret", func(t *testing.T) {
+		mac := hmac.New(sha256.New, []byte(configs.GetJWTSecret()))
+		mac.Write([]byte("OCCUPI1\nguest@example.com\n" + strconv.FormatInt(expires, 10)))
+		assert.NotEqual(t, hex.EncodeToString(mac.Sum(nil)), signature)
+	})
+}
+
+func TestFormatRSVPConfirmPage(t *testing.T) {
+	page := utils.FormatRSVPConfirmPage("Boardroom", time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC), models.RequestRSVPLink{
+		BookingID: "OCCUPI1",
+		Email:     "guest@example.com",
+		Response:  constants.RSVPAccepted,
+		Series:    true,
+		Expires:   1893456000,
+		Signature: `"><script>`,
+	})
+
+	assert.Contains(t, page, `<form method="post">`)
+	assert.Contains(t, page, `this and the following occurrences of the booking of Boardroom`)
+	assert.Contains(t, page, `<input type="hidden" name="bookingId" value="OCCUPI1">`)
+	assert.Contains(t, page, `<input type="hidden" name="series" value="true">`)
+	assert.Contains(t, page, `<input type="hidden" name="exp" value="1893456000">`)
+	assert.Contains(t, page, `<input type="hidden" name="sig" value="&#34;&gt;&lt;script&gt;">`)
+}
+
+func TestFormatRSVPLink(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	link := utils.FormatRSVPLink("https://dev.occupi.tech/", "OCCUPI1", "guest+1@example.com", constants.RSVPDeclined, true, expires)
+
+	parsed, err := url.Parse(link)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/rsvp", parsed.Path)
+	assert.Equal(t, "OCCUPI1", parsed.Query().Get("bookingId"))
+	assert.Equal(t, "guest+1@example.com", parsed.Query().Get("email"))
+	assert.Equal(t, constants.RSVPDeclined, parsed.Query().Get("response"))
+	assert.Equal(t, "true", parsed.Query().Get("series"))
+	assert.Equal(t, strconv.FormatInt(expires.Unix(), 10), parsed.Query().Get("exp"))
+	assert.NoError(t, utils.VerifyRSVP("OCCUPI1", "guest+1@example.com", expires.Unix(), parsed.Query().Get("sig"), time.Now()))
+}
+
+func TestAttendeeResponseOf(t *testing.T) {
+	booking := models.Booking{
+		Creator: "creator@example.com",
+		Emails:  []string{"creator@example.com", "guest@example.com", "other@example.com"},
+		Attendees: []models.AttendeeResponse{
+			{Email: "guest@example.com", Response: constants.RSVPTentative},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		expected string
+	}{
+		{"Attendee responded", "guest@example.com", constants.RSVPTentative},
+		{"Attendee has not responded", "other@example.com", constants.RSVPNeedsAction},
+		{"Creator accepts their own booking", "creator@example.com", constants.RSVPAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.AttendeeResponseOf(booking, tt.email))
+		})
+	}
+
+	t.Run("Invite carries the responses", func(t *testing.T) {
+		// the event lines are checked before they are folded at 75 octets
+		invite := strings.Join(utils.FormatBookingEvent(booking, constants.ICalendarRequest, time.Now()), "\n")
+
+		assert.Contains(t, invite, "PARTSTAT=TENTATIVE;RSVP=TRUE:mailto:guest@example.com")
+		assert.Contains(t, invite, "PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:other@example.com")
+		assert.Contains(t, invite, "PARTSTAT=ACCEPTED;RSVP=TRUE:mailto:creator@example.com")
+	})
+}
+
+func TestKeepAttendeeResponses(t *testing.T) {
+	attendees := []models.AttendeeResponse{
+		{Email: "guest@example.com", Response: constants.RSVPAccepted},
+		{Email: "removed@example.com", Response: constants.RSVPDeclined},
+	}
+
+	kept := utils.KeepAttendeeResponses(attendees, []string{"guest@example.com", "new@example.com"})
+
+	assert.Equal(t, []models.AttendeeResponse{{Email: "guest@example.com", Response: constants.RSVPAccepted}}, kept)
+}
+
+func TestHasCheckedIn(t *testing.T) {
+	booking := models.Booking{
+		CheckIns: []models.AttendeeCheckIn{{Email: "guest@example.com", Method: constants.CheckInNFC}},
+	}
+
+	assert.True(t, utils.HasCheckedIn(booking, "guest@example.com"))
+	assert.False(t, utils.HasCheckedIn(booking, "other@example.com"))
+}
+
+func TestIsBookingAttendee(t *testing.T) {
+	booking := models.Booking{Creator: "creator@example.com", Emails: []string{"guest@example.com"}}
+
+	tests := []struct {
+		name     string
+		email    string
+		expected bool
+	}{
+		{"Creator", "creator@example.com", true},
+		{"Invited attendee", "guest@example.com", true},
+		{"Someone else", "other@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.IsBookingAttendee(booking, tt.email))
+		})
+	}
+}
+
+func TestParseRoomQRPayload(t *testing.T) {
+	now := time.Now()
+	payload := utils.FormatRoomQRPayload("RM001", now)
+	interval := time.Duration(configs.GetQRRotationInterval()) * time.Second
+
+	t.Run("Current code", func(t *testing.T) {
+		roomID, err := utils.ParseRoomQRPayload(payload, now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "RM001", roomID)
+	})
+
+	t.Run("Code scanned just after it rotated", func(t *testing.T) {
+		roomID, err := utils.ParseRoomQRPayload(payload, now.Add(interval))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "RM001", roomID)
+	})
+
+	t.Run("Expired code", func(t *testing.T) {
+		_, err := utils.ParseRoomQRPayload(payload, now.Add(3*interval))
+
+		assert.EqualError(t, err, "qr code has expired")
+	})
+
+	t.Run("Room id changed", func(t *testing.T) {
+		_, err := utils.ParseRoomQRPayload(strings.Replace(payload, "RM001", "RM002", 1), now)
+
+		assert.EqualError(t, err, "invalid qr code")
+	})
+
+	t.Run("Not an occupi code", func(t *testing.T) {
+		_, err := utils.ParseRoomQRPayload("https://example.com", now)
+
+		assert.EqualError(t, err, "invalid qr code")
+	})
+}
+
+func TestRenderQRCode(t *testing.T) {
+	payload := utils.FormatRoomQRPayload("RM001", time.Now())
+
+	t.Run("PNG", func(t *testing.T) {
+		png, err := utils.RenderQRCodePNG(payload, 256)
+
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(png, []byte("\x89PNG")))
+	})
+
+	t.Run("SVG", func(t *testing.T) {
+		svg, err := utils.RenderQRCodeSVG(payload)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(svg, "<svg"))
+		assert.Contains(t, svg, "h1v1h-1z")
+	})
+}
+
+func TestNotificationMessage(t *testing.T) {
+	notification := models.ScheduledNotification{
+		ID:                   "abc",
+		NotiID:               "noti1",
+		Title:                "Booking Starting Soon | Boardroom",
+		Message:              "Starts in 15 mins, bring snacks, coffee",
+		SendTime:             time.Date(2024, 7, 22, 9, 45, 0, 0, time.UTC),
+		UnsentExpoPushTokens: []string{"ExponentPushToken[a]", "ExponentPushToken[b]"},
+		Emails:               []string{"a@example.com", "b@example.com"},
+		UnreadEmails:         []string{"a@example.com"},
+	}
+
+	t.Run("Round trip keeps delimiters in text", func(t *testing.T) {
+		body, err := utils.EncodeNotificationMessage(notification)
+		assert.NoError(t, err)
+
+		decoded, err := utils.DecodeNotificationMessage(body, constants.NotificationContentType)
+
+		assert.NoError(t, err)
+		assert.Equal(t, notification.Title, decoded.Title)
+		assert.Equal(t, notification.Message, decoded.Message)
+		assert.True(t, notification.SendTime.Equal(decoded.SendTime))
+		assert.Equal(t, notification.UnsentExpoPushTokens, decoded.UnsentExpoPushTokens)
+		assert.Equal(t, notification.UnreadEmails, decoded.UnreadEmails)
+	})
+
+	t.Run("Legacy format", func(t *testing.T) {
+		body := "abc|noti1|Title|Message|2024-07-22T09:45:00Z|ExponentPushToken[a]|a@example.com,b@example.com|a@example.com"
+
+		decoded, err := utils.DecodeNotificationMessage([]byte(body), "text/plain")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "noti1", decoded.NotiID)
+		assert.Equal(t, []string{"a@example.com", "b@example.com"}, decoded.Emails)
+	})
+
+	t.Run("Legacy format with a delimiter in the title", func(t *testing.T) {
+		body := "abc|noti1|A | B|Message|2024-07-22T09:45:00Z|token|a@example.com|a@example.com"
+
+		_, err := utils.DecodeNotificationMessage([]byte(body), "text/plain")
+
+		assert.EqualError(t, err, "legacy notification message has 9 fields, expected 8")
+	})
+
+	t.Run("Unsupported version", func(t *testing.T) {
+		body := `{"version":2,"type":"scheduledNotification","payload":{}}`
+
+		_, err := utils.DecodeNotificationMessage([]byte(body), constants.NotificationContentType)
+
+		assert.EqualError(t, err, "unsupported notification message version 2")
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		body := `{"version":1,"type":"booking","payload":{}}`
+
+		_, err := utils.DecodeNotificationMessage([]byte(body), constants.NotificationContentType)
+
+		assert.EqualError(t, err, `unknown notification message type "booking"`)
+	})
+
+	t.Run("Missing send time", func(t *testing.T) {
+		body := `{"version":1,"type":"scheduledNotification","payload":{"title":"Hi"}}`
+
+		_, err := utils.DecodeNotificationMessage([]byte(body), constants.NotificationContentType)
+
+		assert.EqualError(t, err, "notification message has no send time")
+	})
+
+	t.Run("Invalid json", func(t *testing.T) {
+		_, err := utils.DecodeNotificationMessage([]byte(`{"version":`), constants.NotificationContentType)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNotificationHeaders(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		notBefore := time.Date(2024, 7, 22, 9, 45, 0, 0, time.UTC)
+
+		attempt, decoded := utils.DecodeNotificationHeaders(utils.EncodeNotificationHeaders(3, notBefore))
+
+		assert.Equal(t, 3, attempt)
+		assert.True(t, notBefore.Equal(decoded))
+	})
+
+	t.Run("First attempt", func(t *testing.T) {
+		attempt, notBefore := utils.DecodeNotificationHeaders(nil)
+
+		assert.Equal(t, 0, attempt)
+		assert.True(t, notBefore.IsZero())
+	})
+}
+
+func TestNotificationRetryDelay(t *testing.T) {
+	base := 30 * time.Second
+
+	assert.Equal(t, 30*time.Second, utils.NotificationRetryDelay(1, base))
+	assert.Equal(t, time.Minute, utils.NotificationRetryDelay(2, base))
+	assert.Equal(t, 4*time.Minute, utils.NotificationRetryDelay(4, base))
+	assert.Equal(t, time.Hour, utils.NotificationRetryDelay(20, base))
+}
+
+func TestNotificationDelayStep(t *testing.T) {
+	steps := []int{60, 600, 3600}
+
+	assert.Equal(t, 0, utils.NotificationDelayStep(30*time.Second, steps))
+	assert.Equal(t, 60, utils.NotificationDelayStep(5*time.Minute, steps))
+	assert.Equal(t, 600, utils.NotificationDelayStep(10*time.Minute, steps))
+	assert.Equal(t, 3600, utils.NotificationDelayStep(48*time.Hour, steps))
+	assert.Equal(t, 0, utils.NotificationDelayStep(-time.Minute, steps))
+}
+
+func TestIsNotificationStale(t *testing.T) {
+	now := time.Now()
+	staleAfter := time.Hour
+
+	assert.False(t, utils.IsNotificationStale(models.ScheduledNotification{SendTime: now.Add(time.Minute)}, now, staleAfter))
+	assert.False(t, utils.IsNotificationStale(models.ScheduledNotification{SendTime: now.Add(-10 * time.Minute)}, now, staleAfter))
+	assert.True(t, utils.IsNotificationStale(models.ScheduledNotification{SendTime: now.Add(-2 * time.Hour)}, now, staleAfter))
+}
+
+func TestNotificationChannels(t *testing.T) {
+	user := models.User{Notifications: models.Notifications{
+		Invites:         true,
+		BookingReminder: true,
+		Channels: map[string][]string{
+			constants.NotificationInvite: {constants.ChannelEmail, constants.ChannelWebhook},
+		},
+	}}
+
+	t.Run("Chosen channels", func(t *testing.T) {
+		assert.Equal(t, []string{constants.ChannelEmail, constants.ChannelWebhook}, utils.NotificationChannels(user, constants.NotificationInvite))
+	})
+
+	t.Run("Defaults to push", func(t *testing.T) {
+		assert.Equal(t, []string{constants.ChannelPush}, utils.NotificationChannels(user, constants.NotificationUpdate))
+	})
+
+	t.Run("Reminders turned off", func(t *testing.T) {
+		off := user
+		off.Notifications.BookingReminder = false
+
+		assert.Empty(t, utils.NotificationChannels(off, constants.NotificationReminder))
+		assert.NotEmpty(t, utils.NotificationChannels(off, constants.NotificationInvite))
+	})
+
+	t.Run("Notifications turned off", func(t *testing.T) {
+		off := user
+		off.Notifications.Invites = false
+
+		assert.Empty(t, utils.NotificationChannels(off, constants.NotificationInvite))
+	})
+}
+
+func TestValidateNotificationChannels(t *testing.T) {
+	assert.NoError(t, utils.ValidateNotificationChannels(map[string][]string{
+		constants.NotificationReminder: {constants.ChannelPush, constants.ChannelWebPush},
+		constants.NotificationUpdate:   {},
+	}))
+	assert.EqualError(t, utils.ValidateNotificationChannels(map[string][]string{"birthday": {constants.ChannelPush}}), `unknown notification type "birthday"`)
+	assert.EqualError(t, utils.ValidateNotificationChannels(map[string][]string{constants.NotificationInvite: {"sms"}}), `unknown notification channel "sms"`)
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	assert.NoError(t, utils.ValidateWebhookURL(""))
+	assert.NoError(t, utils.ValidateWebhookURL("https://example.com/hooks/occupi"))
+	assert.EqualError(t, utils.ValidateWebhookURL("http://example.com/hooks/occupi"), "webhookUrl must use https")
+	assert.EqualError(t, utils.ValidateWebhookURL("not a url"), "webhookUrl must be a valid url")
+	assert.EqualError(t, utils.ValidateWebhookURL("https://localhost/hooks"), "webhookUrl must not point to a private address")
+	assert.EqualError(t, utils.ValidateWebhookURL("https://127.0.0.1/hooks"), "webhookUrl must not point to a private address")
+	assert.EqualError(t, utils.ValidateWebhookURL("https://10.0.0.5/hooks"), "webhookUrl must not point to a private address")
+	assert.EqualError(t, utils.ValidateWebhookURL("https://169.254.169.254/latest"), "webhookUrl must not point to a private address")
+	assert.EqualError(t, utils.ValidateWebhookURL("https://[::1]/hooks"), "webhookUrl must not point to a private address")
+}
+
+func TestValidateWebPushEndpoint(t *testing.T) {
+	assert.NoError(t, utils.ValidateWebPushEndpoint("https://fcm.googleapis.com/fcm/send/abc"))
+	assert.EqualError(t, utils.ValidateWebPushEndpoint(""), "endpoint must be a valid url")
+	assert.EqualError(t, utils.ValidateWebPushEndpoint("http://fcm.googleapis.com/fcm/send/abc"), "endpoint must use https")
+	assert.EqualError(t, utils.ValidateWebPushEndpoint("https://localhost/push"), "endpoint must not point to a private address")
+	assert.EqualError(t, utils.ValidateWebPushEndpoint("https://169.254.169.254/latest"), "endpoint must not point to a private address")
+}
+
+func TestIsPublicIP(t *testing.T) {
+	assert.True(t, utils.IsPublicIP(net.ParseIP("93.184.216.34")))
+	assert.True(t, utils.IsPublicIP(net.ParseIP("2606:2800:220:1:248:1893:25c8:1946")))
+	assert.False(t, utils.IsPublicIP(net.ParseIP("127.0.0.1")))
+	assert.False(t, utils.IsPublicIP(net.ParseIP("192.168.1.10")))
+	assert.False(t, utils.IsPublicIP(net.ParseIP("172.16.0.1")))
+	assert.False(t, utils.IsPublicIP(net.ParseIP("169.254.169.254")))
+	assert.False(t, utils.IsPublicIP(net.ParseIP("0.0.0.0")))
+	assert.False(t, utils.IsPublicIP(net.ParseIP("fe80::1")))
+	assert.False(t, utils.IsPublicIP(net.ParseIP("::ffff:127.0.0.1")))
+}
+
+func TestActivePushTokens(t *testing.T) {
+	since := time.Now().AddDate(0, 0, -90)
+
+	user := models.User{
+		ExpoPushToken: "ExponentPushToken[phone]",
+		Devices: []models.Device{
+			{Token: "ExponentPushToken[phone]", Platform: "iOS", LastSeen: time.Now()},
+			{Token: "ExponentPushToken[tablet]", Platform: "Android", LastSeen: time.Now().AddDate(0, 0, -1)},
+			{Token: "ExponentPushToken[old]", Platform: "Android", LastSeen: time.Now().AddDate(0, 0, -120)},
+		},
+	}
+
+	assert.Equal(t, []string{"ExponentPushToken[phone]", "ExponentPushToken[tablet]"}, utils.ActivePushTokens(user, since))
+	assert.Empty(t, utils.ActivePushTokens(models.User{}, since))
+
+	user.ExpoPushToken = ""
+	assert.Equal(t, []string{"ExponentPushToken[phone]", "ExponentPushToken[tablet]"}, utils.ActivePushTokens(user, since))
+}